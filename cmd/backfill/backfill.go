@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"github.com/dartkron/leetcodeBot/v3/internal/backfill"
+	"github.com/dartkron/leetcodeBot/v3/internal/common"
+	"github.com/dartkron/leetcodeBot/v3/internal/config"
+	"github.com/dartkron/leetcodeBot/v3/internal/logging"
+	"github.com/dartkron/leetcodeBot/v3/internal/storage"
+	"github.com/dartkron/leetcodeBot/v3/pkg/leetcodeclient"
+	"github.com/dartkron/leetcodeBot/v3/pkg/metrics"
+	"github.com/dartkron/leetcodeBot/v3/pkg/tracing"
+)
+
+// Saves all daily tasks missing in the database. Requires the same YDB settings as the bot
+func main() {
+	configLoader := config.NewLoader(flag.CommandLine)
+	from := flag.String("from", common.FormatDateID(common.FirstDailyTaskDateID), "date in YYYY-MM-DD format to start from")
+	interval := flag.Duration("interval", backfill.DefaultInterval, "pause between requests to LeetCode API")
+	statePath := flag.String("state", "backfill.state", "file to resume from the last not finished month, empty to disable")
+	flag.Parse()
+	cfg, err := configLoader.Load(config.KeyYDBEndpoint, config.KeyYDBDatabase)
+	if err != nil {
+		slog.Error("Wrong config", "error", err)
+		os.Exit(2)
+	}
+	// The tool is run locally, so the text is easier to read
+	if cfg.LogFormat == "" {
+		cfg.LogFormat = logging.FormatText
+	}
+	logger, err := logging.Setup(cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		slog.Error("Wrong logging config", "error", err)
+		os.Exit(2)
+	}
+
+	err = tracing.Setup(cfg.TracingExporter, cfg.OTLPEndpoint)
+	if err != nil {
+		logger.Error("Wrong tracing config", "error", err)
+		os.Exit(2)
+	}
+
+	if cfg.MetricsAddress != "" {
+		go serveMetrics(logger, cfg.MetricsAddress)
+	}
+
+	fromDateID, err := common.ParseDateID(*from)
+	if err != nil {
+		logger.Error("Wrong -from date, it should be in YYYY-MM-DD format", "from", *from)
+		os.Exit(2)
+	}
+	backfiller := backfill.NewBackfiller(storage.NewTracingController(storage.NewYDBController(cfg)), leetcodeclient.NewLeetCodeGraphQlClientWithURL(cfg.LeetcodeGraphQlURL))
+	backfiller.Interval = *interval
+	backfiller.StatePath = *statePath
+	stateDateID, err := backfiller.ReadState()
+	if err != nil {
+		logger.Error("Error on reading backfill state", "error", err)
+		os.Exit(1)
+	}
+	if stateDateID > fromDateID {
+		logger.Info("Resuming from the state", "date", common.FormatDateID(stateDateID))
+		fromDateID = stateDateID
+	}
+
+	ctx, cancelFunc := signal.NotifyContext(logging.WithCorrelation(context.Background(), logger, "run", "backfill"), os.Interrupt)
+	defer cancelFunc()
+	stats, err := backfiller.Run(ctx, fromDateID)
+	// Batches of spans are exported during the run, so only the rest is left
+	flushErr := tracing.Shutdown(context.Background())
+	if flushErr != nil {
+		logger.Error("Error on exporting traces", "error", flushErr)
+	}
+	logger.Info("Finished", "saved", stats.Saved, "stored", stats.Stored, "failed", stats.Failed)
+	if err != nil {
+		logger.Error("Backfill is stopped by error", "error", err)
+		os.Exit(1)
+	}
+}
+
+// serveMetrics serves Prometheus metrics on /metrics while the backfill is running
+func serveMetrics(logger *slog.Logger, address string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	err := http.ListenAndServe(address, mux)
+	logger.Error("Metrics server is stopped", "address", address, "error", err)
+}
@@ -2,32 +2,70 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/dartkron/leetcodeBot/v3/internal/bot"
+	"github.com/dartkron/leetcodeBot/v3/internal/config"
+	"github.com/dartkron/leetcodeBot/v3/internal/logging"
+	"github.com/dartkron/leetcodeBot/v3/pkg/tracing"
 )
 
+// tracesFlushTimeout limits exporting of spans after the response
+const tracesFlushTimeout = 10 * time.Second
+
 // Handler for Yandex.Function requests
 func Handler(resp http.ResponseWriter, req *http.Request) {
 	resp.Header().Set("Content-Type", "application/json")
 	bodyBytes, err := io.ReadAll(req.Body)
 	if err != nil {
-		fmt.Println("Error on reading request body:", err)
+		slog.Error("Error on reading request body", "error", err)
 		return
 	}
-	app := bot.NewApplication(nil)
-	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Duration(5)*time.Second)
-	defer cancelFunc()
-	responseBytes, err := app.ProcessRequestBody(ctx, bodyBytes)
+	cfg, err := config.Load(config.KeySendingToken, config.KeyYDBEndpoint, config.KeyYDBDatabase)
+	if err != nil {
+		slog.Error("Sending 500 error in response, because of the wrong config", "error", err)
+		resp.WriteHeader(500)
+		return
+	}
+	logger, err := logging.Setup(cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		slog.Error("Sending 500 error in response, because of the wrong logging config", "error", err)
+		resp.WriteHeader(500)
+		return
+	}
+	err = tracing.Setup(cfg.TracingExporter, cfg.OTLPEndpoint)
+	if err != nil {
+		logger.Error("Sending 500 error in response, because of the wrong tracing config", "error", err)
+		resp.WriteHeader(500)
+		return
+	}
+	// Default deduplicator is kept between requests, so the warm function instance remembers processed updates
+	app := bot.NewApplication(cfg, bot.WithLogger(logger))
+	// Timeouts are set by the application: slow requests get the placeholder in RESPONSE_TIMEOUT_SECONDS and are answered when processed
+	responseBytes, err := app.ProcessRequestBody(context.Background(), bodyBytes)
 	if err != nil {
-		fmt.Println("Sending 500 error in response, because got error from bot.ProcessRequestBody", err)
+		logger.Error("Sending 500 error in response, because got error from bot.ProcessRequestBody", "error", err)
 		resp.WriteHeader(500)
 		resp.Write([]byte(err.Error()))
 	} else {
 		resp.WriteHeader(200)
 	}
 	resp.Write(responseBytes)
+	if cfg.EarlyAnswerEnabled {
+		// The early answer reaches Telegram before the return only if the server sends flushed responses.
+		// The function could be frozen after the return, so follow-ups of slow requests are awaited here
+		if flusher, ok := resp.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		app.WaitFollowUps()
+	}
+	flushCtx, cancelFunc := context.WithTimeout(context.Background(), tracesFlushTimeout)
+	defer cancelFunc()
+	err = tracing.Flush(flushCtx)
+	if err != nil {
+		logger.Error("Error on exporting traces", "error", err)
+	}
 }
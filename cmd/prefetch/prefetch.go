@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/dartkron/leetcodeBot/v3/internal/bot"
+	"github.com/dartkron/leetcodeBot/v3/internal/config"
+	"github.com/dartkron/leetcodeBot/v3/internal/logging"
+	"github.com/dartkron/leetcodeBot/v3/pkg/tracing"
+)
+
+// tracesFlushTimeout limits exporting of spans before the return
+const tracesFlushTimeout = 10 * time.Second
+
+// Response type for simplified response
+type Response struct {
+	StatusCode int         `json:"statusCode"`
+	Body       interface{} `json:"body"`
+}
+
+// Handler for Yandex.Function requests. Should be triggered shortly after 00:00 UTC,
+// it retries till today's daily task is saved or the function timeout
+func Handler(ctx context.Context) (*Response, error) {
+	response := &Response{
+		StatusCode: 200,
+		Body:       "",
+	}
+	cfg, err := config.Load(config.KeySendingToken, config.KeyYDBEndpoint, config.KeyYDBDatabase)
+	if err != nil {
+		response.StatusCode = 500
+		response.Body = err.Error()
+		return response, err
+	}
+	logger, err := logging.Setup(cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		response.StatusCode = 500
+		response.Body = err.Error()
+		return response, err
+	}
+	err = tracing.Setup(cfg.TracingExporter, cfg.OTLPEndpoint)
+	if err != nil {
+		response.StatusCode = 500
+		response.Body = err.Error()
+		return response, err
+	}
+	// All runs of the invocation share the correlation ID and the trace
+	ctx = logging.WithCorrelation(ctx, logger, "run", "prefetch")
+	ctx, span := tracing.Start(ctx, "prefetch.Handler")
+	defer flushTraces(ctx, span)
+	app := bot.NewApplication(cfg, bot.WithLogger(logger))
+	err = app.WarmUpTodayTask(ctx)
+	span.RecordError(err)
+	if err != nil {
+		response.StatusCode = 500
+		response.Body = err.Error()
+		return response, err
+	}
+	response.Body = "Finished"
+	return response, nil
+}
+
+// flushTraces ends the span of the run and exports all spans, as the function could be frozen after the return
+func flushTraces(ctx context.Context, span *tracing.Span) {
+	span.End()
+	flushCtx, cancelFunc := context.WithTimeout(context.WithoutCancel(ctx), tracesFlushTimeout)
+	defer cancelFunc()
+	err := tracing.Flush(flushCtx)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error on exporting traces", "error", err)
+	}
+}
@@ -2,8 +2,19 @@ package main
 
 import (
 	"context"
+	"time"
 
 	"github.com/dartkron/leetcodeBot/v3/internal/bot"
+	"github.com/dartkron/leetcodeBot/v3/internal/config"
+	"github.com/dartkron/leetcodeBot/v3/internal/logging"
+	"github.com/dartkron/leetcodeBot/v3/pkg/metrics"
+	"github.com/dartkron/leetcodeBot/v3/pkg/tracing"
+)
+
+const (
+	metricsJob         = "leetcodebot_reminder"
+	metricsPushTimeout = 10 * time.Second
+	tracesFlushTimeout = 10 * time.Second
 )
 
 // Response type for simplified response
@@ -18,13 +29,79 @@ func Handler(ctx context.Context) (*Response, error) {
 		StatusCode: 200,
 		Body:       "",
 	}
-	app := bot.NewApplication(nil)
-	err := app.SendDailyTaskToSubscribedUsers(ctx)
+	cfg, err := config.Load(config.KeySendingToken, config.KeyYDBEndpoint, config.KeyYDBDatabase)
+	if err != nil {
+		response.StatusCode = 500
+		response.Body = err.Error()
+		return response, err
+	}
+	logger, err := logging.Setup(cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		response.StatusCode = 500
+		response.Body = err.Error()
+		return response, err
+	}
+	err = tracing.Setup(cfg.TracingExporter, cfg.OTLPEndpoint)
+	if err != nil {
+		response.StatusCode = 500
+		response.Body = err.Error()
+		return response, err
+	}
+	// All runs of the invocation share the correlation ID and the trace
+	ctx = logging.WithCorrelation(ctx, logger, "run", "reminder")
+	ctx, span := tracing.Start(ctx, "reminder.Handler")
+	defer flushTraces(ctx, span)
+	app := bot.NewApplication(cfg, bot.WithLogger(logger))
+	// Daily tasks are sent only from the storage, so try to save today's one if the prefetch hasn't done it yet
+	warmUpCtx, cancelFunc := context.WithTimeout(ctx, bot.WarmUpTimeout)
+	defer cancelFunc()
+	err = app.WarmUpTodayTask(warmUpCtx)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error on warming up today's daily task", "error", err)
+	}
+	summary, err := app.SendDailyTaskToSubscribedUsers(ctx)
+	// Nudges are independent from daily tasks, so try to send them anyway
+	nudgeErr := app.SendNudgesToLinkedUsers(ctx)
+	if err == nil {
+		err = nudgeErr
+	}
+	// Queued messages are sent only after all of them are saved, the rest is retried by the next run
+	stats, dispatchErr := app.DispatchOutbox(ctx)
+	logging.FromContext(ctx).Info("Outbox is dispatched", "sent", stats.Sent, "retried", stats.Retried, "deadLettered", stats.DeadLettered)
+	pushMetrics(ctx, cfg)
+	if err == nil {
+		err = dispatchErr
+	}
+	span.RecordError(err)
 	if err != nil {
 		response.StatusCode = 500
 		response.Body = err.Error()
 		return response, err
 	}
-	response.Body = "Finished"
+	response.Body = summary
 	return response, nil
 }
+
+// pushMetrics pushes metrics of the run to Pushgateway, as the function can't be scraped. Errors don't fail the run
+func pushMetrics(ctx context.Context, cfg *config.Config) {
+	if cfg.MetricsPushURL == "" {
+		return
+	}
+	pushCtx, cancelFunc := context.WithTimeout(ctx, metricsPushTimeout)
+	defer cancelFunc()
+	err := metrics.Push(pushCtx, nil, cfg.MetricsPushURL, metricsJob)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error on pushing metrics", "error", err)
+	}
+}
+
+// flushTraces ends the span of the run and exports all spans, as the function could be frozen after the return
+func flushTraces(ctx context.Context, span *tracing.Span) {
+	span.End()
+	flushCtx, cancelFunc := context.WithTimeout(context.WithoutCancel(ctx), tracesFlushTimeout)
+	defer cancelFunc()
+	err := tracing.Flush(flushCtx)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error on exporting traces", "error", err)
+	}
+}
@@ -62,8 +62,13 @@ def main() -> None:
     deploys: List[Dict[str, Any]] = [
         {'targetFunctionId': os.getenv('TARGET_FUNCTION_ID'), 'archiveName': 'bot.zip', 'slService': slService, 'sdk': sdk},
         {'targetFunctionId': os.getenv('REMINDER_FUNCTION_ID'), 'archiveName': 'reminder.zip', 'slService': slService, 'sdk': sdk},
+        {'targetFunctionId': os.getenv('PREFETCH_FUNCTION_ID'), 'archiveName': 'prefetch.zip', 'slService': slService, 'sdk': sdk},
     ]
     for params in deploys:
+        # Prefetch function is optional, the reminder warms up the daily task too
+        if not params['targetFunctionId']:
+            logging.info('Function for %s is not set, skipping', params['archiveName'])
+            continue
         deployFunction(**params)
 
 if __name__ == '__main__':
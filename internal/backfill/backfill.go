@@ -0,0 +1,164 @@
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dartkron/leetcodeBot/v3/internal/common"
+	"github.com/dartkron/leetcodeBot/v3/internal/logging"
+	"github.com/dartkron/leetcodeBot/v3/internal/storage"
+	"github.com/dartkron/leetcodeBot/v3/pkg/leetcodeclient"
+)
+
+// DefaultInterval is the default pause between requests to LeetCode API, to be polite with it
+const DefaultInterval = 2 * time.Second
+
+// Stats is an amount of daily tasks processed by the backfill
+type Stats struct {
+	Saved  int
+	Stored int
+	Failed int
+}
+
+// Backfiller saves daily tasks missing in the storage from LeetCode API.
+// StatePath is a file with dateID of the month to resume from, the state isn't saved when it's empty
+type Backfiller struct {
+	storageController storage.Controller
+	leetcodeAPIClient leetcodeclient.LeetcodeClient
+	Interval          time.Duration
+	StatePath         string
+	Output            io.Writer
+	Clock             common.Clock
+	lastRequest       time.Time
+}
+
+// wait makes pause after the previous request to LeetCode API, so there are not more than one request per Interval
+func (b *Backfiller) wait(ctx context.Context) error {
+	pause := time.Until(b.lastRequest.Add(b.Interval))
+	if pause > 0 {
+		select {
+		case <-ctx.Done():
+			return common.ErrClosedContext
+		case <-time.After(pause):
+		}
+	}
+	b.lastRequest = time.Now()
+	return nil
+}
+
+// ReadState returns dateID of the month to resume from, zero means there is no saved state
+func (b *Backfiller) ReadState() (uint64, error) {
+	if b.StatePath == "" {
+		return 0, nil
+	}
+	content, err := os.ReadFile(b.StatePath)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+}
+
+func (b *Backfiller) saveState(monthDateID uint64) error {
+	if b.StatePath == "" {
+		return nil
+	}
+	return os.WriteFile(b.StatePath, []byte(strconv.FormatUint(monthDateID, 10)), 0644)
+}
+
+// Run walks months from the fromDateID month till today and saves daily tasks which aren't in the storage yet.
+// The state moves to the next month only when all tasks of the month are stored, so the next run retries failed ones.
+// The current month is never finished, because new daily tasks are still coming
+func (b *Backfiller) Run(ctx context.Context, fromDateID uint64) (Stats, error) {
+	stats := Stats{}
+	today := common.GetDateIDForNow(b.Clock)
+	allStored := true
+	for month := common.GetDateFromDateID(common.GetMonthDateID(fromDateID)); common.GetDateID(month) <= today; month = month.AddDate(0, 1, 0) {
+		monthStats, err := b.backfillMonth(ctx, month, fromDateID, today)
+		stats.Saved += monthStats.Saved
+		stats.Stored += monthStats.Stored
+		stats.Failed += monthStats.Failed
+		if err != nil {
+			return stats, err
+		}
+		fmt.Fprintf(b.Output, "%s: %d saved, %d already stored, %d failed\n", month.Format("2006-01"), monthStats.Saved, monthStats.Stored, monthStats.Failed)
+		allStored = allStored && monthStats.Failed == 0
+		nextMonthDateID := common.GetDateID(month.AddDate(0, 1, 0))
+		if allStored && nextMonthDateID <= today {
+			err = b.saveState(nextMonthDateID)
+			if err != nil {
+				logging.FromContext(ctx).Error("Error on saving backfill state", "error", err)
+			}
+		}
+	}
+	return stats, nil
+}
+
+// backfillMonth saves daily tasks of the month. Only storage errors stop the backfill, LeetCode ones are counted as failed
+func (b *Backfiller) backfillMonth(ctx context.Context, month time.Time, fromDateID uint64, today uint64) (Stats, error) {
+	stats := Stats{}
+	err := b.wait(ctx)
+	if err != nil {
+		return stats, err
+	}
+	challenges, err := b.leetcodeAPIClient.GetMonthlyChallenges(ctx, month)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error on getting daily tasks of the month", "month", month.Format("2006-01"), "error", err)
+		stats.Failed++
+		return stats, nil
+	}
+	for _, challenge := range challenges {
+		dateID := common.GetDateID(challenge.Date)
+		if dateID < fromDateID || dateID > today {
+			continue
+		}
+		_, err := b.storageController.GetTask(ctx, dateID)
+		if err == nil {
+			stats.Stored++
+			continue
+		}
+		if err != storage.ErrNoSuchTask {
+			logging.FromContext(ctx).Error("Error on getting task", "dateID", dateID, "error", err)
+		}
+		err = b.wait(ctx)
+		if err != nil {
+			return stats, err
+		}
+		lcTask, err := b.leetcodeAPIClient.GetQuestionDetailsByTitleSlug(ctx, challenge.TitleSlug)
+		if err != nil {
+			logging.FromContext(ctx).Error("Error on getting daily task", "date", common.FormatDateID(dateID), "titleSlug", challenge.TitleSlug, "error", err)
+			stats.Failed++
+			continue
+		}
+		task := common.BotLeetCodeTask{
+			LeetCodeTask: lcTask,
+			DateID:       dateID,
+		}
+		task.FixTagsAndImages()
+		err = b.storageController.SaveTask(ctx, task)
+		if err != nil {
+			return stats, err
+		}
+		stats.Saved++
+		fmt.Fprintf(b.Output, "%s: saved %s\n", common.FormatDateID(dateID), task.TitleSlug)
+	}
+	return stats, nil
+}
+
+// NewBackfiller constructs Backfiller with DefaultInterval which prints progress to stdout
+func NewBackfiller(storageController storage.Controller, leetcodeAPIClient leetcodeclient.LeetcodeClient) *Backfiller {
+	return &Backfiller{
+		storageController: storageController,
+		leetcodeAPIClient: leetcodeAPIClient,
+		Interval:          DefaultInterval,
+		Output:            os.Stdout,
+		Clock:             common.SystemClock{},
+	}
+}
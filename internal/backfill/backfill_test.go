@@ -0,0 +1,142 @@
+package backfill
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dartkron/leetcodeBot/v3/internal/common"
+	"github.com/dartkron/leetcodeBot/v3/internal/storage"
+	"github.com/dartkron/leetcodeBot/v3/pkg/leetcodeclient"
+	lcclientmocks "github.com/dartkron/leetcodeBot/v3/pkg/leetcodeclient/mocks"
+	"github.com/dartkron/leetcodeBot/v3/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockStorageController implements only tasks methods used by the backfill
+type MockStorageController struct {
+	storage.Controller
+	tasks        map[uint64]common.BotLeetCodeTask
+	callsJournal []string
+	failedTaskID uint64
+}
+
+func (controller *MockStorageController) GetTask(ctx context.Context, dateID uint64) (common.BotLeetCodeTask, error) {
+	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("GetTask %d", dateID))
+	if task, ok := controller.tasks[dateID]; ok {
+		return task, nil
+	}
+	return common.BotLeetCodeTask{}, storage.ErrNoSuchTask
+}
+
+func (controller *MockStorageController) SaveTask(ctx context.Context, task common.BotLeetCodeTask) error {
+	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("SaveTask %d", task.DateID))
+	if task.DateID == controller.failedTaskID {
+		return tests.ErrBypassTest
+	}
+	controller.tasks[task.DateID] = task
+	return nil
+}
+
+func getTestBackfiller(t *testing.T) (*MockStorageController, *lcclientmocks.MockLeetcodeClient, *bytes.Buffer, *Backfiller) {
+	storageController := &MockStorageController{tasks: map[uint64]common.BotLeetCodeTask{}}
+	leetcodeClient := &lcclientmocks.MockLeetcodeClient{}
+	output := &bytes.Buffer{}
+	backfiller := NewBackfiller(storageController, leetcodeClient)
+	backfiller.Interval = 0
+	backfiller.Output = output
+	backfiller.StatePath = filepath.Join(t.TempDir(), "backfill.state")
+	backfiller.Clock = tests.NewFakeClock(time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC))
+	return storageController, leetcodeClient, output, backfiller
+}
+
+func TestRun(t *testing.T) {
+	storageController, leetcodeClient, output, backfiller := getTestBackfiller(t)
+	currentMonth := common.GetDateFromDateID(common.GetMonthDateID(common.GetDateIDForNow(backfiller.Clock)))
+	previousMonth := currentMonth.AddDate(0, -1, 0)
+	previousMonthDateID := common.GetDateID(previousMonth)
+	currentMonthDateID := common.GetDateID(currentMonth)
+	tomorrow := common.GetDateInRightTimeZone(backfiller.Clock).AddDate(0, 0, 1)
+	leetcodeClient.On("GetMonthlyChallenges", previousMonthDateID).Return([]leetcodeclient.DailyChallenge{
+		{Date: previousMonth, TitleSlug: "two-sum", Difficulty: "Easy"},
+		{Date: previousMonth.AddDate(0, 0, 1), TitleSlug: "3sum", Difficulty: "Medium"},
+		{Date: previousMonth.AddDate(0, 0, 2), TitleSlug: "trapping-rain-water", Difficulty: "Hard"},
+	}, nil)
+	leetcodeClient.On("GetMonthlyChallenges", currentMonthDateID).Return([]leetcodeclient.DailyChallenge{
+		{Date: currentMonth, TitleSlug: "4sum", Difficulty: "Medium"},
+		{Date: tomorrow, TitleSlug: "future", Difficulty: "Easy"},
+	}, nil)
+	storageController.tasks[previousMonthDateID] = common.BotLeetCodeTask{DateID: previousMonthDateID}
+	threeSum := leetcodeclient.LeetCodeTask{QuestionID: 15, TitleSlug: "3sum", Title: "3Sum", Content: "<p>Test content</p>"}
+	leetcodeClient.On("GetQuestionDetailsByTitleSlug", "3sum").Return(threeSum, nil).Once()
+	leetcodeClient.On("GetQuestionDetailsByTitleSlug", "trapping-rain-water").Return(leetcodeclient.LeetCodeTask{}, tests.ErrBypassTest).Once()
+	leetcodeClient.On("GetQuestionDetailsByTitleSlug", "4sum").Return(leetcodeclient.LeetCodeTask{QuestionID: 18, TitleSlug: "4sum"}, nil).Once()
+
+	stats, err := backfiller.Run(context.Background(), previousMonthDateID)
+	assert.Nil(t, err, "Unexpected Run error")
+	assert.Equal(t, Stats{Saved: 2, Stored: 1, Failed: 1}, stats, "Unexpected backfill stats")
+	savedTask := common.BotLeetCodeTask{LeetCodeTask: threeSum, DateID: common.GetDateID(previousMonth.AddDate(0, 0, 1))}
+	savedTask.FixTagsAndImages()
+	assert.Equal(t, savedTask, storageController.tasks[savedTask.DateID], "Unexpected saved task")
+	assert.Contains(t, output.String(), common.FormatDateID(savedTask.DateID)+": saved 3sum\n", "Saved task should be reported")
+	assert.Contains(t, output.String(), previousMonth.Format("2006-01")+": 1 saved, 1 already stored, 1 failed\n", "Month should be reported")
+	state, err := backfiller.ReadState()
+	assert.Nil(t, err, "Unexpected ReadState error")
+	assert.Equal(t, uint64(0), state, "Month with failed tasks shouldn't be finished")
+
+	leetcodeClient.On("GetQuestionDetailsByTitleSlug", "trapping-rain-water").Return(leetcodeclient.LeetCodeTask{QuestionID: 42, TitleSlug: "trapping-rain-water"}, nil).Once()
+	stats, err = backfiller.Run(context.Background(), previousMonthDateID)
+	assert.Nil(t, err, "Unexpected Run error")
+	assert.Equal(t, Stats{Saved: 1, Stored: 3}, stats, "Only failed task should be requested again")
+	state, err = backfiller.ReadState()
+	assert.Nil(t, err, "Unexpected ReadState error")
+	assert.Equal(t, currentMonthDateID, state, "The current month shouldn't be finished")
+	leetcodeClient.AssertExpectations(t)
+}
+
+func TestRunStorageError(t *testing.T) {
+	storageController, leetcodeClient, _, backfiller := getTestBackfiller(t)
+	month := common.GetDateFromDateID(common.GetMonthDateID(common.GetDateIDForNow(backfiller.Clock)))
+	monthDateID := common.GetDateID(month)
+	leetcodeClient.On("GetMonthlyChallenges", monthDateID).Return([]leetcodeclient.DailyChallenge{{Date: month, TitleSlug: "two-sum"}}, nil)
+	leetcodeClient.On("GetQuestionDetailsByTitleSlug", "two-sum").Return(leetcodeclient.LeetCodeTask{TitleSlug: "two-sum"}, nil)
+	storageController.failedTaskID = monthDateID
+	_, err := backfiller.Run(context.Background(), monthDateID)
+	assert.Equal(t, tests.ErrBypassTest, err, "Storage errors should stop the backfill")
+
+	leetcodeClient.On("GetMonthlyChallenges", common.GetDateID(month.AddDate(0, -1, 0))).Return([]leetcodeclient.DailyChallenge{}, tests.ErrBypassTest)
+	stats, err := backfiller.Run(context.Background(), common.GetDateID(month.AddDate(0, -1, 0)))
+	assert.Equal(t, tests.ErrBypassTest, err, "Storage errors should stop the backfill")
+	assert.Equal(t, 1, stats.Failed, "Failed month should be counted")
+}
+
+func TestWait(t *testing.T) {
+	backfiller := NewBackfiller(nil, nil)
+	backfiller.Interval = 50 * time.Millisecond
+	start := time.Now()
+	assert.Nil(t, backfiller.wait(context.Background()), "Unexpected wait error")
+	assert.Nil(t, backfiller.wait(context.Background()), "Unexpected wait error")
+	assert.GreaterOrEqual(t, time.Since(start), backfiller.Interval, "Second request should wait for the interval")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, common.ErrClosedContext, backfiller.wait(ctx), "Closed context should stop waiting")
+}
+
+func TestReadState(t *testing.T) {
+	backfiller := NewBackfiller(nil, nil)
+	state, err := backfiller.ReadState()
+	assert.Nil(t, err, "Unexpected ReadState error without state path")
+	assert.Equal(t, uint64(0), state, "Unexpected state without state path")
+	backfiller.StatePath = filepath.Join(t.TempDir(), "backfill.state")
+	assert.Nil(t, os.WriteFile(backfiller.StatePath, []byte("20240301\n"), 0644), "Unexpected WriteFile error")
+	state, err = backfiller.ReadState()
+	assert.Nil(t, err, "Unexpected ReadState error")
+	assert.Equal(t, uint64(20240301), state, "Unexpected state")
+	assert.Nil(t, os.WriteFile(backfiller.StatePath, []byte("broken"), 0644), "Unexpected WriteFile error")
+	_, err = backfiller.ReadState()
+	assert.NotNil(t, err, "Broken state should return error")
+}
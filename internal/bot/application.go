@@ -4,32 +4,126 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"io"
+	"log/slog"
+	"math/rand"
 	"net/http"
-	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/dartkron/leetcodeBot/v3/internal/common"
+	"github.com/dartkron/leetcodeBot/v3/internal/config"
+	"github.com/dartkron/leetcodeBot/v3/internal/logging"
+	"github.com/dartkron/leetcodeBot/v3/internal/outbox"
 	"github.com/dartkron/leetcodeBot/v3/internal/storage"
 	"github.com/dartkron/leetcodeBot/v3/pkg/leetcodeclient"
+	"github.com/dartkron/leetcodeBot/v3/pkg/tracing"
 )
 
 const (
 	helpMessage = `You command "%s" isn't recognized =(
 List of available commands:
 /getDailyTask — get actual dailyTask
+/task YYYY-MM-DD — get the daily task of the date
+/yesterday — get yesterday's daily task
+/calendar [YYYY-MM] — see daily tasks of the month
 /Subscribe — start automatically sending of daily tasks
-/Unsubscribe — stop automatically sending of daily tasks`
+/Unsubscribe — stop automatically sending of daily tasks
+/random [easy|medium|hard] [tag] — get random problem
+/problem [number|slug|keywords] — find problem
+/link [leetcode_username] — link LeetCode profile to see your progress
+/unlink — unlink LeetCode profile
+/progress — see your progress with the daily task and solved problems
+/nudge [hour|off] — remind at the hour if the daily task isn't solved yet
+/timezone [name|offset] — set your time zone for subscription and reminder
+/times — list your delivery times
+/addtime HH:MM [hint] — add one more delivery time of the daily task or its first hint
+/removetime HH:MM — remove the delivery time
+/weekdays — choose days of the week for delivery
+/pause YYYY-MM-DD|off — pause delivery till the date or resume it
+/settings — open settings menu`
+
+	noRandomQuestionsMessage   = "There are no free problems matching your filter. Try another difficulty or topic."
+	problemUsageMessage        = "Please, send the problem number, slug or keywords from the title after the command. For example: /problem 42 or /problem two sum"
+	noSearchResultsMessage     = "There are no free problems matching \"%s\"."
+	linkUsageMessage           = "Please, send your LeetCode username after the command. For example: /link leetcoder"
+	noLeetcodeUserMessage      = "There is no LeetCode user <strong>%s</strong>."
+	linkedMessage              = "%s, LeetCode profile <strong>%s</strong> is linked.\n\n%s"
+	unlinkedMessage            = "%s, LeetCode profile is unlinked."
+	notLinkedMessage           = "%s, you haven't linked LeetCode profile yet. Use /link leetcode_username to do it."
+	solvedCountsMessage        = "<strong>%s</strong> has solved %d problems: %d Easy, %d Medium, %d Hard."
+	dailySolvedMessage         = "✅ Today's daily task is solved by %s."
+	dailyNotSolvedMessage      = "❌ Today's daily task isn't solved by %s yet."
+	nudgeUsageMessage          = "Please, send the hour for the reminder after the command. For example: /nudge 19 or /nudge off to disable it."
+	nudgeSubscribedMessage     = "%s, you'll get a reminder at %d:00 (%s) if today's daily task isn't solved by then."
+	nudgeAlreadySubscribed     = "%s, the reminder is <strong>already set</strong> for the same time, nothing to do."
+	nudgeUnsubscribedMessage   = "%s, the reminder is <strong>disabled</strong>."
+	nudgeAlreadyUnsubscribed   = "%s, the reminder was <strong>not enabled</strong>. No additional actions required."
+	nudgeNotSolvedMessage      = "⏰ %s, today's daily task isn't solved yet. There is still time!\n\n%s"
+	nudgeSolvedMessage         = "🎉 Well done, %s! Today's daily task is solved. Your streak is %d days in a row."
+	timeZoneUsageMessage       = "Your time zone is <strong>%s</strong>. To change it, send the time zone name or UTC offset after the command. For example: /timezone Europe/Berlin or /timezone +3. Or share your location with the button below."
+	timeZoneSetMessage         = "%s, your time zone is <strong>%s</strong> now. Subscription and reminder hours are in this time zone."
+	timeZoneLocationNote       = "\n\nIt's approximated by your location and doesn't follow daylight saving time. Use /timezone Region/City for the exact one."
+	wrongTimeZoneMessage       = "Unknown time zone \"%s\". Please, use the name like Europe/Berlin or UTC offset like +3 or -05:30."
+	subscribeDialogMessage     = "Daily tasks appear each day at 00:00 UTC. For your convenience, this bot can send you tasks at any time of the day. Please, select a suitable hour or type the time like 7:30 to send a new daily task to you. Your time zone is %s, use /timezone to change it."
+	addTimeUsageMessage        = "Please, send the time after the command. For example: /addtime 7:30 to get the daily task or /addtime 13:00 hint to get its first hint."
+	removeTimeUsageMessage     = "Please, send the time to remove after the command. For example: /removetime 7:30"
+	sendingTimeAddedMessage    = "%s, you'll automatically receive the %s every day at %s (%s)."
+	sendingTimeRemovedMessage  = "%s, %s is removed from your delivery times."
+	noSuchSendingTimeMessage   = "%s, you don't have delivery at %s. Use /times to see your delivery times."
+	sendingTimeOccupiedMessage = "%s, you already have another delivery at %s. Use /removetime %s first or choose another time."
+	sendingTimesMessage        = "%s, your delivery times (%s):\n%s"
+	noSendingTimesMessage      = "%s, you aren't subscribed. Use /Subscribe or /addtime to choose the time."
+	taskKindName               = "daily task"
+	hintKindName               = "first hint of the daily task"
+	hintReminderMessage        = "💡 Hint #1 for today's daily task \"%s\": %s"
+	noHintsReminderMessage     = "💡 There are no hints for today's daily task \"%s\". Good luck!"
+	deliverySettingsMessage    = "%s, daily tasks are delivered <strong>%s</strong>. Use the buttons below to choose days of the week."
+	pausedTillMessage          = "\n\nDelivery is <strong>paused</strong> till %s, it will be resumed automatically."
+	pauseUsageMessage          = "Please, send the date to resume delivery after the command. For example: /pause 2026-11-01 or /pause off to resume it now."
+	pauseDateInPastMessage     = "%s, the date to resume delivery should be after today (%s)."
+	pausedMessage              = "%s, delivery is <strong>paused</strong> till %s (%s), it will be resumed automatically."
+	resumedMessage             = "%s, delivery is <strong>resumed</strong>."
+	notPausedMessage           = "%s, delivery was <strong>not paused</strong>. No additional actions required."
+	settingsMessage            = "⚙️ <strong>Settings</strong>\n\nDelivery time: <strong>%s</strong>\nTime zone: <strong>%s</strong>\nLanguage: <strong>English</strong>\nDifficulty: <strong>%s</strong>\nCode language: <strong>%s</strong>\nSubscription: <strong>%s</strong>"
+	sendingTimeSettings        = "Tap the hour to add or remove delivery of the daily task. Your delivery times (%s): <strong>%s</strong>.\n\nUse /addtime for other minutes and hints, /weekdays for days of the week."
+	timeZoneSettings           = "Your time zone is <strong>%s</strong>. Choose another one below or use /timezone for any other time zone."
+	languageSettings           = "The bot speaks <strong>English</strong> only for now."
+	difficultySettings         = "Difficulties of daily tasks for you: <strong>%s</strong>. Tap the difficulty to toggle it.\n\nOn other days you get <strong>%s</strong>."
+	difficultyFallbackOn       = "a random problem of your difficulty"
+	difficultyFallbackOff      = "nothing"
+	difficultyFallbackMessage  = "Today's daily task is %s, so here is a random %s problem for you instead.\n\n%s"
+	codeLanguageSettings       = "Your preferred code language: <strong>%s</strong>."
+	subscriptionSettings       = "Subscription: <strong>%s</strong>."
+	notSetSetting              = "not set"
+	subscribedSetting          = "active"
+	pausedSetting              = "paused till %s"
+	notSubscribedSetting       = "not subscribed"
+	taskUsageMessage           = "Please, send the date after the command. For example: /task 2024-03-15"
+	noDailyTaskMessage         = "There is no daily task for %s. Daily tasks are available from %s till today."
+	pastTasksLimitMessage      = "Too many requests of old daily tasks. Please, try again later."
+	pastTaskHeader             = "📅 Daily task for %s\n\n"
+	warmUpAlertMessage         = "⚠️ Daily task for %s isn't stored %s after the daily flip. Broadcasts can't be sent. Last error: %s"
+	calendarUsageMessage       = "Please, send the month after the command or nothing for the current month. For example: /calendar 2024-03"
+	noCalendarMessage          = "There are no daily tasks in %s. Daily tasks are available from %s till today."
+	calendarMessage            = "📅 Daily tasks of <strong>%s</strong>\n\n🟢 Easy 🟡 Medium 🔴 Hard"
+	calendarSolvedNote         = "\n✅ Solved by %s, only the last %d accepted submissions are checked"
+	calendarTapNote            = "\n\nTap the day to open its daily task."
+	placeholderMessage         = "⏳ Fetching, it takes a bit longer than usual…"
+	followUpErrorMessage       = "Sorry, something went wrong. Please, try again later."
+	leetcodeUnavailableMessage = "LeetCode is unavailable right now. Please, try again in a few minutes."
 
 	unsubscribedMessage = `%s, you have <strong>successfully unsubscribed</strong>. You'll not automatically receive daily tasks.
 If you've found this bot useless and have ideas of possible improvements, please, add them to https://github.com/dartkron/leetcodeBot/issues`
 
 	alreadyUnsubscribedMessage     = "%s, you were <strong>not subscribed</strong>. No additional actions required."
-	subscribedMessage              = "%s, you have <strong>successfully subscribed</strong>. You'll automatically receive daily tasks every day at %d:00 UTC ."
+	subscribedMessage              = "%s, you have <strong>successfully subscribed</strong>. You'll automatically receive daily tasks every day at %s (%s)."
 	alreadySubscribedMessage       = "%s, you have <strong>already subscribed</strong> for daily updates at the same time, nothing to do."
 	getActualDailyTaskCommand      = "Get actual daily task"
 	getActualDailyTaskCommandSlash = "/getDailyTask"
@@ -37,31 +131,157 @@ If you've found this bot useless and have ideas of possible improvements, please
 	subscribeCommandSlash          = "/Subscribe"
 	unsubscribeCommand             = "Unsubscribe"
 	unsubscribeCommandSlash        = "/Unsubscribe"
-	telegramAPIURL                 = "https://api.telegram.org/bot%s/sendMessage"
+	randomCommandSlash             = "/random"
+	problemCommandSlash            = "/problem"
+	linkCommandSlash               = "/link"
+	unlinkCommandSlash             = "/unlink"
+	progressCommandSlash           = "/progress"
+	nudgeCommandSlash              = "/nudge"
+	nudgeOffArgument               = "off"
+	timeZoneCommandSlash           = "/timezone"
+	timesCommandSlash              = "/times"
+	addTimeCommandSlash            = "/addtime"
+	removeTimeCommandSlash         = "/removetime"
+	weekdaysCommandSlash           = "/weekdays"
+	pauseCommandSlash              = "/pause"
+	pauseOffArgument               = "off"
+	settingsCommandSlash           = "/settings"
+	taskCommandSlash               = "/task"
+	yesterdayCommandSlash          = "/yesterday"
+	calendarCommandSlash           = "/calendar"
+	hintArgument                   = "hint"
+	shareLocationCommand           = "Share location to set time zone"
+	telegramAPIURL                 = "https://api.telegram.org/bot%s/%s"
+	sendMessageMethod              = "sendMessage"
+	editMessageTextMethod          = "editMessageText"
+)
+
+var leetcodeUsernameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{1,40}$`)
+
+// Old daily tasks missing in the storage are requested from LeetCode API not more often than these limits
+const (
+	pastTasksFetchPeriod     = time.Hour
+	pastTasksFetchUserLimit  = 5
+	pastTasksFetchTotalLimit = 100
+)
+
+// Today's daily task is requested from LeetCode API with exponential backoff till it's stored
+const (
+	warmUpInitialBackoff  = time.Second
+	warmUpMaxBackoff      = time.Minute
+	defaultWarmUpDeadline = 30 * time.Minute
+	alertTimeout          = 5 * time.Second
+	// warmUpAlertKind is the kind of the alert in the deliveries log, so the alert is sent once per daily task
+	warmUpAlertKind = "alert"
+	// WarmUpTimeout is the time the reminder spends on warming up before sending daily tasks
+	WarmUpTimeout = 30 * time.Second
 )
 
+// Slow requests get the placeholder in ResponseTimeout and are processed till ProcessingTimeout
+const (
+	defaultResponseTimeout   = 3 * time.Second
+	defaultProcessingTimeout = 30 * time.Second
+	followUpTimeout          = 5 * time.Second
+)
+
+// dedupeCapacity and dedupeTTL limit memory used to remember processed updates
+const (
+	dedupeCapacity = 10000
+	dedupeTTL      = time.Hour
+)
+
+// maxDeliveryAttempts is the limit of attempts to send the message, blocked bot or deleted chat shouldn't be retried forever
+const maxDeliveryAttempts = 5
+
+// DeliverySummary is an amount of messages sent, queued to the outbox, failed and skipped because they are already sent,
+// out of attempts or filtered out by difficulty without the fallback problem
+type DeliverySummary struct {
+	Sent    int `json:"sent"`
+	Queued  int `json:"queued"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+}
+
+type deliveryKey struct {
+	userID uint64
+	kind   string
+}
+
+// fetchLimiter counts requests to LeetCode API made on behalf of users during the last pastTasksFetchPeriod
+type fetchLimiter struct {
+	mutex   sync.Mutex
+	fetches map[uint64][]time.Time
+	total   []time.Time
+}
+
+// removeOutdated returns fetches made after the since time
+func removeOutdated(fetches []time.Time, since time.Time) []time.Time {
+	actual := []time.Time{}
+	for _, fetch := range fetches {
+		if fetch.After(since) {
+			actual = append(actual, fetch)
+		}
+	}
+	return actual
+}
+
+// allow checks if the user could make one more request and counts it
+func (l *fetchLimiter) allow(userID uint64, now time.Time) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.fetches == nil {
+		l.fetches = map[uint64][]time.Time{}
+	}
+	since := now.Add(-pastTasksFetchPeriod)
+	l.total = removeOutdated(l.total, since)
+	userFetches := removeOutdated(l.fetches[userID], since)
+	if len(userFetches) >= pastTasksFetchUserLimit || len(l.total) >= pastTasksFetchTotalLimit {
+		l.fetches[userID] = userFetches
+		return false
+	}
+	l.fetches[userID] = append(userFetches, now)
+	l.total = append(l.total, now)
+	return true
+}
+
 // TelegramResponse is a short representation of fields supported by Telegram.
+// MessageID is set only for editMessageText method
 type TelegramResponse struct {
 	Method      string `json:"method"`
 	ParseMode   string `json:"parse_mode"`
 	ChatID      uint64 `json:"chat_id"`
+	MessageID   uint64 `json:"message_id,omitempty"`
 	Text        string `json:"text"`
 	ReplyMarkup string `json:"reply_markup"`
 }
 
 // TelegramRequest represents part of possible fields in Telegram request JSON
 type TelegramRequest struct {
+	UpdateID      uint64 `json:"update_id"`
 	CallbackQuery struct {
 		Data string `json:"data"`
 		From struct {
-			ID uint64 `json:"id"`
+			ID        uint64 `json:"id"`
+			Username  string `json:"username"`
+			FirstName string `json:"first_name"`
+			LastName  string `json:"last_name"`
 		} `json:"from"`
+		Message struct {
+			MessageID uint64 `json:"message_id"`
+			Chat      struct {
+				ID uint64 `json:"id"`
+			} `json:"chat"`
+		} `json:"message"`
 	} `json:"callback_query"`
 	Message struct {
 		Text string `json:"text"`
 		Chat struct {
 			ID uint64 `json:"id"`
 		} `json:"chat"`
+		Location *struct {
+			Latitude  float64 `json:"latitude"`
+			Longitude float64 `json:"longitude"`
+		} `json:"location"`
 		From struct {
 			ID        uint64 `json:"id"`
 			Username  string `json:"username"`
@@ -81,44 +301,261 @@ type KeyboardDef struct {
 }
 
 // Key is one keyboard key
+// RequestLocation makes Telegram to send user location instead of the text
 type Key struct {
-	Text string `json:"text"`
+	Text            string `json:"text"`
+	RequestLocation bool   `json:"request_location,omitempty"`
 }
 
 // NewTelegramResponse is a TelegramResponse constructor with basic fields.
 func NewTelegramResponse() *TelegramResponse {
 	return &TelegramResponse{
-		Method:    "sendMessage",
+		Method:    sendMessageMethod,
 		ParseMode: "HTML",
 	}
 }
 
 // Application holds dependencies for easy injection.
+// SendingSlot is the period of the reminder run, common.DefaultSendingSlot is used when it's zero.
+// Alert is sent to AlertChatID if today's daily task isn't stored in WarmUpDeadline after the daily flip, zero AlertChatID disables alerts.
+// EarlyAnswer answers slow requests before the end of processing, it's useful only with HTTP server sending flushed responses
 type Application struct {
-	storageController storage.Controller
-	leetcodeAPIClient leetcodeclient.LeetcodeClient
-	HTTPClient        *http.Client
+	storageController   storage.Controller
+	leetcodeAPIClient   leetcodeclient.LeetcodeClient
+	HTTPClient          *http.Client
+	SendingToken        string
+	SendingSlot         time.Duration
+	WarmUpDeadline      time.Duration
+	AlertChatID         uint64
+	ResponseTimeout     time.Duration
+	ProcessingTimeout   time.Duration
+	EarlyAnswer         bool
+	outbox              storage.Outbox
+	updatesDeduplicator storage.UpdatesDeduplicator
+	send                Sender
+	clock               common.Clock
+	logger              *slog.Logger
+	pastTasksLimiter    fetchLimiter
+	warmUpBackoff       time.Duration
+	followUps           sync.WaitGroup
+}
+
+// getClock returns the injected clock, the system clock by default
+func (app *Application) getClock() common.Clock {
+	if app.clock == nil {
+		return common.SystemClock{}
+	}
+	return app.clock
+}
+
+// now returns the time of the clock
+func (app *Application) now() time.Time {
+	return app.getClock().Now()
+}
+
+// getLogger returns the injected logger, slog default logger by default
+func (app *Application) getLogger() *slog.Logger {
+	if app.logger == nil {
+		return slog.Default()
+	}
+	return app.logger
+}
+
+// log returns the logger of the context with correlation ID, the application logger otherwise
+func (app *Application) log(ctx context.Context) *slog.Logger {
+	return logging.FromContextOr(ctx, app.getLogger())
+}
+
+// markUpdate returns false when Telegram redelivers already processed update.
+// Requests without update ID and deduplicator errors are always processed
+func (app *Application) markUpdate(ctx context.Context, updateID uint64) bool {
+	if app.updatesDeduplicator == nil || updateID == 0 {
+		return true
+	}
+	isNew, err := app.updatesDeduplicator.MarkUpdate(ctx, updateID)
+	if err != nil {
+		app.log(ctx).Error("Error on marking update", "error", err)
+		return true
+	}
+	return isNew
+}
+
+// forgetUpdate allows to process the failed update again, when Telegram retries it
+func (app *Application) forgetUpdate(ctx context.Context, updateID uint64) {
+	if app.updatesDeduplicator == nil || updateID == 0 {
+		return
+	}
+	err := app.updatesDeduplicator.ForgetUpdate(context.WithoutCancel(ctx), updateID)
+	if err != nil {
+		app.log(ctx).Error("Error on forgetting update", "error", err)
+	}
 }
 
-// ProcessRequestBody parse body json and route request to handlers
-func (app *Application) ProcessRequestBody(ctx context.Context, body []byte) ([]byte, error) {
+// ProcessRequestBody parse body json and route request to handlers.
+// Duplicates of already processed updates get empty response.
+// When processing takes longer than ResponseTimeout, the placeholder is sent and the result is returned when it's ready.
+// With EarlyAnswer the empty response is returned at once and the result is sent by the follow-up in background
+func (app *Application) ProcessRequestBody(ctx context.Context, body []byte) (responseBytes []byte, err error) {
+	ctx, span := tracing.Start(ctx, "bot.ProcessRequestBody")
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
 	telegramRequest := TelegramRequest{}
-	err := json.Unmarshal(body, &telegramRequest)
+	err = json.Unmarshal(body, &telegramRequest)
 	if err != nil {
 		return []byte{}, err
 	}
-	var response *TelegramResponse
-	if len(telegramRequest.CallbackQuery.Data) != 0 {
-		response, err = app.processCallback(ctx, telegramRequest)
-	} else {
-		response, err = app.processMessage(ctx, telegramRequest)
+	span.SetAttributes("updateID", telegramRequest.UpdateID, "command", commandLabel(telegramRequest))
+	correlationArgs := []interface{}{"updateID", telegramRequest.UpdateID}
+	if traceID := span.TraceID(); traceID != "" {
+		correlationArgs = append(correlationArgs, "traceID", traceID)
+	}
+	ctx = logging.WithCorrelation(ctx, app.getLogger(), correlationArgs...)
+	if !app.markUpdate(ctx, telegramRequest.UpdateID) {
+		app.log(ctx).Info("Skip already processed update")
+		return []byte{}, nil
+	}
+	results := app.processRequest(ctx, telegramRequest)
+	responseTimer := time.NewTimer(app.getResponseTimeout())
+	defer responseTimer.Stop()
+	select {
+	case result := <-results:
+		if result.err != nil {
+			app.forgetUpdate(ctx, telegramRequest.UpdateID)
+			return []byte{}, result.err
+		}
+		return json.Marshal(result.response)
+	case <-responseTimer.C:
+	case <-ctx.Done():
+	}
+	if !app.EarlyAnswer && ctx.Err() == nil {
+		chatID, placeholderID := app.startFollowUp(ctx, telegramRequest)
+		return json.Marshal(app.getFollowUpResponse(ctx, chatID, placeholderID, <-results))
+	}
+	app.followUp(ctx, telegramRequest, results)
+	return []byte{}, nil
+}
+
+type processingResult struct {
+	response *TelegramResponse
+	err      error
+}
+
+// isUnavailable returns true for errors of unreachable LeetCode or storage, they are explained to the user instead of failing the request
+func isUnavailable(err error) bool {
+	return errors.Is(err, leetcodeclient.ErrLeetcodeUnavailable) || errors.Is(err, storage.ErrStorageUnavailable)
+}
+
+// processRequest routes request to handlers in background with ProcessingTimeout, which doesn't depend on the request context
+func (app *Application) processRequest(ctx context.Context, request TelegramRequest) <-chan processingResult {
+	results := make(chan processingResult, 1)
+	processingCtx, cancelFunc := context.WithTimeout(context.WithoutCancel(ctx), app.getProcessingTimeout())
+	go func() {
+		defer cancelFunc()
+		updatesTotal.WithLabelValues(commandLabel(request)).Inc()
+		result := processingResult{}
+		if len(request.CallbackQuery.Data) != 0 {
+			result.response, result.err = app.processCallback(processingCtx, request)
+		} else {
+			result.response, result.err = app.processMessage(processingCtx, request)
+		}
+		if isUnavailable(result.err) {
+			app.log(processingCtx).Warn("LeetCode or storage is unavailable", "error", result.err)
+			result.response.Text = leetcodeUnavailableMessage
+			result.err = nil
+		}
+		results <- result
+	}()
+	return results
+}
+
+// startFollowUp sends the placeholder for messages and returns the chat of the request and the placeholder ID,
+// which is zero for callbacks and on errors
+func (app *Application) startFollowUp(ctx context.Context, request TelegramRequest) (uint64, uint64) {
+	if len(request.CallbackQuery.Data) != 0 {
+		return request.CallbackQuery.From.ID, 0
+	}
+	return request.Message.Chat.ID, app.sendPlaceholder(context.WithoutCancel(ctx), request.Message.Chat.ID)
+}
+
+// getFollowUpResponse returns the result of the slow processing, errors are replaced with the apology.
+// The placeholder is replaced with the result if Telegram allows to edit the message with its reply markup
+func (app *Application) getFollowUpResponse(ctx context.Context, chatID uint64, placeholderID uint64, result processingResult) *TelegramResponse {
+	response := result.response
+	if result.err != nil || response == nil {
+		app.log(ctx).Error("Error on processing slow update", "error", result.err)
+		response = NewTelegramResponse()
+		response.ChatID = chatID
+		response.Text = followUpErrorMessage
+	}
+	if response.Method == sendMessageMethod && placeholderID != 0 && isEditableMarkup(response.ReplyMarkup) {
+		response.Method = editMessageTextMethod
+		response.MessageID = placeholderID
+	}
+	return response
+}
+
+// followUp sends the placeholder for messages and then sends the result of the processing in background, when it's ready
+func (app *Application) followUp(ctx context.Context, request TelegramRequest, results <-chan processingResult) {
+	followUpCtx := context.WithoutCancel(ctx)
+	chatID, placeholderID := app.startFollowUp(followUpCtx, request)
+	app.followUps.Add(1)
+	go func() {
+		defer app.followUps.Done()
+		response := app.getFollowUpResponse(followUpCtx, chatID, placeholderID, <-results)
+		bytes, err := json.Marshal(response)
+		if err == nil {
+			sendCtx, cancelFunc := context.WithTimeout(followUpCtx, followUpTimeout)
+			defer cancelFunc()
+			_, err = app.callTelegramAPI(sendCtx, response.Method, bytes)
+		}
+		if err != nil {
+			app.log(followUpCtx).Error("Error on sending follow-up", "chatID", chatID, "error", err)
+		}
+	}()
+}
+
+// sendPlaceholder sends the message about the slow processing and returns its ID, which is zero on errors
+func (app *Application) sendPlaceholder(ctx context.Context, chatID uint64) uint64 {
+	placeholder := NewTelegramResponse()
+	placeholder.ChatID = chatID
+	placeholder.Text = placeholderMessage
+	bytes, err := json.Marshal(placeholder)
+	if err != nil {
+		return 0
 	}
+	sendCtx, cancelFunc := context.WithTimeout(ctx, followUpTimeout)
+	defer cancelFunc()
+	messageID, err := app.sendMessage(sendCtx, bytes)
 	if err != nil {
-		return []byte{}, err
+		app.log(ctx).Error("Error on sending placeholder", "chatID", chatID, "error", err)
+	}
+	return messageID
+}
+
+// isEditableMarkup returns true for markups allowed in editMessageText, which are only inline keyboards
+func isEditableMarkup(replyMarkup string) bool {
+	return replyMarkup == "" || strings.Contains(replyMarkup, "inline_keyboard")
+}
+
+// WaitFollowUps waits till all follow-ups sent in background with EarlyAnswer, the function shouldn't finish before them
+func (app *Application) WaitFollowUps() {
+	app.followUps.Wait()
+}
+
+func (app *Application) getResponseTimeout() time.Duration {
+	if app.ResponseTimeout == 0 {
+		return defaultResponseTimeout
 	}
-	bytes, err := json.Marshal(response)
-	return bytes, err
+	return app.ResponseTimeout
+}
 
+func (app *Application) getProcessingTimeout() time.Duration {
+	if app.ProcessingTimeout == 0 {
+		return defaultProcessingTimeout
+	}
+	return app.ProcessingTimeout
 }
 
 func (app *Application) processCallback(ctx context.Context, request TelegramRequest) (*TelegramResponse, error) {
@@ -128,9 +565,63 @@ func (app *Application) processCallback(ctx context.Context, request TelegramReq
 	if err != nil {
 		return response, err
 	}
+	callbacksTotal.WithLabelValues(callback.Type.String()).Inc()
 	response.ChatID = request.CallbackQuery.From.ID
+	if callback.Type == common.SearchPageRequest || callback.Type == common.ProblemRequest {
+		err = app.problemAction(ctx, callback.Query, callback.Page, response)
+		if err != nil {
+			app.log(ctx).Error("Error on searching problem in LeetCode API", "query", callback.Query, "error", err)
+			response.Text = "Something went completely wrong"
+			if isUnavailable(err) {
+				response.Text = leetcodeUnavailableMessage
+			}
+		}
+		return response, nil
+	}
+	if callback.Type == common.WeekdaysRequest || callback.Type == common.ResumeRequest {
+		err = app.deliverySettingsCallbackAction(ctx, &request, callback, response)
+		if err != nil {
+			app.log(ctx).Error("Error on changing delivery settings", "userID", request.CallbackQuery.From.ID, "error", err)
+			response.Text = "Something went completely wrong"
+		}
+		return response, nil
+	}
+	if callback.Type == common.SettingsRequest || callback.Type == common.SettingRequest {
+		err = app.settingsCallbackAction(ctx, &request, callback, response)
+		if err != nil {
+			app.log(ctx).Error("Error on changing settings", "userID", request.CallbackQuery.From.ID, "error", err)
+			response.Text = "Something went completely wrong"
+		}
+		return response, nil
+	}
+	if callback.Type == common.DailyTaskRequest || callback.Type == common.CalendarRequest {
+		if request.CallbackQuery.Message.MessageID != 0 {
+			response.Method = editMessageTextMethod
+			response.ChatID = request.CallbackQuery.Message.Chat.ID
+			response.MessageID = request.CallbackQuery.Message.MessageID
+		}
+		if callback.Type == common.CalendarRequest {
+			err = app.calendarAction(ctx, request.CallbackQuery.From.ID, callback.DateID, response)
+		} else {
+			err = app.getDailyTaskAction(ctx, request.CallbackQuery.From.ID, callback.DateID, response)
+		}
+		if err != nil {
+			app.log(ctx).Error("Error on getting daily task", "dateID", callback.DateID, "error", err)
+			response.Method = NewTelegramResponse().Method
+			response.MessageID = 0
+			response.Text = "Something went completely wrong"
+		}
+		return response, nil
+	}
 	// Used only storage here to avoid possible use violation, when user could push application to load all leetcode tasks locally
-	task, err := app.storageController.GetTask(ctx, callback.DateID)
+	var task common.BotLeetCodeTask
+	taskID := callback.DateID
+	if callback.DateID != 0 {
+		task, err = app.storageController.GetTask(ctx, callback.DateID)
+	} else {
+		taskID = callback.QuestionID
+		task, err = app.storageController.GetQuestion(ctx, callback.QuestionID)
+	}
 	if err != nil {
 		if err == storage.ErrNoSuchTask {
 			response.Text = "There is not such dailyTask. Try another breach ;)"
@@ -141,7 +632,7 @@ func (app *Application) processCallback(ctx context.Context, request TelegramReq
 	}
 	if callback.Type == common.HintRequest {
 		if callback.Hint > len(task.Hints)-1 {
-			response.Text = fmt.Sprintf("There is no such hint for task %d", callback.DateID)
+			response.Text = fmt.Sprintf("There is no such hint for task %d", taskID)
 			return response, nil
 		}
 		response.Text = fmt.Sprintf("Hint #%d: %s", callback.Hint+1, task.Hints[callback.Hint])
@@ -149,7 +640,7 @@ func (app *Application) processCallback(ctx context.Context, request TelegramReq
 		response.Text = fmt.Sprintf("Task difficulty: %s", task.Difficulty)
 	} else if callback.Type == common.TopicTagsRequest {
 		if len(task.TopicTags) == 0 {
-			response.Text = fmt.Sprintf("There are no topics for task %d", callback.DateID)
+			response.Text = fmt.Sprintf("There are no topics for task %d", taskID)
 			return response, nil
 		}
 		topics := make([]string, len(task.TopicTags))
@@ -157,10 +648,111 @@ func (app *Application) processCallback(ctx context.Context, request TelegramReq
 			topics[i] = tag.Name
 		}
 		response.Text = fmt.Sprintf("Task topics: %s", strings.Join(topics, ", "))
+	} else if callback.Type == common.SimilarQuestionsRequest {
+		if len(task.SimilarQuestions) == 0 {
+			response.Text = fmt.Sprintf("There are no similar problems for task %d", taskID)
+			return response, nil
+		}
+		response.Text = task.GetSimilarQuestionsText()
+		response.ReplyMarkup = task.GetSimilarQuestionsInlineKeyboard()
+	} else if callback.Type == common.SimilarQuestionRequest {
+		if callback.Hint < 0 || callback.Hint > len(task.SimilarQuestions)-1 {
+			response.Text = fmt.Sprintf("There is no such similar problem for task %d", taskID)
+			return response, nil
+		}
+		err = app.questionBySlugAction(ctx, task.SimilarQuestions[callback.Hint].TitleSlug, response)
+		if err != nil {
+			app.log(ctx).Error("Error on getting similar question from LeetCode API", "error", err)
+			response.Text = "Something went completely wrong"
+		}
 	}
 	return response, nil
 }
 
+func (app *Application) questionBySlugAction(ctx context.Context, titleSlug string, response *TelegramResponse) error {
+	task, err := app.storageController.GetQuestionBySlug(ctx, titleSlug)
+	if err == nil {
+		response.Text = task.GetTaskText()
+		response.ReplyMarkup = task.GetInlineKeyboard(app.getClock())
+		return nil
+	}
+	if err != storage.ErrNoSuchTask {
+		app.log(ctx).Warn("Error on getting question from storage, fallback to LeetCode API", "titleSlug", titleSlug, "error", err)
+	}
+	lcTask, err := app.leetcodeAPIClient.GetQuestionDetailsByTitleSlug(ctx, titleSlug)
+	if err != nil {
+		return err
+	}
+	storage.TaskSourceHits.WithLabelValues(storage.KindQuestion, storage.SourceLeetcodeAPI).Inc()
+	app.sendQuestion(ctx, lcTask, response)
+	return nil
+}
+
+// problemAction sends the question if query matches only one question exactly or a page of search results otherwise
+func (app *Application) problemAction(ctx context.Context, query string, page int, response *TelegramResponse) error {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		response.Text = problemUsageMessage
+		return nil
+	}
+	list, err := app.leetcodeAPIClient.SearchQuestions(ctx, query, page*common.SearchResultsPageSize, common.SearchResultsPageSize)
+	if err != nil {
+		return err
+	}
+	if page == 0 {
+		for _, question := range list.Questions {
+			if question.FrontendQuestionID == query || question.TitleSlug == query || strings.EqualFold(question.Title, query) {
+				return app.questionBySlugAction(ctx, question.TitleSlug, response)
+			}
+		}
+		if list.Total == 1 && len(list.Questions) == 1 {
+			return app.questionBySlugAction(ctx, list.Questions[0].TitleSlug, response)
+		}
+	}
+	if len(list.Questions) == 0 {
+		response.Text = fmt.Sprintf(noSearchResultsMessage, html.EscapeString(query))
+		return nil
+	}
+	response.Text = common.GetSearchResultsText(query, list, page)
+	response.ReplyMarkup = common.GetSearchResultsInlineKeyboard(query, list, page)
+	return nil
+}
+
+func (app *Application) randomTaskAction(ctx context.Context, args []string, response *TelegramResponse) error {
+	filter := leetcodeclient.QuestionsFilter{}
+	if len(args) > 0 {
+		switch difficulty := strings.ToUpper(args[0]); difficulty {
+		case "EASY", "MEDIUM", "HARD":
+			filter.Difficulty = difficulty
+			args = args[1:]
+		}
+	}
+	if len(args) > 0 {
+		filter.Tags = []string{strings.ToLower(strings.Join(args, "-"))}
+	}
+	lcTask, err := app.leetcodeAPIClient.GetRandomQuestion(ctx, filter)
+	if err == leetcodeclient.ErrNoSuchQuestions {
+		response.Text = noRandomQuestionsMessage
+		return nil
+	} else if err != nil {
+		return err
+	}
+	app.sendQuestion(ctx, lcTask, response)
+	return nil
+}
+
+// sendQuestion saves not daily task to the storage, so callbacks could find it, and fills the response with it
+func (app *Application) sendQuestion(ctx context.Context, lcTask leetcodeclient.LeetCodeTask, response *TelegramResponse) {
+	task := common.BotLeetCodeTask{LeetCodeTask: lcTask}
+	task.FixTagsAndImages()
+	err := app.storageController.SaveQuestion(ctx, task)
+	if err != nil {
+		app.log(ctx).Error("Error on saving question", "questionID", task.QuestionID, "error", err)
+	}
+	response.Text = task.GetTaskText()
+	response.ReplyMarkup = task.GetInlineKeyboard(app.getClock())
+}
+
 func (app *Application) processMessage(ctx context.Context, request TelegramRequest) (*TelegramResponse, error) {
 	command := request.Message.Text
 	response := NewTelegramResponse()
@@ -170,19 +762,58 @@ func (app *Application) processMessage(ctx context.Context, request TelegramRequ
 		return response, err
 	}
 	response.ReplyMarkup = keyboard
+	if request.Message.Location != nil {
+		err = app.timeZoneFromLocationAction(ctx, &request, response)
+		return response, err
+	}
 	switch command {
 	case getActualDailyTaskCommand, getActualDailyTaskCommandSlash:
-		err = app.getTaskAction(ctx, response)
+		err = app.getTaskForUserAction(ctx, request.Message.From.ID, response)
 	case subscribeCommand, subscribeCommandSlash:
-		err = app.printSubscribeDialog(ctx, response)
+		err = app.printSubscribeDialog(ctx, &request, response)
 	case unsubscribeCommand, unsubscribeCommandSlash:
 		err = app.unsubscribeAction(ctx, &request, response)
+	case unlinkCommandSlash:
+		err = app.unlinkAction(ctx, &request, response)
+	case progressCommandSlash:
+		err = app.progressAction(ctx, &request, response)
+	case timesCommandSlash:
+		err = app.timesAction(ctx, &request, response)
+	case weekdaysCommandSlash:
+		err = app.weekdaysAction(ctx, &request, response)
+	case settingsCommandSlash:
+		err = app.settingsAction(ctx, &request, response)
+	case yesterdayCommandSlash:
+		err = app.getDailyTaskAction(ctx, request.Message.From.ID, common.GetDateID(common.GetDateInRightTimeZone(app.getClock()).AddDate(0, 0, -1)), response)
 	default:
+		commandWithArgs := strings.Fields(command)
 		splittedCommand := strings.Split(command, ":")
-		if len(splittedCommand) == 2 {
-			sendingHour, err2 := strconv.Atoi(splittedCommand[0])
+		if len(commandWithArgs) > 0 && commandWithArgs[0] == randomCommandSlash {
+			err = app.randomTaskAction(ctx, commandWithArgs[1:], response)
+		} else if len(commandWithArgs) > 0 && commandWithArgs[0] == problemCommandSlash {
+			err = app.problemAction(ctx, strings.Join(commandWithArgs[1:], " "), 0, response)
+		} else if len(commandWithArgs) > 0 && commandWithArgs[0] == linkCommandSlash {
+			err = app.linkAction(ctx, &request, commandWithArgs[1:], response)
+		} else if len(commandWithArgs) > 0 && commandWithArgs[0] == timeZoneCommandSlash {
+			err = app.timeZoneAction(ctx, &request, commandWithArgs[1:], response)
+		} else if len(commandWithArgs) > 0 && commandWithArgs[0] == nudgeCommandSlash {
+			err = app.nudgeAction(ctx, &request, commandWithArgs[1:], response)
+		} else if len(commandWithArgs) > 0 && commandWithArgs[0] == addTimeCommandSlash {
+			err = app.addTimeAction(ctx, &request, commandWithArgs[1:], response)
+		} else if len(commandWithArgs) > 0 && commandWithArgs[0] == removeTimeCommandSlash {
+			err = app.removeTimeAction(ctx, &request, commandWithArgs[1:], response)
+		} else if len(commandWithArgs) > 0 && commandWithArgs[0] == taskCommandSlash {
+			err = app.taskAction(ctx, &request, commandWithArgs[1:], response)
+		} else if len(commandWithArgs) > 0 && commandWithArgs[0] == calendarCommandSlash {
+			err = app.calendarCommandAction(ctx, &request, commandWithArgs[1:], response)
+		} else if len(commandWithArgs) > 0 && commandWithArgs[0] == pauseCommandSlash {
+			err = app.pauseAction(ctx, &request, commandWithArgs[1:], response)
+		} else if len(splittedCommand) == 2 {
+			minute, err2 := common.ParseSendingTime(command)
 			if err2 == nil {
-				err = app.subscribeAction(ctx, &request, response, uint8(sendingHour))
+				err = app.subscribeAction(ctx, &request, response, common.SendingTime{Minute: minute, Kind: common.TaskSendingTime})
+			} else {
+				response.Text = fmt.Sprintf(helpMessage, command)
 			}
 		} else {
 			response.Text = fmt.Sprintf(helpMessage, command)
@@ -198,147 +829,1205 @@ func (app *Application) getTaskAction(ctx context.Context, response *TelegramRes
 		return err
 	}
 	response.Text = task.GetTaskText()
-	response.ReplyMarkup = task.GetInlineKeyboard()
+	response.ReplyMarkup = task.GetInlineKeyboard(app.getClock())
 	return nil
 }
 
-func (app *Application) printSubscribeDialog(ctx context.Context, response *TelegramResponse) error {
-	response.Text = "Daily tasks appear each day at 00:00 UTC. For your convenience, this bot can send you tasks at the start of any hour of the day. " +
-		"Please, select a suitable hour to send a new daily task to you. The time zone is UTC."
-	keyboard, err := GetSubscribeHourdsKeyboard()
-	response.ReplyMarkup = keyboard
-	return err
-}
-
-func (app *Application) subscribeAction(ctx context.Context, request *TelegramRequest, response *TelegramResponse, sendingHour uint8) error {
-	user := common.User{
-		ID:        request.Message.From.ID,
-		ChatID:    request.Message.Chat.ID,
-		Username:  request.Message.From.Username,
-		FirstName: request.Message.From.FirstName,
-		LastName:  request.Message.From.LastName,
-	}
-	err := app.storageController.SubscribeUser(ctx, user, sendingHour)
-	if err == storage.ErrUserAlreadySubscribed {
-		response.Text = fmt.Sprintf(alreadySubscribedMessage, user.FirstName)
-
-	} else if err != nil {
+// getTaskForUserAction same as getTaskAction, but also shows if the user has solved the task
+func (app *Application) getTaskForUserAction(ctx context.Context, userID uint64, response *TelegramResponse) error {
+	task, err := app.GetTodayTaskFromAllPossibleSources(ctx)
+	if err != nil {
 		return err
-	} else {
-		response.Text = fmt.Sprintf(subscribedMessage, user.FirstName, sendingHour)
 	}
+	response.Text = task.GetTaskText()
+	response.ReplyMarkup = task.GetInlineKeyboard(app.getClock())
+	user, err := app.storageController.GetUser(ctx, userID)
+	if err != nil {
+		if err != storage.ErrNoSuchUser {
+			app.log(ctx).Error("Error on getting user", "userID", userID, "error", err)
+		}
+		return nil
+	}
+	response.ReplyMarkup = task.GetInlineKeyboardForLanguage(app.getClock(), user.CodeLanguage)
+	if user.LeetcodeUsername == "" {
+		return nil
+	}
+	solvedStatus, err := app.getDailySolvedStatusText(ctx, user.LeetcodeUsername, task)
+	if err != nil {
+		// The task is more important than the status, so show it anyway
+		app.log(ctx).Warn("Error on getting solved status from LeetCode API", "username", user.LeetcodeUsername, "error", err)
+		return nil
+	}
+	response.Text += "\n\n" + solvedStatus
 	return nil
 }
 
-func (app *Application) unsubscribeAction(ctx context.Context, request *TelegramRequest, response *TelegramResponse) error {
-	err := app.storageController.UnsubscribeUser(ctx, request.Message.From.ID)
-	if err == storage.ErrUserAlreadyUnsubscribed {
-		response.Text = fmt.Sprintf(alreadyUnsubscribedMessage, request.Message.From.FirstName)
-	} else if err != nil {
-		return err
-	} else {
-		response.Text = fmt.Sprintf(unsubscribedMessage, request.Message.From.FirstName)
+func (app *Application) taskAction(ctx context.Context, request *TelegramRequest, args []string, response *TelegramResponse) error {
+	if len(args) != 1 {
+		response.Text = taskUsageMessage
+		return nil
 	}
-	return nil
+	dateID, err := common.ParseDateID(args[0])
+	if err != nil {
+		response.Text = taskUsageMessage
+		return nil
+	}
+	return app.getDailyTaskAction(ctx, request.Message.From.ID, dateID, response)
 }
 
-// GetTodayTaskFromAllPossibleSources is the one func to rule them all
-// It's apperared because of necessary to share logic between reminder and bot
-func (app *Application) GetTodayTaskFromAllPossibleSources(ctx context.Context) (common.BotLeetCodeTask, error) {
-	now := common.GetDateInRightTimeZone()
-	taskDateID := common.GetDateID(now)
-	task, err := app.storageController.GetTask(ctx, taskDateID)
+// getDailyTaskAction fills the response with the daily task of the dateID day.
+// Tasks missing in the storage are requested from LeetCode API with limits to avoid abuse
+func (app *Application) getDailyTaskAction(ctx context.Context, userID uint64, dateID uint64, response *TelegramResponse) error {
+	today := common.GetDateIDForNow(app.getClock())
+	if dateID == today {
+		return app.getTaskForUserAction(ctx, userID, response)
+	}
+	if dateID < common.FirstDailyTaskDateID || dateID > today {
+		response.Text = fmt.Sprintf(noDailyTaskMessage, common.FormatDateID(dateID), common.FormatDateID(common.FirstDailyTaskDateID))
+		return nil
+	}
+	task, err := app.storageController.GetTask(ctx, dateID)
 	if err != nil {
 		if err != storage.ErrNoSuchTask {
-			fmt.Println("Got DB error:", err)
-			fmt.Println("Fallback to Leetcode API")
+			app.log(ctx).Error("Error on getting task", "dateID", dateID, "error", err)
 		}
-		lcTask, err := app.leetcodeAPIClient.GetDailyTask(ctx, now)
+		if !app.pastTasksLimiter.allow(userID, app.now()) {
+			response.Text = pastTasksLimitMessage
+			return nil
+		}
+		lcTask, err := app.leetcodeAPIClient.GetDailyTask(ctx, common.GetDateFromDateID(dateID))
 		if err != nil {
-			return common.BotLeetCodeTask{}, err
+			return err
 		}
+		storage.TaskSourceHits.WithLabelValues(storage.KindDailyTask, storage.SourceLeetcodeAPI).Inc()
 		task = common.BotLeetCodeTask{
 			LeetCodeTask: lcTask,
-			DateID:       taskDateID,
+			DateID:       dateID,
 		}
 		task.FixTagsAndImages()
 		err = app.storageController.SaveTask(ctx, task)
 		if err != nil {
-			return task, err
+			app.log(ctx).Error("Error on saving task", "dateID", task.DateID, "error", err)
 		}
-		return task, nil
 	}
-	return task, nil
+	response.Text = fmt.Sprintf(pastTaskHeader, common.FormatDateID(dateID)) + task.GetTaskText()
+	response.ReplyMarkup = task.GetInlineKeyboard(app.getClock())
+	return nil
 }
 
-// SendMessage sends message to particular user.
-func (app *Application) SendMessage(ctx context.Context, requestBody []byte) error {
-	tries := 0
-	for tries < 3 {
-		tries++
-		buf := bytes.NewBuffer(requestBody)
-		request, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf(telegramAPIURL, os.Getenv("SENDING_TOKEN")), buf)
+func (app *Application) calendarCommandAction(ctx context.Context, request *TelegramRequest, args []string, response *TelegramResponse) error {
+	monthDateID := common.GetMonthDateID(common.GetDateIDForNow(app.getClock()))
+	if len(args) > 1 {
+		response.Text = calendarUsageMessage
+		return nil
+	}
+	if len(args) == 1 {
+		var err error
+		monthDateID, err = common.ParseMonthDateID(args[0])
 		if err != nil {
-			return err
-		}
-		request.Header.Add("content-type", "application/json")
-		resp, err := app.HTTPClient.Do(request)
-		if err != nil || resp.StatusCode >= 400 {
-			if tries == 3 {
-				return err
-			}
-			continue
+			response.Text = calendarUsageMessage
+			return nil
 		}
-		// TODO: should we see what's inside the response?
-		_, _ = io.ReadAll(resp.Body)
-		resp.Body.Close()
-		break
 	}
-	return nil
+	return app.calendarAction(ctx, request.Message.From.ID, monthDateID, response)
 }
 
-// SendDailyTaskToSubscribedUsers get subscribed users and send notifications with daily task to them
-func (app *Application) SendDailyTaskToSubscribedUsers(ctx context.Context) error {
-	now := time.Now().UTC()
-	usersSlice, err := app.storageController.GetSubscribedUsers(ctx, uint8(now.Hour()))
-	if err != nil {
-		return err
+// calendarAction fills the response with the calendar of daily tasks of the monthDateID month.
+// Days are marked as solved only for users with linked LeetCode profile
+func (app *Application) calendarAction(ctx context.Context, userID uint64, monthDateID uint64, response *TelegramResponse) error {
+	today := common.GetDateIDForNow(app.getClock())
+	month := common.GetDateFromDateID(monthDateID)
+	if monthDateID < common.GetMonthDateID(common.FirstDailyTaskDateID) || monthDateID > today {
+		response.Text = fmt.Sprintf(noCalendarMessage, month.Format("January 2006"), common.GetDateFromDateID(common.FirstDailyTaskDateID).Format("January 2006"))
+		return nil
 	}
-
-	telegramRequest := NewTelegramResponse()
-	err = app.getTaskAction(ctx, telegramRequest)
+	challenges, err := app.leetcodeAPIClient.GetMonthlyChallenges(ctx, month)
 	if err != nil {
 		return err
 	}
-
-	var wg sync.WaitGroup
-	for _, user := range usersSlice {
-		telegramRequest.ChatID = user.ID
-		bytes, err := json.Marshal(telegramRequest)
-		if err != nil {
-			return err
+	response.Text = fmt.Sprintf(calendarMessage, month.Format("January 2006"))
+	leetcodeUsername, submissions := app.getCalendarSubmissions(ctx, userID)
+	if submissions != nil {
+		response.Text += fmt.Sprintf(calendarSolvedNote, leetcodeUsername, leetcodeclient.RecentSubmissionsLimit)
+	}
+	response.Text += calendarTapNote
+	days := []common.CalendarDay{}
+	for _, challenge := range challenges {
+		dateID := common.GetDateID(challenge.Date)
+		if dateID > today {
+			continue
 		}
-		wg.Add(1)
-		go func(bytes []byte, userID uint64) {
-			err := app.SendMessage(ctx, bytes)
-			if err != nil {
-				fmt.Printf("Failed to send message to user %d, with error: %s\n", userID, err.Error())
-			}
-			wg.Done()
-		}(bytes, user.ID)
+		day := common.CalendarDay{DateID: dateID, Difficulty: challenge.Difficulty}
+		if submissions != nil {
+			day.Solved = leetcodeclient.HasAcceptedSubmission(submissions, challenge.TitleSlug, common.GetDateFromDateID(dateID))
+		}
+		days = append(days, day)
 	}
-	wg.Wait()
+	response.ReplyMarkup = common.GetCalendarInlineKeyboard(app.getClock(), monthDateID, days)
 	return nil
 }
 
-// GetMainKeyboard returns marshaled json for the main keyboard
-func GetMainKeyboard() (string, error) {
-	return keyboardToJSON(generateMainKeyboard())
+// getCalendarSubmissions returns linked LeetCode username and its recent accepted submissions.
+// Submissions are nil if the profile isn't linked or they aren't available, the calendar is shown anyway
+func (app *Application) getCalendarSubmissions(ctx context.Context, userID uint64) (string, []leetcodeclient.AcceptedSubmission) {
+	user, err := app.storageController.GetUser(ctx, userID)
+	if err != nil {
+		if err != storage.ErrNoSuchUser {
+			app.log(ctx).Error("Error on getting user", "userID", userID, "error", err)
+		}
+		return "", nil
+	}
+	if user.LeetcodeUsername == "" {
+		return "", nil
+	}
+	submissions, err := app.leetcodeAPIClient.GetRecentAcceptedSubmissions(ctx, user.LeetcodeUsername, leetcodeclient.RecentSubmissionsLimit)
+	if err != nil {
+		app.log(ctx).Warn("Error on getting solved status from LeetCode API", "username", user.LeetcodeUsername, "error", err)
+		return "", nil
+	}
+	if submissions == nil {
+		submissions = []leetcodeclient.AcceptedSubmission{}
+	}
+	return user.LeetcodeUsername, submissions
 }
 
-// GetSubscribeHourdsKeyboard creates marshalled json with 24-hourds subscribe keyboard
-func GetSubscribeHourdsKeyboard() (string, error) {
-	return keyboardToJSON(generateSubscribeHourdsKeyboard())
+func (app *Application) getDailySolvedStatusText(ctx context.Context, leetcodeUsername string, task common.BotLeetCodeTask) (string, error) {
+	submissions, err := app.leetcodeAPIClient.GetRecentAcceptedSubmissions(ctx, leetcodeUsername, leetcodeclient.RecentSubmissionsLimit)
+	if err != nil {
+		return "", err
+	}
+	if leetcodeclient.HasAcceptedSubmission(submissions, task.TitleSlug, common.GetDateInRightTimeZone(app.getClock()).Truncate(24*time.Hour)) {
+		return fmt.Sprintf(dailySolvedMessage, leetcodeUsername), nil
+	}
+	return fmt.Sprintf(dailyNotSolvedMessage, leetcodeUsername), nil
+}
+
+func getSolvedCountsText(profile leetcodeclient.UserProfile) string {
+	return fmt.Sprintf(
+		solvedCountsMessage,
+		profile.Username,
+		profile.GetSolvedCount("All"),
+		profile.GetSolvedCount("Easy"),
+		profile.GetSolvedCount("Medium"),
+		profile.GetSolvedCount("Hard"),
+	)
+}
+
+func (app *Application) linkAction(ctx context.Context, request *TelegramRequest, args []string, response *TelegramResponse) error {
+	if len(args) != 1 || !leetcodeUsernameRegexp.MatchString(args[0]) {
+		response.Text = linkUsageMessage
+		return nil
+	}
+	profile, err := app.leetcodeAPIClient.GetUserProfile(ctx, args[0])
+	if err == leetcodeclient.ErrNoSuchUser {
+		response.Text = fmt.Sprintf(noLeetcodeUserMessage, args[0])
+		return nil
+	} else if err != nil {
+		return err
+	}
+	err = app.storageController.LinkLeetcodeUsername(ctx, getUserFromRequest(request), profile.Username)
+	if err != nil {
+		return err
+	}
+	response.Text = fmt.Sprintf(linkedMessage, request.Message.From.FirstName, profile.Username, getSolvedCountsText(profile))
+	return nil
+}
+
+func (app *Application) unlinkAction(ctx context.Context, request *TelegramRequest, response *TelegramResponse) error {
+	err := app.storageController.LinkLeetcodeUsername(ctx, getUserFromRequest(request), "")
+	if err != nil {
+		return err
+	}
+	response.Text = fmt.Sprintf(unlinkedMessage, request.Message.From.FirstName)
+	return nil
+}
+
+func (app *Application) progressAction(ctx context.Context, request *TelegramRequest, response *TelegramResponse) error {
+	user, err := app.storageController.GetUser(ctx, request.Message.From.ID)
+	if err != nil && err != storage.ErrNoSuchUser {
+		return err
+	}
+	if user.LeetcodeUsername == "" {
+		response.Text = fmt.Sprintf(notLinkedMessage, request.Message.From.FirstName)
+		return nil
+	}
+	profile, err := app.leetcodeAPIClient.GetUserProfile(ctx, user.LeetcodeUsername)
+	if err != nil {
+		return err
+	}
+	task, err := app.GetTodayTaskFromAllPossibleSources(ctx)
+	if err != nil {
+		return err
+	}
+	solvedStatus, err := app.getDailySolvedStatusText(ctx, user.LeetcodeUsername, task)
+	if err != nil {
+		return err
+	}
+	response.Text = solvedStatus + "\n\n" + getSolvedCountsText(profile)
+	return nil
+}
+
+func (app *Application) nudgeAction(ctx context.Context, request *TelegramRequest, args []string, response *TelegramResponse) error {
+	if len(args) != 1 {
+		response.Text = nudgeUsageMessage
+		return nil
+	}
+	if strings.ToLower(args[0]) == nudgeOffArgument {
+		err := app.storageController.UnsubscribeUserFromNudge(ctx, request.Message.From.ID)
+		if err == storage.ErrUserAlreadyUnsubscribed {
+			response.Text = fmt.Sprintf(nudgeAlreadyUnsubscribed, request.Message.From.FirstName)
+		} else if err != nil {
+			return err
+		} else {
+			response.Text = fmt.Sprintf(nudgeUnsubscribedMessage, request.Message.From.FirstName)
+		}
+		return nil
+	}
+	nudgeHour, err := strconv.Atoi(strings.TrimSuffix(args[0], ":00"))
+	if err != nil || nudgeHour < 0 || nudgeHour > 23 {
+		response.Text = nudgeUsageMessage
+		return nil
+	}
+	// Nudge makes sense only when we can check submissions
+	storedUser, err := app.storageController.GetUser(ctx, request.Message.From.ID)
+	if err != nil && err != storage.ErrNoSuchUser {
+		return err
+	}
+	if storedUser.LeetcodeUsername == "" {
+		response.Text = fmt.Sprintf(notLinkedMessage, request.Message.From.FirstName)
+		return nil
+	}
+	err = app.storageController.SubscribeUserToNudge(ctx, getUserFromRequest(request), uint8(nudgeHour))
+	if err == storage.ErrUserAlreadySubscribed {
+		response.Text = fmt.Sprintf(nudgeAlreadySubscribed, request.Message.From.FirstName)
+	} else if err != nil {
+		return err
+	} else {
+		response.Text = fmt.Sprintf(nudgeSubscribedMessage, request.Message.From.FirstName, nudgeHour, storedUser.GetTimeZoneName())
+	}
+	return nil
+}
+
+func getUserFromRequest(request *TelegramRequest) common.User {
+	return common.User{
+		ID:        request.Message.From.ID,
+		ChatID:    request.Message.Chat.ID,
+		Username:  request.Message.From.Username,
+		FirstName: request.Message.From.FirstName,
+		LastName:  request.Message.From.LastName,
+	}
+}
+
+// getUserTimeZoneName returns time zone name of the stored user or UTC for the new one
+func (app *Application) getUserTimeZoneName(ctx context.Context, userID uint64) (string, error) {
+	user, err := app.storageController.GetUser(ctx, userID)
+	if err != nil && err != storage.ErrNoSuchUser {
+		return "", err
+	}
+	return user.GetTimeZoneName(), nil
+}
+
+func (app *Application) timeZoneAction(ctx context.Context, request *TelegramRequest, args []string, response *TelegramResponse) error {
+	if len(args) == 0 {
+		timeZoneName, err := app.getUserTimeZoneName(ctx, request.Message.From.ID)
+		if err != nil {
+			return err
+		}
+		response.Text = fmt.Sprintf(timeZoneUsageMessage, timeZoneName)
+		keyboard, err := GetTimeZoneKeyboard()
+		response.ReplyMarkup = keyboard
+		return err
+	}
+	timeZone, err := common.NormalizeTimeZone(strings.Join(args, " "))
+	if err != nil {
+		response.Text = fmt.Sprintf(wrongTimeZoneMessage, html.EscapeString(strings.Join(args, " ")))
+		return nil
+	}
+	err = app.storageController.SetUserTimeZone(ctx, getUserFromRequest(request), timeZone)
+	if err != nil {
+		return err
+	}
+	response.Text = fmt.Sprintf(timeZoneSetMessage, request.Message.From.FirstName, timeZone)
+	return nil
+}
+
+func (app *Application) timeZoneFromLocationAction(ctx context.Context, request *TelegramRequest, response *TelegramResponse) error {
+	timeZone := common.GetTimeZoneForLongitude(request.Message.Location.Longitude)
+	err := app.storageController.SetUserTimeZone(ctx, getUserFromRequest(request), timeZone)
+	if err != nil {
+		return err
+	}
+	response.Text = fmt.Sprintf(timeZoneSetMessage, request.Message.From.FirstName, timeZone) + timeZoneLocationNote
+	return nil
+}
+
+func (app *Application) printSubscribeDialog(ctx context.Context, request *TelegramRequest, response *TelegramResponse) error {
+	timeZoneName, err := app.getUserTimeZoneName(ctx, request.Message.From.ID)
+	if err != nil {
+		return err
+	}
+	response.Text = fmt.Sprintf(subscribeDialogMessage, timeZoneName)
+	keyboard, err := GetSubscribeHourdsKeyboard()
+	response.ReplyMarkup = keyboard
+	return err
+}
+
+func (app *Application) subscribeAction(ctx context.Context, request *TelegramRequest, response *TelegramResponse, sendingTime common.SendingTime) error {
+	user := getUserFromRequest(request)
+	err := app.storageController.SubscribeUser(ctx, user, sendingTime)
+	if err == storage.ErrUserAlreadySubscribed {
+		response.Text = fmt.Sprintf(alreadySubscribedMessage, user.FirstName)
+
+	} else if err == storage.ErrSendingTimeOccupied {
+		response.Text = fmt.Sprintf(sendingTimeOccupiedMessage, user.FirstName, sendingTime, sendingTime)
+	} else if err != nil {
+		return err
+	} else {
+		timeZoneName, err := app.getUserTimeZoneName(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		response.Text = fmt.Sprintf(subscribedMessage, user.FirstName, sendingTime, timeZoneName)
+	}
+	return nil
+}
+
+func getSendingTimeKindName(kind common.SendingTimeKind) string {
+	if kind == common.HintSendingTime {
+		return hintKindName
+	}
+	return taskKindName
+}
+
+func (app *Application) addTimeAction(ctx context.Context, request *TelegramRequest, args []string, response *TelegramResponse) error {
+	if len(args) == 0 || len(args) > 2 || (len(args) == 2 && strings.ToLower(args[1]) != hintArgument) {
+		response.Text = addTimeUsageMessage
+		return nil
+	}
+	minute, err := common.ParseSendingTime(args[0])
+	if err != nil {
+		response.Text = addTimeUsageMessage
+		return nil
+	}
+	sendingTime := common.SendingTime{Minute: minute, Kind: common.TaskSendingTime}
+	if len(args) == 2 {
+		sendingTime.Kind = common.HintSendingTime
+	}
+	user := getUserFromRequest(request)
+	err = app.storageController.SubscribeUser(ctx, user, sendingTime)
+	if err == storage.ErrUserAlreadySubscribed {
+		response.Text = fmt.Sprintf(alreadySubscribedMessage, user.FirstName)
+		return nil
+	} else if err == storage.ErrSendingTimeOccupied {
+		response.Text = fmt.Sprintf(sendingTimeOccupiedMessage, user.FirstName, sendingTime, sendingTime)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	timeZoneName, err := app.getUserTimeZoneName(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	response.Text = fmt.Sprintf(sendingTimeAddedMessage, user.FirstName, getSendingTimeKindName(sendingTime.Kind), sendingTime, timeZoneName)
+	return nil
+}
+
+func (app *Application) removeTimeAction(ctx context.Context, request *TelegramRequest, args []string, response *TelegramResponse) error {
+	if len(args) != 1 {
+		response.Text = removeTimeUsageMessage
+		return nil
+	}
+	minute, err := common.ParseSendingTime(args[0])
+	if err != nil {
+		response.Text = removeTimeUsageMessage
+		return nil
+	}
+	sendingTime := common.SendingTime{Minute: minute}
+	err = app.storageController.RemoveSendingTime(ctx, request.Message.From.ID, minute)
+	if err == storage.ErrNoSuchSendingTime {
+		response.Text = fmt.Sprintf(noSuchSendingTimeMessage, request.Message.From.FirstName, sendingTime)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	response.Text = fmt.Sprintf(sendingTimeRemovedMessage, request.Message.From.FirstName, sendingTime)
+	return nil
+}
+
+func (app *Application) timesAction(ctx context.Context, request *TelegramRequest, response *TelegramResponse) error {
+	user, err := app.storageController.GetUser(ctx, request.Message.From.ID)
+	if err != nil && err != storage.ErrNoSuchUser {
+		return err
+	}
+	if !user.Subscribed || len(user.SendingTimes) == 0 {
+		response.Text = fmt.Sprintf(noSendingTimesMessage, request.Message.From.FirstName)
+		return nil
+	}
+	lines := make([]string, len(user.SendingTimes))
+	for i, sendingTime := range user.SendingTimes {
+		lines[i] = fmt.Sprintf("%s — %s", sendingTime, getSendingTimeKindName(sendingTime.Kind))
+	}
+	response.Text = fmt.Sprintf(sendingTimesMessage, request.Message.From.FirstName, user.GetTimeZoneName(), strings.Join(lines, "\n"))
+	return nil
+}
+
+// getUserToday returns dateID of the current date of the clock in the user time zone
+func getUserToday(clock common.Clock, user common.User) (uint64, error) {
+	location, err := common.LoadLocation(user.TimeZone)
+	if err != nil {
+		return 0, err
+	}
+	return common.GetDateID(clock.Now().In(location)), nil
+}
+
+// getDeliverySettings returns text and inline keyboard with delivery weekdays and pause of the user
+func (app *Application) getDeliverySettings(user common.User, firstName string) (string, string, error) {
+	today, err := getUserToday(app.getClock(), user)
+	if err != nil {
+		return "", "", err
+	}
+	text := fmt.Sprintf(deliverySettingsMessage, firstName, user.Weekdays)
+	paused := user.PausedUntil > today
+	if paused {
+		text += fmt.Sprintf(pausedTillMessage, common.FormatDateID(user.PausedUntil))
+	}
+	return text, common.GetDeliverySettingsInlineKeyboard(user.Weekdays, paused), nil
+}
+
+func (app *Application) weekdaysAction(ctx context.Context, request *TelegramRequest, response *TelegramResponse) error {
+	user, err := app.storageController.GetUser(ctx, request.Message.From.ID)
+	if err != nil && err != storage.ErrNoSuchUser {
+		return err
+	}
+	response.Text, response.ReplyMarkup, err = app.getDeliverySettings(user, request.Message.From.FirstName)
+	return err
+}
+
+func getUserFromCallback(request *TelegramRequest) common.User {
+	return common.User{
+		ID:        request.CallbackQuery.From.ID,
+		ChatID:    request.CallbackQuery.From.ID,
+		Username:  request.CallbackQuery.From.Username,
+		FirstName: request.CallbackQuery.From.FirstName,
+		LastName:  request.CallbackQuery.From.LastName,
+	}
+}
+
+func (app *Application) deliverySettingsCallbackAction(ctx context.Context, request *TelegramRequest, callback common.CallbackData, response *TelegramResponse) error {
+	user := getUserFromCallback(request)
+	var err error
+	if callback.Type == common.WeekdaysRequest {
+		if callback.Weekdays&common.EveryDay == 0 {
+			response.Text = "There are no such days of the week. Try another breach ;)"
+			return nil
+		}
+		err = app.storageController.SetUserWeekdays(ctx, user, callback.Weekdays&common.EveryDay)
+	} else {
+		err = app.storageController.SetUserPausedUntil(ctx, user, 0)
+	}
+	if err != nil {
+		return err
+	}
+	storedUser, err := app.storageController.GetUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	response.Text, response.ReplyMarkup, err = app.getDeliverySettings(storedUser, user.FirstName)
+	return err
+}
+
+// getSettingsText returns text of the settings menu section for the user
+func getSettingsText(section common.SettingsSection, user common.User, today uint64) string {
+	sendingTimes := make([]string, len(user.SendingTimes))
+	for i, sendingTime := range user.SendingTimes {
+		sendingTimes[i] = sendingTime.String()
+		if sendingTime.Kind == common.HintSendingTime {
+			sendingTimes[i] += " " + hintArgument
+		}
+	}
+	sendingTimesText := notSetSetting
+	if user.Subscribed && len(sendingTimes) > 0 {
+		sendingTimesText = strings.Join(sendingTimes, ", ")
+	}
+	codeLanguage := common.GetCodeLanguageName(user.CodeLanguage)
+	if codeLanguage == "" {
+		codeLanguage = notSetSetting
+	}
+	subscription := notSubscribedSetting
+	if user.Subscribed && user.PausedUntil > today {
+		subscription = fmt.Sprintf(pausedSetting, common.FormatDateID(user.PausedUntil))
+	} else if user.Subscribed {
+		subscription = subscribedSetting
+	}
+	switch section {
+	case common.SendingTimeSettings:
+		return fmt.Sprintf(sendingTimeSettings, user.GetTimeZoneName(), sendingTimesText)
+	case common.TimeZoneSettings:
+		return fmt.Sprintf(timeZoneSettings, user.GetTimeZoneName())
+	case common.LanguageSettings:
+		return languageSettings
+	case common.DifficultySettings:
+		difficultyFallback := difficultyFallbackOff
+		if user.DifficultyFallback {
+			difficultyFallback = difficultyFallbackOn
+		}
+		return fmt.Sprintf(difficultySettings, user.Difficulties, difficultyFallback)
+	case common.CodeLanguageSettings:
+		return fmt.Sprintf(codeLanguageSettings, codeLanguage)
+	case common.SubscriptionSettings:
+		return fmt.Sprintf(subscriptionSettings, subscription)
+	}
+	if user.Subscribed && len(sendingTimes) > 0 && user.Weekdays != 0 && user.Weekdays != common.EveryDay {
+		sendingTimesText += ", " + user.Weekdays.String()
+	}
+	return fmt.Sprintf(settingsMessage, sendingTimesText, user.GetTimeZoneName(), user.Difficulties, codeLanguage, subscription)
+}
+
+// fillSettingsResponse sets text and inline keyboard of the settings menu section for the user
+func (app *Application) fillSettingsResponse(section common.SettingsSection, user common.User, response *TelegramResponse) error {
+	today, err := getUserToday(app.getClock(), user)
+	if err != nil {
+		return err
+	}
+	response.Text = getSettingsText(section, user, today)
+	response.ReplyMarkup = common.GetSettingsInlineKeyboard(section, user, user.PausedUntil > today)
+	return nil
+}
+
+func (app *Application) settingsAction(ctx context.Context, request *TelegramRequest, response *TelegramResponse) error {
+	user, err := app.storageController.GetUser(ctx, request.Message.From.ID)
+	if err != nil && err != storage.ErrNoSuchUser {
+		return err
+	}
+	return app.fillSettingsResponse(common.MainSettings, user, response)
+}
+
+// settingsCallbackAction applies the setting if necessary and edits the settings menu message
+func (app *Application) settingsCallbackAction(ctx context.Context, request *TelegramRequest, callback common.CallbackData, response *TelegramResponse) error {
+	if request.CallbackQuery.Message.MessageID != 0 {
+		response.Method = editMessageTextMethod
+		response.ChatID = request.CallbackQuery.Message.Chat.ID
+		response.MessageID = request.CallbackQuery.Message.MessageID
+	}
+	user := getUserFromCallback(request)
+	if callback.Type == common.SettingRequest {
+		storedUser, err := app.storageController.GetUser(ctx, user.ID)
+		if err != nil && err != storage.ErrNoSuchUser {
+			return err
+		}
+		applied, err := app.applySetting(ctx, user, storedUser, callback)
+		if err != nil {
+			return err
+		}
+		if !applied {
+			response.Method = NewTelegramResponse().Method
+			response.MessageID = 0
+			response.Text = "There is no such setting. Try another breach ;)"
+			return nil
+		}
+	}
+	storedUser, err := app.storageController.GetUser(ctx, user.ID)
+	if err != nil && err != storage.ErrNoSuchUser {
+		return err
+	}
+	return app.fillSettingsResponse(callback.Setting, storedUser, response)
+}
+
+// applySetting stores the value from the settings menu, returns false for unknown setting or value
+func (app *Application) applySetting(ctx context.Context, user common.User, storedUser common.User, callback common.CallbackData) (bool, error) {
+	var err error
+	switch callback.Setting {
+	case common.SendingTimeSettings:
+		minute, parseErr := common.ParseSendingTime(callback.Value)
+		if parseErr != nil {
+			return false, nil
+		}
+		for _, sendingTime := range storedUser.SendingTimes {
+			if storedUser.Subscribed && sendingTime.Minute == minute && sendingTime.Kind == common.TaskSendingTime {
+				err = app.storageController.RemoveSendingTime(ctx, user.ID, minute)
+				return true, err
+			}
+		}
+		err = app.storageController.SubscribeUser(ctx, user, common.SendingTime{Minute: minute, Kind: common.TaskSendingTime})
+		// The hint at the same time is kept, the menu shows the hour unchanged
+		if err == storage.ErrUserAlreadySubscribed || err == storage.ErrSendingTimeOccupied {
+			err = nil
+		}
+	case common.TimeZoneSettings:
+		index, parseErr := strconv.Atoi(callback.Value)
+		if parseErr != nil || index < 0 || index >= len(common.SettingsTimeZones) {
+			return false, nil
+		}
+		err = app.storageController.SetUserTimeZone(ctx, user, common.SettingsTimeZones[index])
+	case common.DifficultySettings:
+		if callback.Value == common.DifficultyFallbackOn || callback.Value == common.DifficultyFallbackOff {
+			err = app.storageController.SetUserDifficultyFallback(ctx, user, callback.Value == common.DifficultyFallbackOn)
+			break
+		}
+		difficulties, parseErr := strconv.Atoi(callback.Value)
+		if parseErr != nil || difficulties <= 0 || difficulties > int(common.AllDifficulties) {
+			return false, nil
+		}
+		err = app.storageController.SetUserDifficulties(ctx, user, common.Difficulties(difficulties))
+	case common.CodeLanguageSettings:
+		if common.GetCodeLanguageName(callback.Value) == "" {
+			return false, nil
+		}
+		err = app.storageController.SetUserCodeLanguage(ctx, user, callback.Value)
+	case common.SubscriptionSettings:
+		if callback.Value == common.SubscriptionOff {
+			err = app.storageController.UnsubscribeUser(ctx, user.ID)
+			if err == storage.ErrUserAlreadyUnsubscribed {
+				err = nil
+			}
+		} else if callback.Value == common.SubscriptionResume {
+			err = app.storageController.SetUserPausedUntil(ctx, user, 0)
+		} else {
+			return false, nil
+		}
+	default:
+		return false, nil
+	}
+	return true, err
+}
+
+func (app *Application) pauseAction(ctx context.Context, request *TelegramRequest, args []string, response *TelegramResponse) error {
+	if len(args) != 1 {
+		response.Text = pauseUsageMessage
+		return nil
+	}
+	user, err := app.storageController.GetUser(ctx, request.Message.From.ID)
+	if err != nil && err != storage.ErrNoSuchUser {
+		return err
+	}
+	today, err := getUserToday(app.getClock(), user)
+	if err != nil {
+		return err
+	}
+	if strings.ToLower(args[0]) == pauseOffArgument {
+		if user.PausedUntil <= today {
+			response.Text = fmt.Sprintf(notPausedMessage, request.Message.From.FirstName)
+			return nil
+		}
+		err = app.storageController.SetUserPausedUntil(ctx, getUserFromRequest(request), 0)
+		if err != nil {
+			return err
+		}
+		response.Text = fmt.Sprintf(resumedMessage, request.Message.From.FirstName)
+		return nil
+	}
+	pausedUntil, err := common.ParseDateID(args[0])
+	if err != nil {
+		response.Text = pauseUsageMessage
+		return nil
+	}
+	if pausedUntil <= today {
+		response.Text = fmt.Sprintf(pauseDateInPastMessage, request.Message.From.FirstName, common.FormatDateID(today))
+		return nil
+	}
+	err = app.storageController.SetUserPausedUntil(ctx, getUserFromRequest(request), pausedUntil)
+	if err != nil {
+		return err
+	}
+	response.Text = fmt.Sprintf(pausedMessage, request.Message.From.FirstName, common.FormatDateID(pausedUntil), user.GetTimeZoneName())
+	return nil
+}
+
+func (app *Application) unsubscribeAction(ctx context.Context, request *TelegramRequest, response *TelegramResponse) error {
+	err := app.storageController.UnsubscribeUser(ctx, request.Message.From.ID)
+	if err == storage.ErrUserAlreadyUnsubscribed {
+		response.Text = fmt.Sprintf(alreadyUnsubscribedMessage, request.Message.From.FirstName)
+	} else if err != nil {
+		return err
+	} else {
+		response.Text = fmt.Sprintf(unsubscribedMessage, request.Message.From.FirstName)
+	}
+	return nil
+}
+
+// GetTodayTaskFromAllPossibleSources is the one func to rule them all
+// It's apperared because of necessary to share logic between reminder and bot
+func (app *Application) GetTodayTaskFromAllPossibleSources(ctx context.Context) (common.BotLeetCodeTask, error) {
+	now := common.GetDateInRightTimeZone(app.getClock())
+	taskDateID := common.GetDateID(now)
+	task, err := app.storageController.GetTask(ctx, taskDateID)
+	if err != nil {
+		if err != storage.ErrNoSuchTask {
+			app.log(ctx).Warn("Error on getting task from storage, fallback to LeetCode API", "dateID", taskDateID, "error", err)
+		}
+		return app.fetchDailyTask(ctx, now)
+	}
+	return task, nil
+}
+
+// fetchDailyTask requests daily task of the date from LeetCode API and saves it to the storage
+func (app *Application) fetchDailyTask(ctx context.Context, date time.Time) (common.BotLeetCodeTask, error) {
+	lcTask, err := app.leetcodeAPIClient.GetDailyTask(ctx, date)
+	if err != nil {
+		return common.BotLeetCodeTask{}, err
+	}
+	storage.TaskSourceHits.WithLabelValues(storage.KindDailyTask, storage.SourceLeetcodeAPI).Inc()
+	task := common.BotLeetCodeTask{
+		LeetCodeTask: lcTask,
+		DateID:       common.GetDateID(date),
+	}
+	task.FixTagsAndImages()
+	err = app.storageController.SaveTask(ctx, task)
+	return task, err
+}
+
+// WarmUpTodayTask saves today's daily task to the storage, so broadcasts don't depend on LeetCode API.
+// Requests are retried with exponential backoff till the task is saved or the context is closed
+func (app *Application) WarmUpTodayTask(ctx context.Context) error {
+	ctx = logging.WithRunID(ctx, app.getLogger(), "job", "warmUp")
+	now := common.GetDateInRightTimeZone(app.getClock())
+	taskDateID := common.GetDateID(now)
+	_, err := app.storageController.GetTask(ctx, taskDateID)
+	if err == nil {
+		return nil
+	}
+	if err != storage.ErrNoSuchTask {
+		app.log(ctx).Error("Error on getting task", "dateID", taskDateID, "error", err)
+	}
+	backoff := app.getWarmUpBackoff()
+	for {
+		_, err = app.fetchDailyTask(ctx, now)
+		if err == nil {
+			return nil
+		}
+		app.log(ctx).Warn("Error on warming up daily task", "dateID", taskDateID, "retryIn", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			app.alertIfLate(ctx, taskDateID, err)
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, warmUpMaxBackoff)
+	}
+}
+
+func (app *Application) getWarmUpBackoff() time.Duration {
+	if app.warmUpBackoff == 0 {
+		return warmUpInitialBackoff
+	}
+	return app.warmUpBackoff
+}
+
+func (app *Application) getWarmUpDeadline() time.Duration {
+	if app.WarmUpDeadline == 0 {
+		return defaultWarmUpDeadline
+	}
+	return app.WarmUpDeadline
+}
+
+// alertIfLate sends alert to AlertChatID if the daily task isn't stored by the deadline. The alert is sent once per daily task,
+// as it's saved to the deliveries log. The context of the warm-up could be already closed, so the alert has its own timeout
+func (app *Application) alertIfLate(ctx context.Context, taskDateID uint64, lastErr error) {
+	deadline := common.GetDateFromDateID(taskDateID).Add(app.getWarmUpDeadline())
+	if common.GetDateInRightTimeZone(app.getClock()).Before(deadline) {
+		return
+	}
+	message := NewTelegramResponse()
+	message.ChatID = app.AlertChatID
+	message.Text = fmt.Sprintf(warmUpAlertMessage, common.FormatDateID(taskDateID), app.getWarmUpDeadline(), html.EscapeString(lastErr.Error()))
+	app.log(ctx).Error("Daily task is late", "dateID", taskDateID, "error", lastErr)
+	if app.AlertChatID == 0 {
+		return
+	}
+	alertCtx, cancelFunc := context.WithTimeout(context.WithoutCancel(ctx), alertTimeout)
+	defer cancelFunc()
+	delivery, ok := app.getDeliveries(alertCtx, taskDateID)[deliveryKey{userID: app.AlertChatID, kind: warmUpAlertKind}]
+	if ok && delivery.Status != common.DeliveryFailed {
+		return
+	}
+	if !ok {
+		delivery = common.Delivery{UserID: app.AlertChatID, DateID: taskDateID, Kind: warmUpAlertKind}
+	}
+	err := app.deliver(alertCtx, &delivery, message)
+	if err != nil {
+		app.log(ctx).Error("Error on sending alert", "chatID", app.AlertChatID, "error", err)
+	}
+}
+
+// SendMessage sends message to particular user.
+func (app *Application) SendMessage(ctx context.Context, requestBody []byte) error {
+	ctx, span := tracing.Start(ctx, "bot.SendMessage")
+	defer span.End()
+	_, err := app.sendMessage(ctx, requestBody)
+	span.RecordError(err)
+	return err
+}
+
+// telegramSendResult is a part of Telegram response to sendMessage
+type telegramSendResult struct {
+	Result struct {
+		MessageID uint64 `json:"message_id"`
+	} `json:"result"`
+}
+
+// sendMessage sends message and returns its Telegram message ID, which is zero if the response isn't parsed
+func (app *Application) sendMessage(ctx context.Context, requestBody []byte) (uint64, error) {
+	return app.callTelegramAPI(ctx, sendMessageMethod, requestBody)
+}
+
+// callTelegramAPI calls the Telegram API method by the injected sender or by HTTP
+func (app *Application) callTelegramAPI(ctx context.Context, method string, requestBody []byte) (uint64, error) {
+	ctx, span := tracing.Start(ctx, "telegram."+method)
+	defer span.End()
+	send := app.postTelegramAPI
+	if app.send != nil {
+		send = app.send
+	}
+	messageID, err := send(ctx, method, requestBody)
+	telegramRequestsTotal.WithLabelValues(method, telegramCodeLabel(err)).Inc()
+	span.RecordError(err)
+	return messageID, err
+}
+
+// postTelegramAPI posts to the Telegram API method with retries and returns message ID from the response
+func (app *Application) postTelegramAPI(ctx context.Context, method string, requestBody []byte) (uint64, error) {
+	tries := 0
+	for tries < 3 {
+		tries++
+		buf := bytes.NewBuffer(requestBody)
+		request, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf(telegramAPIURL, app.SendingToken, method), buf)
+		if err != nil {
+			return 0, err
+		}
+		request.Header.Add("content-type", "application/json")
+		tracing.InjectHeaders(ctx, request.Header)
+		resp, err := app.HTTPClient.Do(request)
+		if err == nil && resp.StatusCode >= 400 {
+			resp.Body.Close()
+			err = &telegramStatusError{StatusCode: resp.StatusCode}
+		}
+		if err != nil {
+			if tries == 3 {
+				return 0, err
+			}
+			continue
+		}
+		responseBytes, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		result := telegramSendResult{}
+		_ = json.Unmarshal(responseBytes, &result)
+		return result.Result.MessageID, nil
+	}
+	return 0, nil
+}
+
+func (app *Application) getSendingSlot() time.Duration {
+	if app.SendingSlot == 0 {
+		return common.DefaultSendingSlot
+	}
+	return app.SendingSlot
+}
+
+// SendDailyTaskToSubscribedUsers get subscribed users with sending times in the current slot
+// and send notifications with daily task or its first hint to them.
+// The task is taken only from the storage, WarmUpTodayTask should save it before.
+// Every delivery is logged, so the rerun skips messages already sent and retries failed ones
+func (app *Application) SendDailyTaskToSubscribedUsers(ctx context.Context) (DeliverySummary, error) {
+	ctx = logging.WithRunID(ctx, app.getLogger(), "job", "dailyTask")
+	defer observeBroadcast("dailyTask", time.Now())
+	summary := DeliverySummary{}
+	usersSlice, err := app.storageController.GetSubscribedUsers(ctx, app.now(), app.getSendingSlot())
+	if err != nil {
+		return summary, err
+	}
+	broadcastUsers.WithLabelValues("dailyTask", strconv.Itoa(app.now().UTC().Hour())).Set(float64(len(usersSlice)))
+
+	task, err := app.storageController.GetTask(ctx, common.GetDateIDForNow(app.getClock()))
+	if err != nil {
+		return summary, err
+	}
+
+	deliveries := app.getDeliveries(ctx, task.DateID)
+	fallbackTasks := app.getFallbackTasks(ctx, usersSlice, task)
+
+	var summaryMutex sync.Mutex
+	app.sendToUsers(ctx, usersSlice, func(ctx context.Context, user common.User) error {
+		var lastErr error
+		for _, sendingTime := range user.SendingTimes {
+			delivery, ok := deliveries[deliveryKey{userID: user.ID, kind: sendingTime.GetDeliveryKind()}]
+			if !ok {
+				delivery = common.Delivery{UserID: user.ID, DateID: task.DateID, Kind: sendingTime.GetDeliveryKind()}
+			}
+			if delivery.Status != common.DeliveryFailed || delivery.Attempts >= maxDeliveryAttempts {
+				summaryMutex.Lock()
+				summary.Skipped++
+				summaryMutex.Unlock()
+				continue
+			}
+			message := getScheduledMessage(app.getClock(), user, sendingTime, task)
+			if !user.Difficulties.Contains(task.GetDifficultyNum()) {
+				message = getFallbackMessage(app.getClock(), user, sendingTime, task, fallbackTasks)
+				if message == nil {
+					summaryMutex.Lock()
+					summary.Skipped++
+					summaryMutex.Unlock()
+					continue
+				}
+			}
+			err := app.deliver(ctx, &delivery, message)
+			summaryMutex.Lock()
+			switch {
+			case err != nil:
+				lastErr = err
+				summary.Failed++
+			case delivery.Status == common.DeliveryQueued:
+				summary.Queued++
+			default:
+				summary.Sent++
+			}
+			summaryMutex.Unlock()
+		}
+		return lastErr
+	})
+	deliveriesTotal.WithLabelValues("sent").Add(float64(summary.Sent))
+	deliveriesTotal.WithLabelValues("queued").Add(float64(summary.Queued))
+	deliveriesTotal.WithLabelValues("skipped").Add(float64(summary.Skipped))
+	deliveriesTotal.WithLabelValues("failed").Add(float64(summary.Failed))
+	app.log(ctx).Info("Daily task is sent", "dateID", task.DateID, "users", len(usersSlice), "sent", summary.Sent, "queued", summary.Queued, "skipped", summary.Skipped, "failed", summary.Failed)
+	return summary, nil
+}
+
+// sendOrEnqueue saves the message to the outbox if it's configured, so the message isn't lost when the function dies.
+// Without the outbox the message is sent directly
+func (app *Application) sendOrEnqueue(ctx context.Context, chatID uint64, requestBody []byte) error {
+	if app.outbox == nil {
+		_, err := app.sendMessage(ctx, requestBody)
+		return err
+	}
+	return app.outbox.Enqueue(ctx, []common.OutboxMessage{{ChatID: chatID, Method: sendMessageMethod, Body: requestBody}})
+}
+
+// enqueueDelivery saves the message to the outbox with its delivery as queued. The outbox saves both at once,
+// so the rerun doesn't queue the message twice, and the dispatcher updates the delivery after sending
+func (app *Application) enqueueDelivery(ctx context.Context, delivery *common.Delivery, chatID uint64, requestBody []byte) error {
+	queued := *delivery
+	queued.Status = common.DeliveryQueued
+	queued.LastError = ""
+	err := app.outbox.Enqueue(ctx, []common.OutboxMessage{{ChatID: chatID, Method: sendMessageMethod, Body: requestBody, Delivery: queued}})
+	if err == nil {
+		*delivery = queued
+	}
+	return err
+}
+
+// DispatchOutbox sends messages waiting in the outbox, it does nothing when the outbox isn't configured
+func (app *Application) DispatchOutbox(ctx context.Context) (outbox.Stats, error) {
+	ctx = logging.WithRunID(ctx, app.getLogger(), "job", "outbox")
+	if app.outbox == nil {
+		return outbox.Stats{}, nil
+	}
+	dispatcher := outbox.NewDispatcher(app.outbox, func(ctx context.Context, message common.OutboxMessage) error {
+		_, err := app.callTelegramAPI(ctx, message.Method, message.Body)
+		return err
+	})
+	dispatcher.Clock = app.getClock()
+	return dispatcher.Dispatch(ctx)
+}
+
+// getDeliveries returns log of the dateID task deliveries. Messages could be sent twice without the log,
+// but it's better than not to send them at all, so storage errors are only printed
+func (app *Application) getDeliveries(ctx context.Context, dateID uint64) map[deliveryKey]common.Delivery {
+	deliveries := map[deliveryKey]common.Delivery{}
+	deliveriesSlice, err := app.storageController.GetDeliveries(ctx, dateID)
+	if err != nil {
+		app.log(ctx).Error("Error on getting deliveries", "dateID", dateID, "error", err)
+		return deliveries
+	}
+	for _, delivery := range deliveriesSlice {
+		deliveries[deliveryKey{userID: delivery.UserID, kind: delivery.Kind}] = delivery
+	}
+	return deliveries
+}
+
+// deliver sends or enqueues the message and saves the result of the attempt to the delivery log
+func (app *Application) deliver(ctx context.Context, delivery *common.Delivery, message *TelegramResponse) error {
+	delivery.Attempts++
+	bytes, err := json.Marshal(message)
+	switch {
+	case err == nil && app.outbox != nil:
+		// The outbox saves the queued delivery together with the message
+		err = app.enqueueDelivery(ctx, delivery, message.ChatID, bytes)
+		if err == nil {
+			return nil
+		}
+	case err == nil:
+		delivery.MessageID, err = app.sendMessage(ctx, bytes)
+	}
+	if err != nil {
+		delivery.Status = common.DeliveryFailed
+		delivery.LastError = err.Error()
+	} else {
+		delivery.Status = common.DeliverySent
+		delivery.LastError = ""
+	}
+	saveErr := app.storageController.SaveDelivery(ctx, *delivery)
+	if saveErr != nil {
+		app.log(ctx).Error("Error on saving delivery", "userID", delivery.UserID, "error", saveErr)
+	}
+	return err
+}
+
+// getFallbackTasks returns random problems of difficulties allowed for users, whose filter excludes today's task
+// and who have chosen to get random problem instead
+func (app *Application) getFallbackTasks(ctx context.Context, users []common.User, task common.BotLeetCodeTask) map[uint8]common.BotLeetCodeTask {
+	fallbackTasks := map[uint8]common.BotLeetCodeTask{}
+	for _, user := range users {
+		if !user.DifficultyFallback || user.Difficulties.Contains(task.GetDifficultyNum()) {
+			continue
+		}
+		for _, difficulty := range user.Difficulties.GetDifficultiesNums() {
+			if _, ok := fallbackTasks[difficulty]; ok {
+				continue
+			}
+			fallbackTask := common.BotLeetCodeTask{}
+			fallbackTask.SetDifficultyFromNum(difficulty)
+			lcTask, err := app.leetcodeAPIClient.GetRandomQuestion(ctx, leetcodeclient.QuestionsFilter{Difficulty: strings.ToUpper(fallbackTask.Difficulty)})
+			if err != nil {
+				app.log(ctx).Error("Error on getting random problem", "difficulty", fallbackTask.Difficulty, "error", err)
+				continue
+			}
+			fallbackTask.LeetCodeTask = lcTask
+			fallbackTask.FixTagsAndImages()
+			// Callbacks could find the problem only in the storage
+			err = app.storageController.SaveQuestion(ctx, fallbackTask)
+			if err != nil {
+				app.log(ctx).Error("Error on saving question", "questionID", fallbackTask.QuestionID, "error", err)
+			}
+			fallbackTasks[difficulty] = fallbackTask
+		}
+	}
+	return fallbackTasks
+}
+
+// getFallbackMessage returns random problem of allowed difficulty instead of filtered out daily task.
+// Returns nil when nothing should be sent: user haven't chosen fallback, there is no fallback problem or it's a hint time
+func getFallbackMessage(clock common.Clock, user common.User, sendingTime common.SendingTime, task common.BotLeetCodeTask, fallbackTasks map[uint8]common.BotLeetCodeTask) *TelegramResponse {
+	if !user.DifficultyFallback || sendingTime.Kind == common.HintSendingTime {
+		return nil
+	}
+	allowedTasks := []common.BotLeetCodeTask{}
+	for _, difficulty := range user.Difficulties.GetDifficultiesNums() {
+		if fallbackTask, ok := fallbackTasks[difficulty]; ok {
+			allowedTasks = append(allowedTasks, fallbackTask)
+		}
+	}
+	if len(allowedTasks) == 0 {
+		return nil
+	}
+	fallbackTask := allowedTasks[rand.Intn(len(allowedTasks))]
+	telegramRequest := NewTelegramResponse()
+	telegramRequest.ChatID = user.ID
+	telegramRequest.Text = fmt.Sprintf(difficultyFallbackMessage, task.Difficulty, fallbackTask.Difficulty, fallbackTask.GetTaskText())
+	telegramRequest.ReplyMarkup = fallbackTask.GetInlineKeyboardForLanguage(clock, user.CodeLanguage)
+	return telegramRequest
+}
+
+// getScheduledMessage returns the daily task or its first hint depending on the kind of the sending time
+func getScheduledMessage(clock common.Clock, user common.User, sendingTime common.SendingTime, task common.BotLeetCodeTask) *TelegramResponse {
+	telegramRequest := NewTelegramResponse()
+	telegramRequest.ChatID = user.ID
+	telegramRequest.ReplyMarkup = task.GetInlineKeyboardForLanguage(clock, user.CodeLanguage)
+	if sendingTime.Kind != common.HintSendingTime {
+		telegramRequest.Text = task.GetTaskText()
+		return telegramRequest
+	}
+	if len(task.Hints) == 0 {
+		telegramRequest.Text = fmt.Sprintf(noHintsReminderMessage, task.Title)
+		return telegramRequest
+	}
+	telegramRequest.Text = fmt.Sprintf(hintReminderMessage, task.Title, task.Hints[0])
+	return telegramRequest
+}
+
+// SendNudgesToLinkedUsers checks if users with the nudge at the current hour have solved today's daily task.
+// Sends reminder to users who haven't and congratulation with the streak to users who have.
+// Every user gets only one nudge per day, even if the function is called several times.
+func (app *Application) SendNudgesToLinkedUsers(ctx context.Context) error {
+	ctx = logging.WithRunID(ctx, app.getLogger(), "job", "nudges")
+	defer observeBroadcast("nudges", time.Now())
+	task, err := app.storageController.GetTask(ctx, common.GetDateIDForNow(app.getClock()))
+	if err != nil {
+		return err
+	}
+	usersSlice, err := app.storageController.GetNudgeUsers(ctx, app.now(), app.getSendingSlot(), task.DateID)
+	if err != nil {
+		return err
+	}
+	broadcastUsers.WithLabelValues("nudges", strconv.Itoa(app.now().UTC().Hour())).Set(float64(len(usersSlice)))
+
+	app.sendToUsers(ctx, usersSlice, func(ctx context.Context, user common.User) error {
+		telegramRequest, err := app.getNudgeMessage(ctx, user, task)
+		if err != nil {
+			return err
+		}
+		bytes, err := json.Marshal(telegramRequest)
+		if err != nil {
+			return err
+		}
+		err = app.sendOrEnqueue(ctx, telegramRequest.ChatID, bytes)
+		if err != nil {
+			return err
+		}
+		return app.storageController.MarkNudgeSent(ctx, user.ID, task.DateID)
+	})
+	return nil
+}
+
+func (app *Application) getNudgeMessage(ctx context.Context, user common.User, task common.BotLeetCodeTask) (*TelegramResponse, error) {
+	submissions, err := app.leetcodeAPIClient.GetRecentAcceptedSubmissions(ctx, user.LeetcodeUsername, leetcodeclient.RecentSubmissionsLimit)
+	if err != nil {
+		return nil, err
+	}
+	telegramRequest := NewTelegramResponse()
+	telegramRequest.ChatID = user.ID
+	if !leetcodeclient.HasAcceptedSubmission(submissions, task.TitleSlug, common.GetDateInRightTimeZone(app.getClock()).Truncate(24*time.Hour)) {
+		telegramRequest.Text = fmt.Sprintf(nudgeNotSolvedMessage, user.FirstName, task.GetTaskText())
+		telegramRequest.ReplyMarkup = task.GetInlineKeyboardForLanguage(app.getClock(), user.CodeLanguage)
+		return telegramRequest, nil
+	}
+	profile, err := app.leetcodeAPIClient.GetUserProfile(ctx, user.LeetcodeUsername)
+	if err != nil {
+		return nil, err
+	}
+	telegramRequest.Text = fmt.Sprintf(nudgeSolvedMessage, user.FirstName, profile.Streak)
+	return telegramRequest, nil
+}
+
+// sendToUsers concurrently calls send for every user and waits for all of them. Errors are only printed,
+// because one broken chat shouldn't break sending to others.
+func (app *Application) sendToUsers(ctx context.Context, users []common.User, send func(context.Context, common.User) error) {
+	var wg sync.WaitGroup
+	for _, user := range users {
+		wg.Add(1)
+		go func(user common.User) {
+			err := send(ctx, user)
+			if err != nil {
+				app.log(ctx).Error("Error on sending message", "userID", user.ID, "error", err)
+			}
+			wg.Done()
+		}(user)
+	}
+	wg.Wait()
+}
+
+// GetMainKeyboard returns marshaled json for the main keyboard
+func GetMainKeyboard() (string, error) {
+	return keyboardToJSON(generateMainKeyboard())
+}
+
+// GetTimeZoneKeyboard creates marshalled json with the button to share location for time zone
+func GetTimeZoneKeyboard() (string, error) {
+	return keyboardToJSON(generateTimeZoneKeyboard())
+}
+
+// GetSubscribeHourdsKeyboard creates marshalled json with 24-hourds subscribe keyboard
+func GetSubscribeHourdsKeyboard() (string, error) {
+	return keyboardToJSON(generateSubscribeHourdsKeyboard())
 }
 
 func keyboardToJSON(keyboard KeyboardDef) (string, error) {
@@ -360,6 +2049,12 @@ func generateMainKeyboard() KeyboardDef {
 	}
 }
 
+func generateTimeZoneKeyboard() KeyboardDef {
+	keyboard := generateMainKeyboard()
+	keyboard.Keyboard = append([][]Key{{{Text: shareLocationCommand, RequestLocation: true}}}, keyboard.Keyboard...)
+	return keyboard
+}
+
 func generateSubscribeHourdsKeyboard() KeyboardDef {
 	keys := [][]Key{}
 	rowsNum := 6
@@ -372,19 +2067,83 @@ func generateSubscribeHourdsKeyboard() KeyboardDef {
 	}
 	return KeyboardDef{
 		ResizeKeyboard:        true,
-		InputFieldPlaceholder: "Please, choose the sending hour below:",
+		InputFieldPlaceholder: "Please, choose the hour below or type the time:",
 		Keyboard:              keys,
 	}
 }
 
-// NewApplication Application constructor with default values
-func NewApplication(httpClient *http.Client) *Application {
-	if httpClient == nil {
-		httpClient = &http.Client{}
+// NewApplication Application constructor with values of the config. Options replace default components,
+// so the bot could be embedded with other storage, LeetCode client or Telegram sender
+func NewApplication(cfg *config.Config, options ...Option) *Application {
+	defaultOutbox := getOutbox(cfg)
+	app := &Application{
+		storageController:   storage.NewTracingController(storage.NewYDBandFileCacheController(cfg)),
+		leetcodeAPIClient:   leetcodeclient.NewLeetCodeGraphQlClientWithURL(cfg.LeetcodeGraphQlURL),
+		HTTPClient:          &http.Client{},
+		SendingToken:        cfg.SendingToken,
+		SendingSlot:         cfg.SendingSlot,
+		WarmUpDeadline:      cfg.WarmUpDeadline,
+		AlertChatID:         cfg.AlertChatID,
+		ResponseTimeout:     cfg.ResponseTimeout,
+		ProcessingTimeout:   cfg.ProcessingTimeout,
+		EarlyAnswer:         cfg.EarlyAnswerEnabled,
+		outbox:              defaultOutbox,
+		updatesDeduplicator: NewUpdatesDeduplicator(cfg),
+		clock:               common.SystemClock{},
+		logger:              slog.Default(),
 	}
-	return &Application{
-		storageController: storage.NewYDBandFileCacheController(),
-		leetcodeAPIClient: leetcodeclient.NewLeetCodeGraphQlClient(),
-		HTTPClient:        httpClient,
+	for _, option := range options {
+		option(app)
+	}
+	// IDs of the default outbox messages are assigned by the clock of the application
+	if ydbOutbox, ok := app.outbox.(*storage.YDBOutbox); ok && app.outbox == defaultOutbox {
+		ydbOutbox.Clock = app.getClock()
+	}
+	return app
+}
+
+type deduplicatorKey struct {
+	capacity   int
+	ttl        time.Duration
+	persistent string
+}
+
+var initializedDeduplicators = map[deduplicatorKey]storage.UpdatesDeduplicator{}
+var initializedDeduplicatorsMutex sync.Mutex
+
+// NewUpdatesDeduplicator returns in-memory deduplicator with the capacity and TTL of the config.
+// With persistent dedupe enabled updates are also stored in YDB to be seen by other instances.
+// Deduplicator is shared by all applications of the process with the same settings, so the warm function
+// instance remembers updates processed by previous requests
+func NewUpdatesDeduplicator(cfg *config.Config) storage.UpdatesDeduplicator {
+	key := deduplicatorKey{capacity: dedupeCapacity, ttl: dedupeTTL}
+	if cfg.DedupeCapacity > 0 {
+		key.capacity = int(cfg.DedupeCapacity)
+	}
+	if cfg.DedupeTTL > 0 {
+		key.ttl = cfg.DedupeTTL
+	}
+	if cfg.PersistentDedupeEnabled {
+		key.persistent = cfg.YDBConnectionString()
+	}
+	initializedDeduplicatorsMutex.Lock()
+	defer initializedDeduplicatorsMutex.Unlock()
+	if deduplicator, ok := initializedDeduplicators[key]; ok {
+		return deduplicator
+	}
+	var persistent storage.UpdatesDeduplicator
+	if cfg.PersistentDedupeEnabled {
+		persistent = storage.NewYDBDeduplicator(cfg)
+	}
+	deduplicator := storage.NewLRUDeduplicator(key.capacity, key.ttl, persistent)
+	initializedDeduplicators[key] = deduplicator
+	return deduplicator
+}
+
+// getOutbox returns YDB outbox when it's enabled, otherwise messages are sent directly
+func getOutbox(cfg *config.Config) storage.Outbox {
+	if !cfg.OutboxEnabled {
+		return nil
 	}
+	return storage.NewYDBOutbox(cfg)
 }
@@ -1,31 +1,52 @@
 package bot
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/dartkron/leetcodeBot/v3/internal/common"
+	"github.com/dartkron/leetcodeBot/v3/internal/config"
+	"github.com/dartkron/leetcodeBot/v3/internal/logging"
+	"github.com/dartkron/leetcodeBot/v3/internal/outbox"
 	"github.com/dartkron/leetcodeBot/v3/internal/storage"
 	"github.com/dartkron/leetcodeBot/v3/pkg/leetcodeclient"
 	lcclientmocks "github.com/dartkron/leetcodeBot/v3/pkg/leetcodeclient/mocks"
+	"github.com/dartkron/leetcodeBot/v3/pkg/tracing"
 	"github.com/dartkron/leetcodeBot/v3/tests"
 	"github.com/dartkron/leetcodeBot/v3/tests/mocks"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 type MockStorageController struct {
 	tasks                      map[uint64]*common.BotLeetCodeTask
+	questions                  map[uint64]*common.BotLeetCodeTask
 	users                      map[uint64]*common.User
 	callsJournal               []string
 	getSubscribedUsersMustFail bool
 	failedUserID               uint64
 	failedTaskID               uint64
+	nudgeSent                  map[uint64]uint64
+	nudgesMutex                sync.Mutex
+	deliveries                 []common.Delivery
+	deliveriesMutex            sync.Mutex
 }
 
 func (controller *MockStorageController) GetTask(ctx context.Context, dateID uint64) (common.BotLeetCodeTask, error) {
@@ -48,25 +69,83 @@ func (controller *MockStorageController) SaveTask(ctx context.Context, task comm
 	return nil
 }
 
-func (controller *MockStorageController) SubscribeUser(ctx context.Context, user common.User, sendingHour uint8) error {
-	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("SubscribeUser %d %d", user.ID, sendingHour))
+func (controller *MockStorageController) GetQuestion(ctx context.Context, questionID uint64) (common.BotLeetCodeTask, error) {
+	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("GetQuestion %d", questionID))
+	if questionID == controller.failedTaskID {
+		return common.BotLeetCodeTask{}, tests.ErrBypassTest
+	}
+	if task, ok := controller.questions[questionID]; ok {
+		return *task, nil
+	}
+	return common.BotLeetCodeTask{}, storage.ErrNoSuchTask
+}
+
+func (controller *MockStorageController) GetQuestionBySlug(ctx context.Context, titleSlug string) (common.BotLeetCodeTask, error) {
+	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("GetQuestionBySlug %s", titleSlug))
+	for _, task := range controller.questions {
+		if task.TitleSlug == titleSlug {
+			if task.QuestionID == controller.failedTaskID {
+				return common.BotLeetCodeTask{}, tests.ErrBypassTest
+			}
+			return *task, nil
+		}
+	}
+	return common.BotLeetCodeTask{}, storage.ErrNoSuchTask
+}
+
+func (controller *MockStorageController) SaveQuestion(ctx context.Context, task common.BotLeetCodeTask) error {
+	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("SaveQuestion %d", task.QuestionID))
+	if task.QuestionID == controller.failedTaskID {
+		return tests.ErrBypassTest
+	}
+	controller.questions[task.QuestionID] = &task
+	return nil
+}
+
+func (controller *MockStorageController) SubscribeUser(ctx context.Context, user common.User, sendingTime common.SendingTime) error {
+	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("SubscribeUser %d %s %d", user.ID, sendingTime, sendingTime.Kind))
 	if user.ID == controller.failedUserID {
 		return tests.ErrBypassTest
 	}
-	if storedUser, ok := controller.users[user.ID]; ok {
-		if storedUser.Subscribed {
-			return storage.ErrUserAlreadySubscribed
-		}
-		controller.users[user.ID].Subscribed = true
-		controller.users[user.ID].SendingHour = sendingHour
-	} else {
-		user.Subscribed = true
-		user.SendingHour = sendingHour
+	storedUser, ok := controller.users[user.ID]
+	if !ok {
 		controller.users[user.ID] = &user
+		storedUser = &user
 	}
+	if storedUser.Subscribed {
+		for _, storedTime := range storedUser.SendingTimes {
+			if storedTime == sendingTime {
+				return storage.ErrUserAlreadySubscribed
+			}
+			if storedTime.Minute == sendingTime.Minute {
+				return storage.ErrSendingTimeOccupied
+			}
+		}
+	}
+	storedUser.Subscribed = true
+	storedUser.SendingTimes = append(storedUser.SendingTimes, sendingTime)
 	return nil
 }
 
+func (controller *MockStorageController) RemoveSendingTime(ctx context.Context, userID uint64, minute uint16) error {
+	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("RemoveSendingTime %d %d", userID, minute))
+	if userID == controller.failedUserID {
+		return tests.ErrBypassTest
+	}
+	user, ok := controller.users[userID]
+	if !ok {
+		return storage.ErrNoSuchSendingTime
+	}
+	for i, sendingTime := range user.SendingTimes {
+		if sendingTime.Minute == minute {
+			user.SendingTimes = append(user.SendingTimes[:i], user.SendingTimes[i+1:]...)
+			user.Subscribed = len(user.SendingTimes) > 0
+			return nil
+		}
+	}
+	return storage.ErrNoSuchSendingTime
+}
+
 func (controller *MockStorageController) UnsubscribeUser(ctx context.Context, userID uint64) error {
 	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("UnsubscribeUser %d", userID))
 	if userID == controller.failedUserID {
@@ -83,8 +162,33 @@ func (controller *MockStorageController) UnsubscribeUser(ctx context.Context, us
 	return nil
 }
 
-func (controller *MockStorageController) GetSubscribedUsers(ctx context.Context, sendingHour uint8) ([]common.User, error) {
-	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("GetSubscribedUsers %d", sendingHour))
+func (controller *MockStorageController) GetUser(ctx context.Context, userID uint64) (common.User, error) {
+	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("GetUser %d", userID))
+	if userID == controller.failedUserID {
+		return common.User{}, tests.ErrBypassTest
+	}
+	if user, ok := controller.users[userID]; ok {
+		return *user, nil
+	}
+	return common.User{}, storage.ErrNoSuchUser
+}
+
+func (controller *MockStorageController) LinkLeetcodeUsername(ctx context.Context, user common.User, leetcodeUsername string) error {
+	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("LinkLeetcodeUsername %d %s", user.ID, leetcodeUsername))
+	if user.ID == controller.failedUserID {
+		return tests.ErrBypassTest
+	}
+	if storedUser, ok := controller.users[user.ID]; ok {
+		storedUser.LeetcodeUsername = leetcodeUsername
+	} else {
+		user.LeetcodeUsername = leetcodeUsername
+		controller.users[user.ID] = &user
+	}
+	return nil
+}
+
+func (controller *MockStorageController) GetSubscribedUsers(ctx context.Context, now time.Time, slot time.Duration) ([]common.User, error) {
+	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("GetSubscribedUsers %d %s", now.UTC().Hour(), slot))
 	if controller.getSubscribedUsersMustFail {
 		return []common.User{}, tests.ErrBypassTest
 	}
@@ -97,6 +201,168 @@ func (controller *MockStorageController) GetSubscribedUsers(ctx context.Context,
 	return resp, nil
 }
 
+func (controller *MockStorageController) SetUserTimeZone(ctx context.Context, user common.User, timeZone string) error {
+	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("SetUserTimeZone %d %s", user.ID, timeZone))
+	if user.ID == controller.failedUserID {
+		return tests.ErrBypassTest
+	}
+	if storedUser, ok := controller.users[user.ID]; ok {
+		storedUser.TimeZone = timeZone
+	} else {
+		user.TimeZone = timeZone
+		controller.users[user.ID] = &user
+	}
+	return nil
+}
+
+func (controller *MockStorageController) SetUserWeekdays(ctx context.Context, user common.User, weekdays common.Weekdays) error {
+	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("SetUserWeekdays %d %d", user.ID, weekdays))
+	if user.ID == controller.failedUserID {
+		return tests.ErrBypassTest
+	}
+	if storedUser, ok := controller.users[user.ID]; ok {
+		storedUser.Weekdays = weekdays
+	} else {
+		user.Weekdays = weekdays
+		controller.users[user.ID] = &user
+	}
+	return nil
+}
+
+func (controller *MockStorageController) SetUserPausedUntil(ctx context.Context, user common.User, pausedUntil uint64) error {
+	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("SetUserPausedUntil %d %d", user.ID, pausedUntil))
+	if user.ID == controller.failedUserID {
+		return tests.ErrBypassTest
+	}
+	if storedUser, ok := controller.users[user.ID]; ok {
+		storedUser.PausedUntil = pausedUntil
+	} else {
+		user.PausedUntil = pausedUntil
+		controller.users[user.ID] = &user
+	}
+	return nil
+}
+
+func (controller *MockStorageController) SetUserDifficulties(ctx context.Context, user common.User, difficulties common.Difficulties) error {
+	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("SetUserDifficulties %d %d", user.ID, difficulties))
+	if user.ID == controller.failedUserID {
+		return tests.ErrBypassTest
+	}
+	if storedUser, ok := controller.users[user.ID]; ok {
+		storedUser.Difficulties = difficulties
+	} else {
+		user.Difficulties = difficulties
+		controller.users[user.ID] = &user
+	}
+	return nil
+}
+
+func (controller *MockStorageController) SetUserDifficultyFallback(ctx context.Context, user common.User, difficultyFallback bool) error {
+	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("SetUserDifficultyFallback %d %t", user.ID, difficultyFallback))
+	if user.ID == controller.failedUserID {
+		return tests.ErrBypassTest
+	}
+	if storedUser, ok := controller.users[user.ID]; ok {
+		storedUser.DifficultyFallback = difficultyFallback
+	} else {
+		user.DifficultyFallback = difficultyFallback
+		controller.users[user.ID] = &user
+	}
+	return nil
+}
+
+func (controller *MockStorageController) SetUserCodeLanguage(ctx context.Context, user common.User, codeLanguage string) error {
+	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("SetUserCodeLanguage %d %s", user.ID, codeLanguage))
+	if user.ID == controller.failedUserID {
+		return tests.ErrBypassTest
+	}
+	if storedUser, ok := controller.users[user.ID]; ok {
+		storedUser.CodeLanguage = codeLanguage
+	} else {
+		user.CodeLanguage = codeLanguage
+		controller.users[user.ID] = &user
+	}
+	return nil
+}
+
+func (controller *MockStorageController) SubscribeUserToNudge(ctx context.Context, user common.User, nudgeHour uint8) error {
+	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("SubscribeUserToNudge %d %d", user.ID, nudgeHour))
+	if user.ID == controller.failedUserID {
+		return tests.ErrBypassTest
+	}
+	storedUser := controller.users[user.ID]
+	if storedUser.NudgeSubscribed && storedUser.NudgeHour == nudgeHour {
+		return storage.ErrUserAlreadySubscribed
+	}
+	storedUser.NudgeSubscribed = true
+	storedUser.NudgeHour = nudgeHour
+	return nil
+}
+
+func (controller *MockStorageController) UnsubscribeUserFromNudge(ctx context.Context, userID uint64) error {
+	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("UnsubscribeUserFromNudge %d", userID))
+	if userID == controller.failedUserID {
+		return tests.ErrBypassTest
+	}
+	if user, ok := controller.users[userID]; ok && user.NudgeSubscribed {
+		user.NudgeSubscribed = false
+		return nil
+	}
+	return storage.ErrUserAlreadyUnsubscribed
+}
+
+func (controller *MockStorageController) GetNudgeUsers(ctx context.Context, now time.Time, slot time.Duration, dateID uint64) ([]common.User, error) {
+	controller.nudgesMutex.Lock()
+	defer controller.nudgesMutex.Unlock()
+	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("GetNudgeUsers %d %s %d", now.UTC().Hour(), slot, dateID))
+	if controller.getSubscribedUsersMustFail {
+		return []common.User{}, tests.ErrBypassTest
+	}
+	resp := []common.User{}
+	for _, user := range controller.users {
+		if user.NudgeSubscribed && user.LeetcodeUsername != "" && controller.nudgeSent[user.ID] != dateID {
+			resp = append(resp, *user)
+		}
+	}
+	return resp, nil
+}
+
+func (controller *MockStorageController) MarkNudgeSent(ctx context.Context, userID uint64, dateID uint64) error {
+	controller.nudgesMutex.Lock()
+	defer controller.nudgesMutex.Unlock()
+	controller.callsJournal = append(controller.callsJournal, fmt.Sprintf("MarkNudgeSent %d %d", userID, dateID))
+	if userID == controller.failedUserID {
+		return tests.ErrBypassTest
+	}
+	controller.nudgeSent[userID] = dateID
+	return nil
+}
+
+func (controller *MockStorageController) GetDeliveries(ctx context.Context, dateID uint64) ([]common.Delivery, error) {
+	controller.deliveriesMutex.Lock()
+	defer controller.deliveriesMutex.Unlock()
+	resp := []common.Delivery{}
+	for _, delivery := range controller.deliveries {
+		if delivery.DateID == dateID {
+			resp = append(resp, delivery)
+		}
+	}
+	return resp, nil
+}
+
+func (controller *MockStorageController) SaveDelivery(ctx context.Context, delivery common.Delivery) error {
+	controller.deliveriesMutex.Lock()
+	defer controller.deliveriesMutex.Unlock()
+	for i, stored := range controller.deliveries {
+		if stored.UserID == delivery.UserID && stored.DateID == delivery.DateID && stored.Kind == delivery.Kind {
+			controller.deliveries[i] = delivery
+			return nil
+		}
+	}
+	controller.deliveries = append(controller.deliveries, delivery)
+	return nil
+}
+
 func TestGetMainKeyboard(t *testing.T) {
 	waitKeyboard := "{\"keyboard\":[[{\"text\":\"Get actual daily task\"}],[{\"text\":\"Subscribe\"},{\"text\":\"Unsubscribe\"}]],\"input_field_placeholder\":\"Please, use buttons below:\",\"resize_keyboard\":true}"
 	keyboard, err := GetMainKeyboard()
@@ -105,7 +371,7 @@ func TestGetMainKeyboard(t *testing.T) {
 }
 
 func getTodaySendMessageString(chatID uint64) string {
-	dateID := common.GetDateIDForNow()
+	dateID := common.GetDateIDForNow(common.SystemClock{})
 	task := common.BotLeetCodeTask{
 		DateID: dateID,
 		LeetCodeTask: leetcodeclient.LeetCodeTask{
@@ -120,7 +386,7 @@ func getTodaySendMessageString(chatID uint64) string {
 	response := NewTelegramResponse()
 	response.ChatID = chatID
 	response.Text = task.GetTaskText()
-	response.ReplyMarkup = task.GetInlineKeyboard()
+	response.ReplyMarkup = task.GetInlineKeyboard(common.SystemClock{})
 	bytes, _ := json.Marshal(response)
 	return string(bytes)
 }
@@ -129,51 +395,54 @@ func getTestApp() (*mocks.MockHTTPTransport, *MockStorageController, *lcclientmo
 	httpTransportMock := &mocks.MockHTTPTransport{}
 	leetcodeClient := &lcclientmocks.MockLeetcodeClient{}
 	storageController := &MockStorageController{
-		tasks: map[uint64]*common.BotLeetCodeTask{},
+		tasks:     map[uint64]*common.BotLeetCodeTask{},
+		questions: map[uint64]*common.BotLeetCodeTask{},
+		nudgeSent: map[uint64]uint64{},
 		users: map[uint64]*common.User{
 			1124: {
-				ID:          1124,
-				ChatID:      1124,
-				Username:    "testuser1124",
-				FirstName:   "1124firstname",
-				LastName:    "1124lastname",
-				Subscribed:  false,
-				SendingHour: 0,
+				ID:         1124,
+				ChatID:     1124,
+				Username:   "testuser1124",
+				FirstName:  "1124firstname",
+				LastName:   "1124lastname",
+				Subscribed: false,
 			},
 			1126: {
-				ID:          1126,
-				ChatID:      1126,
-				Username:    "testuser1126",
-				FirstName:   "1126firstname",
-				LastName:    "1126lastname",
-				Subscribed:  true,
-				SendingHour: 0,
+				ID:           1126,
+				ChatID:       1126,
+				Username:     "testuser1126",
+				FirstName:    "1126firstname",
+				LastName:     "1126lastname",
+				Subscribed:   true,
+				SendingTimes: []common.SendingTime{{Minute: 0}},
 			},
 			1128: {
-				ID:          1128,
-				ChatID:      1128,
-				Username:    "testuser1128",
-				FirstName:   "1128firstname",
-				LastName:    "1128lastname",
-				Subscribed:  false,
-				SendingHour: 0,
+				ID:         1128,
+				ChatID:     1128,
+				Username:   "testuser1128",
+				FirstName:  "1128firstname",
+				LastName:   "1128lastname",
+				Subscribed: false,
 			},
 			1120: {
-				ID:          1120,
-				ChatID:      1120,
-				Username:    "testuser1120",
-				FirstName:   "1120firstname",
-				LastName:    "1120lastname",
-				Subscribed:  true,
-				SendingHour: 0,
+				ID:           1120,
+				ChatID:       1120,
+				Username:     "testuser1120",
+				FirstName:    "1120firstname",
+				LastName:     "1120lastname",
+				Subscribed:   true,
+				SendingTimes: []common.SendingTime{{Minute: 0}},
 			},
 		},
 	}
-	app := &Application{
-		leetcodeAPIClient: leetcodeClient,
-		HTTPClient:        &http.Client{Transport: httpTransportMock},
-		storageController: storageController,
-	}
+	app := NewApplication(
+		config.Default(),
+		WithStorage(storageController),
+		WithLeetcodeClient(leetcodeClient),
+		WithHTTPClient(&http.Client{Transport: httpTransportMock}),
+		WithDeduplicator(nil),
+		WithLogger(logging.Discard()),
+	)
 	return httpTransportMock, storageController, leetcodeClient, app
 }
 
@@ -197,7 +466,7 @@ func TestSendDailyTaskToSubscribedUsers(t *testing.T) {
 		&http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("1126"))},
 		nil,
 	).Times(1)
-	taskDateID := common.GetDateIDForNow()
+	taskDateID := common.GetDateIDForNow(common.SystemClock{})
 	storageController.tasks[taskDateID] = &common.BotLeetCodeTask{
 		DateID: taskDateID,
 		LeetCodeTask: leetcodeclient.LeetCodeTask{
@@ -210,9 +479,70 @@ func TestSendDailyTaskToSubscribedUsers(t *testing.T) {
 		},
 	}
 
-	err := app.SendDailyTaskToSubscribedUsers(context.Background())
+	summary, err := app.SendDailyTaskToSubscribedUsers(context.Background())
 	assert.Equal(t, err, nil, "Got unexprected error from SendDailyTaskToSubscribedUsers")
+	assert.Equal(t, DeliverySummary{Sent: 2}, summary, "Unexpected delivery summary")
+	httpMock.AssertExpectations(t)
+
+	// Rerun in the same slot shouldn't send messages twice
+	summary, err = app.SendDailyTaskToSubscribedUsers(context.Background())
+	assert.Equal(t, err, nil, "Got unexprected error from SendDailyTaskToSubscribedUsers")
+	assert.Equal(t, DeliverySummary{Skipped: 2}, summary, "Delivered messages should be skipped")
+	httpMock.AssertExpectations(t)
+}
+
+func TestSendDailyTaskToSubscribedUsersWithOutbox(t *testing.T) {
+	httpMock, storageController, _, app := getTestApp()
+	app.outbox = storage.NewMemoryOutbox(storageController)
+	taskDateID := common.GetDateIDForNow(common.SystemClock{})
+	storageController.tasks[taskDateID] = &common.BotLeetCodeTask{
+		DateID: taskDateID,
+		LeetCodeTask: leetcodeclient.LeetCodeTask{
+			QuestionID: 1445,
+			TitleSlug:  "6534",
+			Title:      "Test title",
+			Content:    "Test content",
+			Hints:      []string{"first hint", "Second Hint"},
+			Difficulty: "Easy",
+		},
+	}
+
+	summary, err := app.SendDailyTaskToSubscribedUsers(context.Background())
+	assert.Nil(t, err, "Got unexprected error from SendDailyTaskToSubscribedUsers")
+	assert.Equal(t, DeliverySummary{Queued: 2}, summary, "Messages should be queued instead of sending")
+	httpMock.AssertNotCalled(t, "RoundTrip", mock.Anything, mock.Anything, mock.Anything)
+	summary, err = app.SendDailyTaskToSubscribedUsers(context.Background())
+	assert.Nil(t, err, "Got unexprected error from SendDailyTaskToSubscribedUsers")
+	assert.Equal(t, DeliverySummary{Skipped: 2}, summary, "Queued messages shouldn't be queued twice")
+	assert.Len(t, storageController.deliveries, 2, "Queued deliveries should be logged")
+	for _, delivery := range storageController.deliveries {
+		assert.Equal(t, common.DeliveryQueued, delivery.Status, "Delivery should stay queued until the dispatch")
+	}
+
+	for _, chatID := range []uint64{1120, 1126} {
+		httpMock.On(
+			"RoundTrip",
+			"https://api.telegram.org/bot/sendMessage",
+			http.Header{"Content-Type": []string{"application/json"}},
+			getTodaySendMessageString(chatID),
+		).Return(
+			&http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("{}"))},
+			nil,
+		).Once()
+	}
+	stats, err := app.DispatchOutbox(context.Background())
+	assert.Nil(t, err, "Unexpected DispatchOutbox error")
+	assert.Equal(t, outbox.Stats{Sent: 2}, stats, "Unexpected dispatch stats")
 	httpMock.AssertExpectations(t)
+	assert.Len(t, storageController.deliveries, 2, "Dispatch shouldn't add deliveries")
+	for _, delivery := range storageController.deliveries {
+		assert.Equal(t, common.DeliverySent, delivery.Status, "Dispatched delivery should be marked as sent")
+	}
+
+	app.outbox = nil
+	stats, err = app.DispatchOutbox(context.Background())
+	assert.Nil(t, err, "DispatchOutbox without the outbox should do nothing")
+	assert.Equal(t, outbox.Stats{}, stats, "DispatchOutbox without the outbox should do nothing")
 }
 
 func TestSendDailyTaskToSubscribedUsersError(t *testing.T) {
@@ -253,7 +583,7 @@ func TestSendDailyTaskToSubscribedUsersError(t *testing.T) {
 		&http.Response{StatusCode: 500, Body: io.NopCloser(strings.NewReader("1120"))},
 		nil,
 	).Times(3)
-	taskDateID := common.GetDateIDForNow()
+	taskDateID := common.GetDateIDForNow(common.SystemClock{})
 	storageController.tasks[taskDateID] = &common.BotLeetCodeTask{
 		DateID: taskDateID,
 		LeetCodeTask: leetcodeclient.LeetCodeTask{
@@ -267,53 +597,128 @@ func TestSendDailyTaskToSubscribedUsersError(t *testing.T) {
 	}
 
 	storageController.users[1121] = &common.User{
-		ID:         1121,
-		ChatID:     1121,
-		Username:   "testuser1121",
-		FirstName:  "1121firstname",
-		LastName:   "1121lastname",
-		Subscribed: true,
+		ID:           1121,
+		ChatID:       1121,
+		Username:     "testuser1121",
+		FirstName:    "1121firstname",
+		LastName:     "1121lastname",
+		Subscribed:   true,
+		SendingTimes: []common.SendingTime{{Minute: 0}},
 	}
 
 	storageController.users[1127] = &common.User{
-		ID:         1127,
-		ChatID:     1127,
-		Username:   "testuser1127",
-		FirstName:  "1127firstname",
-		LastName:   "1127lastname",
-		Subscribed: true,
+		ID:           1127,
+		ChatID:       1127,
+		Username:     "testuser1127",
+		FirstName:    "1127firstname",
+		LastName:     "1127lastname",
+		Subscribed:   true,
+		SendingTimes: []common.SendingTime{{Minute: 0}},
 	}
 
-	err := app.SendDailyTaskToSubscribedUsers(context.Background())
+	summary, err := app.SendDailyTaskToSubscribedUsers(context.Background())
 	assert.Equal(t, err, nil, "Unexprected error from SendDailyTaskToSubscribedUsers")
+	assert.Equal(t, DeliverySummary{Sent: 2, Failed: 2}, summary, "Unexpected delivery summary")
 	httpMock.AssertExpectations(t)
+	failedDelivery := common.Delivery{
+		UserID:    1120,
+		DateID:    taskDateID,
+		Kind:      common.SendingTime{Minute: 0}.GetDeliveryKind(),
+		Status:    common.DeliveryFailed,
+		Attempts:  1,
+		LastError: "telegram API responded with status 500",
+	}
+	assert.Contains(t, storageController.deliveries, failedDelivery, "Failed delivery should be logged")
 }
 
 func TestSendDailyTaskToSubscribedUsersWithoutTasks(t *testing.T) {
 	httpMock, storageController, leetcodeClient, app := getTestApp()
-	taskDateID := common.GetDateIDForNow()
+	taskDateID := common.GetDateIDForNow(common.SystemClock{})
 	storageController.failedTaskID = taskDateID
-	leetcodeClient.On(
-		"GetDailyTask",
-		taskDateID,
-	).Return(
-		leetcodeclient.LeetCodeTask{},
-		tests.ErrBypassTest,
-	).Times(1)
 
-	err := app.SendDailyTaskToSubscribedUsers(context.Background())
+	_, err := app.SendDailyTaskToSubscribedUsers(context.Background())
 	assert.Equal(t, err, tests.ErrBypassTest, "Unexprected error from SendDailyTaskToSubscribedUsers")
+
+	// Broadcasts are served only from the storage
+	storageController.failedTaskID = 0
+	_, err = app.SendDailyTaskToSubscribedUsers(context.Background())
+	assert.Equal(t, err, storage.ErrNoSuchTask, "Unexprected error from SendDailyTaskToSubscribedUsers")
+	leetcodeClient.AssertNotCalled(t, "GetDailyTask", taskDateID)
+	httpMock.AssertExpectations(t)
+}
+
+func TestWarmUpTodayTask(t *testing.T) {
+	httpMock, storageController, leetcodeClient, app := getTestApp()
+	app.warmUpBackoff = time.Millisecond
+	todayDateID := common.GetDateIDForNow(common.SystemClock{})
+	lcTask := leetcodeclient.LeetCodeTask{
+		QuestionID: 202,
+		TitleSlug:  "667",
+		Title:      "Test task from client",
+		Content:    "Test content from client",
+		Difficulty: "Easy",
+	}
+	leetcodeClient.On("GetDailyTask", todayDateID).Return(leetcodeclient.LeetCodeTask{}, tests.ErrBypassTest).Twice()
+	leetcodeClient.On("GetDailyTask", todayDateID).Return(lcTask, nil).Once()
+	err := app.WarmUpTodayTask(context.Background())
+	assert.Nil(t, err, "Unexpected WarmUpTodayTask error")
+	assert.Equal(t, common.BotLeetCodeTask{DateID: todayDateID, LeetCodeTask: lcTask}, *storageController.tasks[todayDateID], "Warmed up task should be saved")
+
+	storageController.callsJournal = []string{}
+	err = app.WarmUpTodayTask(context.Background())
+	assert.Nil(t, err, "Unexpected WarmUpTodayTask error")
+	assert.Equal(t, []string{fmt.Sprintf("GetTask %d", todayDateID)}, storageController.callsJournal, "Stored task shouldn't be requested again")
 	leetcodeClient.AssertExpectations(t)
 	httpMock.AssertExpectations(t)
 }
 
+func TestWarmUpTodayTaskAlert(t *testing.T) {
+	httpMock, storageController, leetcodeClient, app := getTestApp()
+	app.warmUpBackoff = time.Millisecond
+	app.AlertChatID = 1
+	todayDateID := common.GetDateIDForNow(common.SystemClock{})
+	leetcodeClient.On("GetDailyTask", todayDateID).Return(leetcodeclient.LeetCodeTask{}, tests.ErrBypassTest)
+
+	// Deadline isn't reached yet, so there is no alert
+	app.WarmUpDeadline = 25 * time.Hour
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelFunc()
+	err := app.WarmUpTodayTask(ctx)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected WarmUpTodayTask error")
+	httpMock.AssertNotCalled(t, "RoundTrip", mock.Anything, mock.Anything, mock.Anything)
+
+	app.WarmUpDeadline = time.Nanosecond
+	alert := NewTelegramResponse()
+	alert.ChatID = 1
+	alert.Text = fmt.Sprintf("⚠️ Daily task for %s isn't stored 1ns after the daily flip. Broadcasts can't be sent. Last error: %s", common.FormatDateID(todayDateID), tests.ErrBypassTest)
+	alertBytes, _ := json.Marshal(alert)
+	httpMock.On(
+		"RoundTrip",
+		"https://api.telegram.org/bot/sendMessage",
+		http.Header{"Content-Type": []string{"application/json"}},
+		string(alertBytes),
+	).Return(
+		&http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("1"))},
+		nil,
+	).Once()
+	for i := 0; i < 2; i++ {
+		ctx, cancelFunc = context.WithTimeout(context.Background(), 10*time.Millisecond)
+		err = app.WarmUpTodayTask(ctx)
+		cancelFunc()
+		assert.Equal(t, tests.ErrBypassTest, err, "Unexpected WarmUpTodayTask error")
+	}
+	httpMock.AssertExpectations(t)
+	httpMock.AssertNumberOfCalls(t, "RoundTrip", 1)
+	assert.Equal(t, []common.Delivery{{UserID: 1, DateID: todayDateID, Kind: warmUpAlertKind, Status: common.DeliverySent, Attempts: 1}}, storageController.deliveries, "Alert should be saved to the deliveries log")
+}
+
 func TestSendDailyTaskToSubscribedUsersWithErrorOnUsersList(t *testing.T) {
 	httpMock, storageController, leetcodeClient, app := getTestApp()
-	taskDateID := common.GetDateIDForNow()
+	taskDateID := common.GetDateIDForNow(common.SystemClock{})
 	storageController.failedTaskID = taskDateID
 	storageController.getSubscribedUsersMustFail = true
 
-	err := app.SendDailyTaskToSubscribedUsers(context.Background())
+	_, err := app.SendDailyTaskToSubscribedUsers(context.Background())
 	assert.Equal(t, err, tests.ErrBypassTest, "Unexprected error from SendDailyTaskToSubscribedUsers")
 	leetcodeClient.AssertExpectations(t)
 	httpMock.AssertExpectations(t)
@@ -321,7 +726,7 @@ func TestSendDailyTaskToSubscribedUsersWithErrorOnUsersList(t *testing.T) {
 
 func TestGetTodayTaskFromAllPossibleSourcesFromClient(t *testing.T) {
 	_, storageController, leetcodeClient, app := getTestApp()
-	todayDateID := common.GetDateIDForNow()
+	todayDateID := common.GetDateIDForNow(common.SystemClock{})
 	testTask := common.BotLeetCodeTask{
 		DateID: todayDateID,
 		LeetCodeTask: leetcodeclient.LeetCodeTask{
@@ -354,7 +759,7 @@ func TestGetTodayTaskFromAllPossibleSourcesFromClient(t *testing.T) {
 
 func TestGetTodayTaskFromAllPossibleSourcesFromClientWithErrorFromStorage(t *testing.T) {
 	_, storageController, leetcodeClient, app := getTestApp()
-	todayDateID := common.GetDateIDForNow()
+	todayDateID := common.GetDateIDForNow(common.SystemClock{})
 	testTask := common.BotLeetCodeTask{
 		DateID: todayDateID,
 		LeetCodeTask: leetcodeclient.LeetCodeTask{
@@ -387,7 +792,7 @@ func TestGetTodayTaskFromAllPossibleSourcesFromClientWithErrorFromStorage(t *tes
 
 func TestGetTodayTaskFromAllPossibleSourcesFromStorage(t *testing.T) {
 	_, storageController, leetcodeClient, app := getTestApp()
-	todayDateID := common.GetDateIDForNow()
+	todayDateID := common.GetDateIDForNow(common.SystemClock{})
 	testTask := common.BotLeetCodeTask{
 		DateID: todayDateID,
 		LeetCodeTask: leetcodeclient.LeetCodeTask{
@@ -407,11 +812,55 @@ func TestGetTodayTaskFromAllPossibleSourcesFromStorage(t *testing.T) {
 	leetcodeClient.AssertExpectations(t)
 }
 
+func TestGetTodayTaskMidnightRollover(t *testing.T) {
+	_, storageController, _, app := getTestApp()
+	clock := tests.NewFakeClock(time.Date(2025, time.December, 31, 23, 59, 59, 0, time.UTC))
+	WithClock(clock)(app)
+	storageController.tasks[20251231] = &common.BotLeetCodeTask{DateID: 20251231}
+	storageController.tasks[20260101] = &common.BotLeetCodeTask{DateID: 20260101}
+	task, err := app.GetTodayTaskFromAllPossibleSources(context.Background())
+	assert.Nil(t, err, "Unexpected GetTodayTaskFromAllPossibleSources error")
+	assert.Equal(t, uint64(20251231), task.DateID, "Task of the last day of the year is expected before midnight")
+	clock.Add(time.Second)
+	task, err = app.GetTodayTaskFromAllPossibleSources(context.Background())
+	assert.Nil(t, err, "Unexpected GetTodayTaskFromAllPossibleSources error")
+	assert.Equal(t, uint64(20260101), task.DateID, "Task of the new year is expected after midnight")
+	assert.Equal(t, []string{"GetTask 20251231", "GetTask 20260101"}, storageController.callsJournal, "Unexpected storageController calls journal")
+}
+
+func TestSendDailyTaskToSubscribedUsersClock(t *testing.T) {
+	_, storageController, _, app := getTestApp()
+	WithClock(tests.NewFakeClock(time.Date(2026, time.March, 29, 1, 0, 0, 0, time.UTC)))(app)
+	storageController.getSubscribedUsersMustFail = true
+	_, err := app.SendDailyTaskToSubscribedUsers(context.Background())
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected SendDailyTaskToSubscribedUsers error")
+	assert.Equal(t, []string{"GetSubscribedUsers 1 15m0s"}, storageController.callsJournal, "Users should be selected by the clock time")
+}
+
 func TestNewApplication(t *testing.T) {
-	app := NewApplication(nil)
+	cfg := config.Default()
+	cfg.SendingToken = "token"
+	cfg.SendingSlot = 5 * time.Minute
+	cfg.WarmUpDeadline = time.Hour
+	cfg.AlertChatID = 1126
+	cfg.ResponseTimeout = time.Second
+	cfg.ProcessingTimeout = time.Minute
+	cfg.EarlyAnswerEnabled = true
+	app := NewApplication(cfg)
 	assert.NotNil(t, app.leetcodeAPIClient, "leetcodeAPIClient must be set in constructor")
 	assert.NotNil(t, app.storageController, "storageController must be set in constructor")
 	assert.NotNil(t, app.HTTPClient, "HTTPClient must be set in constructor")
+	assert.NotNil(t, app.updatesDeduplicator, "updatesDeduplicator must be set in constructor")
+	assert.Nil(t, app.outbox, "outbox should be disabled by default")
+	assert.Equal(t, "token", app.SendingToken, "SendingToken must be set from config")
+	assert.Equal(t, 5*time.Minute, app.SendingSlot, "SendingSlot must be set from config")
+	assert.Equal(t, time.Hour, app.WarmUpDeadline, "WarmUpDeadline must be set from config")
+	assert.Equal(t, uint64(1126), app.AlertChatID, "AlertChatID must be set from config")
+	assert.Equal(t, time.Second, app.ResponseTimeout, "ResponseTimeout must be set from config")
+	assert.Equal(t, time.Minute, app.ProcessingTimeout, "ProcessingTimeout must be set from config")
+	assert.True(t, app.EarlyAnswer, "EarlyAnswer must be set from config")
+	assert.Equal(t, common.SystemClock{}, app.clock, "clock must be set in constructor")
+	assert.NotNil(t, app.logger, "logger must be set in constructor")
 }
 
 func TestSubscribeAction(t *testing.T) {
@@ -425,12 +874,12 @@ func TestSubscribeAction(t *testing.T) {
 	request.Message.From.FirstName = "1125firstname"
 	request.Message.From.LastName = "1125lastname"
 	response := TelegramResponse{}
-	err := app.subscribeAction(context.Background(), &request, &response, userBeforeRequest.SendingHour+2)
+	err := app.subscribeAction(context.Background(), &request, &response, common.SendingTime{Minute: 450})
 	assert.Nil(t, err, "Unexpected subscribeAction error")
 	userBeforeRequest.Subscribed = true
-	userBeforeRequest.SendingHour += 2
+	userBeforeRequest.SendingTimes = []common.SendingTime{{Minute: 450}}
 	assert.Equal(t, userBeforeRequest, *storageController.users[1124], "Unexpected changes in stored user after subscribe action")
-	assert.Equal(t, fmt.Sprintf(subscribedMessage, request.Message.From.FirstName, userBeforeRequest.SendingHour), response.Text, "Unexpected response text")
+	assert.Equal(t, fmt.Sprintf(subscribedMessage, request.Message.From.FirstName, "07:30", "UTC"), response.Text, "Unexpected response text")
 }
 
 func TestSubscribeActionAlreadySubscribed(t *testing.T) {
@@ -444,7 +893,7 @@ func TestSubscribeActionAlreadySubscribed(t *testing.T) {
 	request.Message.From.FirstName = "1125firstname"
 	request.Message.From.LastName = "1125lastname"
 	response := TelegramResponse{}
-	err := app.subscribeAction(context.Background(), &request, &response, 7)
+	err := app.subscribeAction(context.Background(), &request, &response, common.SendingTime{Minute: 0})
 	assert.Nil(t, err, "Unexpected subscribeAction error")
 	assert.Equal(t, userBeforeRequest, *storageController.users[1126], "Unexpected changes in stored user after subscribe action")
 	assert.Equal(t, fmt.Sprintf(alreadySubscribedMessage, request.Message.From.FirstName), response.Text, "Unexpected response text")
@@ -460,7 +909,7 @@ func TestSubscribeActionWithError(t *testing.T) {
 	request.Message.From.FirstName = "1125firstname"
 	request.Message.From.LastName = "1125lastname"
 	response := TelegramResponse{}
-	err := app.subscribeAction(context.Background(), &request, &response, 7)
+	err := app.subscribeAction(context.Background(), &request, &response, common.SendingTime{Minute: 420})
 	assert.Equal(t, err, tests.ErrBypassTest, "Unexpected subscribeAction error")
 	assert.Empty(t, response.Text, "Response text should be empty on error")
 }
@@ -518,7 +967,7 @@ func TestUnsubscribeActionWithError(t *testing.T) {
 
 func TestGetTaskAction(t *testing.T) {
 	_, storageController, _, app := getTestApp()
-	todayTaskID := common.GetDateIDForNow()
+	todayTaskID := common.GetDateIDForNow(common.SystemClock{})
 	storageController.tasks[todayTaskID] = &common.BotLeetCodeTask{
 		DateID: todayTaskID,
 		LeetCodeTask: leetcodeclient.LeetCodeTask{
@@ -534,12 +983,12 @@ func TestGetTaskAction(t *testing.T) {
 	err := app.getTaskAction(context.Background(), &response)
 	assert.Nil(t, err, "Unexpected getTaskAction error")
 	assert.Equal(t, response.Text, storageController.tasks[todayTaskID].GetTaskText(), "Unexpected response text")
-	assert.Equal(t, response.ReplyMarkup, storageController.tasks[todayTaskID].GetInlineKeyboard(), "Unexpected reply markup text")
+	assert.Equal(t, response.ReplyMarkup, storageController.tasks[todayTaskID].GetInlineKeyboard(common.SystemClock{}), "Unexpected reply markup text")
 }
 
 func TestGetTaskActionError(t *testing.T) {
 	_, storageController, lcClient, app := getTestApp()
-	todayTaskID := common.GetDateIDForNow()
+	todayTaskID := common.GetDateIDForNow(common.SystemClock{})
 	lcClient.On(
 		"GetDailyTask",
 		todayTaskID,
@@ -570,10 +1019,155 @@ func TestProcessRequestBodyHelp(t *testing.T) {
 	assert.Nil(t, err, "Unexpected json.Marshal error")
 	responseBytes, err := app.ProcessRequestBody(context.Background(), requestbytes)
 	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
-	expectedResponse := "{\"method\":\"sendMessage\",\"parse_mode\":\"HTML\",\"chat_id\":0,\"text\":\"You command \\\"My test request!\\\" isn't recognized =(\\nList of available commands:\\n/getDailyTask — get actual dailyTask\\n/Subscribe — start automatically sending of daily tasks\\n/Unsubscribe — stop automatically sending of daily tasks\",\"reply_markup\":\"{\\\"keyboard\\\":[[{\\\"text\\\":\\\"Get actual daily task\\\"}],[{\\\"text\\\":\\\"Subscribe\\\"},{\\\"text\\\":\\\"Unsubscribe\\\"}]],\\\"input_field_placeholder\\\":\\\"Please, use buttons below:\\\",\\\"resize_keyboard\\\":true}\"}"
+	expectedResponse := "{\"method\":\"sendMessage\",\"parse_mode\":\"HTML\",\"chat_id\":0,\"text\":\"You command \\\"My test request!\\\" isn't recognized =(\\nList of available commands:\\n/getDailyTask — get actual dailyTask\\n/task YYYY-MM-DD — get the daily task of the date\\n/yesterday — get yesterday's daily task\\n/calendar [YYYY-MM] — see daily tasks of the month\\n/Subscribe — start automatically sending of daily tasks\\n/Unsubscribe — stop automatically sending of daily tasks\\n/random [easy|medium|hard] [tag] — get random problem\\n/problem [number|slug|keywords] — find problem\\n/link [leetcode_username] — link LeetCode profile to see your progress\\n/unlink — unlink LeetCode profile\\n/progress — see your progress with the daily task and solved problems\\n/nudge [hour|off] — remind at the hour if the daily task isn't solved yet\\n/timezone [name|offset] — set your time zone for subscription and reminder\\n/times — list your delivery times\\n/addtime HH:MM [hint] — add one more delivery time of the daily task or its first hint\\n/removetime HH:MM — remove the delivery time\\n/weekdays — choose days of the week for delivery\\n/pause YYYY-MM-DD|off — pause delivery till the date or resume it\\n/settings — open settings menu\",\"reply_markup\":\"{\\\"keyboard\\\":[[{\\\"text\\\":\\\"Get actual daily task\\\"}],[{\\\"text\\\":\\\"Subscribe\\\"},{\\\"text\\\":\\\"Unsubscribe\\\"}]],\\\"input_field_placeholder\\\":\\\"Please, use buttons below:\\\",\\\"resize_keyboard\\\":true}\"}"
 	assert.Equal(t, responseBytes, []byte(expectedResponse), "Unexprected response bytes")
 }
 
+func TestProcessRequestBodyDuplicate(t *testing.T) {
+	_, storageController, _, app := getTestApp()
+	app.updatesDeduplicator = storage.NewLRUDeduplicator(10, time.Hour, nil)
+	request := TelegramRequest{UpdateID: 100500}
+	request.Message.Chat.ID = 1126
+	request.Message.From.ID = 1126
+	request.Message.Text = unsubscribeCommandSlash
+	requestBytes, _ := json.Marshal(request)
+
+	storageController.failedUserID = 1126
+	_, err := app.ProcessRequestBody(context.Background(), requestBytes)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected ProcessRequestBody error")
+	storageController.failedUserID = 0
+	responseBytes, err := app.ProcessRequestBody(context.Background(), requestBytes)
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.NotEmpty(t, responseBytes, "Failed update should be processed again")
+	responseBytes, err = app.ProcessRequestBody(context.Background(), requestBytes)
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Empty(t, responseBytes, "Duplicate should get empty response")
+	assert.Equal(t, []string{"UnsubscribeUser 1126", "UnsubscribeUser 1126"}, storageController.callsJournal, "Duplicate shouldn't be processed")
+}
+
+func TestProcessRequestBodyCorrelation(t *testing.T) {
+	_, _, _, app := getTestApp()
+	output := &bytes.Buffer{}
+	app.logger = slog.New(slog.NewJSONHandler(output, nil))
+	app.updatesDeduplicator = storage.NewLRUDeduplicator(10, time.Hour, nil)
+	request := TelegramRequest{UpdateID: 100500}
+	request.Message.Text = "Unknown command"
+	requestBytes, _ := json.Marshal(request)
+	for i := 0; i < 3; i++ {
+		_, err := app.ProcessRequestBody(context.Background(), requestBytes)
+		assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	}
+	records := strings.Split(strings.TrimSpace(output.String()), "\n")
+	assert.Len(t, records, 2, "Every duplicate should be logged")
+	correlationIDs := map[string]bool{}
+	for _, line := range records {
+		record := map[string]interface{}{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &record), "Log record should be JSON")
+		assert.Equal(t, "Skip already processed update", record["msg"], "Unexpected message")
+		assert.Equal(t, float64(100500), record["updateID"], "Record should have update ID")
+		assert.NotEmpty(t, record[logging.CorrelationIDKey], "Record should have correlation ID")
+		correlationIDs[record[logging.CorrelationIDKey].(string)] = true
+	}
+	assert.Len(t, correlationIDs, 2, "Every request should get its own correlation ID")
+}
+
+func TestBroadcastRunID(t *testing.T) {
+	_, storageController, _, app := getTestApp()
+	output := &bytes.Buffer{}
+	app.logger = slog.New(slog.NewJSONHandler(output, nil))
+	taskDateID := common.GetDateIDForNow(app.getClock())
+	storageController.tasks[taskDateID] = &common.BotLeetCodeTask{DateID: taskDateID}
+	for _, user := range storageController.users {
+		user.Subscribed = false
+	}
+	ctx := logging.WithCorrelation(context.Background(), app.logger, "run", "reminder")
+	for i := 0; i < 2; i++ {
+		_, err := app.SendDailyTaskToSubscribedUsers(ctx)
+		assert.Nil(t, err, "Unexpected SendDailyTaskToSubscribedUsers error")
+	}
+	records := []map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		record := map[string]interface{}{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &record), "Log record should be JSON")
+		records = append(records, record)
+	}
+	if assert.Len(t, records, 2, "Every run should be logged") {
+		assert.Equal(t, "Daily task is sent", records[0]["msg"], "Unexpected message")
+		assert.Equal(t, "dailyTask", records[0]["job"], "Record should have the job")
+		assert.Equal(t, records[0][logging.CorrelationIDKey], records[1][logging.CorrelationIDKey], "Runs of the invocation should share correlation ID")
+		assert.NotEmpty(t, records[0][logging.RunIDKey], "Record should have run ID")
+		assert.NotEqual(t, records[0][logging.RunIDKey], records[1][logging.RunIDKey], "Every run should get its own ID")
+	}
+}
+
+func TestProcessRequestBodyTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracing.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer tracing.SetTracerProvider(nil)
+	_, _, _, app := getTestApp()
+	WithSender(func(ctx context.Context, method string, body []byte) (uint64, error) {
+		return 0, tests.ErrBypassTest
+	})(app)
+	output := &bytes.Buffer{}
+	app.logger = slog.New(slog.NewJSONHandler(output, nil))
+	app.updatesDeduplicator = storage.NewLRUDeduplicator(10, time.Hour, nil)
+	request := TelegramRequest{UpdateID: 100500}
+	request.Message.Text = "Unknown command"
+	requestBytes, _ := json.Marshal(request)
+	for i := 0; i < 2; i++ {
+		_, err := app.ProcessRequestBody(context.Background(), requestBytes)
+		assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	}
+	_, err := app.ProcessRequestBody(context.Background(), []byte("{"))
+	assert.NotNil(t, err, "Wrong body should fail")
+	err = app.SendMessage(context.Background(), []byte("{}"))
+	assert.Equal(t, tests.ErrBypassTest, err, "Sender error should be returned")
+
+	spans := exporter.GetSpans()
+	if assert.Len(t, spans, 5, "Every call should have the span") {
+		names := []string{}
+		for _, span := range spans {
+			names = append(names, span.Name)
+		}
+		assert.Equal(t, []string{"bot.ProcessRequestBody", "bot.ProcessRequestBody", "bot.ProcessRequestBody", "telegram.sendMessage", "bot.SendMessage"}, names, "Unexpected span names")
+		assert.Contains(t, spans[0].Attributes, attribute.Int64("updateID", 100500), "Span should have update ID")
+		assert.Contains(t, spans[0].Attributes, attribute.String("command", "unknown"), "Span should have command")
+		assert.Equal(t, codes.Unset, spans[1].Status.Code, "Duplicate update isn't an error")
+		assert.Equal(t, codes.Error, spans[2].Status.Code, "Wrong body should fail the span")
+		assert.Equal(t, spans[4].SpanContext.SpanID(), spans[3].Parent.SpanID(), "Telegram call should be the child of SendMessage")
+		assert.Equal(t, tests.ErrBypassTest.Error(), spans[4].Status.Description, "Sender error should fail the span")
+		record := map[string]interface{}{}
+		assert.Nil(t, json.Unmarshal(output.Bytes(), &record), "Log record should be JSON")
+		assert.Equal(t, spans[1].SpanContext.TraceID().String(), record["traceID"], "Record should have trace ID")
+	}
+}
+
+func TestTelegramTraceparent(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracing.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer tracing.SetTracerProvider(nil)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	httpMock, _, _, app := getTestApp()
+	traceparents := []string{}
+	httpMock.On(
+		"RoundTrip",
+		"https://api.telegram.org/bot/sendMessage",
+		mock.MatchedBy(func(header http.Header) bool {
+			traceparents = append(traceparents, header.Get("traceparent"))
+			return true
+		}),
+		"{}",
+	).Return(&http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("{}"))}, nil).Once()
+	assert.Nil(t, app.SendMessage(context.Background(), []byte("{}")), "Unexpected SendMessage error")
+	httpMock.AssertExpectations(t)
+
+	spans := exporter.GetSpans()
+	if assert.Len(t, spans, 2, "Every call should have the span") && assert.NotEmpty(t, traceparents, "Request should be sent") {
+		telegramSpan := spans[0].SpanContext
+		assert.Equal(t, "00-"+telegramSpan.TraceID().String()+"-"+telegramSpan.SpanID().String()+"-01", traceparents[len(traceparents)-1], "Request should have traceparent of the Telegram span")
+	}
+}
+
 func TestProcessSubscribeKeyboard(t *testing.T) {
 	_, _, _, app := getTestApp()
 	request := TelegramRequest{}
@@ -583,7 +1177,7 @@ func TestProcessSubscribeKeyboard(t *testing.T) {
 	assert.Nil(t, err, "Unexpected json.Marshal error")
 	responseBytes, err := app.ProcessRequestBody(context.Background(), requestbytes)
 	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
-	expectedResponse := "{\"method\":\"sendMessage\",\"parse_mode\":\"HTML\",\"chat_id\":0,\"text\":\"Daily tasks appear each day at 00:00 UTC. For your convenience, this bot can send you tasks at the start of any hour of the day. Please, select a suitable hour to send a new daily task to you. The time zone is UTC.\",\"reply_markup\":\"{\\\"keyboard\\\":[[{\\\"text\\\":\\\"0:00\\\"},{\\\"text\\\":\\\"1:00\\\"},{\\\"text\\\":\\\"2:00\\\"},{\\\"text\\\":\\\"3:00\\\"}],[{\\\"text\\\":\\\"4:00\\\"},{\\\"text\\\":\\\"5:00\\\"},{\\\"text\\\":\\\"6:00\\\"},{\\\"text\\\":\\\"7:00\\\"}],[{\\\"text\\\":\\\"8:00\\\"},{\\\"text\\\":\\\"9:00\\\"},{\\\"text\\\":\\\"10:00\\\"},{\\\"text\\\":\\\"11:00\\\"}],[{\\\"text\\\":\\\"12:00\\\"},{\\\"text\\\":\\\"13:00\\\"},{\\\"text\\\":\\\"14:00\\\"},{\\\"text\\\":\\\"15:00\\\"}],[{\\\"text\\\":\\\"16:00\\\"},{\\\"text\\\":\\\"17:00\\\"},{\\\"text\\\":\\\"18:00\\\"},{\\\"text\\\":\\\"19:00\\\"}],[{\\\"text\\\":\\\"20:00\\\"},{\\\"text\\\":\\\"21:00\\\"},{\\\"text\\\":\\\"22:00\\\"},{\\\"text\\\":\\\"23:00\\\"}]],\\\"input_field_placeholder\\\":\\\"Please, choose the sending hour below:\\\",\\\"resize_keyboard\\\":true}\"}"
+	expectedResponse := "{\"method\":\"sendMessage\",\"parse_mode\":\"HTML\",\"chat_id\":0,\"text\":\"Daily tasks appear each day at 00:00 UTC. For your convenience, this bot can send you tasks at any time of the day. Please, select a suitable hour or type the time like 7:30 to send a new daily task to you. Your time zone is UTC, use /timezone to change it.\",\"reply_markup\":\"{\\\"keyboard\\\":[[{\\\"text\\\":\\\"0:00\\\"},{\\\"text\\\":\\\"1:00\\\"},{\\\"text\\\":\\\"2:00\\\"},{\\\"text\\\":\\\"3:00\\\"}],[{\\\"text\\\":\\\"4:00\\\"},{\\\"text\\\":\\\"5:00\\\"},{\\\"text\\\":\\\"6:00\\\"},{\\\"text\\\":\\\"7:00\\\"}],[{\\\"text\\\":\\\"8:00\\\"},{\\\"text\\\":\\\"9:00\\\"},{\\\"text\\\":\\\"10:00\\\"},{\\\"text\\\":\\\"11:00\\\"}],[{\\\"text\\\":\\\"12:00\\\"},{\\\"text\\\":\\\"13:00\\\"},{\\\"text\\\":\\\"14:00\\\"},{\\\"text\\\":\\\"15:00\\\"}],[{\\\"text\\\":\\\"16:00\\\"},{\\\"text\\\":\\\"17:00\\\"},{\\\"text\\\":\\\"18:00\\\"},{\\\"text\\\":\\\"19:00\\\"}],[{\\\"text\\\":\\\"20:00\\\"},{\\\"text\\\":\\\"21:00\\\"},{\\\"text\\\":\\\"22:00\\\"},{\\\"text\\\":\\\"23:00\\\"}]],\\\"input_field_placeholder\\\":\\\"Please, choose the hour below or type the time:\\\",\\\"resize_keyboard\\\":true}\"}"
 	assert.Equal(t, responseBytes, []byte(expectedResponse), "Unexprected response bytes")
 }
 
@@ -598,7 +1192,7 @@ func TestProcessRequestSubscribe(t *testing.T) {
 	assert.Nil(t, err, "Unexpected json.Marshal error")
 	responseBytes, err := app.ProcessRequestBody(context.Background(), requestbytes)
 	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
-	expectedResponse := "{\"method\":\"sendMessage\",\"parse_mode\":\"HTML\",\"chat_id\":1126,\"text\":\"You command \\\"/Subscribe 7\\\" isn't recognized =(\\nList of available commands:\\n/getDailyTask — get actual dailyTask\\n/Subscribe — start automatically sending of daily tasks\\n/Unsubscribe — stop automatically sending of daily tasks\",\"reply_markup\":\"{\\\"keyboard\\\":[[{\\\"text\\\":\\\"Get actual daily task\\\"}],[{\\\"text\\\":\\\"Subscribe\\\"},{\\\"text\\\":\\\"Unsubscribe\\\"}]],\\\"input_field_placeholder\\\":\\\"Please, use buttons below:\\\",\\\"resize_keyboard\\\":true}\"}"
+	expectedResponse := "{\"method\":\"sendMessage\",\"parse_mode\":\"HTML\",\"chat_id\":1126,\"text\":\"You command \\\"/Subscribe 7\\\" isn't recognized =(\\nList of available commands:\\n/getDailyTask — get actual dailyTask\\n/task YYYY-MM-DD — get the daily task of the date\\n/yesterday — get yesterday's daily task\\n/calendar [YYYY-MM] — see daily tasks of the month\\n/Subscribe — start automatically sending of daily tasks\\n/Unsubscribe — stop automatically sending of daily tasks\\n/random [easy|medium|hard] [tag] — get random problem\\n/problem [number|slug|keywords] — find problem\\n/link [leetcode_username] — link LeetCode profile to see your progress\\n/unlink — unlink LeetCode profile\\n/progress — see your progress with the daily task and solved problems\\n/nudge [hour|off] — remind at the hour if the daily task isn't solved yet\\n/timezone [name|offset] — set your time zone for subscription and reminder\\n/times — list your delivery times\\n/addtime HH:MM [hint] — add one more delivery time of the daily task or its first hint\\n/removetime HH:MM — remove the delivery time\\n/weekdays — choose days of the week for delivery\\n/pause YYYY-MM-DD|off — pause delivery till the date or resume it\\n/settings — open settings menu\",\"reply_markup\":\"{\\\"keyboard\\\":[[{\\\"text\\\":\\\"Get actual daily task\\\"}],[{\\\"text\\\":\\\"Subscribe\\\"},{\\\"text\\\":\\\"Unsubscribe\\\"}]],\\\"input_field_placeholder\\\":\\\"Please, use buttons below:\\\",\\\"resize_keyboard\\\":true}\"}"
 	assert.Equal(t, []byte(expectedResponse), responseBytes, "Unexprected response bytes")
 }
 
@@ -654,7 +1248,7 @@ func TestProcessRequestTaskMessage(t *testing.T) {
 	request.Message.Chat.ID = 1126
 	request.Message.From.FirstName = "TestUser"
 	request.Message.Text = getActualDailyTaskCommand
-	todayTaskID := common.GetDateIDForNow()
+	todayTaskID := common.GetDateIDForNow(common.SystemClock{})
 	storageController.tasks[todayTaskID] = &common.BotLeetCodeTask{
 		DateID: todayTaskID,
 		LeetCodeTask: leetcodeclient.LeetCodeTask{
@@ -673,7 +1267,7 @@ func TestProcessRequestTaskMessage(t *testing.T) {
 	expectedTelegramResponse := NewTelegramResponse()
 	expectedTelegramResponse.ChatID = 1126
 	expectedTelegramResponse.Text = storageController.tasks[todayTaskID].GetTaskText()
-	expectedTelegramResponse.ReplyMarkup = storageController.tasks[todayTaskID].GetInlineKeyboard()
+	expectedTelegramResponse.ReplyMarkup = storageController.tasks[todayTaskID].GetInlineKeyboard(common.SystemClock{})
 	expectedResponse, err := json.Marshal(expectedTelegramResponse)
 	assert.Nil(t, err, "Unexpected json.Marshal error")
 	assert.Equal(t, responseBytes, expectedResponse, "Unexprected response bytes")
@@ -686,7 +1280,7 @@ func TestProcessRequestTaskMessageError(t *testing.T) {
 	request.Message.Chat.ID = 1126
 	request.Message.From.FirstName = "TestUser"
 	request.Message.Text = getActualDailyTaskCommand
-	todayTaskID := common.GetDateIDForNow()
+	todayTaskID := common.GetDateIDForNow(common.SystemClock{})
 	delete(storageController.tasks, todayTaskID)
 	lcClient.On(
 		"GetDailyTask",
@@ -704,7 +1298,7 @@ func TestProcessRequestTaskMessageError(t *testing.T) {
 
 func TestProcessRequestTaskHint(t *testing.T) {
 	_, storageController, _, app := getTestApp()
-	todayTaskID := common.GetDateIDForNow()
+	todayTaskID := common.GetDateIDForNow(common.SystemClock{})
 	storageController.tasks[todayTaskID] = &common.BotLeetCodeTask{
 		DateID: todayTaskID,
 		LeetCodeTask: leetcodeclient.LeetCodeTask{
@@ -758,7 +1352,7 @@ func TestProcessRequestTaskHintMoreThenExists(t *testing.T) {
 
 func TestProcessRequestTaskHintError(t *testing.T) {
 	_, storageController, _, app := getTestApp()
-	todayTaskID := common.GetDateIDForNow()
+	todayTaskID := common.GetDateIDForNow(common.SystemClock{})
 	storageController.failedTaskID = todayTaskID
 	request := TelegramRequest{}
 	request.CallbackQuery.From.ID = 1126
@@ -775,7 +1369,7 @@ func TestProcessRequestTaskHintError(t *testing.T) {
 
 func TestProcessRequestTaskHintNoTask(t *testing.T) {
 	_, storageController, _, app := getTestApp()
-	todayTaskID := common.GetDateIDForNow()
+	todayTaskID := common.GetDateIDForNow(common.SystemClock{})
 	delete(storageController.tasks, todayTaskID)
 	request := TelegramRequest{}
 	request.CallbackQuery.From.ID = 1126
@@ -792,7 +1386,7 @@ func TestProcessRequestTaskHintNoTask(t *testing.T) {
 
 func TestProcessRequestTaskDifficulty(t *testing.T) {
 	_, storageController, _, app := getTestApp()
-	todayTaskID := common.GetDateIDForNow()
+	todayTaskID := common.GetDateIDForNow(common.SystemClock{})
 	storageController.tasks[todayTaskID] = &common.BotLeetCodeTask{
 		DateID: todayTaskID,
 		LeetCodeTask: leetcodeclient.LeetCodeTask{
@@ -819,7 +1413,7 @@ func TestProcessRequestTaskDifficulty(t *testing.T) {
 
 func TestProcessRequestTaskTopics(t *testing.T) {
 	_, storageController, _, app := getTestApp()
-	todayTaskID := common.GetDateIDForNow()
+	todayTaskID := common.GetDateIDForNow(common.SystemClock{})
 	storageController.tasks[todayTaskID] = &common.BotLeetCodeTask{
 		DateID: todayTaskID,
 		LeetCodeTask: leetcodeclient.LeetCodeTask{
@@ -886,7 +1480,7 @@ func TestProcessRequestBrokenBody(t *testing.T) {
 
 func TestProcessRequestBrokenData(t *testing.T) {
 	_, storageController, _, app := getTestApp()
-	todayTaskID := common.GetDateIDForNow()
+	todayTaskID := common.GetDateIDForNow(common.SystemClock{})
 	storageController.tasks[todayTaskID] = &common.BotLeetCodeTask{
 		DateID: todayTaskID,
 		LeetCodeTask: leetcodeclient.LeetCodeTask{
@@ -907,3 +1501,1405 @@ func TestProcessRequestBrokenData(t *testing.T) {
 	assert.Equal(t, err.(*json.SyntaxError).Offset, int64(3), "Unexpected ProcessRequestBody error")
 	assert.Empty(t, responseBytes, "Unexprected response bytes")
 }
+
+func getTestTaskWithSimilarQuestions(dateID uint64) *common.BotLeetCodeTask {
+	return &common.BotLeetCodeTask{
+		DateID: dateID,
+		LeetCodeTask: leetcodeclient.LeetCodeTask{
+			QuestionID: 1445,
+			TitleSlug:  "6534",
+			Title:      "Test title",
+			Content:    "Test content",
+			Hints:      []string{"first hint", "Second Hint"},
+			Difficulty: "Easy",
+			SimilarQuestions: leetcodeclient.SimilarQuestions{
+				{Title: "Two Sum", TitleSlug: "two-sum", Difficulty: "Easy"},
+				{Title: "3Sum", TitleSlug: "3sum", Difficulty: "Medium"},
+			},
+		},
+	}
+}
+
+func TestProcessRequestTaskSimilarQuestions(t *testing.T) {
+	_, storageController, _, app := getTestApp()
+	taskID := uint64(20210929)
+	storageController.tasks[taskID] = getTestTaskWithSimilarQuestions(taskID)
+	request := TelegramRequest{}
+	request.CallbackQuery.From.ID = 1126
+	data, err := common.GetMarshalledCallbackData(taskID, 0, common.SimilarQuestionsRequest)
+	assert.Nil(t, err, "Unexpected GetMarshalledCallbackData error")
+	request.CallbackQuery.Data = data
+	requestbytes, err := json.Marshal(request)
+	assert.Nil(t, err, "Unexpected json.Marshal error")
+	responseBytes, err := app.ProcessRequestBody(context.Background(), requestbytes)
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	expectedTelegramResponse := NewTelegramResponse()
+	expectedTelegramResponse.ChatID = 1126
+	expectedTelegramResponse.Text = storageController.tasks[taskID].GetSimilarQuestionsText()
+	expectedTelegramResponse.ReplyMarkup = storageController.tasks[taskID].GetSimilarQuestionsInlineKeyboard()
+	expectedResponse, err := json.Marshal(expectedTelegramResponse)
+	assert.Nil(t, err, "Unexpected json.Marshal error")
+	assert.Equal(t, expectedResponse, responseBytes, "Unexprected response bytes")
+}
+
+func TestProcessRequestTaskSimilarQuestionsEmpty(t *testing.T) {
+	_, storageController, _, app := getTestApp()
+	taskID := uint64(20210929)
+	storageController.tasks[taskID] = getTestTaskWithSimilarQuestions(taskID)
+	storageController.tasks[taskID].SimilarQuestions = nil
+	request := TelegramRequest{}
+	request.CallbackQuery.From.ID = 1126
+	data, err := common.GetMarshalledCallbackData(taskID, 0, common.SimilarQuestionsRequest)
+	assert.Nil(t, err, "Unexpected GetMarshalledCallbackData error")
+	request.CallbackQuery.Data = data
+	requestbytes, err := json.Marshal(request)
+	assert.Nil(t, err, "Unexpected json.Marshal error")
+	responseBytes, err := app.ProcessRequestBody(context.Background(), requestbytes)
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	expectedResponse := "{\"method\":\"sendMessage\",\"parse_mode\":\"HTML\",\"chat_id\":1126,\"text\":\"There are no similar problems for task 20210929\",\"reply_markup\":\"\"}"
+	assert.Equal(t, []byte(expectedResponse), responseBytes, "Unexprected response bytes")
+}
+
+func TestProcessRequestTaskSimilarQuestion(t *testing.T) {
+	_, storageController, lcClient, app := getTestApp()
+	taskID := uint64(20210929)
+	storageController.tasks[taskID] = getTestTaskWithSimilarQuestions(taskID)
+	lcClient.On(
+		"GetQuestionDetailsByTitleSlug",
+		"3sum",
+	).Return(
+		leetcodeclient.LeetCodeTask{
+			QuestionID: 15,
+			TitleSlug:  "3sum",
+			Title:      "3Sum",
+			Content:    "<p>Test content</p>",
+			Hints:      []string{"first hint"},
+			Difficulty: "Medium",
+		},
+		nil,
+	).Times(1)
+	request := TelegramRequest{}
+	request.CallbackQuery.From.ID = 1126
+	data, err := common.GetMarshalledCallbackData(taskID, 1, common.SimilarQuestionRequest)
+	assert.Nil(t, err, "Unexpected GetMarshalledCallbackData error")
+	request.CallbackQuery.Data = data
+	requestbytes, err := json.Marshal(request)
+	assert.Nil(t, err, "Unexpected json.Marshal error")
+	responseBytes, err := app.ProcessRequestBody(context.Background(), requestbytes)
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	savedTask, ok := storageController.questions[15]
+	if assert.True(t, ok, "Similar question should be saved to the storage") {
+		expectedTelegramResponse := NewTelegramResponse()
+		expectedTelegramResponse.ChatID = 1126
+		expectedTelegramResponse.Text = "<strong>3Sum</strong>\n\nTest content"
+		expectedTelegramResponse.ReplyMarkup = savedTask.GetInlineKeyboard(common.SystemClock{})
+		expectedResponse, err := json.Marshal(expectedTelegramResponse)
+		assert.Nil(t, err, "Unexpected json.Marshal error")
+		assert.Equal(t, expectedResponse, responseBytes, "Unexprected response bytes")
+		assert.Contains(t, expectedTelegramResponse.ReplyMarkup, "questionID", "Similar question keyboard should use questionID callbacks")
+	}
+	lcClient.AssertExpectations(t)
+}
+
+func TestProcessRequestTaskSimilarQuestionMoreThenExists(t *testing.T) {
+	_, storageController, lcClient, app := getTestApp()
+	taskID := uint64(20210929)
+	storageController.tasks[taskID] = getTestTaskWithSimilarQuestions(taskID)
+	request := TelegramRequest{}
+	request.CallbackQuery.From.ID = 1126
+	data, err := common.GetMarshalledCallbackData(taskID, 2, common.SimilarQuestionRequest)
+	assert.Nil(t, err, "Unexpected GetMarshalledCallbackData error")
+	request.CallbackQuery.Data = data
+	requestbytes, err := json.Marshal(request)
+	assert.Nil(t, err, "Unexpected json.Marshal error")
+	responseBytes, err := app.ProcessRequestBody(context.Background(), requestbytes)
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	expectedResponse := "{\"method\":\"sendMessage\",\"parse_mode\":\"HTML\",\"chat_id\":1126,\"text\":\"There is no such similar problem for task 20210929\",\"reply_markup\":\"\"}"
+	assert.Equal(t, []byte(expectedResponse), responseBytes, "Unexprected response bytes")
+	lcClient.AssertExpectations(t)
+}
+
+func TestProcessRequestTaskSimilarQuestionError(t *testing.T) {
+	_, storageController, lcClient, app := getTestApp()
+	taskID := uint64(20210929)
+	storageController.tasks[taskID] = getTestTaskWithSimilarQuestions(taskID)
+	lcClient.On(
+		"GetQuestionDetailsByTitleSlug",
+		"two-sum",
+	).Return(
+		leetcodeclient.LeetCodeTask{},
+		tests.ErrBypassTest,
+	).Times(1)
+	request := TelegramRequest{}
+	request.CallbackQuery.From.ID = 1126
+	data, err := common.GetMarshalledCallbackData(taskID, 0, common.SimilarQuestionRequest)
+	assert.Nil(t, err, "Unexpected GetMarshalledCallbackData error")
+	request.CallbackQuery.Data = data
+	requestbytes, err := json.Marshal(request)
+	assert.Nil(t, err, "Unexpected json.Marshal error")
+	responseBytes, err := app.ProcessRequestBody(context.Background(), requestbytes)
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	expectedResponse := "{\"method\":\"sendMessage\",\"parse_mode\":\"HTML\",\"chat_id\":1126,\"text\":\"Something went completely wrong\",\"reply_markup\":\"\"}"
+	assert.Equal(t, []byte(expectedResponse), responseBytes, "Unexprected response bytes")
+	lcClient.AssertExpectations(t)
+}
+
+func getTestMessageRequest(text string) []byte {
+	request := TelegramRequest{}
+	request.Message.Chat.ID = 1126
+	request.Message.From.ID = 1126
+	request.Message.Text = text
+	requestbytes, _ := json.Marshal(request)
+	return requestbytes
+}
+
+func TestProcessRequestRandom(t *testing.T) {
+	_, storageController, lcClient, app := getTestApp()
+	lcTask := leetcodeclient.LeetCodeTask{
+		QuestionID: 15,
+		TitleSlug:  "3sum",
+		Title:      "3Sum",
+		Content:    "<p>Test content</p>",
+		Hints:      []string{"first hint"},
+		Difficulty: "Medium",
+	}
+	lcClient.On(
+		"GetRandomQuestion",
+		leetcodeclient.QuestionsFilter{Difficulty: "MEDIUM", Tags: []string{"dynamic-programming"}},
+	).Return(lcTask, nil).Times(1)
+	lcClient.On("GetRandomQuestion", leetcodeclient.QuestionsFilter{}).Return(lcTask, nil).Times(1)
+	lcClient.On("GetRandomQuestion", leetcodeclient.QuestionsFilter{Tags: []string{"array"}}).Return(lcTask, nil).Times(1)
+
+	responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest("/random Medium Dynamic Programming"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	savedTask, ok := storageController.questions[15]
+	if assert.True(t, ok, "Random question should be saved to the storage") {
+		expectedTelegramResponse := NewTelegramResponse()
+		expectedTelegramResponse.ChatID = 1126
+		expectedTelegramResponse.Text = savedTask.GetTaskText()
+		expectedTelegramResponse.ReplyMarkup = savedTask.GetInlineKeyboard(common.SystemClock{})
+		expectedResponse, err := json.Marshal(expectedTelegramResponse)
+		assert.Nil(t, err, "Unexpected json.Marshal error")
+		assert.Equal(t, expectedResponse, responseBytes, "Unexprected response bytes")
+	}
+	_, err = app.ProcessRequestBody(context.Background(), getTestMessageRequest("/random"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	_, err = app.ProcessRequestBody(context.Background(), getTestMessageRequest("/random array"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(t, []string{"SaveQuestion 15", "SaveQuestion 15", "SaveQuestion 15"}, storageController.callsJournal, "Unexpected storage calls journal")
+	lcClient.AssertExpectations(t)
+}
+
+func TestProcessRequestRandomNoQuestions(t *testing.T) {
+	_, storageController, lcClient, app := getTestApp()
+	lcClient.On(
+		"GetRandomQuestion",
+		leetcodeclient.QuestionsFilter{Difficulty: "HARD"},
+	).Return(leetcodeclient.LeetCodeTask{}, leetcodeclient.ErrNoSuchQuestions).Times(1)
+	responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest("/random hard"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	response := TelegramResponse{}
+	assert.Nil(t, json.Unmarshal(responseBytes, &response), "Unexpected json.Unmarshal error")
+	assert.Equal(t, noRandomQuestionsMessage, response.Text, "Unexpected response text")
+	assert.Empty(t, storageController.callsJournal, "Storage shouldn't be called when there is no question")
+	lcClient.AssertExpectations(t)
+}
+
+func TestProcessRequestRandomError(t *testing.T) {
+	_, _, lcClient, app := getTestApp()
+	lcClient.On(
+		"GetRandomQuestion",
+		leetcodeclient.QuestionsFilter{Difficulty: "EASY"},
+	).Return(leetcodeclient.LeetCodeTask{}, tests.ErrBypassTest).Times(1)
+	responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest("/random easy"))
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected ProcessRequestBody error")
+	assert.Empty(t, responseBytes, "Unexprected response bytes")
+	lcClient.AssertExpectations(t)
+}
+
+func TestProcessRequestQuestionHint(t *testing.T) {
+	_, storageController, _, app := getTestApp()
+	questionID := uint64(15)
+	storageController.questions[questionID] = &common.BotLeetCodeTask{
+		LeetCodeTask: leetcodeclient.LeetCodeTask{
+			QuestionID: questionID,
+			TitleSlug:  "3sum",
+			Title:      "3Sum",
+			Hints:      []string{"first hint"},
+			Difficulty: "Medium",
+		},
+	}
+	request := TelegramRequest{}
+	request.CallbackQuery.From.ID = 1126
+	for hint, expectedText := range []string{"Hint #1: first hint", "There is no such hint for task 15"} {
+		data, err := common.GetMarshalledQuestionCallbackData(questionID, hint, common.HintRequest)
+		assert.Nil(t, err, "Unexpected GetMarshalledQuestionCallbackData error")
+		request.CallbackQuery.Data = data
+		requestbytes, err := json.Marshal(request)
+		assert.Nil(t, err, "Unexpected json.Marshal error")
+		responseBytes, err := app.ProcessRequestBody(context.Background(), requestbytes)
+		assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+		response := TelegramResponse{}
+		assert.Nil(t, json.Unmarshal(responseBytes, &response), "Unexpected json.Unmarshal error")
+		assert.Equal(t, expectedText, response.Text, "Unexpected response text")
+	}
+	assert.Equal(t, []string{"GetQuestion 15", "GetQuestion 15"}, storageController.callsJournal, "Unexpected storage calls journal")
+}
+
+func getTestSearchResults() leetcodeclient.QuestionsList {
+	return leetcodeclient.QuestionsList{
+		Total: 7,
+		Questions: []leetcodeclient.QuestionsListItem{
+			{FrontendQuestionID: "1", Title: "Two Sum", TitleSlug: "two-sum", Difficulty: "Easy"},
+			{FrontendQuestionID: "167", Title: "Two Sum II - Input Array Is Sorted", TitleSlug: "two-sum-ii-input-array-is-sorted", Difficulty: "Medium"},
+		},
+	}
+}
+
+func getTestResponseText(t *testing.T, responseBytes []byte) string {
+	response := TelegramResponse{}
+	assert.Nil(t, json.Unmarshal(responseBytes, &response), "Unexpected json.Unmarshal error")
+	return response.Text
+}
+
+func TestProcessRequestProblemByNumber(t *testing.T) {
+	_, storageController, lcClient, app := getTestApp()
+	lcClient.On("SearchQuestions", "167", 0, common.SearchResultsPageSize).Return(getTestSearchResults(), nil).Times(1)
+	lcClient.On("GetQuestionDetailsByTitleSlug", "two-sum-ii-input-array-is-sorted").Return(
+		leetcodeclient.LeetCodeTask{
+			QuestionID: 167,
+			TitleSlug:  "two-sum-ii-input-array-is-sorted",
+			Title:      "Two Sum II - Input Array Is Sorted",
+			Content:    "<p>Test content</p>",
+			Difficulty: "Medium",
+		},
+		nil,
+	).Times(1)
+	responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest("/problem 167"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	savedTask, ok := storageController.questions[167]
+	if assert.True(t, ok, "Found question should be saved to the storage") {
+		expectedTelegramResponse := NewTelegramResponse()
+		expectedTelegramResponse.ChatID = 1126
+		expectedTelegramResponse.Text = savedTask.GetTaskText()
+		expectedTelegramResponse.ReplyMarkup = savedTask.GetInlineKeyboard(common.SystemClock{})
+		expectedResponse, err := json.Marshal(expectedTelegramResponse)
+		assert.Nil(t, err, "Unexpected json.Marshal error")
+		assert.Equal(t, expectedResponse, responseBytes, "Unexprected response bytes")
+	}
+	assert.Equal(t, []string{"GetQuestionBySlug two-sum-ii-input-array-is-sorted", "SaveQuestion 167"}, storageController.callsJournal, "Unexpected storage calls journal")
+	lcClient.AssertExpectations(t)
+}
+
+func TestProcessRequestProblemBySlugFromStorage(t *testing.T) {
+	_, storageController, lcClient, app := getTestApp()
+	storageController.questions[1] = &common.BotLeetCodeTask{
+		LeetCodeTask: leetcodeclient.LeetCodeTask{QuestionID: 1, TitleSlug: "two-sum", Title: "Two Sum", Content: "Stored content"},
+	}
+	lcClient.On("SearchQuestions", "two-sum", 0, common.SearchResultsPageSize).Return(getTestSearchResults(), nil).Times(1)
+	responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest("/problem two-sum"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(t, storageController.questions[1].GetTaskText(), getTestResponseText(t, responseBytes), "Unexpected response text")
+	assert.Equal(t, []string{"GetQuestionBySlug two-sum"}, storageController.callsJournal, "Unexpected storage calls journal")
+	lcClient.AssertExpectations(t)
+}
+
+func TestProcessRequestProblemSearchResults(t *testing.T) {
+	_, _, lcClient, app := getTestApp()
+	lcClient.On("SearchQuestions", "sum", 0, common.SearchResultsPageSize).Return(getTestSearchResults(), nil).Times(1)
+	responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest("/problem   sum "))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	expectedTelegramResponse := NewTelegramResponse()
+	expectedTelegramResponse.ChatID = 1126
+	expectedTelegramResponse.Text = common.GetSearchResultsText("sum", getTestSearchResults(), 0)
+	expectedTelegramResponse.ReplyMarkup = common.GetSearchResultsInlineKeyboard("sum", getTestSearchResults(), 0)
+	expectedResponse, err := json.Marshal(expectedTelegramResponse)
+	assert.Nil(t, err, "Unexpected json.Marshal error")
+	assert.Equal(t, expectedResponse, responseBytes, "Unexprected response bytes")
+	lcClient.AssertExpectations(t)
+}
+
+func TestProcessRequestProblemWithoutArgs(t *testing.T) {
+	_, _, lcClient, app := getTestApp()
+	responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest("/problem"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(t, problemUsageMessage, getTestResponseText(t, responseBytes), "Unexpected response text")
+	lcClient.AssertExpectations(t)
+}
+
+func TestProcessRequestProblemNotFound(t *testing.T) {
+	_, _, lcClient, app := getTestApp()
+	lcClient.On("SearchQuestions", "<b>nothing</b>", 0, common.SearchResultsPageSize).Return(leetcodeclient.QuestionsList{}, nil).Times(1)
+	responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest("/problem <b>nothing</b>"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(t, "There are no free problems matching \"&lt;b&gt;nothing&lt;/b&gt;\".", getTestResponseText(t, responseBytes), "Unexpected response text")
+	lcClient.AssertExpectations(t)
+}
+
+func TestProcessRequestProblemError(t *testing.T) {
+	_, _, lcClient, app := getTestApp()
+	lcClient.On("SearchQuestions", "42", 0, common.SearchResultsPageSize).Return(leetcodeclient.QuestionsList{}, tests.ErrBypassTest).Times(1)
+	responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest("/problem 42"))
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected ProcessRequestBody error")
+	assert.Empty(t, responseBytes, "Unexprected response bytes")
+	lcClient.AssertExpectations(t)
+}
+
+func TestProcessRequestSearchCallbacks(t *testing.T) {
+	_, storageController, lcClient, app := getTestApp()
+	storageController.questions[1] = &common.BotLeetCodeTask{
+		LeetCodeTask: leetcodeclient.LeetCodeTask{QuestionID: 1, TitleSlug: "two-sum", Title: "Two Sum", Content: "Stored content"},
+	}
+	secondPage := getTestSearchResults()
+	secondPage.Questions = secondPage.Questions[1:]
+	lcClient.On("SearchQuestions", "two sum", common.SearchResultsPageSize, common.SearchResultsPageSize).Return(secondPage, nil).Times(1)
+	lcClient.On("SearchQuestions", "1", 0, common.SearchResultsPageSize).Return(getTestSearchResults(), nil).Times(1)
+	lcClient.On("SearchQuestions", "2", 0, common.SearchResultsPageSize).Return(leetcodeclient.QuestionsList{}, tests.ErrBypassTest).Times(1)
+
+	request := TelegramRequest{}
+	request.CallbackQuery.From.ID = 1126
+	testCases := []struct {
+		query        string
+		page         int
+		dataType     common.CallbackType
+		expectedText string
+	}{
+		{"two sum", 1, common.SearchPageRequest, common.GetSearchResultsText("two sum", secondPage, 1)},
+		{"1", 0, common.ProblemRequest, storageController.questions[1].GetTaskText()},
+		{"2", 0, common.ProblemRequest, "Something went completely wrong"},
+	}
+	for _, testCase := range testCases {
+		data, err := common.GetMarshalledSearchCallbackData(testCase.query, testCase.page, testCase.dataType)
+		assert.Nil(t, err, "Unexpected GetMarshalledSearchCallbackData error")
+		request.CallbackQuery.Data = data
+		requestbytes, err := json.Marshal(request)
+		assert.Nil(t, err, "Unexpected json.Marshal error")
+		responseBytes, err := app.ProcessRequestBody(context.Background(), requestbytes)
+		assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+		assert.Equal(t, testCase.expectedText, getTestResponseText(t, responseBytes), "Unexpected response text")
+	}
+	assert.Equal(t, []string{"GetQuestionBySlug two-sum"}, storageController.callsJournal, "Search callbacks shouldn't look for tasks by IDs")
+	lcClient.AssertExpectations(t)
+}
+
+func getTestLinkedApp() (*MockStorageController, *lcclientmocks.MockLeetcodeClient, *Application, *common.BotLeetCodeTask) {
+	_, storageController, lcClient, app := getTestApp()
+	storageController.users[1126].LeetcodeUsername = "leetcoder"
+	todayTaskID := common.GetDateIDForNow(common.SystemClock{})
+	storageController.tasks[todayTaskID] = &common.BotLeetCodeTask{
+		DateID: todayTaskID,
+		LeetCodeTask: leetcodeclient.LeetCodeTask{
+			QuestionID: 1445,
+			TitleSlug:  "two-sum",
+			Title:      "Two Sum",
+			Content:    "Test content",
+			Difficulty: "Easy",
+		},
+	}
+	return storageController, lcClient, app, storageController.tasks[todayTaskID]
+}
+
+func getTestProfile() leetcodeclient.UserProfile {
+	return leetcodeclient.UserProfile{
+		Username: "leetcoder",
+		SolvedCounts: []leetcodeclient.SolvedCount{
+			{Difficulty: "All", Count: 412},
+			{Difficulty: "Easy", Count: 153},
+			{Difficulty: "Medium", Count: 214},
+			{Difficulty: "Hard", Count: 45},
+		},
+	}
+}
+
+func TestProcessRequestTaskMessageWithSolvedStatus(t *testing.T) {
+	_, lcClient, app, task := getTestLinkedApp()
+	now := common.GetDateInRightTimeZone(common.SystemClock{})
+	lcClient.On("GetRecentAcceptedSubmissions", "leetcoder", leetcodeclient.RecentSubmissionsLimit).Return(
+		[]leetcodeclient.AcceptedSubmission{{TitleSlug: "two-sum", Timestamp: now.Unix()}},
+		nil,
+	).Times(1)
+	lcClient.On("GetRecentAcceptedSubmissions", "leetcoder", leetcodeclient.RecentSubmissionsLimit).Return(
+		[]leetcodeclient.AcceptedSubmission{{TitleSlug: "two-sum", Timestamp: now.Add(-48 * time.Hour).Unix()}},
+		nil,
+	).Times(1)
+	lcClient.On("GetRecentAcceptedSubmissions", "leetcoder", leetcodeclient.RecentSubmissionsLimit).Return(
+		[]leetcodeclient.AcceptedSubmission{},
+		tests.ErrBypassTest,
+	).Times(1)
+	for _, expectedStatus := range []string{"\n\n✅ Today's daily task is solved by leetcoder.", "\n\n❌ Today's daily task isn't solved by leetcoder yet.", ""} {
+		responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest(getActualDailyTaskCommandSlash))
+		assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+		assert.Equal(t, task.GetTaskText()+expectedStatus, getTestResponseText(t, responseBytes), "Unexpected response text")
+	}
+	lcClient.AssertExpectations(t)
+}
+
+func TestProcessRequestBodyFollowUp(t *testing.T) {
+	httpMock, storageController, lcClient, app := getTestApp()
+	storageController.users[1126].LeetcodeUsername = "leetcoder"
+	task := common.BotLeetCodeTask{
+		DateID:       common.GetDateIDForNow(common.SystemClock{}),
+		LeetCodeTask: leetcodeclient.LeetCodeTask{QuestionID: 1445, TitleSlug: "two-sum", Title: "Two Sum", Content: "Test content", Difficulty: "Easy"},
+	}
+	storageController.tasks[task.DateID] = &task
+	lcClient.On("GetRecentAcceptedSubmissions", "leetcoder", leetcodeclient.RecentSubmissionsLimit).Return([]leetcodeclient.AcceptedSubmission{}, tests.ErrBypassTest).Once()
+	responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest(getActualDailyTaskCommandSlash))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	followUp := TelegramResponse{}
+	assert.Nil(t, json.Unmarshal(responseBytes, &followUp), "Unexpected json.Unmarshal error")
+	followUp.Method = editMessageTextMethod
+	followUp.MessageID = 77
+	followUpBytes, _ := json.Marshal(followUp)
+
+	app.ResponseTimeout = time.Millisecond
+	app.EarlyAnswer = true
+	lcClient.On("GetRecentAcceptedSubmissions", "leetcoder", leetcodeclient.RecentSubmissionsLimit).Return([]leetcodeclient.AcceptedSubmission{}, tests.ErrBypassTest).After(50 * time.Millisecond).Once()
+	placeholder := NewTelegramResponse()
+	placeholder.ChatID = 1126
+	placeholder.Text = placeholderMessage
+	placeholderBytes, _ := json.Marshal(placeholder)
+	httpMock.On(
+		"RoundTrip",
+		"https://api.telegram.org/bot/sendMessage",
+		http.Header{"Content-Type": []string{"application/json"}},
+		string(placeholderBytes),
+	).Return(
+		&http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`{"ok":true,"result":{"message_id":77}}`))},
+		nil,
+	).Once()
+	httpMock.On(
+		"RoundTrip",
+		"https://api.telegram.org/bot/editMessageText",
+		http.Header{"Content-Type": []string{"application/json"}},
+		string(followUpBytes),
+	).Return(
+		&http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("{}"))},
+		nil,
+	).Once()
+	responseBytes, err = app.ProcessRequestBody(context.Background(), getTestMessageRequest(getActualDailyTaskCommandSlash))
+	assert.Nil(t, err, "Slow request shouldn't fail")
+	assert.Empty(t, responseBytes, "Slow request should get empty response")
+	app.WaitFollowUps()
+	httpMock.AssertExpectations(t)
+	lcClient.AssertExpectations(t)
+}
+
+func TestProcessRequestBodyFollowUpError(t *testing.T) {
+	httpMock, _, lcClient, app := getTestApp()
+	app.ResponseTimeout = time.Millisecond
+	app.EarlyAnswer = true
+	lcClient.On("GetDailyTask", common.GetDateIDForNow(common.SystemClock{})).Return(leetcodeclient.LeetCodeTask{}, tests.ErrBypassTest).After(50 * time.Millisecond)
+	placeholder := NewTelegramResponse()
+	placeholder.ChatID = 1126
+	placeholder.Text = placeholderMessage
+	placeholderBytes, _ := json.Marshal(placeholder)
+	httpMock.On(
+		"RoundTrip",
+		"https://api.telegram.org/bot/sendMessage",
+		http.Header{"Content-Type": []string{"application/json"}},
+		string(placeholderBytes),
+	).Return(
+		&http.Response{StatusCode: 500, Body: io.NopCloser(strings.NewReader(""))},
+		nil,
+	).Times(3)
+	followUp := NewTelegramResponse()
+	followUp.ChatID = 1126
+	followUp.Text = followUpErrorMessage
+	followUpBytes, _ := json.Marshal(followUp)
+	httpMock.On(
+		"RoundTrip",
+		"https://api.telegram.org/bot/sendMessage",
+		http.Header{"Content-Type": []string{"application/json"}},
+		string(followUpBytes),
+	).Return(
+		&http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("{}"))},
+		nil,
+	).Once()
+	responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest(getActualDailyTaskCommandSlash))
+	assert.Nil(t, err, "Errors of slow request should be sent by the follow-up")
+	assert.Empty(t, responseBytes, "Slow request should get empty response")
+	app.WaitFollowUps()
+	httpMock.AssertExpectations(t)
+}
+
+func TestProcessRequestBodyLateAnswer(t *testing.T) {
+	httpMock, _, lcClient, app := getTestApp()
+	app.ResponseTimeout = time.Millisecond
+	lcClient.On("GetDailyTask", common.GetDateIDForNow(common.SystemClock{})).Return(leetcodeclient.LeetCodeTask{}, tests.ErrBypassTest).After(50 * time.Millisecond)
+	placeholder := NewTelegramResponse()
+	placeholder.ChatID = 1126
+	placeholder.Text = placeholderMessage
+	placeholderBytes, _ := json.Marshal(placeholder)
+	httpMock.On(
+		"RoundTrip",
+		"https://api.telegram.org/bot/sendMessage",
+		http.Header{"Content-Type": []string{"application/json"}},
+		string(placeholderBytes),
+	).Return(
+		&http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`{"ok":true,"result":{"message_id":77}}`))},
+		nil,
+	).Once()
+	responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest(getActualDailyTaskCommandSlash))
+	assert.Nil(t, err, "Errors of slow request should be answered with the apology")
+	answer := NewTelegramResponse()
+	answer.Method = editMessageTextMethod
+	answer.ChatID = 1126
+	answer.MessageID = 77
+	answer.Text = followUpErrorMessage
+	answerBytes, _ := json.Marshal(answer)
+	assert.Equal(t, string(answerBytes), string(responseBytes), "Slow request should be answered with the edit of the placeholder")
+	httpMock.AssertExpectations(t)
+}
+
+func TestProcessRequestBodyLeetcodeUnavailable(t *testing.T) {
+	for _, unavailableErr := range []error{
+		leetcodeclient.ErrLeetcodeUnavailable,
+		fmt.Errorf("%w: %w", leetcodeclient.ErrLeetcodeUnavailable, tests.ErrBypassTest),
+		storage.ErrStorageUnavailable,
+	} {
+		_, _, lcClient, app := getTestApp()
+		lcClient.On("GetDailyTask", common.GetDateIDForNow(common.SystemClock{})).Return(leetcodeclient.LeetCodeTask{}, unavailableErr).Once()
+		responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest(getActualDailyTaskCommandSlash))
+		assert.Nil(t, err, "Unavailable LeetCode shouldn't fail the request")
+		response := TelegramResponse{}
+		assert.Nil(t, json.Unmarshal(responseBytes, &response), "Unexpected response")
+		assert.Equal(t, uint64(1126), response.ChatID, "Unexpected response chat")
+		assert.Equal(t, leetcodeUnavailableMessage, response.Text, "Unavailable LeetCode should be explained to the user: %s", unavailableErr)
+		lcClient.AssertExpectations(t)
+	}
+}
+
+func TestLeetcodeBreakerSharedByApplications(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	cfg := config.Default()
+	cfg.LeetcodeGraphQlURL = server.URL
+	newApp := func() *Application {
+		_, storageController, _, _ := getTestApp()
+		return NewApplication(cfg, WithStorage(storageController), WithDeduplicator(nil), WithLogger(logging.Discard()))
+	}
+
+	app := newApp()
+	for i := 0; i < 5; i++ {
+		responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest(getActualDailyTaskCommandSlash))
+		assert.Nil(t, err, "Failed LeetCode shouldn't fail the request")
+		assert.Equal(t, leetcodeUnavailableMessage, getTestResponseText(t, responseBytes), "Failed LeetCode should be explained to the user")
+	}
+	assert.Equal(t, 5, requests, "Every request should reach LeetCode before the breaker opens")
+	responseBytes, err := newApp().ProcessRequestBody(context.Background(), getTestMessageRequest(getActualDailyTaskCommandSlash))
+	assert.Nil(t, err, "Unavailable LeetCode shouldn't fail the request")
+	assert.Equal(t, leetcodeUnavailableMessage, getTestResponseText(t, responseBytes), "Unavailable LeetCode should be explained to the user")
+	assert.Equal(t, 5, requests, "Next application should short-circuit requests to the failing LeetCode")
+}
+
+func TestIsEditableMarkup(t *testing.T) {
+	assert.True(t, isEditableMarkup(""), "Empty markup should be editable")
+	assert.True(t, isEditableMarkup(`{"inline_keyboard":[]}`), "Inline keyboard should be editable")
+	keyboard, _ := GetMainKeyboard()
+	assert.False(t, isEditableMarkup(keyboard), "Reply keyboard shouldn't be editable")
+}
+
+func TestProcessRequestTaskMessageWithUserError(t *testing.T) {
+	storageController, lcClient, app, task := getTestLinkedApp()
+	storageController.failedUserID = 1126
+	responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest(getActualDailyTaskCommandSlash))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(t, task.GetTaskText(), getTestResponseText(t, responseBytes), "Unexpected response text")
+	lcClient.AssertExpectations(t)
+}
+
+func TestProcessRequestLink(t *testing.T) {
+	storageController, lcClient, app, _ := getTestLinkedApp()
+	storageController.users[1126].LeetcodeUsername = ""
+	lcClient.On("GetUserProfile", "LeetCoder").Return(getTestProfile(), nil).Times(1)
+	responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest("/link LeetCoder"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(
+		t,
+		", LeetCode profile <strong>leetcoder</strong> is linked.\n\n<strong>leetcoder</strong> has solved 412 problems: 153 Easy, 214 Medium, 45 Hard.",
+		getTestResponseText(t, responseBytes),
+		"Unexpected response text",
+	)
+	assert.Equal(t, "leetcoder", storageController.users[1126].LeetcodeUsername, "LeetCode username should be stored as LeetCode returns it")
+	lcClient.AssertExpectations(t)
+}
+
+func TestProcessRequestLinkWrongUsername(t *testing.T) {
+	storageController, lcClient, app, _ := getTestLinkedApp()
+	lcClient.On("GetUserProfile", "nobody").Return(leetcodeclient.UserProfile{}, leetcodeclient.ErrNoSuchUser).Times(1)
+	testCases := map[string]string{
+		"/link":                  linkUsageMessage,
+		"/link two words":        linkUsageMessage,
+		"/link <b>html</b>":      linkUsageMessage,
+		"/link nobody":           "There is no LeetCode user <strong>nobody</strong>.",
+		"/link   nobody_at_all ": "",
+	}
+	lcClient.On("GetUserProfile", "nobody_at_all").Return(leetcodeclient.UserProfile{}, tests.ErrBypassTest).Times(1)
+	for command, expectedText := range testCases {
+		responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest(command))
+		if expectedText == "" {
+			assert.Equal(t, tests.ErrBypassTest, err, "Unexpected ProcessRequestBody error")
+			continue
+		}
+		assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+		assert.Equal(t, expectedText, getTestResponseText(t, responseBytes), "Unexpected response text")
+	}
+	assert.Equal(t, "leetcoder", storageController.users[1126].LeetcodeUsername, "LeetCode username shouldn't be changed")
+	lcClient.AssertExpectations(t)
+}
+
+func TestProcessRequestLinkStorageError(t *testing.T) {
+	storageController, lcClient, app, _ := getTestLinkedApp()
+	storageController.failedUserID = 1126
+	lcClient.On("GetUserProfile", "leetcoder").Return(getTestProfile(), nil).Times(1)
+	_, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest("/link leetcoder"))
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected ProcessRequestBody error")
+	_, err = app.ProcessRequestBody(context.Background(), getTestMessageRequest("/unlink"))
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected ProcessRequestBody error")
+	_, err = app.ProcessRequestBody(context.Background(), getTestMessageRequest("/progress"))
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected ProcessRequestBody error")
+	lcClient.AssertExpectations(t)
+}
+
+func TestProcessRequestUnlink(t *testing.T) {
+	storageController, _, app, _ := getTestLinkedApp()
+	responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest("/unlink"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(t, ", LeetCode profile is unlinked.", getTestResponseText(t, responseBytes), "Unexpected response text")
+	assert.Empty(t, storageController.users[1126].LeetcodeUsername, "LeetCode username should be removed")
+}
+
+func TestProcessRequestProgress(t *testing.T) {
+	storageController, lcClient, app, _ := getTestLinkedApp()
+	lcClient.On("GetUserProfile", "leetcoder").Return(getTestProfile(), nil).Times(1)
+	lcClient.On("GetRecentAcceptedSubmissions", "leetcoder", leetcodeclient.RecentSubmissionsLimit).Return(
+		[]leetcodeclient.AcceptedSubmission{{TitleSlug: "two-sum", Timestamp: common.GetDateInRightTimeZone(common.SystemClock{}).Unix()}},
+		nil,
+	).Times(1)
+	responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest("/progress"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(
+		t,
+		"✅ Today's daily task is solved by leetcoder.\n\n<strong>leetcoder</strong> has solved 412 problems: 153 Easy, 214 Medium, 45 Hard.",
+		getTestResponseText(t, responseBytes),
+		"Unexpected response text",
+	)
+
+	storageController.users[1126].LeetcodeUsername = ""
+	responseBytes, err = app.ProcessRequestBody(context.Background(), getTestMessageRequest("/progress"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(t, ", you haven't linked LeetCode profile yet. Use /link leetcode_username to do it.", getTestResponseText(t, responseBytes), "Unexpected response text")
+	lcClient.AssertExpectations(t)
+}
+
+func TestProcessRequestProgressErrors(t *testing.T) {
+	storageController, lcClient, app, task := getTestLinkedApp()
+	lcClient.On("GetUserProfile", "leetcoder").Return(leetcodeclient.UserProfile{}, tests.ErrBypassTest).Times(1)
+	_, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest("/progress"))
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected ProcessRequestBody error")
+
+	lcClient.On("GetUserProfile", "leetcoder").Return(getTestProfile(), nil).Times(2)
+	lcClient.On("GetRecentAcceptedSubmissions", "leetcoder", leetcodeclient.RecentSubmissionsLimit).Return(
+		[]leetcodeclient.AcceptedSubmission{},
+		tests.ErrBypassTest,
+	).Times(1)
+	_, err = app.ProcessRequestBody(context.Background(), getTestMessageRequest("/progress"))
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected ProcessRequestBody error")
+
+	storageController.callsJournal = []string{}
+	storageController.failedTaskID = task.DateID
+	lcClient.On("GetDailyTask", task.DateID).Return(leetcodeclient.LeetCodeTask{}, tests.ErrBypassTest).Times(1)
+	_, err = app.ProcessRequestBody(context.Background(), getTestMessageRequest("/progress"))
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected ProcessRequestBody error")
+	lcClient.AssertExpectations(t)
+}
+
+func TestProcessRequestNudge(t *testing.T) {
+	storageController, _, app, _ := getTestLinkedApp()
+	testCases := []struct {
+		command      string
+		expectedText string
+	}{
+		{"/nudge", nudgeUsageMessage},
+		{"/nudge 19 20", nudgeUsageMessage},
+		{"/nudge 24", nudgeUsageMessage},
+		{"/nudge evening", nudgeUsageMessage},
+		{"/nudge off", ", the reminder was <strong>not enabled</strong>. No additional actions required."},
+		{"/nudge 19", ", you'll get a reminder at 19:00 (UTC) if today's daily task isn't solved by then."},
+		{"/nudge 19:00", ", the reminder is <strong>already set</strong> for the same time, nothing to do."},
+		{"/nudge 0:00", ", you'll get a reminder at 0:00 (UTC) if today's daily task isn't solved by then."},
+		{"/nudge OFF", ", the reminder is <strong>disabled</strong>."},
+	}
+	for _, testCase := range testCases {
+		responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest(testCase.command))
+		assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+		assert.Equal(t, testCase.expectedText, getTestResponseText(t, responseBytes), "Unexpected response text for %s", testCase.command)
+	}
+	assert.False(t, storageController.users[1126].NudgeSubscribed, "Nudge should be disabled")
+	assert.Equal(t, uint8(0), storageController.users[1126].NudgeHour, "Unexpected nudge hour")
+	assert.True(t, storageController.users[1126].Subscribed, "Nudge shouldn't change daily task subscription")
+
+	storageController.users[1126].LeetcodeUsername = ""
+	responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest("/nudge 19"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(t, ", you haven't linked LeetCode profile yet. Use /link leetcode_username to do it.", getTestResponseText(t, responseBytes), "Unexpected response text")
+	assert.False(t, storageController.users[1126].NudgeSubscribed, "Nudge shouldn't be enabled without linked profile")
+
+	storageController.failedUserID = 1126
+	for _, command := range []string{"/nudge 19", "/nudge off"} {
+		_, err = app.ProcessRequestBody(context.Background(), getTestMessageRequest(command))
+		assert.Equal(t, tests.ErrBypassTest, err, "Unexpected ProcessRequestBody error")
+	}
+}
+
+func getNudgeSendMessageString(chatID uint64, text string, replyMarkup string) string {
+	response := NewTelegramResponse()
+	response.ChatID = chatID
+	response.Text = text
+	response.ReplyMarkup = replyMarkup
+	bytes, _ := json.Marshal(response)
+	return string(bytes)
+}
+
+func TestSendNudgesToLinkedUsers(t *testing.T) {
+	storageController, lcClient, app, task := getTestLinkedApp()
+	httpMock := app.HTTPClient.Transport.(*mocks.MockHTTPTransport)
+	storageController.users[1126].NudgeSubscribed = true
+	storageController.users[1120].NudgeSubscribed = true
+	storageController.users[1120].LeetcodeUsername = "solver"
+	storageController.users[1124].NudgeSubscribed = true
+	storageController.users[1124].LeetcodeUsername = "broken"
+	lcClient.On("GetRecentAcceptedSubmissions", "leetcoder", leetcodeclient.RecentSubmissionsLimit).Return(
+		[]leetcodeclient.AcceptedSubmission{{TitleSlug: "add-two-numbers", Timestamp: common.GetDateInRightTimeZone(common.SystemClock{}).Unix()}},
+		nil,
+	).Times(1)
+	lcClient.On("GetRecentAcceptedSubmissions", "solver", leetcodeclient.RecentSubmissionsLimit).Return(
+		[]leetcodeclient.AcceptedSubmission{{TitleSlug: "two-sum", Timestamp: common.GetDateInRightTimeZone(common.SystemClock{}).Unix()}},
+		nil,
+	).Times(1)
+	lcClient.On("GetRecentAcceptedSubmissions", "broken", leetcodeclient.RecentSubmissionsLimit).Return(
+		[]leetcodeclient.AcceptedSubmission{},
+		tests.ErrBypassTest,
+	).Times(2)
+	lcClient.On("GetUserProfile", "solver").Return(leetcodeclient.UserProfile{Username: "solver", Streak: 12}, nil).Times(1)
+	httpMock.On(
+		"RoundTrip",
+		"https://api.telegram.org/bot/sendMessage",
+		http.Header{"Content-Type": []string{"application/json"}},
+		getNudgeSendMessageString(1126, "⏰ 1126firstname, today's daily task isn't solved yet. There is still time!\n\n"+task.GetTaskText(), task.GetInlineKeyboard(common.SystemClock{})),
+	).Return(
+		&http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("1126"))},
+		nil,
+	).Times(1)
+	httpMock.On(
+		"RoundTrip",
+		"https://api.telegram.org/bot/sendMessage",
+		http.Header{"Content-Type": []string{"application/json"}},
+		getNudgeSendMessageString(1120, "🎉 Well done, 1120firstname! Today's daily task is solved. Your streak is 12 days in a row.", ""),
+	).Return(
+		&http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("1120"))},
+		nil,
+	).Times(1)
+
+	err := app.SendNudgesToLinkedUsers(context.Background())
+	assert.Nil(t, err, "Unexpected SendNudgesToLinkedUsers error")
+	assert.Equal(t, map[uint64]uint64{1126: task.DateID, 1120: task.DateID}, storageController.nudgeSent, "Unexpected sent nudges")
+
+	// Second call the same day sends nothing to users who already got the nudge
+	err = app.SendNudgesToLinkedUsers(context.Background())
+	assert.Nil(t, err, "Unexpected SendNudgesToLinkedUsers error")
+	lcClient.AssertExpectations(t)
+	httpMock.AssertExpectations(t)
+}
+
+func TestSendNudgesToLinkedUsersErrors(t *testing.T) {
+	storageController, lcClient, app, task := getTestLinkedApp()
+	storageController.getSubscribedUsersMustFail = true
+	err := app.SendNudgesToLinkedUsers(context.Background())
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected SendNudgesToLinkedUsers error")
+
+	storageController.callsJournal = []string{}
+	storageController.failedTaskID = task.DateID
+	err = app.SendNudgesToLinkedUsers(context.Background())
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected SendNudgesToLinkedUsers error")
+	assert.Equal(t, []string{fmt.Sprintf("GetTask %d", task.DateID)}, storageController.callsJournal, "Users shouldn't be requested without the task")
+	lcClient.AssertNotCalled(t, "GetDailyTask", mock.Anything)
+}
+
+func TestGetTimeZoneKeyboard(t *testing.T) {
+	waitKeyboard := "{\"keyboard\":[[{\"text\":\"Share location to set time zone\",\"request_location\":true}],[{\"text\":\"Get actual daily task\"}],[{\"text\":\"Subscribe\"},{\"text\":\"Unsubscribe\"}]],\"input_field_placeholder\":\"Please, use buttons below:\",\"resize_keyboard\":true}"
+	keyboard, err := GetTimeZoneKeyboard()
+	assert.Nil(t, err, "Unexpected GetTimeZoneKeyboard error")
+	assert.Equal(t, waitKeyboard, keyboard, "Returned keyboard not equal with expected")
+}
+
+func TestProcessRequestTimeZone(t *testing.T) {
+	_, storageController, _, app := getTestApp()
+	responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest("/timezone"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	response := TelegramResponse{}
+	assert.Nil(t, json.Unmarshal(responseBytes, &response), "Unexpected json.Unmarshal error")
+	assert.Equal(t, fmt.Sprintf(timeZoneUsageMessage, "UTC"), response.Text, "Unexpected response text")
+	keyboard, _ := GetTimeZoneKeyboard()
+	assert.Equal(t, keyboard, response.ReplyMarkup, "Keyboard with location button expected")
+
+	testCases := map[string]string{
+		"/timezone Europe/Berlin": "Europe/Berlin",
+		"/timezone utc-5:30":      "UTC-05:30",
+		"/timezone +3":            "UTC+03:00",
+	}
+	for command, timeZone := range testCases {
+		responseBytes, err = app.ProcessRequestBody(context.Background(), getTestMessageRequest(command))
+		assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+		assert.Equal(t, fmt.Sprintf(timeZoneSetMessage, "", timeZone), getTestResponseText(t, responseBytes), "Unexpected response text")
+		assert.Equal(t, timeZone, storageController.users[1126].TimeZone, "Time zone isn't stored")
+	}
+
+	responseBytes, err = app.ProcessRequestBody(context.Background(), getTestMessageRequest("/timezone <b>Mars</b>"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(t, "Unknown time zone \"&lt;b&gt;Mars&lt;/b&gt;\". Please, use the name like Europe/Berlin or UTC offset like +3 or -05:30.", getTestResponseText(t, responseBytes), "Unexpected response text")
+
+	storageController.users[1126].TimeZone = "Europe/Berlin"
+	responseBytes, err = app.ProcessRequestBody(context.Background(), getTestMessageRequest("/timezone"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(t, fmt.Sprintf(timeZoneUsageMessage, "Europe/Berlin"), getTestResponseText(t, responseBytes), "Unexpected response text")
+	responseBytes, err = app.ProcessRequestBody(context.Background(), getTestMessageRequest("/Subscribe"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(t, fmt.Sprintf(subscribeDialogMessage, "Europe/Berlin"), getTestResponseText(t, responseBytes), "Unexpected response text")
+
+	storageController.failedUserID = 1126
+	for _, command := range []string{"/timezone", "/timezone Europe/Berlin", "/Subscribe"} {
+		_, err = app.ProcessRequestBody(context.Background(), getTestMessageRequest(command))
+		assert.Equal(t, tests.ErrBypassTest, err, "Unexpected ProcessRequestBody error")
+	}
+}
+
+func TestProcessRequestLocation(t *testing.T) {
+	_, storageController, _, app := getTestApp()
+	request := TelegramRequest{}
+	request.Message.Chat.ID = 1000
+	request.Message.From.ID = 1000
+	request.Message.From.FirstName = "Traveller"
+	request.Message.Location = &struct {
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+	}{Latitude: 52.52, Longitude: 13.4}
+	requestBytes, _ := json.Marshal(request)
+	responseBytes, err := app.ProcessRequestBody(context.Background(), requestBytes)
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(t, fmt.Sprintf(timeZoneSetMessage, "Traveller", "UTC+01:00")+timeZoneLocationNote, getTestResponseText(t, responseBytes), "Unexpected response text")
+	assert.Equal(t, "UTC+01:00", storageController.users[1000].TimeZone, "Time zone isn't stored")
+
+	storageController.failedUserID = 1000
+	_, err = app.ProcessRequestBody(context.Background(), requestBytes)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected ProcessRequestBody error")
+}
+
+func TestSubscribeActionWithTimeZone(t *testing.T) {
+	_, storageController, _, app := getTestApp()
+	storageController.users[1124].TimeZone = "Asia/Tokyo"
+	responseBytes, err := app.ProcessRequestBody(context.Background(), []byte(`{"message":{"text":"9:00","chat":{"id":1124},"From":{"id":1124,"first_name":"Taro"}}}`))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(t, fmt.Sprintf(subscribedMessage, "Taro", "09:00", "Asia/Tokyo"), getTestResponseText(t, responseBytes), "Unexpected response text")
+	assert.Equal(t, []common.SendingTime{{Minute: 540}}, storageController.users[1124].SendingTimes, "Sending time should be stored as local")
+}
+
+func TestProcessRequestSendingTimes(t *testing.T) {
+	_, storageController, _, app := getTestApp()
+	testCases := []struct {
+		command      string
+		expectedText string
+	}{
+		{"/addtime", addTimeUsageMessage},
+		{"/addtime 25:00", addTimeUsageMessage},
+		{"/addtime 13:00 task", addTimeUsageMessage},
+		{"/addtime 7:30", ", you'll automatically receive the daily task every day at 07:30 (UTC)."},
+		{"/addtime 13:05 HINT", ", you'll automatically receive the first hint of the daily task every day at 13:05 (UTC)."},
+		{"/addtime 13:05 hint", ", you have <strong>already subscribed</strong> for daily updates at the same time, nothing to do."},
+		{"/addtime 7:30 hint", ", you already have another delivery at 07:30. Use /removetime 07:30 first or choose another time."},
+		{"/addtime 13:05", ", you already have another delivery at 13:05. Use /removetime 13:05 first or choose another time."},
+		{"/times", ", your delivery times (UTC):\n00:00 — daily task\n07:30 — daily task\n13:05 — first hint of the daily task"},
+		{"/removetime", removeTimeUsageMessage},
+		{"/removetime noon", removeTimeUsageMessage},
+		{"/removetime 8:00", ", you don't have delivery at 08:00. Use /times to see your delivery times."},
+		{"/removetime 0:00", ", 00:00 is removed from your delivery times."},
+		{"/removetime 07:30", ", 07:30 is removed from your delivery times."},
+		{"/times", ", your delivery times (UTC):\n13:05 — first hint of the daily task"},
+		{"/removetime 13:05", ", 13:05 is removed from your delivery times."},
+		{"/times", ", you aren't subscribed. Use /Subscribe or /addtime to choose the time."},
+		{"7:60", "You command \"7:60\" isn't recognized =(" + strings.SplitN(helpMessage, "=(", 2)[1]},
+	}
+	for _, testCase := range testCases {
+		responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest(testCase.command))
+		assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+		assert.Equal(t, testCase.expectedText, getTestResponseText(t, responseBytes), "Unexpected response text for %s", testCase.command)
+	}
+	assert.False(t, storageController.users[1126].Subscribed, "User without delivery times should be unsubscribed")
+
+	storageController.failedUserID = 1126
+	for _, command := range []string{"/addtime 7:30", "/removetime 7:30", "/times"} {
+		_, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest(command))
+		assert.Equal(t, tests.ErrBypassTest, err, "Unexpected ProcessRequestBody error for %s", command)
+	}
+}
+
+func TestSendDailyTaskHintToSubscribedUsers(t *testing.T) {
+	httpMock, storageController, _, app := getTestApp()
+	app.SendingSlot = time.Minute
+	taskDateID := common.GetDateIDForNow(common.SystemClock{})
+	task := common.BotLeetCodeTask{
+		DateID: taskDateID,
+		LeetCodeTask: leetcodeclient.LeetCodeTask{
+			QuestionID: 1445,
+			TitleSlug:  "6534",
+			Title:      "Test title",
+			Content:    "Test content",
+			Hints:      []string{"first hint", "Second Hint"},
+			Difficulty: "Easy",
+		},
+	}
+	storageController.tasks[taskDateID] = &task
+	delete(storageController.users, 1126)
+	storageController.users[1120].SendingTimes = []common.SendingTime{{Minute: 0}, {Minute: 0, Kind: common.HintSendingTime}}
+	for _, text := range []string{task.GetTaskText(), "💡 Hint #1 for today's daily task \"Test title\": first hint"} {
+		response := NewTelegramResponse()
+		response.ChatID = 1120
+		response.Text = text
+		response.ReplyMarkup = task.GetInlineKeyboard(common.SystemClock{})
+		bytes, _ := json.Marshal(response)
+		httpMock.On(
+			"RoundTrip",
+			"https://api.telegram.org/bot/sendMessage",
+			http.Header{"Content-Type": []string{"application/json"}},
+			string(bytes),
+		).Return(
+			&http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("1120"))},
+			nil,
+		).Times(1)
+	}
+
+	_, err := app.SendDailyTaskToSubscribedUsers(context.Background())
+	assert.Nil(t, err, "Got unexprected error from SendDailyTaskToSubscribedUsers")
+	httpMock.AssertExpectations(t)
+	assert.Equal(t, fmt.Sprintf("GetSubscribedUsers %d 1m0s", time.Now().UTC().Hour()), storageController.callsJournal[0], "Sending slot should be passed to the storage")
+
+	task.Hints = []string{}
+	response := getScheduledMessage(common.SystemClock{}, common.User{ID: 1120}, common.SendingTime{Kind: common.HintSendingTime}, task)
+	assert.Equal(t, "💡 There are no hints for today's daily task \"Test title\". Good luck!", response.Text, "Unexpected text without hints")
+	response = getScheduledMessage(common.SystemClock{}, common.User{ID: 1120, CodeLanguage: "rust"}, common.SendingTime{}, task)
+	assert.Equal(t, task.GetInlineKeyboardForLanguage(common.SystemClock{}, "rust"), response.ReplyMarkup, "Solutions in the preferred language should be linked")
+}
+
+func TestGetOutbox(t *testing.T) {
+	cfg := config.Default()
+	assert.Nil(t, getOutbox(cfg), "Outbox should be disabled by default")
+	cfg.OutboxEnabled = true
+	assert.IsType(t, &storage.YDBOutbox{}, getOutbox(cfg), "Enabled outbox should be stored in YDB")
+}
+
+func TestProcessRequestWeekdays(t *testing.T) {
+	_, storageController, _, app := getTestApp()
+	responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest("/weekdays"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	response := TelegramResponse{}
+	assert.Nil(t, json.Unmarshal(responseBytes, &response), "Unexpected json.Unmarshal error")
+	assert.Equal(t, ", daily tasks are delivered <strong>every day</strong>. Use the buttons below to choose days of the week.", response.Text, "Unexpected response text")
+	assert.Equal(t, common.GetDeliverySettingsInlineKeyboard(0, false), response.ReplyMarkup, "Unexpected inline keyboard")
+
+	callbackRequest := []byte(`{"callback_query":{"data":"{\"callback_type\":7,\"hint\":0,\"weekdays\":62}","from":{"id":1126,"first_name":"Ann"}}}`)
+	responseBytes, err = app.ProcessRequestBody(context.Background(), callbackRequest)
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	response = TelegramResponse{}
+	assert.Nil(t, json.Unmarshal(responseBytes, &response), "Unexpected json.Unmarshal error")
+	assert.Equal(t, "Ann, daily tasks are delivered <strong>Monday to Friday</strong>. Use the buttons below to choose days of the week.", response.Text, "Unexpected response text")
+	assert.Equal(t, common.GetDeliverySettingsInlineKeyboard(common.WorkingDays, false), response.ReplyMarkup, "Unexpected inline keyboard")
+	assert.Equal(t, common.WorkingDays, storageController.users[1126].Weekdays, "Weekdays isn't stored")
+
+	responseBytes, err = app.ProcessRequestBody(context.Background(), []byte(`{"callback_query":{"data":"{\"callback_type\":7,\"hint\":0,\"weekdays\":128}","from":{"id":1126}}}`))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(t, "There are no such days of the week. Try another breach ;)", getTestResponseText(t, responseBytes), "Unexpected response text")
+
+	storageController.failedUserID = 1126
+	_, err = app.ProcessRequestBody(context.Background(), getTestMessageRequest("/weekdays"))
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected ProcessRequestBody error")
+	responseBytes, err = app.ProcessRequestBody(context.Background(), callbackRequest)
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(t, "Something went completely wrong", getTestResponseText(t, responseBytes), "Unexpected response text")
+}
+
+func TestProcessRequestPause(t *testing.T) {
+	_, storageController, _, app := getTestApp()
+	testCases := []struct {
+		command      string
+		expectedText string
+	}{
+		{"/pause", pauseUsageMessage},
+		{"/pause 2099-13-01", pauseUsageMessage},
+		{"/pause off", ", delivery was <strong>not paused</strong>. No additional actions required."},
+		{"/pause 2000-01-01", ", the date to resume delivery should be after today (" + common.FormatDateID(common.GetDateIDForNow(common.SystemClock{})) + ")."},
+		{"/pause 2099-11-01", ", delivery is <strong>paused</strong> till 2099-11-01 (UTC), it will be resumed automatically."},
+		{"/weekdays", ", daily tasks are delivered <strong>every day</strong>. Use the buttons below to choose days of the week.\n\nDelivery is <strong>paused</strong> till 2099-11-01, it will be resumed automatically."},
+		{"/pause OFF", ", delivery is <strong>resumed</strong>."},
+	}
+	for _, testCase := range testCases {
+		responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest(testCase.command))
+		assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+		assert.Equal(t, testCase.expectedText, getTestResponseText(t, responseBytes), "Unexpected response text for %s", testCase.command)
+	}
+	assert.Equal(t, uint64(0), storageController.users[1126].PausedUntil, "Delivery should be resumed")
+
+	storageController.users[1126].PausedUntil = 20991101
+	responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest("/weekdays"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	response := TelegramResponse{}
+	assert.Nil(t, json.Unmarshal(responseBytes, &response), "Unexpected json.Unmarshal error")
+	assert.Equal(t, common.GetDeliverySettingsInlineKeyboard(0, true), response.ReplyMarkup, "Resume button expected")
+	responseBytes, err = app.ProcessRequestBody(context.Background(), []byte(`{"callback_query":{"data":"{\"callback_type\":8,\"hint\":0}","from":{"id":1126,"first_name":"Ann"}}}`))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(t, "Ann, daily tasks are delivered <strong>every day</strong>. Use the buttons below to choose days of the week.", getTestResponseText(t, responseBytes), "Unexpected response text")
+	assert.Equal(t, uint64(0), storageController.users[1126].PausedUntil, "Delivery should be resumed")
+
+	storageController.failedUserID = 1126
+	for _, command := range []string{"/pause 2099-11-01", "/pause off"} {
+		_, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest(command))
+		assert.Equal(t, tests.ErrBypassTest, err, "Unexpected ProcessRequestBody error for %s", command)
+	}
+}
+
+func getTestSettingsCallback(setting common.SettingsSection, value string) []byte {
+	callbackData := common.CallbackData{Type: common.SettingsRequest, Setting: setting}
+	if value != "" {
+		callbackData.Type = common.SettingRequest
+		callbackData.Value = value
+	}
+	data, _ := json.Marshal(callbackData)
+	request := TelegramRequest{}
+	request.CallbackQuery.Data = string(data)
+	request.CallbackQuery.From.ID = 1126
+	request.CallbackQuery.From.FirstName = "Ann"
+	request.CallbackQuery.Message.MessageID = 42
+	request.CallbackQuery.Message.Chat.ID = 1126
+	requestBytes, _ := json.Marshal(request)
+	return requestBytes
+}
+
+func TestProcessRequestSettings(t *testing.T) {
+	_, storageController, _, app := getTestApp()
+	storageController.users[1126].SendingTimes = []common.SendingTime{{Minute: 420}, {Minute: 780, Kind: common.HintSendingTime}}
+	storageController.users[1126].Weekdays = common.WorkingDays
+	responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest("/settings"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	response := TelegramResponse{}
+	assert.Nil(t, json.Unmarshal(responseBytes, &response), "Unexpected json.Unmarshal error")
+	assert.Equal(t, "sendMessage", response.Method, "Settings command should send new message")
+	assert.Equal(t, "⚙️ <strong>Settings</strong>\n\nDelivery time: <strong>07:00, 13:00 hint, Monday to Friday</strong>\nTime zone: <strong>UTC</strong>\nLanguage: <strong>English</strong>\nDifficulty: <strong>all</strong>\nCode language: <strong>not set</strong>\nSubscription: <strong>active</strong>", response.Text, "Unexpected response text")
+	assert.Equal(t, common.GetSettingsInlineKeyboard(common.MainSettings, *storageController.users[1126], false), response.ReplyMarkup, "Unexpected inline keyboard")
+
+	testCases := []struct {
+		setting      common.SettingsSection
+		value        string
+		expectedText string
+	}{
+		{common.SendingTimeSettings, "", "Tap the hour to add or remove delivery of the daily task. Your delivery times (UTC): <strong>07:00, 13:00 hint</strong>.\n\nUse /addtime for other minutes and hints, /weekdays for days of the week."},
+		{common.SendingTimeSettings, "07:00", "Tap the hour to add or remove delivery of the daily task. Your delivery times (UTC): <strong>13:00 hint</strong>.\n\nUse /addtime for other minutes and hints, /weekdays for days of the week."},
+		{common.SendingTimeSettings, "15:00", "Tap the hour to add or remove delivery of the daily task. Your delivery times (UTC): <strong>13:00 hint, 15:00</strong>.\n\nUse /addtime for other minutes and hints, /weekdays for days of the week."},
+		{common.TimeZoneSettings, "2", "Your time zone is <strong>Europe/Berlin</strong>. Choose another one below or use /timezone for any other time zone."},
+		{common.LanguageSettings, "", languageSettings},
+		{common.DifficultySettings, "6", "Difficulties of daily tasks for you: <strong>Medium, Hard</strong>. Tap the difficulty to toggle it.\n\nOn other days you get <strong>nothing</strong>."},
+		{common.DifficultySettings, common.DifficultyFallbackOn, "Difficulties of daily tasks for you: <strong>Medium, Hard</strong>. Tap the difficulty to toggle it.\n\nOn other days you get <strong>a random problem of your difficulty</strong>."},
+		{common.CodeLanguageSettings, "golang", "Your preferred code language: <strong>Go</strong>."},
+		{common.SubscriptionSettings, "", "Subscription: <strong>active</strong>."},
+		{common.SubscriptionSettings, common.SubscriptionOff, "Subscription: <strong>not subscribed</strong>."},
+		{common.MainSettings, "", "⚙️ <strong>Settings</strong>\n\nDelivery time: <strong>not set</strong>\nTime zone: <strong>Europe/Berlin</strong>\nLanguage: <strong>English</strong>\nDifficulty: <strong>Medium, Hard</strong>\nCode language: <strong>Go</strong>\nSubscription: <strong>not subscribed</strong>"},
+	}
+	for _, testCase := range testCases {
+		responseBytes, err := app.ProcessRequestBody(context.Background(), getTestSettingsCallback(testCase.setting, testCase.value))
+		assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+		response := TelegramResponse{}
+		assert.Nil(t, json.Unmarshal(responseBytes, &response), "Unexpected json.Unmarshal error")
+		assert.Equal(t, editMessageTextMethod, response.Method, "Settings callback should edit the message")
+		assert.Equal(t, uint64(42), response.MessageID, "Unexpected edited message")
+		assert.Equal(t, testCase.expectedText, response.Text, "Unexpected response text for %d %q", testCase.setting, testCase.value)
+		assert.Equal(t, common.GetSettingsInlineKeyboard(testCase.setting, *storageController.users[1126], false), response.ReplyMarkup, "Unexpected inline keyboard")
+	}
+
+	storageController.users[1126].Subscribed = true
+	storageController.users[1126].PausedUntil = 20991101
+	responseBytes, err = app.ProcessRequestBody(context.Background(), getTestSettingsCallback(common.SubscriptionSettings, common.SubscriptionResume))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(t, "Subscription: <strong>active</strong>.", getTestResponseText(t, responseBytes), "Unexpected response text")
+	assert.Equal(t, uint64(0), storageController.users[1126].PausedUntil, "Delivery should be resumed")
+
+	for _, wrongCallback := range [][]byte{
+		getTestSettingsCallback(common.SendingTimeSettings, "noon"),
+		getTestSettingsCallback(common.TimeZoneSettings, "100"),
+		getTestSettingsCallback(common.DifficultySettings, "8"),
+		getTestSettingsCallback(common.CodeLanguageSettings, "cobol"),
+		getTestSettingsCallback(common.SubscriptionSettings, "on"),
+		getTestSettingsCallback(common.LanguageSettings, "ru"),
+	} {
+		responseBytes, err = app.ProcessRequestBody(context.Background(), wrongCallback)
+		assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+		response := TelegramResponse{}
+		assert.Nil(t, json.Unmarshal(responseBytes, &response), "Unexpected json.Unmarshal error")
+		assert.Equal(t, "There is no such setting. Try another breach ;)", response.Text, "Unexpected response text")
+		assert.Equal(t, "sendMessage", response.Method, "Wrong setting shouldn't edit the message")
+	}
+
+	storageController.failedUserID = 1126
+	_, err = app.ProcessRequestBody(context.Background(), getTestMessageRequest("/settings"))
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected ProcessRequestBody error")
+	responseBytes, err = app.ProcessRequestBody(context.Background(), getTestSettingsCallback(common.TimeZoneSettings, "0"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(t, "Something went completely wrong", getTestResponseText(t, responseBytes), "Unexpected response text")
+}
+
+func TestSendDailyTaskToSubscribedUsersWithDifficultyFilter(t *testing.T) {
+	httpMock, storageController, lcClient, app := getTestApp()
+	taskDateID := common.GetDateIDForNow(common.SystemClock{})
+	task := common.BotLeetCodeTask{
+		DateID: taskDateID,
+		LeetCodeTask: leetcodeclient.LeetCodeTask{
+			QuestionID: 1445,
+			TitleSlug:  "6534",
+			Title:      "Test title",
+			Content:    "Test content",
+			Difficulty: "Easy",
+		},
+	}
+	storageController.tasks[taskDateID] = &task
+	storageController.users[1126].Difficulties = 1 << 2
+	storageController.users[1120].Difficulties = 1 << 2
+	storageController.users[1120].DifficultyFallback = true
+	storageController.users[1120].SendingTimes = []common.SendingTime{{Minute: 0}, {Minute: 0, Kind: common.HintSendingTime}}
+	lcTask := leetcodeclient.LeetCodeTask{
+		QuestionID: 42,
+		TitleSlug:  "trapping-rain-water",
+		Title:      "Trapping Rain Water",
+		Content:    "Test content",
+		Difficulty: "Hard",
+	}
+	lcClient.On("GetRandomQuestion", leetcodeclient.QuestionsFilter{Difficulty: "HARD"}).Return(lcTask, nil).Once()
+	fallbackTask := common.BotLeetCodeTask{LeetCodeTask: lcTask}
+	response := NewTelegramResponse()
+	response.ChatID = 1120
+	response.Text = "Today's daily task is Easy, so here is a random Hard problem for you instead.\n\n" + fallbackTask.GetTaskText()
+	response.ReplyMarkup = fallbackTask.GetInlineKeyboard(common.SystemClock{})
+	bytes, _ := json.Marshal(response)
+	httpMock.On(
+		"RoundTrip",
+		"https://api.telegram.org/bot/sendMessage",
+		http.Header{"Content-Type": []string{"application/json"}},
+		string(bytes),
+	).Return(
+		&http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("1120"))},
+		nil,
+	).Once()
+
+	summary, err := app.SendDailyTaskToSubscribedUsers(context.Background())
+	assert.Nil(t, err, "Got unexprected error from SendDailyTaskToSubscribedUsers")
+	httpMock.AssertExpectations(t)
+	lcClient.AssertExpectations(t)
+	assert.Equal(t, lcTask, storageController.questions[42].LeetCodeTask, "Fallback problem should be saved for callbacks")
+	// The hint of the fallback problem isn't sent, the user without the fallback is filtered out
+	assert.Equal(t, DeliverySummary{Sent: 1, Skipped: 2}, summary, "Filtered out messages should be skipped")
+
+	// Forget deliveries to check that nothing is sent without the fallback problem
+	storageController.deliveries = nil
+	lcClient.On("GetRandomQuestion", leetcodeclient.QuestionsFilter{Difficulty: "HARD"}).Return(leetcodeclient.LeetCodeTask{}, tests.ErrBypassTest).Once()
+	summary, err = app.SendDailyTaskToSubscribedUsers(context.Background())
+	assert.Nil(t, err, "Got unexprected error from SendDailyTaskToSubscribedUsers")
+	assert.Equal(t, DeliverySummary{Skipped: 3}, summary, "Filtered out messages should be skipped")
+	httpMock.AssertExpectations(t)
+	lcClient.AssertExpectations(t)
+}
+
+func TestProcessRequestPastTasks(t *testing.T) {
+	_, storageController, lcClient, app := getTestApp()
+	storedTask := common.BotLeetCodeTask{
+		DateID: 20240315,
+		LeetCodeTask: leetcodeclient.LeetCodeTask{
+			QuestionID: 1,
+			TitleSlug:  "two-sum",
+			Title:      "Two Sum",
+			Content:    "Stored content",
+			Difficulty: "Easy",
+		},
+	}
+	storageController.tasks[20240315] = &storedTask
+	yesterdayDateID := common.GetDateID(common.GetDateInRightTimeZone(common.SystemClock{}).AddDate(0, 0, -1))
+	yesterdayTask := storedTask
+	yesterdayTask.DateID = yesterdayDateID
+	storageController.tasks[yesterdayDateID] = &yesterdayTask
+	lcTask := leetcodeclient.LeetCodeTask{
+		QuestionID: 42,
+		TitleSlug:  "trapping-rain-water",
+		Title:      "Trapping Rain Water",
+		Content:    "Fetched content",
+		Difficulty: "Hard",
+	}
+	lcClient.On("GetDailyTask", uint64(20240316)).Return(lcTask, nil).Once()
+	fetchedTask := common.BotLeetCodeTask{DateID: 20240316, LeetCodeTask: lcTask}
+
+	testCases := []struct {
+		command      string
+		expectedText string
+	}{
+		{"/task", taskUsageMessage},
+		{"/task 15.03.2024", taskUsageMessage},
+		{"/task 2020-03-31", "There is no daily task for 2020-03-31. Daily tasks are available from 2020-04-01 till today."},
+		{"/task 2099-01-01", "There is no daily task for 2099-01-01. Daily tasks are available from 2020-04-01 till today."},
+		{"/task 2024-03-15", "📅 Daily task for 2024-03-15\n\n" + storedTask.GetTaskText()},
+		{"/task 2024-03-16", "📅 Daily task for 2024-03-16\n\n" + fetchedTask.GetTaskText()},
+		{"/yesterday", "📅 Daily task for " + common.FormatDateID(yesterdayDateID) + "\n\n" + yesterdayTask.GetTaskText()},
+	}
+	for _, testCase := range testCases {
+		responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest(testCase.command))
+		assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+		assert.Equal(t, testCase.expectedText, getTestResponseText(t, responseBytes), "Unexpected response text for %s", testCase.command)
+	}
+	lcClient.AssertExpectations(t)
+	assert.Equal(t, fetchedTask, *storageController.tasks[20240316], "Fetched task should be saved")
+
+	callbackData, _ := common.GetMarshalledCallbackData(20240315, 0, common.DailyTaskRequest)
+	request := TelegramRequest{}
+	request.CallbackQuery.Data = callbackData
+	request.CallbackQuery.From.ID = 1126
+	request.CallbackQuery.Message.MessageID = 42
+	request.CallbackQuery.Message.Chat.ID = 1126
+	requestBytes, _ := json.Marshal(request)
+	responseBytes, err := app.ProcessRequestBody(context.Background(), requestBytes)
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	response := TelegramResponse{}
+	assert.Nil(t, json.Unmarshal(responseBytes, &response), "Unexpected json.Unmarshal error")
+	assert.Equal(t, editMessageTextMethod, response.Method, "Navigation should edit the message")
+	assert.Equal(t, uint64(42), response.MessageID, "Unexpected edited message")
+	assert.Equal(t, storedTask.GetInlineKeyboard(common.SystemClock{}), response.ReplyMarkup, "Unexpected inline keyboard")
+
+	lcClient.On("GetDailyTask", uint64(20240317)).Return(leetcodeclient.LeetCodeTask{}, tests.ErrBypassTest)
+	_, err = app.ProcessRequestBody(context.Background(), getTestMessageRequest("/task 2024-03-17"))
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected ProcessRequestBody error")
+	callbackData, _ = common.GetMarshalledCallbackData(20240317, 0, common.DailyTaskRequest)
+	request.CallbackQuery.Data = callbackData
+	requestBytes, _ = json.Marshal(request)
+	responseBytes, err = app.ProcessRequestBody(context.Background(), requestBytes)
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	response = TelegramResponse{}
+	assert.Nil(t, json.Unmarshal(responseBytes, &response), "Unexpected json.Unmarshal error")
+	assert.Equal(t, "Something went completely wrong", response.Text, "Unexpected response text")
+	assert.Equal(t, "sendMessage", response.Method, "Error shouldn't edit the message")
+
+	// 3 requests to LeetCode API are already made by the user
+	for i := 0; i < pastTasksFetchUserLimit-3; i++ {
+		assert.True(t, app.pastTasksLimiter.allow(1126, time.Now()), "Request should be allowed before the limit")
+	}
+	responseBytes, err = app.ProcessRequestBody(context.Background(), getTestMessageRequest("/task 2024-03-18"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(t, pastTasksLimitMessage, getTestResponseText(t, responseBytes), "Unexpected response text")
+	responseBytes, err = app.ProcessRequestBody(context.Background(), getTestMessageRequest("/task 2024-03-15"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(t, "📅 Daily task for 2024-03-15\n\n"+storedTask.GetTaskText(), getTestResponseText(t, responseBytes), "Stored tasks shouldn't be limited")
+}
+
+func TestProcessRequestCalendar(t *testing.T) {
+	_, storageController, lcClient, app := getTestApp()
+	loc, _ := time.LoadLocation("America/Los_Angeles")
+	challenges := []leetcodeclient.DailyChallenge{
+		{Date: time.Date(2024, time.March, 1, 0, 0, 0, 0, loc), TitleSlug: "two-sum", Difficulty: "Easy"},
+		{Date: time.Date(2024, time.March, 2, 0, 0, 0, 0, loc), TitleSlug: "3sum", Difficulty: "Medium"},
+		{Date: time.Date(2024, time.March, 3, 0, 0, 0, 0, loc), TitleSlug: "trapping-rain-water", Difficulty: "Hard"},
+	}
+	days := []common.CalendarDay{
+		{DateID: 20240301, Difficulty: "Easy"},
+		{DateID: 20240302, Difficulty: "Medium"},
+		{DateID: 20240303, Difficulty: "Hard"},
+	}
+	lcClient.On("GetMonthlyChallenges", uint64(20240301)).Return(challenges, nil)
+	lcClient.On("GetMonthlyChallenges", uint64(20240401)).Return([]leetcodeclient.DailyChallenge{}, tests.ErrBypassTest)
+	currentMonthDateID := common.GetMonthDateID(common.GetDateIDForNow(common.SystemClock{}))
+	lcClient.On("GetMonthlyChallenges", currentMonthDateID).Return([]leetcodeclient.DailyChallenge{
+		{Date: common.GetDateFromDateID(currentMonthDateID), TitleSlug: "two-sum", Difficulty: "Easy"},
+		{Date: common.GetDateFromDateID(common.GetDateID(common.GetDateInRightTimeZone(common.SystemClock{}).AddDate(0, 0, 1))), TitleSlug: "3sum", Difficulty: "Medium"},
+	}, nil)
+
+	testCases := []struct {
+		command      string
+		expectedText string
+	}{
+		{"/calendar 03.2024", calendarUsageMessage},
+		{"/calendar 2024 03", calendarUsageMessage},
+		{"/calendar 2020-03", "There are no daily tasks in March 2020. Daily tasks are available from April 2020 till today."},
+		{"/calendar 2099-01", "There are no daily tasks in January 2099. Daily tasks are available from April 2020 till today."},
+		{"/calendar 2024-03", "📅 Daily tasks of <strong>March 2024</strong>\n\n🟢 Easy 🟡 Medium 🔴 Hard\n\nTap the day to open its daily task."},
+	}
+	for _, testCase := range testCases {
+		responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest(testCase.command))
+		assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+		assert.Equal(t, testCase.expectedText, getTestResponseText(t, responseBytes), "Unexpected response text for %s", testCase.command)
+	}
+	responseBytes, err := app.ProcessRequestBody(context.Background(), getTestMessageRequest("/calendar 2024-03"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	response := TelegramResponse{}
+	assert.Nil(t, json.Unmarshal(responseBytes, &response), "Unexpected json.Unmarshal error")
+	assert.Equal(t, common.GetCalendarInlineKeyboard(common.SystemClock{}, 20240301, days), response.ReplyMarkup, "Unexpected calendar")
+
+	responseBytes, err = app.ProcessRequestBody(context.Background(), getTestMessageRequest("/calendar"))
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	response = TelegramResponse{}
+	assert.Nil(t, json.Unmarshal(responseBytes, &response), "Unexpected json.Unmarshal error")
+	currentDays := []common.CalendarDay{{DateID: currentMonthDateID, Difficulty: "Easy"}}
+	assert.Equal(t, common.GetCalendarInlineKeyboard(common.SystemClock{}, currentMonthDateID, currentDays), response.ReplyMarkup, "Future days shouldn't be in the calendar")
+
+	storageController.users[1126].LeetcodeUsername = "leetcoder"
+	lcClient.On("GetRecentAcceptedSubmissions", "leetcoder", leetcodeclient.RecentSubmissionsLimit).Return([]leetcodeclient.AcceptedSubmission{
+		{TitleSlug: "two-sum", Timestamp: time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC).Unix()},
+		{TitleSlug: "3sum", Timestamp: time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC).Unix()},
+	}, nil)
+	callbackData, _ := json.Marshal(common.CallbackData{Type: common.CalendarRequest, DateID: 20240301})
+	request := TelegramRequest{}
+	request.CallbackQuery.Data = string(callbackData)
+	request.CallbackQuery.From.ID = 1126
+	request.CallbackQuery.Message.MessageID = 42
+	request.CallbackQuery.Message.Chat.ID = 1126
+	requestBytes, _ := json.Marshal(request)
+	responseBytes, err = app.ProcessRequestBody(context.Background(), requestBytes)
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	response = TelegramResponse{}
+	assert.Nil(t, json.Unmarshal(responseBytes, &response), "Unexpected json.Unmarshal error")
+	assert.Equal(t, editMessageTextMethod, response.Method, "Months navigation should edit the message")
+	assert.Equal(t, uint64(42), response.MessageID, "Unexpected edited message")
+	assert.Equal(t, "📅 Daily tasks of <strong>March 2024</strong>\n\n🟢 Easy 🟡 Medium 🔴 Hard\n✅ Solved by leetcoder, only the last 20 accepted submissions are checked\n\nTap the day to open its daily task.", response.Text, "Unexpected response text")
+	// 3sum is solved before its day, so it doesn't count
+	days[0].Solved = true
+	assert.Equal(t, common.GetCalendarInlineKeyboard(common.SystemClock{}, 20240301, days), response.ReplyMarkup, "Unexpected calendar with solved days")
+
+	callbackData, _ = json.Marshal(common.CallbackData{Type: common.CalendarRequest, DateID: 20240401})
+	request.CallbackQuery.Data = string(callbackData)
+	requestBytes, _ = json.Marshal(request)
+	responseBytes, err = app.ProcessRequestBody(context.Background(), requestBytes)
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	response = TelegramResponse{}
+	assert.Nil(t, json.Unmarshal(responseBytes, &response), "Unexpected json.Unmarshal error")
+	assert.Equal(t, "Something went completely wrong", response.Text, "Unexpected response text")
+	assert.Equal(t, "sendMessage", response.Method, "Error shouldn't edit the message")
+	lcClient.AssertExpectations(t)
+}
+
+func TestFetchLimiter(t *testing.T) {
+	limiter := fetchLimiter{}
+	now := time.Now()
+	for i := 0; i < pastTasksFetchUserLimit; i++ {
+		assert.True(t, limiter.allow(1, now), "Request should be allowed before the limit")
+	}
+	assert.False(t, limiter.allow(1, now), "Request over the user limit shouldn't be allowed")
+	assert.True(t, limiter.allow(2, now), "Other users shouldn't be limited")
+	assert.True(t, limiter.allow(1, now.Add(pastTasksFetchPeriod)), "Requests should be allowed after the period")
+	for i := uint64(0); len(limiter.total) < pastTasksFetchTotalLimit; i++ {
+		limiter.allow(100+i, now.Add(pastTasksFetchPeriod))
+	}
+	assert.False(t, limiter.allow(3, now.Add(pastTasksFetchPeriod)), "Request over the total limit shouldn't be allowed")
+}
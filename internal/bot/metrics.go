@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dartkron/leetcodeBot/v3/internal/common"
+	"github.com/dartkron/leetcodeBot/v3/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// broadcastBuckets are upper bounds of broadcast durations in seconds, which are limited by the function timeout
+var broadcastBuckets = []float64{1, 5, 10, 30, 60, 120, 300, 600}
+
+var (
+	updatesTotal = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "leetcodebot_updates_total",
+		Help: "Telegram updates processed by command",
+	}, []string{"command"})
+	callbacksTotal = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "leetcodebot_callbacks_total",
+		Help: "Callback queries processed by type",
+	}, []string{"type"})
+	telegramRequestsTotal = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "leetcodebot_telegram_requests_total",
+		Help: "Telegram API requests by method and response code",
+	}, []string{"method", "code"})
+	deliveriesTotal = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "leetcodebot_deliveries_total",
+		Help: "Scheduled daily task deliveries by status",
+	}, []string{"status"})
+	broadcastDuration = metrics.Factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "leetcodebot_broadcast_duration_seconds",
+		Help:    "Duration of broadcasts",
+		Buckets: broadcastBuckets,
+	}, []string{"run"})
+	broadcastUsers = metrics.Factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "leetcodebot_broadcast_users",
+		Help: "Users of the last broadcast by UTC hour",
+	}, []string{"run", "hour"})
+)
+
+// knownCommands are commands with arguments, other commands are matched as the whole message
+var knownCommands = map[string]bool{
+	getActualDailyTaskCommandSlash: true,
+	subscribeCommandSlash:          true,
+	unsubscribeCommandSlash:        true,
+	randomCommandSlash:             true,
+	problemCommandSlash:            true,
+	linkCommandSlash:               true,
+	unlinkCommandSlash:             true,
+	progressCommandSlash:           true,
+	nudgeCommandSlash:              true,
+	timeZoneCommandSlash:           true,
+	timesCommandSlash:              true,
+	addTimeCommandSlash:            true,
+	removeTimeCommandSlash:         true,
+	weekdaysCommandSlash:           true,
+	pauseCommandSlash:              true,
+	settingsCommandSlash:           true,
+	taskCommandSlash:               true,
+	yesterdayCommandSlash:          true,
+	calendarCommandSlash:           true,
+}
+
+// commandLabel returns the command of the update for metrics. Free text isn't used as a label to keep the number of series bounded
+func commandLabel(request TelegramRequest) string {
+	if len(request.CallbackQuery.Data) != 0 {
+		return "callback"
+	}
+	if request.Message.Location != nil {
+		return "location"
+	}
+	switch request.Message.Text {
+	case getActualDailyTaskCommand:
+		return getActualDailyTaskCommandSlash
+	case subscribeCommand:
+		return subscribeCommandSlash
+	case unsubscribeCommand:
+		return unsubscribeCommandSlash
+	}
+	fields := strings.Fields(request.Message.Text)
+	if len(fields) > 0 && knownCommands[fields[0]] {
+		return fields[0]
+	}
+	if _, err := common.ParseSendingTime(request.Message.Text); err == nil {
+		return "sendingTime"
+	}
+	return "unknown"
+}
+
+// telegramStatusError is returned when Telegram API responds with error status
+type telegramStatusError struct {
+	StatusCode int
+}
+
+func (e *telegramStatusError) Error() string {
+	return "telegram API responded with status " + strconv.Itoa(e.StatusCode)
+}
+
+// telegramCodeLabel returns the response code of Telegram API for metrics, "error" for network errors
+func telegramCodeLabel(err error) string {
+	statusError := &telegramStatusError{}
+	switch {
+	case err == nil:
+		return "200"
+	case errors.As(err, &statusError):
+		return strconv.Itoa(statusError.StatusCode)
+	}
+	return "error"
+}
+
+// observeBroadcast observes duration of the broadcast run since the start, it's called with defer
+func observeBroadcast(run string, start time.Time) {
+	broadcastDuration.WithLabelValues(run).Observe(time.Since(start).Seconds())
+}
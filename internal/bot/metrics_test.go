@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dartkron/leetcodeBot/v3/internal/common"
+	"github.com/dartkron/leetcodeBot/v3/tests"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandLabel(t *testing.T) {
+	testCases := map[string]string{
+		getActualDailyTaskCommand:      getActualDailyTaskCommandSlash,
+		getActualDailyTaskCommandSlash: getActualDailyTaskCommandSlash,
+		unsubscribeCommand:             unsubscribeCommandSlash,
+		"/random medium array":         randomCommandSlash,
+		"/problem two sum":             problemCommandSlash,
+		"10:30":                        "sendingTime",
+		"Some free text":               "unknown",
+		"":                             "unknown",
+	}
+	for text, expected := range testCases {
+		request := TelegramRequest{}
+		request.Message.Text = text
+		assert.Equal(t, expected, commandLabel(request), "Unexpected label of %q", text)
+	}
+	request := TelegramRequest{}
+	_ = json.Unmarshal([]byte(`{"message":{"location":{"latitude":55.75,"longitude":37.62}}}`), &request)
+	assert.Equal(t, "location", commandLabel(request), "Unexpected label of location")
+	request = TelegramRequest{}
+	request.CallbackQuery.Data = "{}"
+	assert.Equal(t, "callback", commandLabel(request), "Unexpected label of callback")
+}
+
+func TestTelegramCodeLabel(t *testing.T) {
+	assert.Equal(t, "200", telegramCodeLabel(nil), "Unexpected label of success")
+	assert.Equal(t, "429", telegramCodeLabel(fmt.Errorf("wrapped: %w", &telegramStatusError{StatusCode: 429})), "Unexpected label of status error")
+	assert.Equal(t, "error", telegramCodeLabel(tests.ErrBypassTest), "Unexpected label of network error")
+}
+
+func TestUpdatesMetrics(t *testing.T) {
+	_, _, _, app := getTestApp()
+	WithSender(func(ctx context.Context, method string, body []byte) (uint64, error) {
+		return 0, &telegramStatusError{StatusCode: 403}
+	})(app)
+	updates := testutil.ToFloat64(updatesTotal.WithLabelValues("unknown"))
+	callbacks := testutil.ToFloat64(callbacksTotal.WithLabelValues("settings"))
+	request := TelegramRequest{}
+	request.Message.Text = "Some free text"
+	requestBytes, _ := json.Marshal(request)
+	_, err := app.ProcessRequestBody(context.Background(), requestBytes)
+	assert.Nil(t, err, "Unexpected ProcessRequestBody error")
+	assert.Equal(t, updates+1, testutil.ToFloat64(updatesTotal.WithLabelValues("unknown")), "Update should be counted by command")
+
+	request = TelegramRequest{}
+	request.CallbackQuery.From.ID = 1124
+	request.CallbackQuery.Data, _ = common.GetMarshalledCallbackData(0, 0, common.SettingsRequest)
+	requestBytes, _ = json.Marshal(request)
+	_, _ = app.ProcessRequestBody(context.Background(), requestBytes)
+	assert.Equal(t, callbacks+1, testutil.ToFloat64(callbacksTotal.WithLabelValues("settings")), "Callback should be counted by type")
+
+	sends := testutil.ToFloat64(telegramRequestsTotal.WithLabelValues(sendMessageMethod, "403"))
+	_, err = app.sendMessage(context.Background(), []byte("{}"))
+	assert.NotNil(t, err, "Sender error should be returned")
+	assert.Equal(t, sends+1, testutil.ToFloat64(telegramRequestsTotal.WithLabelValues(sendMessageMethod, "403")), "Telegram request should be counted by code")
+}
+
+func TestBroadcastMetrics(t *testing.T) {
+	_, storageController, _, app := getTestApp()
+	WithClock(tests.NewFakeClock(time.Date(2026, time.March, 29, 5, 0, 0, 0, time.UTC)))(app)
+	broadcasts := tests.HistogramCount(broadcastDuration.WithLabelValues("dailyTask"))
+	subscribed := 0
+	for _, user := range storageController.users {
+		if user.Subscribed {
+			subscribed++
+		}
+	}
+	_, err := app.SendDailyTaskToSubscribedUsers(context.Background())
+	assert.NotNil(t, err, "Broadcast without today's task should fail")
+	assert.Equal(t, broadcasts+1, tests.HistogramCount(broadcastDuration.WithLabelValues("dailyTask")), "Broadcast duration should be observed")
+	assert.Equal(t, float64(subscribed), testutil.ToFloat64(broadcastUsers.WithLabelValues("dailyTask", "5")), "Subscribers should be counted by UTC hour")
+}
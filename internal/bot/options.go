@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/dartkron/leetcodeBot/v3/internal/common"
+	"github.com/dartkron/leetcodeBot/v3/internal/storage"
+	"github.com/dartkron/leetcodeBot/v3/pkg/leetcodeclient"
+)
+
+// Sender calls Telegram API method with JSON body and returns ID of the sent message
+type Sender func(ctx context.Context, method string, body []byte) (uint64, error)
+
+// Option replaces the default component of Application
+type Option func(*Application)
+
+// WithStorage replaces YDB and file cache storage
+func WithStorage(storageController storage.Controller) Option {
+	return func(app *Application) {
+		app.storageController = storageController
+	}
+}
+
+// WithLeetcodeClient replaces LeetCode GraphQL API client
+func WithLeetcodeClient(leetcodeAPIClient leetcodeclient.LeetcodeClient) Option {
+	return func(app *Application) {
+		app.leetcodeAPIClient = leetcodeAPIClient
+	}
+}
+
+// WithSender replaces calls of Telegram API by HTTP
+func WithSender(send Sender) Option {
+	return func(app *Application) {
+		app.send = send
+	}
+}
+
+// WithHTTPClient sets HTTP client for Telegram API calls
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(app *Application) {
+		app.HTTPClient = httpClient
+	}
+}
+
+// WithClock replaces the system clock
+func WithClock(clock common.Clock) Option {
+	return func(app *Application) {
+		app.clock = clock
+	}
+}
+
+// WithLogger replaces the default slog logger
+func WithLogger(logger *slog.Logger) Option {
+	return func(app *Application) {
+		app.logger = logger
+	}
+}
+
+// WithOutbox replaces the outbox, nil sends messages directly
+func WithOutbox(outbox storage.Outbox) Option {
+	return func(app *Application) {
+		app.outbox = outbox
+	}
+}
+
+// WithDeduplicator replaces the deduplicator of Telegram updates, nil disables deduplication
+func WithDeduplicator(updatesDeduplicator storage.UpdatesDeduplicator) Option {
+	return func(app *Application) {
+		app.updatesDeduplicator = updatesDeduplicator
+	}
+}
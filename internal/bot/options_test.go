@@ -0,0 +1,101 @@
+package bot
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dartkron/leetcodeBot/v3/internal/common"
+	"github.com/dartkron/leetcodeBot/v3/internal/config"
+	"github.com/dartkron/leetcodeBot/v3/internal/storage"
+	lcclientmocks "github.com/dartkron/leetcodeBot/v3/pkg/leetcodeclient/mocks"
+	"github.com/dartkron/leetcodeBot/v3/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewApplicationOptions(t *testing.T) {
+	storageController := &MockStorageController{}
+	leetcodeClient := &lcclientmocks.MockLeetcodeClient{}
+	httpClient := &http.Client{}
+	clock := tests.NewFakeClock(time.Date(2026, 1, 1, 23, 59, 0, 0, time.UTC))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	outbox := storage.NewMemoryOutbox(nil)
+	deduplicator := storage.NewLRUDeduplicator(1, time.Hour, nil)
+	app := NewApplication(
+		config.Default(),
+		WithStorage(storageController),
+		WithLeetcodeClient(leetcodeClient),
+		WithHTTPClient(httpClient),
+		WithClock(clock),
+		WithLogger(logger),
+		WithOutbox(outbox),
+		WithDeduplicator(deduplicator),
+	)
+	assert.Same(t, storageController, app.storageController, "WithStorage should replace storage")
+	assert.Same(t, leetcodeClient, app.leetcodeAPIClient, "WithLeetcodeClient should replace LeetCode client")
+	assert.Same(t, httpClient, app.HTTPClient, "WithHTTPClient should replace HTTP client")
+	assert.Equal(t, clock.Now(), app.now(), "WithClock should replace clock")
+	assert.Same(t, logger, app.getLogger(), "WithLogger should replace logger")
+	assert.Same(t, outbox, app.outbox, "WithOutbox should replace outbox")
+	assert.Same(t, deduplicator, app.updatesDeduplicator, "WithDeduplicator should replace deduplicator")
+}
+
+func TestNewUpdatesDeduplicator(t *testing.T) {
+	initializedDeduplicatorsMutex.Lock()
+	initializedDeduplicators = map[deduplicatorKey]storage.UpdatesDeduplicator{}
+	initializedDeduplicatorsMutex.Unlock()
+	cfg := config.Default()
+	cfg.DedupeCapacity = 1
+	first := NewApplication(cfg)
+	second := NewApplication(config.Default())
+	assert.NotSame(t, first.updatesDeduplicator, second.updatesDeduplicator, "Applications with different settings shouldn't share deduplicator")
+	assert.Same(t, first.updatesDeduplicator, NewApplication(cfg).updatesDeduplicator, "Applications with the same settings should share deduplicator")
+	for _, updateID := range []uint64{1, 2, 1} {
+		isNew, err := first.updatesDeduplicator.MarkUpdate(context.Background(), updateID)
+		assert.Nil(t, err, "Unexpected MarkUpdate error")
+		assert.True(t, isNew, "Update %d should be evicted by the capacity of the config", updateID)
+	}
+	isNew, _ := second.updatesDeduplicator.MarkUpdate(context.Background(), 1)
+	assert.True(t, isNew, "Applications with different settings shouldn't share processed updates")
+	isNew, _ = second.updatesDeduplicator.MarkUpdate(context.Background(), 2)
+	assert.True(t, isNew, "Default capacity should keep both updates")
+	isNew, _ = second.updatesDeduplicator.MarkUpdate(context.Background(), 1)
+	assert.False(t, isNew, "Default capacity should keep both updates")
+	isNew, _ = NewApplication(config.Default()).updatesDeduplicator.MarkUpdate(context.Background(), 2)
+	assert.False(t, isNew, "Next application of the process should remember updates processed by the previous one")
+}
+
+func TestNewApplicationOutboxClock(t *testing.T) {
+	cfg := config.Default()
+	cfg.OutboxEnabled = true
+	clock := tests.NewFakeClock(time.Date(2026, 1, 1, 23, 59, 0, 0, time.UTC))
+	app := NewApplication(cfg, WithClock(clock), WithDeduplicator(nil))
+	ydbOutbox, ok := app.outbox.(*storage.YDBOutbox)
+	assert.True(t, ok, "Default outbox should be YDB one")
+	assert.Same(t, clock, ydbOutbox.Clock, "Default outbox should use the clock of the application")
+
+	outbox := storage.NewMemoryOutbox(nil)
+	app = NewApplication(cfg, WithClock(clock), WithOutbox(outbox))
+	assert.Equal(t, common.SystemClock{}, outbox.Clock, "Clock of the replaced outbox shouldn't be changed")
+}
+
+func TestWithSender(t *testing.T) {
+	calls := []string{}
+	send := func(ctx context.Context, method string, body []byte) (uint64, error) {
+		calls = append(calls, method+" "+string(body))
+		if method == editMessageTextMethod {
+			return 0, tests.ErrBypassTest
+		}
+		return 42, nil
+	}
+	app := NewApplication(config.Default(), WithSender(send), WithDeduplicator(nil))
+	messageID, err := app.sendMessage(context.Background(), []byte("{}"))
+	assert.Nil(t, err, "Unexpected sendMessage error")
+	assert.Equal(t, uint64(42), messageID, "Message ID should be returned by the sender")
+	_, err = app.callTelegramAPI(context.Background(), editMessageTextMethod, []byte("{}"))
+	assert.Equal(t, tests.ErrBypassTest, err, "Sender errors should be returned")
+	assert.Equal(t, []string{"sendMessage {}", "editMessageText {}"}, calls, "Telegram API should be called only by the sender")
+}
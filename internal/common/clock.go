@@ -0,0 +1,16 @@
+package common
+
+import "time"
+
+// Clock provides the current time, so date-dependent logic could be tested with a fake one
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is Clock of the real time
+type SystemClock struct{}
+
+// Now returns the current local time
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
@@ -4,10 +4,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
+	"log/slog"
+	"math"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+	// Serverless environment may not have system time zones database
+	_ "time/tzdata"
 
 	"github.com/dartkron/leetcodeBot/v3/pkg/leetcodeclient"
 )
@@ -19,7 +25,33 @@ const (
 	notSet
 )
 
-const callbackDataMarshalErrorMessage = "Got error on marshalling callback data:"
+const callbackDataMarshalErrorMessage = "Error on marshalling callback data"
+
+// Telegram doesn't allow callback data longer than 64 bytes
+const callbackDataMaxLength = 64
+
+// utcOffsetRegexp matches offsets like +3, -0530, UTC+05:30 or GMT-8
+var utcOffsetRegexp = regexp.MustCompile(`^(?i:UTC|GMT)?([+-])(\d{1,2})(?::?(\d{2}))?$`)
+
+// ErrWrongTimeZone is returned when time zone is neither IANA name nor UTC offset
+var ErrWrongTimeZone = errors.New("wrong time zone")
+
+// ErrWrongSendingTime is returned when sending time isn't in H, HH:MM or H:MM format
+var ErrWrongSendingTime = errors.New("wrong sending time")
+
+// ErrWrongDate is returned when date isn't in YYYY-MM-DD format
+var ErrWrongDate = errors.New("wrong date")
+
+// sendingTimeRegexp matches times like 7, 07:30 or 7:05
+var sendingTimeRegexp = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?$`)
+
+// DefaultSendingSlot is the default period of the reminder, users get messages with this precision
+const DefaultSendingSlot = 15 * time.Minute
+
+const minutesInDay = 24 * 60
+
+// SearchResultsPageSize is an amount of questions on the one page of search results
+const SearchResultsPageSize = 5
 
 type inlineButton struct {
 	Text         string `json:"text"`
@@ -37,16 +69,130 @@ const (
 	DifficultyRequest
 	// TopicTagsRequest means that callback requires task topic tags.
 	TopicTagsRequest
+	// SimilarQuestionsRequest means that callback requires list of questions similar to the task.
+	SimilarQuestionsRequest
+	// SimilarQuestionRequest means that callback requires one of similar questions as a full task.
+	SimilarQuestionRequest
+	// SearchPageRequest means that callback requires another page of search results for Query.
+	SearchPageRequest
+	// ProblemRequest means that callback requires question with number from Query as a full task.
+	ProblemRequest
+	// WeekdaysRequest means that callback sets delivery weekdays of the user to Weekdays.
+	WeekdaysRequest
+	// ResumeRequest means that callback resumes paused delivery.
+	ResumeRequest
+	// SettingsRequest means that callback opens Setting section of the settings menu.
+	SettingsRequest
+	// SettingRequest means that callback sets Value in Setting section of the settings menu.
+	SettingRequest
+	// DailyTaskRequest means that callback requires daily task with DateID as a full task.
+	DailyTaskRequest
+	// CalendarRequest means that callback requires calendar of daily tasks of the DateID month.
+	CalendarRequest
+)
+
+var callbackTypeNames = []string{"hint", "difficulty", "topicTags", "similarQuestions", "similarQuestion", "searchPage", "problem", "weekdays", "resume", "settings", "setting", "dailyTask", "calendar"}
+
+// String returns the name of the callback type, "unknown" for types out of the list
+func (t CallbackType) String() string {
+	if int(t) < len(callbackTypeNames) {
+		return callbackTypeNames[t]
+	}
+	return "unknown"
+}
+
+// FirstDailyTaskDateID is the date of the first LeetCode daily task
+const FirstDailyTaskDateID = 20200401
+
+// SettingsSection is a section of the settings menu
+type SettingsSection uint8
+
+const (
+	// MainSettings is the settings menu itself
+	MainSettings SettingsSection = iota
+	// SendingTimeSettings is the section to choose delivery hours
+	SendingTimeSettings
+	// TimeZoneSettings is the section to choose time zone
+	TimeZoneSettings
+	// LanguageSettings is the section to choose language of the bot
+	LanguageSettings
+	// DifficultySettings is the section to choose difficulties of delivered tasks
+	DifficultySettings
+	// CodeLanguageSettings is the section to choose preferred programming language
+	CodeLanguageSettings
+	// SubscriptionSettings is the section to manage subscription state
+	SubscriptionSettings
 )
 
+// SubscriptionOff and SubscriptionResume are values of SubscriptionSettings callbacks
+const (
+	SubscriptionOff    = "off"
+	SubscriptionResume = "resume"
+)
+
+// DifficultyFallbackOn and DifficultyFallbackOff are values of DifficultySettings callbacks to choose what to send
+// instead of filtered out daily task
+const (
+	DifficultyFallbackOn  = "random"
+	DifficultyFallbackOff = "nothing"
+)
+
+// CodeLanguage is a programming language supported by LeetCode
+type CodeLanguage struct {
+	Slug string
+	Name string
+}
+
+// CodeLanguages are programming languages to choose in the settings menu
+var CodeLanguages = []CodeLanguage{
+	{Slug: "cpp", Name: "C++"},
+	{Slug: "java", Name: "Java"},
+	{Slug: "python3", Name: "Python3"},
+	{Slug: "c", Name: "C"},
+	{Slug: "csharp", Name: "C#"},
+	{Slug: "javascript", Name: "JavaScript"},
+	{Slug: "typescript", Name: "TypeScript"},
+	{Slug: "golang", Name: "Go"},
+	{Slug: "kotlin", Name: "Kotlin"},
+	{Slug: "swift", Name: "Swift"},
+	{Slug: "rust", Name: "Rust"},
+	{Slug: "ruby", Name: "Ruby"},
+}
+
+// SettingsTimeZones are time zones offered in the settings menu, others could be set with the command
+var SettingsTimeZones = []string{
+	"UTC",
+	"Europe/London",
+	"Europe/Berlin",
+	"Europe/Moscow",
+	"Asia/Dubai",
+	"Asia/Kolkata",
+	"Asia/Shanghai",
+	"Asia/Tokyo",
+	"America/New_York",
+	"America/Chicago",
+	"America/Denver",
+	"America/Los_Angeles",
+}
+
 // ErrClosedContext universal error about closed context
 var ErrClosedContext error = errors.New("context closed during execution")
 
 // CallbackData is used to unmarshal callback request JSON and marshal inline keyboard data.
+// Daily tasks are found by DateID, all other tasks by QuestionID.
+// Hint is an index of the hint for HintRequest and an index of the similar question for SimilarQuestionRequest.
+// Query and Page are used only by search callbacks, which are not related to any task.
+// Weekdays is used only by weekdays callbacks, Setting and Value only by settings menu callbacks.
 type CallbackData struct {
-	DateID uint64       `json:"dateID,string,omitempty"`
-	Type   CallbackType `json:"callback_type"`
-	Hint   int          `json:"hint"`
+	DateID     uint64          `json:"dateID,string,omitempty"`
+	QuestionID uint64          `json:"questionID,string,omitempty"`
+	Type       CallbackType    `json:"callback_type"`
+	Hint       int             `json:"hint"`
+	Query      string          `json:"query,omitempty"`
+	Page       int             `json:"page,omitempty"`
+	Weekdays   Weekdays        `json:"weekdays,omitempty"`
+	Setting    SettingsSection `json:"setting,omitempty"`
+	Value      string          `json:"value,omitempty"`
 }
 
 // BotLeetCodeTask is internal LeetCodeTask representation with bot-related info: DateID.
@@ -56,14 +202,28 @@ type BotLeetCodeTask struct {
 }
 
 // User struct for the whole application
+// LeetcodeUsername is set when the user has linked LeetCode profile
+// TimeZone is IANA time zone name or UTC offset, empty means UTC. SendingTimes and NudgeHour are in this time zone
+// Weekdays are days of delivery, zero means every day. Delivery is paused till PausedUntil dateID, zero means not paused
+// Difficulties are difficulties of delivered tasks, zero means all. CodeLanguage is LeetCode slug of preferred language
+// DifficultyFallback means that random problem of allowed difficulty is sent instead of filtered out daily task
 type User struct {
-	ID          uint64
-	ChatID      uint64
-	Username    string
-	FirstName   string
-	LastName    string
-	Subscribed  bool
-	SendingHour uint8
+	ID                 uint64
+	ChatID             uint64
+	Username           string
+	FirstName          string
+	LastName           string
+	Subscribed         bool
+	SendingTimes       []SendingTime
+	TimeZone           string
+	LeetcodeUsername   string
+	NudgeSubscribed    bool
+	NudgeHour          uint8
+	Weekdays           Weekdays
+	PausedUntil        uint64
+	Difficulties       Difficulties
+	DifficultyFallback bool
+	CodeLanguage       string
 }
 
 // GetTaskText returns task text representation.
@@ -73,22 +233,60 @@ func (task *BotLeetCodeTask) GetTaskText() string {
 
 // GetMarshalledCallbackData returns serialized callback data.
 func GetMarshalledCallbackData(dateID uint64, hintID int, dataType CallbackType) (string, error) {
-	callbackData := CallbackData{DateID: dateID, Type: dataType}
-	if dataType == HintRequest {
+	return marshalCallbackData(CallbackData{DateID: dateID, Type: dataType}, hintID)
+}
+
+// GetMarshalledQuestionCallbackData returns serialized callback data for task which isn't daily.
+func GetMarshalledQuestionCallbackData(questionID uint64, hintID int, dataType CallbackType) (string, error) {
+	return marshalCallbackData(CallbackData{QuestionID: questionID, Type: dataType}, hintID)
+}
+
+func marshalCallbackData(callbackData CallbackData, hintID int) (string, error) {
+	if callbackData.Type == HintRequest || callbackData.Type == SimilarQuestionRequest {
 		callbackData.Hint = int(hintID)
 	}
 	callbackDataMarshaled, err := json.Marshal(callbackData)
 	return string(callbackDataMarshaled), err
 }
 
+// GetMarshalledCallbackData returns serialized callback data with the key which allows to find the task
+func (task *BotLeetCodeTask) GetMarshalledCallbackData(hintID int, dataType CallbackType) (string, error) {
+	if task.DateID == 0 {
+		return GetMarshalledQuestionCallbackData(task.QuestionID, hintID, dataType)
+	}
+	return GetMarshalledCallbackData(task.DateID, hintID, dataType)
+}
+
 // GetInlineKeyboard returns inline keyboard for task marshalled into JSON string
-func (task *BotLeetCodeTask) GetInlineKeyboard() string {
+func (task *BotLeetCodeTask) GetInlineKeyboard(clock Clock) string {
+	return task.GetInlineKeyboardForLanguage(clock, "")
+}
+
+// GetInlineKeyboardForLanguage same as GetInlineKeyboard, but also links solutions in the preferred code language.
+// Unknown and empty language slugs are ignored
+func (task *BotLeetCodeTask) GetInlineKeyboardForLanguage(clock Clock, codeLanguage string) string {
+	linkButton := []inlineButton{
+		{
+			Text: "See task on LeetCode website",
+			URL:  fmt.Sprintf("https://leetcode.com/problems/%s", task.TitleSlug),
+		},
+	}
+	if languageName := GetCodeLanguageName(codeLanguage); languageName != "" {
+		linkButton = append(linkButton, inlineButton{
+			Text: languageName + " solutions",
+			URL:  fmt.Sprintf("https://leetcode.com/problems/%s/solutions/?languageTags=%s", task.TitleSlug, codeLanguage),
+		})
+	}
+	// Callbacks couldn't find the task without any ID, so such tasks have only the link
+	if task.DateID == 0 && task.QuestionID == 0 {
+		return marshalInlineKeyboard([][]inlineButton{linkButton})
+	}
 	listOfHints := [][]inlineButton{{}}
 	level := 0
 	for i := range task.Hints {
-		callbackData, err := GetMarshalledCallbackData(task.DateID, i, HintRequest)
+		callbackData, err := task.GetMarshalledCallbackData(i, HintRequest)
 		if err != nil {
-			fmt.Println(callbackDataMarshalErrorMessage, err)
+			slog.Error(callbackDataMarshalErrorMessage, "error", err)
 		}
 
 		// 5 hints in the row
@@ -104,19 +302,12 @@ func (task *BotLeetCodeTask) GetInlineKeyboard() string {
 				CallbackData: string(callbackData),
 			})
 	}
-	listOfHints = append([][]inlineButton{
-		{
-			{
-				Text: "See task on LeetCode website",
-				URL:  fmt.Sprintf("https://leetcode.com/problems/%s", task.TitleSlug),
-			},
-		},
-	}, listOfHints...)
+	listOfHints = append([][]inlineButton{linkButton}, listOfHints...)
 
 	// Append difficulty hint to task inline keyboard
-	getDifficultyCallbackData, err := GetMarshalledCallbackData(task.DateID, 0, DifficultyRequest)
+	getDifficultyCallbackData, err := task.GetMarshalledCallbackData(0, DifficultyRequest)
 	if err != nil {
-		fmt.Println(callbackDataMarshalErrorMessage, err)
+		slog.Error(callbackDataMarshalErrorMessage, "error", err)
 	}
 	listOfHints = append(
 		listOfHints,
@@ -128,9 +319,9 @@ func (task *BotLeetCodeTask) GetInlineKeyboard() string {
 		},
 	)
 
-	getTopicTagsCallbackData, err := GetMarshalledCallbackData(task.DateID, 0, TopicTagsRequest)
+	getTopicTagsCallbackData, err := task.GetMarshalledCallbackData(0, TopicTagsRequest)
 	if err != nil {
-		fmt.Println(callbackDataMarshalErrorMessage, err)
+		slog.Error(callbackDataMarshalErrorMessage, "error", err)
 	}
 	listOfHints = append(
 		listOfHints,
@@ -142,9 +333,215 @@ func (task *BotLeetCodeTask) GetInlineKeyboard() string {
 		},
 	)
 
-	inlineKeyboard, err := json.Marshal(map[string][][]inlineButton{"inline_keyboard": listOfHints})
+	if task.DateID != 0 {
+		listOfHints = appendDailyTasksNavigation(listOfHints, task.DateID, GetDateIDForNow(clock))
+	}
+
+	if len(task.SimilarQuestions) > 0 {
+		getSimilarQuestionsCallbackData, err := task.GetMarshalledCallbackData(0, SimilarQuestionsRequest)
+		if err != nil {
+			slog.Error(callbackDataMarshalErrorMessage, "error", err)
+		}
+		listOfHints = append(
+			listOfHints,
+			[]inlineButton{
+				{
+					Text:         "Similar problems",
+					CallbackData: getSimilarQuestionsCallbackData,
+				},
+			},
+		)
+	}
+
+	return marshalInlineKeyboard(listOfHints)
+}
+
+// appendDailyTasksNavigation adds buttons to the previous and next daily tasks, if they exist
+func appendDailyTasksNavigation(buttons [][]inlineButton, dateID uint64, todayDateID uint64) [][]inlineButton {
+	date := GetDateFromDateID(dateID)
+	navigation := []inlineButton{}
+	if previousDateID := GetDateID(date.AddDate(0, 0, -1)); previousDateID >= FirstDailyTaskDateID {
+		navigation = appendDailyTaskButton(navigation, "◀ previous day", previousDateID)
+	}
+	if nextDateID := GetDateID(date.AddDate(0, 0, 1)); nextDateID <= todayDateID {
+		navigation = appendDailyTaskButton(navigation, "next day ▶", nextDateID)
+	}
+	if len(navigation) == 0 {
+		return buttons
+	}
+	return append(buttons, navigation)
+}
+
+func appendDailyTaskButton(buttons []inlineButton, text string, dateID uint64) []inlineButton {
+	callbackData, err := GetMarshalledCallbackData(dateID, 0, DailyTaskRequest)
+	if err != nil {
+		slog.Error(callbackDataMarshalErrorMessage, "error", err)
+		return buttons
+	}
+	return append(buttons, inlineButton{Text: text, CallbackData: callbackData})
+}
+
+// CalendarDay is a day of the daily tasks calendar. Solved is set only for users with linked LeetCode profile
+type CalendarDay struct {
+	DateID     uint64
+	Difficulty string
+	Solved     bool
+}
+
+// calendarRowLength is an amount of days in one row of the calendar, like in a week
+const calendarRowLength = 7
+
+// GetDifficultyMark returns colored mark of the difficulty, empty for unknown difficulty
+func GetDifficultyMark(difficulty string) string {
+	switch difficulty {
+	case "Easy":
+		return "🟢"
+	case "Medium":
+		return "🟡"
+	case "Hard":
+		return "🔴"
+	default:
+		return ""
+	}
+}
+
+// GetMonthDateID returns dateID of the first day of the dateID month
+func GetMonthDateID(dateID uint64) uint64 {
+	return dateID/100*100 + 1
+}
+
+// GetCalendarInlineKeyboard returns inline keyboard with days of the month to open their daily tasks
+// and buttons to the previous and next months, if they have daily tasks
+func GetCalendarInlineKeyboard(clock Clock, monthDateID uint64, days []CalendarDay) string {
+	buttons := [][]inlineButton{}
+	row := []inlineButton{}
+	for _, day := range days {
+		text := fmt.Sprintf("%d%s", day.DateID%100, GetDifficultyMark(day.Difficulty))
+		if day.Solved {
+			text += "✅"
+		}
+		row = appendDailyTaskButton(row, text, day.DateID)
+		if len(row) == calendarRowLength {
+			buttons = append(buttons, row)
+			row = []inlineButton{}
+		}
+	}
+	if len(row) > 0 {
+		buttons = append(buttons, row)
+	}
+	month := GetDateFromDateID(monthDateID)
+	navigation := []inlineButton{}
+	if previousMonthDateID := GetDateID(month.AddDate(0, -1, 0)); previousMonthDateID >= GetMonthDateID(FirstDailyTaskDateID) {
+		navigation = append(navigation, getCallbackButton("◀ "+month.AddDate(0, -1, 0).Format("January"), CallbackData{Type: CalendarRequest, DateID: previousMonthDateID}))
+	}
+	if nextMonthDateID := GetDateID(month.AddDate(0, 1, 0)); nextMonthDateID <= GetDateIDForNow(clock) {
+		navigation = append(navigation, getCallbackButton(month.AddDate(0, 1, 0).Format("January")+" ▶", CallbackData{Type: CalendarRequest, DateID: nextMonthDateID}))
+	}
+	if len(navigation) > 0 {
+		buttons = append(buttons, navigation)
+	}
+	return marshalInlineKeyboard(buttons)
+}
+
+// GetSimilarQuestionsText returns list of similar questions with difficulty and links
+func (task *BotLeetCodeTask) GetSimilarQuestionsText() string {
+	lines := []string{"<strong>Similar problems:</strong>"}
+	for i, question := range task.SimilarQuestions {
+		lines = append(
+			lines,
+			fmt.Sprintf("%d. <a href=\"https://leetcode.com/problems/%s\">%s</a> — %s", i+1, question.TitleSlug, question.Title, question.Difficulty),
+		)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// GetSimilarQuestionsInlineKeyboard returns inline keyboard to get any of similar questions as a full task
+func (task *BotLeetCodeTask) GetSimilarQuestionsInlineKeyboard() string {
+	buttons := [][]inlineButton{}
+	for i, question := range task.SimilarQuestions {
+		callbackData, err := task.GetMarshalledCallbackData(i, SimilarQuestionRequest)
+		if err != nil {
+			slog.Error(callbackDataMarshalErrorMessage, "error", err)
+		}
+		buttons = append(
+			buttons,
+			[]inlineButton{
+				{
+					Text:         fmt.Sprintf("%d. %s", i+1, question.Title),
+					CallbackData: callbackData,
+				},
+			},
+		)
+	}
+	return marshalInlineKeyboard(buttons)
+}
+
+// GetMarshalledSearchCallbackData returns serialized callback data for search results
+func GetMarshalledSearchCallbackData(query string, page int, dataType CallbackType) (string, error) {
+	return marshalCallbackData(CallbackData{Query: query, Page: page, Type: dataType}, 0)
+}
+
+// GetSearchResultsText returns one page of questions matched the query with difficulty and links
+func GetSearchResultsText(query string, list leetcodeclient.QuestionsList, page int) string {
+	pagesNum := (list.Total + SearchResultsPageSize - 1) / SearchResultsPageSize
+	lines := []string{fmt.Sprintf("<strong>Problems matching \"%s\"</strong> (page %d of %d):", html.EscapeString(query), page+1, pagesNum)}
+	for _, question := range list.Questions {
+		lines = append(
+			lines,
+			fmt.Sprintf("%s. <a href=\"https://leetcode.com/problems/%s\">%s</a> — %s", question.FrontendQuestionID, question.TitleSlug, question.Title, question.Difficulty),
+		)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// GetSearchResultsInlineKeyboard returns inline keyboard to get any of found questions as a full task and to switch pages.
+// Pages switching is possible only when the query fits into the callback data.
+func GetSearchResultsInlineKeyboard(query string, list leetcodeclient.QuestionsList, page int) string {
+	buttons := [][]inlineButton{}
+	for _, question := range list.Questions {
+		callbackData, err := GetMarshalledSearchCallbackData(question.FrontendQuestionID, 0, ProblemRequest)
+		if err != nil {
+			slog.Error(callbackDataMarshalErrorMessage, "error", err)
+		}
+		buttons = append(
+			buttons,
+			[]inlineButton{
+				{
+					Text:         fmt.Sprintf("%s. %s", question.FrontendQuestionID, question.Title),
+					CallbackData: callbackData,
+				},
+			},
+		)
+	}
+	navigation := []inlineButton{}
+	if page > 0 {
+		navigation = appendSearchPageButton(navigation, "« Previous", query, page-1)
+	}
+	if (page+1)*SearchResultsPageSize < list.Total {
+		navigation = appendSearchPageButton(navigation, "Next »", query, page+1)
+	}
+	if len(navigation) > 0 {
+		buttons = append(buttons, navigation)
+	}
+	return marshalInlineKeyboard(buttons)
+}
+
+func appendSearchPageButton(buttons []inlineButton, text string, query string, page int) []inlineButton {
+	callbackData, err := GetMarshalledSearchCallbackData(query, page, SearchPageRequest)
+	if err != nil {
+		slog.Error(callbackDataMarshalErrorMessage, "error", err)
+		return buttons
+	}
+	if len(callbackData) > callbackDataMaxLength {
+		return buttons
+	}
+	return append(buttons, inlineButton{Text: text, CallbackData: callbackData})
+}
+
+func marshalInlineKeyboard(buttons [][]inlineButton) string {
+	inlineKeyboard, err := json.Marshal(map[string][][]inlineButton{"inline_keyboard": buttons})
 	if err != nil {
-		fmt.Println("Error during marshal inlineKeyboard:", err)
+		slog.Error("Error on marshalling inline keyboard", "error", err)
 	}
 	return string(inlineKeyboard)
 }
@@ -186,25 +583,540 @@ func (task *BotLeetCodeTask) SetDifficultyFromNum(difficulty uint8) {
 	}
 }
 
-// GetDateInRightTimeZone returns time in the correct time zone for LeetCode.
-func GetDateInRightTimeZone() time.Time {
-	return time.Now().UTC()
+// GetDateInRightTimeZone returns time of the clock in the correct time zone for LeetCode.
+func GetDateInRightTimeZone(clock Clock) time.Time {
+	return clock.Now().UTC()
 }
 
-// GetDateIDForNow dateID for current time in correct time zone for Leetcode
-func GetDateIDForNow() uint64 {
-	return GetDateID(GetDateInRightTimeZone())
+// GetDateIDForNow dateID for current time of the clock in correct time zone for Leetcode
+func GetDateIDForNow(clock Clock) uint64 {
+	return GetDateID(GetDateInRightTimeZone(clock))
 }
 
 // GetDateID right provider of ID based on task Date. Persistent key.
 func GetDateID(date time.Time) uint64 {
 	dateID, err := strconv.ParseUint(fmt.Sprintf("%02d%02d%02d", date.Year(), date.Month(), date.Day()), 10, 64)
 	if err != nil {
-		fmt.Println("Got error on dateID generation:", err)
+		slog.Error("Error on dateID generation", "error", err)
 	}
 	return dateID
 }
 
+// NormalizeTimeZone checks IANA time zone name or UTC offset and returns it in the form to store.
+// Offsets are stored as UTC+HH:MM
+func NormalizeTimeZone(timeZone string) (string, error) {
+	if parts := utcOffsetRegexp.FindStringSubmatch(timeZone); parts != nil {
+		hours, _ := strconv.Atoi(parts[2])
+		minutes := 0
+		if parts[3] != "" {
+			minutes, _ = strconv.Atoi(parts[3])
+		}
+		if hours > 14 || minutes > 59 {
+			return "", ErrWrongTimeZone
+		}
+		return fmt.Sprintf("UTC%s%02d:%02d", parts[1], hours, minutes), nil
+	}
+	if strings.EqualFold(timeZone, "UTC") || strings.EqualFold(timeZone, "GMT") {
+		return "UTC", nil
+	}
+	// Local is the time zone of the server, not the user
+	if timeZone == "" || timeZone == "Local" {
+		return "", ErrWrongTimeZone
+	}
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return "", ErrWrongTimeZone
+	}
+	return location.String(), nil
+}
+
+// GetTimeZoneForLongitude approximates UTC offset by longitude. It's the best we can do with location without
+// time zones borders database, so DST isn't supported for such time zones.
+func GetTimeZoneForLongitude(longitude float64) string {
+	hours := int(math.Round(longitude / 15))
+	sign := "+"
+	if hours < 0 {
+		sign = "-"
+		hours = -hours
+	}
+	return fmt.Sprintf("UTC%s%02d:00", sign, hours)
+}
+
+// LoadLocation returns location for the stored time zone. Empty time zone is UTC
+func LoadLocation(timeZone string) (*time.Location, error) {
+	if timeZone == "" {
+		return time.UTC, nil
+	}
+	normalized, err := NormalizeTimeZone(timeZone)
+	if err != nil {
+		return nil, err
+	}
+	if parts := utcOffsetRegexp.FindStringSubmatch(normalized); parts != nil {
+		hours, _ := strconv.Atoi(parts[2])
+		minutes, _ := strconv.Atoi(parts[3])
+		offset := hours*3600 + minutes*60
+		if parts[1] == "-" {
+			offset = -offset
+		}
+		return time.FixedZone(normalized, offset), nil
+	}
+	return time.LoadLocation(normalized)
+}
+
+// GetTimeZoneName returns user time zone name to show
+func (u *User) GetTimeZoneName() string {
+	if u.TimeZone == "" {
+		return "UTC"
+	}
+	return u.TimeZone
+}
+
+// SendingTimeKind defines which message is sent at the sending time
+type SendingTimeKind uint8
+
+const (
+	// TaskSendingTime sends the daily task
+	TaskSendingTime SendingTimeKind = iota
+	// HintSendingTime sends the first hint of the daily task
+	HintSendingTime
+)
+
+// SendingTime is a local time of the day, when user wants to get the message of the kind.
+// Minute is an amount of minutes from the midnight
+type SendingTime struct {
+	Minute uint16
+	Kind   SendingTimeKind
+}
+
+// String returns sending time in HH:MM format
+func (t SendingTime) String() string {
+	return fmt.Sprintf("%02d:%02d", t.Minute/60, t.Minute%60)
+}
+
+// GetDeliveryKind returns the key of the sending time deliveries, like "task 07:30" or "hint 13:00"
+func (t SendingTime) GetDeliveryKind() string {
+	if t.Kind == HintSendingTime {
+		return "hint " + t.String()
+	}
+	return "task " + t.String()
+}
+
+// DeliveryStatus is a result of the last attempt to deliver the message
+type DeliveryStatus uint8
+
+const (
+	// DeliveryFailed means that the message should be sent again
+	DeliveryFailed DeliveryStatus = iota
+	// DeliverySent means that Telegram has accepted the message
+	DeliverySent
+	// DeliveryQueued means that the message is saved to the outbox and will be sent by the dispatcher
+	DeliveryQueued
+)
+
+// Delivery is a log record about the message of Kind with the DateID daily task sent to the user.
+// MessageID is Telegram message ID of the sent message, LastError is the error of the last failed attempt
+type Delivery struct {
+	UserID    uint64
+	DateID    uint64
+	Kind      string
+	Status    DeliveryStatus
+	Attempts  uint32
+	MessageID uint64
+	LastError string
+}
+
+// OutboxStatus is a state of the message in the outbox
+type OutboxStatus uint8
+
+const (
+	// OutboxPending means that the message is waiting for sending or for the next attempt
+	OutboxPending OutboxStatus = iota
+	// OutboxDead means that the message is out of attempts and is kept only for investigation
+	OutboxDead
+)
+
+// OutboxMessage is a rendered Telegram API request waiting in the outbox. Messages of the same chat are sent in order of IDs.
+// The message isn't sent before AvailableAt and isn't given to another dispatcher before LeaseUntil.
+// Delivery is the record of the deliveries log updated together with the message, messages with empty Delivery.Kind aren't logged
+type OutboxMessage struct {
+	ChatID      uint64
+	ID          uint64
+	Method      string
+	Body        []byte
+	Status      OutboxStatus
+	Attempts    uint32
+	AvailableAt time.Time
+	LeaseUntil  time.Time
+	LastError   string
+	Delivery    Delivery
+}
+
+// ParseSendingTime parses time of the day in H, HH:MM or H:MM format and returns minutes from the midnight
+func ParseSendingTime(text string) (uint16, error) {
+	parts := sendingTimeRegexp.FindStringSubmatch(text)
+	if parts == nil {
+		return 0, ErrWrongSendingTime
+	}
+	hours, _ := strconv.Atoi(parts[1])
+	minutes := 0
+	if parts[2] != "" {
+		minutes, _ = strconv.Atoi(parts[2])
+	}
+	if hours > 23 || minutes > 59 {
+		return 0, ErrWrongSendingTime
+	}
+	return uint16(hours*60 + minutes), nil
+}
+
+// MinuteRange is a range of minutes of the day, From is included and To isn't
+type MinuteRange struct {
+	From uint16
+	To   uint16
+}
+
+// Contains checks if the minute is in the range
+func (r MinuteRange) Contains(minute uint16) bool {
+	return minute >= r.From && minute < r.To
+}
+
+func getMinuteOfDay(date time.Time) int {
+	return date.Hour()*60 + date.Minute()
+}
+
+// GetLocalRangeDate returns local date of the minute range returned by GetLocalMinuteRanges.
+// Ranges ended before the current local time belong to the next day, it happens when the slot crosses midnight
+func GetLocalRangeDate(now time.Time, location *time.Location, slot time.Duration, minuteRange MinuteRange) time.Time {
+	local := now.Truncate(slot).In(location)
+	if int(minuteRange.To) <= getMinuteOfDay(local) {
+		local = local.AddDate(0, 0, 1)
+	}
+	return local
+}
+
+// GetLocalMinuteRanges returns local minutes ranges in the location which should be handled in the slot which starts at now.
+// It's usually one range with slot length, but it's empty for the repeated time on DST end
+// and includes skipped time on DST start, so nobody get notification twice or lose it.
+func GetLocalMinuteRanges(now time.Time, location *time.Location, slot time.Duration) []MinuteRange {
+	now = now.Truncate(slot)
+	local := now.In(location)
+	_, offset := local.Zone()
+	// DST shifts are much less than 3 hours, so earlier offset is enough to find repeated time
+	_, earlierOffset := now.Add(-3 * time.Hour).In(location).Zone()
+	if earlierOffset > offset {
+		sameLocalTimeBefore := now.Add(-time.Duration(earlierOffset-offset) * time.Second).In(location)
+		if getMinuteOfDay(sameLocalTimeBefore) == getMinuteOfDay(local) {
+			return []MinuteRange{}
+		}
+	}
+	slotMinutes := int(slot / time.Minute)
+	from := getMinuteOfDay(now.Add(-slot).In(location)) + slotMinutes
+	if from >= minutesInDay {
+		from -= minutesInDay
+	}
+	to := getMinuteOfDay(local) + slotMinutes
+	if to < from {
+		to += minutesInDay
+	}
+	if to-from > minutesInDay/2 {
+		// Time goes back, but it's not repeated time handled above, so only the slot itself
+		from = getMinuteOfDay(local)
+		to = from + slotMinutes
+	}
+	if to <= minutesInDay {
+		return []MinuteRange{{From: uint16(from), To: uint16(to)}}
+	}
+	return []MinuteRange{{From: uint16(from), To: minutesInDay}, {From: 0, To: uint16(to - minutesInDay)}}
+}
+
+// Weekdays is a set of days of the week, bit N means time.Weekday(N)
+type Weekdays uint8
+
+const (
+	// EveryDay contains all days of the week
+	EveryDay Weekdays = 1<<7 - 1
+	// WorkingDays contains days from Monday to Friday
+	WorkingDays Weekdays = EveryDay &^ (1<<time.Saturday | 1<<time.Sunday)
+)
+
+// Contains checks if the day is in the set, empty set means every day
+func (w Weekdays) Contains(day time.Weekday) bool {
+	return w == 0 || w&(1<<day) != 0
+}
+
+// Toggle adds the day to the set or removes it
+func (w Weekdays) Toggle(day time.Weekday) Weekdays {
+	if w == 0 {
+		w = EveryDay
+	}
+	return w ^ (1 << day)
+}
+
+// String returns short names of days starting from Monday or "every day"
+func (w Weekdays) String() string {
+	if w == 0 || w == EveryDay {
+		return "every day"
+	}
+	if w == WorkingDays {
+		return "Monday to Friday"
+	}
+	days := []string{}
+	for _, day := range GetWeekdaysFromMonday() {
+		if w.Contains(day) {
+			days = append(days, day.String()[:3])
+		}
+	}
+	return strings.Join(days, ", ")
+}
+
+// GetWeekdaysFromMonday returns days of the week in the order used by most of users
+func GetWeekdaysFromMonday() []time.Weekday {
+	return []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday}
+}
+
+// ParseDateID parses date in YYYY-MM-DD format and returns its dateID
+func ParseDateID(text string) (uint64, error) {
+	date, err := time.Parse("2006-01-02", text)
+	if err != nil {
+		return 0, ErrWrongDate
+	}
+	return GetDateID(date), nil
+}
+
+// ParseMonthDateID parses month in YYYY-MM format and returns dateID of its first day
+func ParseMonthDateID(text string) (uint64, error) {
+	date, err := time.Parse("2006-01", text)
+	if err != nil {
+		return 0, ErrWrongDate
+	}
+	return GetDateID(date), nil
+}
+
+// GetDateFromDateID returns the beginning of the dateID day in UTC
+func GetDateFromDateID(dateID uint64) time.Time {
+	return time.Date(int(dateID/10000), time.Month(dateID/100%100), int(dateID%100), 0, 0, 0, 0, time.UTC)
+}
+
+// FormatDateID returns dateID in YYYY-MM-DD format
+func FormatDateID(dateID uint64) string {
+	return fmt.Sprintf("%04d-%02d-%02d", dateID/10000, dateID/100%100, dateID%100)
+}
+
+// IsDeliveryDay checks if the user wants to get messages at the local date
+func (u *User) IsDeliveryDay(localDate time.Time) bool {
+	return u.Weekdays.Contains(localDate.Weekday()) && GetDateID(localDate) >= u.PausedUntil
+}
+
+// GetDeliverySettingsInlineKeyboard returns inline keyboard to toggle delivery weekdays and to resume paused delivery
+func GetDeliverySettingsInlineKeyboard(weekdays Weekdays, paused bool) string {
+	days := []inlineButton{}
+	for _, day := range GetWeekdaysFromMonday() {
+		text := day.String()[:2]
+		if weekdays.Contains(day) {
+			text = "✅ " + text
+		}
+		newWeekdays := weekdays.Toggle(day)
+		if newWeekdays == 0 {
+			// At least one day should stay selected
+			newWeekdays = weekdays
+		}
+		days = append(days, getCallbackButton(text, CallbackData{Type: WeekdaysRequest, Weekdays: newWeekdays}))
+	}
+	buttons := [][]inlineButton{
+		days[:4],
+		days[4:],
+		{
+			getCallbackButton("Every day", CallbackData{Type: WeekdaysRequest, Weekdays: EveryDay}),
+			getCallbackButton("Monday to Friday", CallbackData{Type: WeekdaysRequest, Weekdays: WorkingDays}),
+		},
+	}
+	if paused {
+		buttons = append(buttons, []inlineButton{getCallbackButton("Resume delivery now", CallbackData{Type: ResumeRequest})})
+	}
+	return marshalInlineKeyboard(buttons)
+}
+
+// getCallbackButton returns button sending the callback data, it's shared by menus and the calendar
+func getCallbackButton(text string, callbackData CallbackData) inlineButton {
+	marshalledData, err := json.Marshal(callbackData)
+	if err != nil {
+		slog.Error(callbackDataMarshalErrorMessage, "error", err)
+	}
+	return inlineButton{Text: text, CallbackData: string(marshalledData)}
+}
+
+// Difficulties is a set of task difficulties, bit N means difficulty returned by GetDifficultyNum
+type Difficulties uint8
+
+// AllDifficulties contains Easy, Medium and Hard
+const AllDifficulties Difficulties = 1<<easy | 1<<medium | 1<<hard
+
+// Contains checks if the difficulty is in the set, empty set means all difficulties
+// Unknown difficulty is never filtered out
+func (d Difficulties) Contains(difficulty uint8) bool {
+	return d == 0 || difficulty > hard || d&(1<<difficulty) != 0
+}
+
+// GetDifficultiesNums returns difficulties of the set in order from Easy to Hard
+func (d Difficulties) GetDifficultiesNums() []uint8 {
+	nums := []uint8{}
+	for _, difficulty := range []uint8{easy, medium, hard} {
+		if d.Contains(difficulty) {
+			nums = append(nums, difficulty)
+		}
+	}
+	return nums
+}
+
+// Toggle adds the difficulty to the set or removes it
+func (d Difficulties) Toggle(difficulty uint8) Difficulties {
+	if d == 0 {
+		d = AllDifficulties
+	}
+	return d ^ (1 << difficulty)
+}
+
+// String returns names of difficulties or "all"
+func (d Difficulties) String() string {
+	if d == 0 || d&AllDifficulties == AllDifficulties {
+		return "all"
+	}
+	names := []string{}
+	task := BotLeetCodeTask{}
+	for _, difficulty := range d.GetDifficultiesNums() {
+		task.SetDifficultyFromNum(difficulty)
+		names = append(names, task.Difficulty)
+	}
+	return strings.Join(names, ", ")
+}
+
+// GetCodeLanguageName returns name of the language with the slug or empty string for unknown slug
+func GetCodeLanguageName(slug string) string {
+	for _, language := range CodeLanguages {
+		if language.Slug == slug {
+			return language.Name
+		}
+	}
+	return ""
+}
+
+// GetSettingsInlineKeyboard returns inline keyboard for the section of the settings menu
+// Paused means that the user delivery is paused now
+func GetSettingsInlineKeyboard(section SettingsSection, user User, paused bool) string {
+	var buttons [][]inlineButton
+	switch section {
+	case SendingTimeSettings:
+		buttons = getSendingHoursButtons(user.SendingTimes)
+	case TimeZoneSettings:
+		buttons = getTimeZonesButtons(user.GetTimeZoneName())
+	case DifficultySettings:
+		buttons = getDifficultiesButtons(user.Difficulties, user.DifficultyFallback)
+	case CodeLanguageSettings:
+		buttons = getCodeLanguagesButtons(user.CodeLanguage)
+	case SubscriptionSettings:
+		buttons = getSubscriptionButtons(user.Subscribed, paused)
+	case LanguageSettings:
+		buttons = [][]inlineButton{}
+	default:
+		return marshalInlineKeyboard([][]inlineButton{
+			{getSettingsSectionButton("🕒 Delivery time", SendingTimeSettings), getSettingsSectionButton("🌍 Time zone", TimeZoneSettings)},
+			{getSettingsSectionButton("🗣 Language", LanguageSettings), getSettingsSectionButton("📊 Difficulty", DifficultySettings)},
+			{getSettingsSectionButton("💻 Code language", CodeLanguageSettings), getSettingsSectionButton("🔔 Subscription", SubscriptionSettings)},
+		})
+	}
+	buttons = append(buttons, []inlineButton{getSettingsSectionButton("⬅ Back", MainSettings)})
+	return marshalInlineKeyboard(buttons)
+}
+
+func getSettingsSectionButton(text string, section SettingsSection) inlineButton {
+	return getCallbackButton(text, CallbackData{Type: SettingsRequest, Setting: section})
+}
+
+func getSettingValueButton(text string, section SettingsSection, value string, selected bool) inlineButton {
+	if selected {
+		text = "✅ " + text
+	}
+	return getCallbackButton(text, CallbackData{Type: SettingRequest, Setting: section, Value: value})
+}
+
+// getSendingHoursButtons returns buttons to toggle delivery of the daily task at the beginning of each hour
+func getSendingHoursButtons(sendingTimes []SendingTime) [][]inlineButton {
+	selected := map[uint16]bool{}
+	for _, sendingTime := range sendingTimes {
+		if sendingTime.Kind == TaskSendingTime {
+			selected[sendingTime.Minute] = true
+		}
+	}
+	buttons := [][]inlineButton{}
+	for hour := 0; hour < 24; hour++ {
+		if hour%4 == 0 {
+			buttons = append(buttons, []inlineButton{})
+		}
+		sendingTime := SendingTime{Minute: uint16(hour * 60)}
+		button := getSettingValueButton(fmt.Sprintf("%d:00", hour), SendingTimeSettings, sendingTime.String(), selected[sendingTime.Minute])
+		buttons[len(buttons)-1] = append(buttons[len(buttons)-1], button)
+	}
+	return buttons
+}
+
+func getTimeZonesButtons(timeZone string) [][]inlineButton {
+	buttons := [][]inlineButton{}
+	for i, settingsTimeZone := range SettingsTimeZones {
+		if i%2 == 0 {
+			buttons = append(buttons, []inlineButton{})
+		}
+		// Time zone names could be too long for callback data, so index is used
+		button := getSettingValueButton(settingsTimeZone, TimeZoneSettings, strconv.Itoa(i), settingsTimeZone == timeZone)
+		buttons[len(buttons)-1] = append(buttons[len(buttons)-1], button)
+	}
+	return buttons
+}
+
+func getDifficultiesButtons(difficulties Difficulties, difficultyFallback bool) [][]inlineButton {
+	row := []inlineButton{}
+	task := BotLeetCodeTask{}
+	for _, difficulty := range []uint8{easy, medium, hard} {
+		task.SetDifficultyFromNum(difficulty)
+		newDifficulties := difficulties.Toggle(difficulty)
+		if newDifficulties == 0 {
+			// At least one difficulty should stay selected
+			newDifficulties = difficulties
+		}
+		value := strconv.Itoa(int(newDifficulties))
+		row = append(row, getSettingValueButton(task.Difficulty, DifficultySettings, value, difficulties.Contains(difficulty)))
+	}
+	return [][]inlineButton{
+		row,
+		{getCallbackButton("All", CallbackData{Type: SettingRequest, Setting: DifficultySettings, Value: strconv.Itoa(int(AllDifficulties))})},
+		{
+			getSettingValueButton("Skip other days", DifficultySettings, DifficultyFallbackOff, !difficultyFallback),
+			getSettingValueButton("Random problem instead", DifficultySettings, DifficultyFallbackOn, difficultyFallback),
+		},
+	}
+}
+
+func getCodeLanguagesButtons(codeLanguage string) [][]inlineButton {
+	buttons := [][]inlineButton{}
+	for i, language := range CodeLanguages {
+		if i%3 == 0 {
+			buttons = append(buttons, []inlineButton{})
+		}
+		button := getSettingValueButton(language.Name, CodeLanguageSettings, language.Slug, language.Slug == codeLanguage)
+		buttons[len(buttons)-1] = append(buttons[len(buttons)-1], button)
+	}
+	return buttons
+}
+
+func getSubscriptionButtons(subscribed bool, paused bool) [][]inlineButton {
+	if !subscribed {
+		return [][]inlineButton{{getSettingsSectionButton("Choose delivery time", SendingTimeSettings)}}
+	}
+	buttons := [][]inlineButton{}
+	if paused {
+		buttons = append(buttons, []inlineButton{getSettingValueButton("Resume delivery now", SubscriptionSettings, SubscriptionResume, false)})
+	}
+	return append(buttons, []inlineButton{getSettingValueButton("Unsubscribe", SubscriptionSettings, SubscriptionOff, false)})
+}
+
 // RemoveUnsupportedTags shortcut for removing all unsopurted tags and returns fixed string
 func RemoveUnsupportedTags(source string) string {
 	source = RemoveSimpleUnsupportedTags(source)
@@ -2,9 +2,13 @@ package common
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/dartkron/leetcodeBot/v3/pkg/leetcodeclient"
+	"github.com/dartkron/leetcodeBot/v3/tests"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -128,6 +132,8 @@ func TestGetMarshalledCallbackData(t *testing.T) {
 		{dateID: 10, hintID: 0, dataType: HintRequest, awaitingResult: "{\"dateID\":\"10\",\"callback_type\":0,\"hint\":0}"},
 		{dateID: 10, hintID: 22, dataType: DifficultyRequest, awaitingResult: "{\"dateID\":\"10\",\"callback_type\":1,\"hint\":0}"},
 		{dateID: 10, hintID: 22, dataType: TopicTagsRequest, awaitingResult: "{\"dateID\":\"10\",\"callback_type\":2,\"hint\":0}"},
+		{dateID: 10, hintID: 22, dataType: SimilarQuestionsRequest, awaitingResult: "{\"dateID\":\"10\",\"callback_type\":3,\"hint\":0}"},
+		{dateID: 10, hintID: 2, dataType: SimilarQuestionRequest, awaitingResult: "{\"dateID\":\"10\",\"callback_type\":4,\"hint\":2}"},
 	}
 	for _, testCase := range testCases {
 		result, err := GetMarshalledCallbackData(testCase.dateID, testCase.hintID, testCase.dataType)
@@ -156,8 +162,8 @@ func TestGetInlineKeyboard(t *testing.T) {
 		task := BotLeetCodeTask{DateID: testCase.DateID}
 		task.TitleSlug = testCase.TitleSlug
 		task.Hints = testCase.Hints
-		result := task.GetInlineKeyboard()
-		assert.Equal(t, result, withTopicTagsButton(t, testCase.awaitingResult, testCase.DateID), "Unexpected GetInlineKeyboard response")
+		result := task.GetInlineKeyboard(SystemClock{})
+		assert.Equal(t, result, withNavigationButtons(t, withTopicTagsButton(t, testCase.awaitingResult, testCase.DateID), testCase.DateID), "Unexpected GetInlineKeyboard response")
 	}
 }
 
@@ -179,6 +185,152 @@ func withTopicTagsButton(t *testing.T, keyboard string, dateID uint64) string {
 	return string(encoded)
 }
 
+// withNavigationButtons adds buttons to the previous and the next daily tasks, the first daily task was in 2020
+func withNavigationButtons(t *testing.T, keyboard string, dateID uint64) string {
+	t.Helper()
+	navigation := ""
+	if dateID > FirstDailyTaskDateID {
+		navigation = fmt.Sprintf("{\"text\":\"◀ previous day\",\"callback_data\":\"{\\\"dateID\\\":\\\"%d\\\",\\\"callback_type\\\":11,\\\"hint\\\":0}\"},", GetDateID(GetDateFromDateID(dateID).AddDate(0, 0, -1)))
+	}
+	navigation += fmt.Sprintf("{\"text\":\"next day ▶\",\"callback_data\":\"{\\\"dateID\\\":\\\"%d\\\",\\\"callback_type\\\":11,\\\"hint\\\":0}\"}", GetDateID(GetDateFromDateID(dateID).AddDate(0, 0, 1)))
+	return strings.TrimSuffix(keyboard, "]}") + ",[" + navigation + "]]}"
+}
+
+func TestGetInlineKeyboardNavigation(t *testing.T) {
+	clock := tests.NewFakeClock(time.Date(2024, time.March, 5, 23, 59, 59, 0, time.UTC))
+	getNavigation := func(dateID uint64) []inlineButton {
+		task := BotLeetCodeTask{DateID: dateID}
+		keyboard := map[string][][]inlineButton{}
+		err := json.Unmarshal([]byte(task.GetInlineKeyboard(clock)), &keyboard)
+		assert.Nil(t, err, "Unexpected json.Unmarshal error")
+		buttons := keyboard["inline_keyboard"]
+		return buttons[len(buttons)-1]
+	}
+	navigation := getNavigation(20240301)
+	assert.Equal(t, "{\"dateID\":\"20240229\",\"callback_type\":11,\"hint\":0}", navigation[0].CallbackData, "Unexpected previous day")
+	assert.Equal(t, "{\"dateID\":\"20240302\",\"callback_type\":11,\"hint\":0}", navigation[1].CallbackData, "Unexpected next day")
+	navigation = getNavigation(FirstDailyTaskDateID)
+	assert.Equal(t, []string{"next day ▶"}, []string{navigation[0].Text}, "The first daily task shouldn't have previous day")
+	assert.Equal(t, 1, len(navigation), "The first daily task shouldn't have previous day")
+	navigation = getNavigation(20240305)
+	assert.Equal(t, 1, len(navigation), "Today task shouldn't have next day")
+	assert.Equal(t, "◀ previous day", navigation[0].Text, "Today task should have previous day")
+	clock.Add(time.Second)
+	navigation = getNavigation(20240305)
+	assert.Equal(t, 2, len(navigation), "Yesterday task should have next day after midnight")
+	assert.Equal(t, "{\"dateID\":\"20240306\",\"callback_type\":11,\"hint\":0}", navigation[1].CallbackData, "Unexpected next day after midnight")
+	assert.Equal(t, time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC), GetDateFromDateID(20240229), "Unexpected date from dateID")
+}
+
+func TestGetCalendarInlineKeyboard(t *testing.T) {
+	clock := tests.NewFakeClock(time.Date(2024, time.April, 30, 23, 59, 0, 0, time.UTC))
+	getButtons := func(monthDateID uint64, days []CalendarDay) [][]inlineButton {
+		keyboard := map[string][][]inlineButton{}
+		err := json.Unmarshal([]byte(GetCalendarInlineKeyboard(clock, monthDateID, days)), &keyboard)
+		assert.Nil(t, err, "Unexpected json.Unmarshal error")
+		return keyboard["inline_keyboard"]
+	}
+	days := []CalendarDay{}
+	difficulties := []string{"Easy", "Medium", "Hard", ""}
+	for day := uint64(1); day <= 9; day++ {
+		days = append(days, CalendarDay{DateID: 20240300 + day, Difficulty: difficulties[day%4], Solved: day == 2})
+	}
+	buttons := getButtons(20240301, days)
+	assert.Equal(t, 3, len(buttons), "Unexpected amount of calendar rows")
+	assert.Equal(t, 7, len(buttons[0]), "Unexpected amount of days in the first row")
+	assert.Equal(t, 2, len(buttons[1]), "Unexpected amount of days in the second row")
+	assert.Equal(t, []string{"1🟡", "2🔴✅", "3", "4🟢"}, []string{buttons[0][0].Text, buttons[0][1].Text, buttons[0][2].Text, buttons[0][3].Text}, "Unexpected day buttons")
+	assert.Equal(t, "{\"dateID\":\"20240302\",\"callback_type\":11,\"hint\":0}", buttons[0][1].CallbackData, "Day should open its daily task")
+	assert.Equal(t, []inlineButton{
+		{Text: "◀ February", CallbackData: "{\"dateID\":\"20240201\",\"callback_type\":12,\"hint\":0}"},
+		{Text: "April ▶", CallbackData: "{\"dateID\":\"20240401\",\"callback_type\":12,\"hint\":0}"},
+	}, buttons[2], "Unexpected months navigation")
+
+	buttons = getButtons(GetMonthDateID(FirstDailyTaskDateID), []CalendarDay{})
+	assert.Equal(t, []string{"May ▶"}, []string{buttons[0][0].Text}, "The first month shouldn't have previous month")
+	assert.Equal(t, 1, len(buttons[0]), "The first month shouldn't have previous month")
+	buttons = getButtons(20240401, []CalendarDay{})
+	assert.Equal(t, 1, len(buttons[0]), "The current month shouldn't have next month")
+	clock.Add(time.Minute)
+	buttons = getButtons(20240401, []CalendarDay{})
+	assert.Equal(t, "May ▶", buttons[0][1].Text, "The previous month should have next month after midnight")
+	assert.Equal(t, uint64(20240301), GetMonthDateID(20240315), "Unexpected month dateID")
+}
+
+func TestGetInlineKeyboardWithSimilarQuestions(t *testing.T) {
+	task := BotLeetCodeTask{DateID: 20230101}
+	task.TitleSlug = "5567"
+	task.SimilarQuestions = leetcodeclient.SimilarQuestions{{Title: "Two Sum", TitleSlug: "two-sum", Difficulty: "Easy"}}
+	awaitingResult := withNavigationButtons(t, withTopicTagsButton(t, "{\"inline_keyboard\":[[{\"text\":\"See task on LeetCode website\",\"url\":\"https://leetcode.com/problems/5567\"}],[],[{\"text\":\"Hint: Get the difficulty of the task\",\"callback_data\":\"{\\\"dateID\\\":\\\"20230101\\\",\\\"callback_type\\\":1,\\\"hint\\\":0}\"}]]}", task.DateID), task.DateID)
+	awaitingResult = strings.TrimSuffix(awaitingResult, "]}") + ",[{\"text\":\"Similar problems\",\"callback_data\":\"{\\\"dateID\\\":\\\"20230101\\\",\\\"callback_type\\\":3,\\\"hint\\\":0}\"}]]}"
+	assert.Equal(t, awaitingResult, task.GetInlineKeyboard(SystemClock{}), "Unexpected GetInlineKeyboard response")
+}
+
+func TestGetInlineKeyboardWithoutDateID(t *testing.T) {
+	task := BotLeetCodeTask{}
+	task.TitleSlug = "two-sum"
+	task.Hints = []string{"First hint"}
+	awaitingResult := "{\"inline_keyboard\":[[{\"text\":\"See task on LeetCode website\",\"url\":\"https://leetcode.com/problems/two-sum\"}]]}"
+	assert.Equal(t, awaitingResult, task.GetInlineKeyboard(SystemClock{}), "Unexpected GetInlineKeyboard response")
+}
+
+func TestGetInlineKeyboardForLanguage(t *testing.T) {
+	task := BotLeetCodeTask{}
+	task.TitleSlug = "two-sum"
+	awaitingResult := "{\"inline_keyboard\":[[{\"text\":\"See task on LeetCode website\",\"url\":\"https://leetcode.com/problems/two-sum\"}," +
+		"{\"text\":\"Go solutions\",\"url\":\"https://leetcode.com/problems/two-sum/solutions/?languageTags=golang\"}]]}"
+	assert.Equal(t, awaitingResult, task.GetInlineKeyboardForLanguage(SystemClock{}, "golang"), "Solutions in the language should be linked")
+	assert.Equal(t, task.GetInlineKeyboard(SystemClock{}), task.GetInlineKeyboardForLanguage(SystemClock{}, "cobol"), "Unknown language should be ignored")
+}
+
+func TestGetMarshalledQuestionCallbackData(t *testing.T) {
+	result, err := GetMarshalledQuestionCallbackData(15, 1, HintRequest)
+	assert.Nil(t, err, "Unexpected error from GetMarshalledQuestionCallbackData")
+	assert.Equal(t, "{\"questionID\":\"15\",\"callback_type\":0,\"hint\":1}", result, "Unexpected GetMarshalledQuestionCallbackData response")
+	task := BotLeetCodeTask{}
+	task.QuestionID = 15
+	result, err = task.GetMarshalledCallbackData(1, DifficultyRequest)
+	assert.Nil(t, err, "Unexpected error from task GetMarshalledCallbackData")
+	assert.Equal(t, "{\"questionID\":\"15\",\"callback_type\":1,\"hint\":0}", result, "Question without DateID should be found by questionID")
+	task.DateID = 20230101
+	result, err = task.GetMarshalledCallbackData(1, DifficultyRequest)
+	assert.Nil(t, err, "Unexpected error from task GetMarshalledCallbackData")
+	assert.Equal(t, "{\"dateID\":\"20230101\",\"callback_type\":1,\"hint\":0}", result, "Daily task should be found by dateID")
+}
+
+func TestGetInlineKeyboardWithQuestionID(t *testing.T) {
+	task := BotLeetCodeTask{}
+	task.QuestionID = 15
+	task.TitleSlug = "3sum"
+	task.Hints = []string{"First hint"}
+	hintCallbackData, err := GetMarshalledQuestionCallbackData(15, 0, HintRequest)
+	assert.Nil(t, err, "Unexpected GetMarshalledQuestionCallbackData error")
+	parsed := map[string][][]inlineButton{}
+	assert.Nil(t, json.Unmarshal([]byte(task.GetInlineKeyboard(SystemClock{})), &parsed), "Unexpected json.Unmarshal error")
+	assert.Equal(t, hintCallbackData, parsed["inline_keyboard"][1][0].CallbackData, "Hint button should use questionID callback")
+	assert.Len(t, parsed["inline_keyboard"], 4, "Unexpected amount of keyboard rows")
+}
+
+func TestGetSimilarQuestionsText(t *testing.T) {
+	task := BotLeetCodeTask{DateID: 20230101}
+	task.SimilarQuestions = leetcodeclient.SimilarQuestions{
+		{Title: "Two Sum", TitleSlug: "two-sum", Difficulty: "Easy"},
+		{Title: "3Sum", TitleSlug: "3sum", Difficulty: "Medium"},
+	}
+	waited := "<strong>Similar problems:</strong>\n1. <a href=\"https://leetcode.com/problems/two-sum\">Two Sum</a> — Easy\n2. <a href=\"https://leetcode.com/problems/3sum\">3Sum</a> — Medium"
+	assert.Equal(t, waited, task.GetSimilarQuestionsText(), "Unexpected GetSimilarQuestionsText response")
+}
+
+func TestGetSimilarQuestionsInlineKeyboard(t *testing.T) {
+	task := BotLeetCodeTask{DateID: 20230101}
+	task.SimilarQuestions = leetcodeclient.SimilarQuestions{
+		{Title: "Two Sum", TitleSlug: "two-sum", Difficulty: "Easy"},
+		{Title: "3Sum", TitleSlug: "3sum", Difficulty: "Medium"},
+	}
+	waited := "{\"inline_keyboard\":[[{\"text\":\"1. Two Sum\",\"callback_data\":\"{\\\"dateID\\\":\\\"20230101\\\",\\\"callback_type\\\":4,\\\"hint\\\":0}\"}],[{\"text\":\"2. 3Sum\",\"callback_data\":\"{\\\"dateID\\\":\\\"20230101\\\",\\\"callback_type\\\":4,\\\"hint\\\":1}\"}]]}"
+	assert.Equal(t, waited, task.GetSimilarQuestionsInlineKeyboard(), "Unexpected GetSimilarQuestionsInlineKeyboard response")
+}
+
 func TestFixTagsAndImages(t *testing.T) {
 	task := BotLeetCodeTask{}
 	task.Title = "<br>Test<em>Title"
@@ -223,5 +375,386 @@ func TestSetDifficultyFromNum(t *testing.T) {
 func TestDateIDFunctions(t *testing.T) {
 	loc, _ := time.LoadLocation("UTC")
 	now := time.Now().In(loc)
-	assert.Equal(t, GetDateID(now), GetDateIDForNow(), "GetDateIDForNow now equal to what it supposed to be")
+	assert.Equal(t, GetDateID(now), GetDateIDForNow(SystemClock{}), "GetDateIDForNow now equal to what it supposed to be")
+
+	clock := tests.NewFakeClock(time.Date(2025, time.December, 31, 23, 59, 59, 0, time.UTC))
+	assert.Equal(t, uint64(20251231), GetDateIDForNow(clock), "Unexpected dateID before midnight")
+	clock.Add(time.Second)
+	assert.Equal(t, uint64(20260101), GetDateIDForNow(clock), "Unexpected dateID after midnight")
+	moscow := time.FixedZone("UTC+3", 3*60*60)
+	clock.Set(time.Date(2026, time.January, 1, 2, 0, 0, 0, moscow))
+	assert.Equal(t, uint64(20251231), GetDateIDForNow(clock), "DateID should be in UTC, not in the local time zone")
+	assert.Equal(t, time.UTC, GetDateInRightTimeZone(clock).Location(), "Date should be in UTC")
+	// 2021-03-28 clocks in Berlin jump from 2:00 to 3:00, but UTC dateID doesn't care
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	clock.Set(time.Date(2021, time.March, 28, 3, 30, 0, 0, berlin))
+	assert.Equal(t, uint64(20210328), GetDateIDForNow(clock), "Unexpected dateID after DST switch")
+}
+
+func getTestSearchResults() leetcodeclient.QuestionsList {
+	return leetcodeclient.QuestionsList{
+		Total: 7,
+		Questions: []leetcodeclient.QuestionsListItem{
+			{FrontendQuestionID: "1", Title: "Two Sum", TitleSlug: "two-sum", Difficulty: "Easy"},
+			{FrontendQuestionID: "167", Title: "Two Sum II - Input Array Is Sorted", TitleSlug: "two-sum-ii-input-array-is-sorted", Difficulty: "Medium"},
+		},
+	}
+}
+
+func TestGetMarshalledSearchCallbackData(t *testing.T) {
+	result, err := GetMarshalledSearchCallbackData("two sum", 1, SearchPageRequest)
+	assert.Nil(t, err, "Unexpected error from GetMarshalledSearchCallbackData")
+	assert.Equal(t, "{\"callback_type\":5,\"hint\":0,\"query\":\"two sum\",\"page\":1}", result, "Unexpected GetMarshalledSearchCallbackData response")
+	callback := CallbackData{}
+	assert.Nil(t, json.Unmarshal([]byte(result), &callback), "Unexpected json.Unmarshal error")
+	assert.Equal(t, CallbackData{Type: SearchPageRequest, Query: "two sum", Page: 1}, callback, "Unexpected unmarshalled callback")
+}
+
+func TestGetSearchResultsText(t *testing.T) {
+	expected := "<strong>Problems matching \"two &lt;sum&gt;\"</strong> (page 2 of 2):\n" +
+		"1. <a href=\"https://leetcode.com/problems/two-sum\">Two Sum</a> — Easy\n" +
+		"167. <a href=\"https://leetcode.com/problems/two-sum-ii-input-array-is-sorted\">Two Sum II - Input Array Is Sorted</a> — Medium"
+	assert.Equal(t, expected, GetSearchResultsText("two <sum>", getTestSearchResults(), 1), "Unexpected search results text")
+}
+
+func TestGetSearchResultsInlineKeyboard(t *testing.T) {
+	list := getTestSearchResults()
+	firstCallbackData, _ := GetMarshalledSearchCallbackData("1", 0, ProblemRequest)
+	secondCallbackData, _ := GetMarshalledSearchCallbackData("167", 0, ProblemRequest)
+	nextCallbackData, _ := GetMarshalledSearchCallbackData("two sum", 1, SearchPageRequest)
+	previousCallbackData, _ := GetMarshalledSearchCallbackData("two sum", 0, SearchPageRequest)
+	questionsButtons := [][]inlineButton{
+		{{Text: "1. Two Sum", CallbackData: firstCallbackData}},
+		{{Text: "167. Two Sum II - Input Array Is Sorted", CallbackData: secondCallbackData}},
+	}
+
+	parsed := map[string][][]inlineButton{}
+	assert.Nil(t, json.Unmarshal([]byte(GetSearchResultsInlineKeyboard("two sum", list, 0)), &parsed), "Unexpected json.Unmarshal error")
+	assert.Equal(t, append(questionsButtons, []inlineButton{{Text: "Next »", CallbackData: nextCallbackData}}), parsed["inline_keyboard"], "Unexpected first page keyboard")
+
+	parsed = map[string][][]inlineButton{}
+	assert.Nil(t, json.Unmarshal([]byte(GetSearchResultsInlineKeyboard("two sum", list, 1)), &parsed), "Unexpected json.Unmarshal error")
+	assert.Equal(t, append(questionsButtons, []inlineButton{{Text: "« Previous", CallbackData: previousCallbackData}}), parsed["inline_keyboard"], "Unexpected last page keyboard")
+
+	parsed = map[string][][]inlineButton{}
+	longQuery := strings.Repeat("two sum ", 6)
+	assert.Nil(t, json.Unmarshal([]byte(GetSearchResultsInlineKeyboard(longQuery, list, 0)), &parsed), "Unexpected json.Unmarshal error")
+	assert.Equal(t, questionsButtons, parsed["inline_keyboard"], "Pages buttons shouldn't be added when query doesn't fit into callback data")
+}
+
+func TestNormalizeTimeZone(t *testing.T) {
+	testCases := map[string]string{
+		"Europe/Berlin":    "Europe/Berlin",
+		"America/New_York": "America/New_York",
+		"utc":              "UTC",
+		"GMT":              "UTC",
+		"+3":               "UTC+03:00",
+		"-8":               "UTC-08:00",
+		"UTC+5:30":         "UTC+05:30",
+		"gmt-0930":         "UTC-09:30",
+		"+530":             "UTC+05:30",
+		"UTC+14":           "UTC+14:00",
+	}
+	for timeZone, expected := range testCases {
+		normalized, err := NormalizeTimeZone(timeZone)
+		assert.Nilf(t, err, "Unexpected NormalizeTimeZone error for %s", timeZone)
+		assert.Equalf(t, expected, normalized, "Unexpected normalized time zone for %s", timeZone)
+	}
+	for _, timeZone := range []string{"", "Local", "Mars/Olympus", "+15", "UTC+3:75", "3", "../../etc/passwd"} {
+		_, err := NormalizeTimeZone(timeZone)
+		assert.Equalf(t, ErrWrongTimeZone, err, "Unexpected NormalizeTimeZone error for %s", timeZone)
+	}
+}
+
+func TestGetTimeZoneForLongitude(t *testing.T) {
+	testCases := map[float64]string{
+		13.4:   "UTC+01:00",
+		-73.9:  "UTC-05:00",
+		0:      "UTC+00:00",
+		179.9:  "UTC+12:00",
+		-7:     "UTC+00:00",
+		-122.4: "UTC-08:00",
+	}
+	for longitude, expected := range testCases {
+		assert.Equalf(t, expected, GetTimeZoneForLongitude(longitude), "Unexpected time zone for longitude %f", longitude)
+	}
+}
+
+func TestLoadLocation(t *testing.T) {
+	location, err := LoadLocation("")
+	assert.Nil(t, err, "Unexpected LoadLocation error")
+	assert.Equal(t, time.UTC, location, "Empty time zone should be UTC")
+	location, err = LoadLocation("Europe/Berlin")
+	assert.Nil(t, err, "Unexpected LoadLocation error")
+	assert.Equal(t, "Europe/Berlin", location.String(), "Unexpected location")
+	location, err = LoadLocation("UTC-05:30")
+	assert.Nil(t, err, "Unexpected LoadLocation error")
+	_, offset := time.Date(2021, 10, 13, 0, 0, 0, 0, location).Zone()
+	assert.Equal(t, -(5*3600 + 30*60), offset, "Unexpected offset")
+	_, err = LoadLocation("Mars/Olympus")
+	assert.Equal(t, ErrWrongTimeZone, err, "Unexpected LoadLocation error")
+}
+
+func TestGetTimeZoneName(t *testing.T) {
+	user := User{}
+	assert.Equal(t, "UTC", user.GetTimeZoneName(), "Empty time zone should be shown as UTC")
+	user.TimeZone = "Europe/Berlin"
+	assert.Equal(t, "Europe/Berlin", user.GetTimeZoneName(), "Unexpected time zone name")
+}
+
+func TestGetLocalMinuteRanges(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	india, _ := LoadLocation("UTC+05:30")
+	testCases := []struct {
+		now      time.Time
+		location *time.Location
+		slot     time.Duration
+		ranges   []MinuteRange
+	}{
+		{time.Date(2021, 10, 13, 7, 0, 0, 0, time.UTC), time.UTC, 15 * time.Minute, []MinuteRange{{420, 435}}},
+		{time.Date(2021, 10, 13, 7, 3, 15, 0, time.UTC), time.UTC, 15 * time.Minute, []MinuteRange{{420, 435}}},
+		{time.Date(2021, 10, 13, 7, 3, 15, 0, time.UTC), time.UTC, time.Minute, []MinuteRange{{423, 424}}},
+		{time.Date(2021, 10, 13, 7, 0, 0, 0, time.UTC), time.UTC, time.Hour, []MinuteRange{{420, 480}}},
+		{time.Date(2021, 10, 13, 0, 0, 0, 0, time.UTC), time.UTC, 15 * time.Minute, []MinuteRange{{0, 15}}},
+		{time.Date(2021, 10, 13, 23, 45, 0, 0, time.UTC), time.UTC, 15 * time.Minute, []MinuteRange{{1425, 1440}}},
+		// Summer time in Berlin is UTC+2
+		{time.Date(2021, 7, 13, 7, 0, 0, 0, time.UTC), berlin, 15 * time.Minute, []MinuteRange{{540, 555}}},
+		// Winter time in Berlin is UTC+1
+		{time.Date(2021, 12, 13, 7, 0, 0, 0, time.UTC), berlin, 15 * time.Minute, []MinuteRange{{480, 495}}},
+		// 2021-03-28 clocks in Berlin jump from 2:00 to 3:00, so 2:00-2:59 subscribers get task at 3:00
+		{time.Date(2021, 3, 28, 0, 45, 0, 0, time.UTC), berlin, 15 * time.Minute, []MinuteRange{{105, 120}}},
+		{time.Date(2021, 3, 28, 1, 0, 0, 0, time.UTC), berlin, 15 * time.Minute, []MinuteRange{{120, 195}}},
+		{time.Date(2021, 3, 28, 1, 15, 0, 0, time.UTC), berlin, 15 * time.Minute, []MinuteRange{{195, 210}}},
+		// 2021-10-31 clocks in Berlin go back from 3:00 to 2:00, so 2:00-2:59 happens twice, but sent only once
+		{time.Date(2021, 10, 31, 0, 45, 0, 0, time.UTC), berlin, 15 * time.Minute, []MinuteRange{{165, 180}}},
+		{time.Date(2021, 10, 31, 1, 0, 0, 0, time.UTC), berlin, 15 * time.Minute, []MinuteRange{}},
+		{time.Date(2021, 10, 31, 1, 45, 0, 0, time.UTC), berlin, 15 * time.Minute, []MinuteRange{}},
+		{time.Date(2021, 10, 31, 2, 0, 0, 0, time.UTC), berlin, 15 * time.Minute, []MinuteRange{{180, 195}}},
+		{time.Date(2021, 10, 13, 7, 0, 0, 0, time.UTC), india, 15 * time.Minute, []MinuteRange{{750, 765}}},
+		{time.Date(2021, 10, 13, 18, 15, 0, 0, time.UTC), india, 15 * time.Minute, []MinuteRange{{1425, 1440}}},
+		{time.Date(2021, 10, 13, 18, 30, 0, 0, time.UTC), india, 15 * time.Minute, []MinuteRange{{0, 15}}},
+		{time.Date(2021, 10, 13, 18, 30, 0, 0, time.UTC), india, time.Hour, []MinuteRange{{1410, 1440}, {0, 30}}},
+	}
+	for _, testCase := range testCases {
+		assert.Equalf(
+			t,
+			testCase.ranges,
+			GetLocalMinuteRanges(testCase.now, testCase.location, testCase.slot),
+			"Unexpected local ranges for %s in %s with slot %s", testCase.now, testCase.location, testCase.slot,
+		)
+	}
+}
+
+func TestMinuteRangeContains(t *testing.T) {
+	minuteRange := MinuteRange{From: 420, To: 435}
+	for minute, expected := range map[uint16]bool{419: false, 420: true, 434: true, 435: false} {
+		assert.Equalf(t, expected, minuteRange.Contains(minute), "Unexpected Contains result for %d", minute)
+	}
+}
+
+func TestParseSendingTime(t *testing.T) {
+	testCases := map[string]uint16{
+		"7":     420,
+		"07":    420,
+		"7:00":  420,
+		"7:30":  450,
+		"07:05": 425,
+		"0:00":  0,
+		"23:59": 1439,
+	}
+	for text, expected := range testCases {
+		minute, err := ParseSendingTime(text)
+		assert.Nilf(t, err, "Unexpected ParseSendingTime error for %s", text)
+		assert.Equalf(t, expected, minute, "Unexpected minute for %s", text)
+	}
+	for _, text := range []string{"", "24:00", "7:60", "7:5", "07:30:00", "seven", "-1"} {
+		_, err := ParseSendingTime(text)
+		assert.Equalf(t, ErrWrongSendingTime, err, "Unexpected ParseSendingTime error for %s", text)
+	}
+}
+
+func TestSendingTimeString(t *testing.T) {
+	assert.Equal(t, "07:05", SendingTime{Minute: 425}.String(), "Unexpected sending time string")
+	assert.Equal(t, "00:00", SendingTime{}.String(), "Unexpected sending time string")
+	assert.Equal(t, "23:59", SendingTime{Minute: 1439, Kind: HintSendingTime}.String(), "Unexpected sending time string")
+}
+
+func TestGetDeliveryKind(t *testing.T) {
+	assert.Equal(t, "task 07:05", SendingTime{Minute: 425}.GetDeliveryKind(), "Unexpected task delivery kind")
+	assert.Equal(t, "hint 13:00", SendingTime{Minute: 780, Kind: HintSendingTime}.GetDeliveryKind(), "Unexpected hint delivery kind")
+}
+
+func TestWeekdays(t *testing.T) {
+	assert.True(t, Weekdays(0).Contains(time.Sunday), "Empty weekdays should mean every day")
+	assert.True(t, WorkingDays.Contains(time.Monday), "Monday should be a working day")
+	assert.False(t, WorkingDays.Contains(time.Saturday), "Saturday shouldn't be a working day")
+	assert.Equal(t, EveryDay&^(1<<time.Sunday), Weekdays(0).Toggle(time.Sunday), "Toggle of empty weekdays should start from every day")
+	assert.Equal(t, WorkingDays, WorkingDays.Toggle(time.Sunday).Toggle(time.Sunday), "Double toggle should return the same weekdays")
+	testCases := map[Weekdays]string{
+		0:                                  "every day",
+		EveryDay:                           "every day",
+		WorkingDays:                        "Monday to Friday",
+		1<<time.Sunday | 1<<time.Wednesday: "Wed, Sun",
+		WorkingDays.Toggle(time.Friday):    "Mon, Tue, Wed, Thu",
+	}
+	for weekdays, expected := range testCases {
+		assert.Equalf(t, expected, weekdays.String(), "Unexpected string for %d", weekdays)
+	}
+}
+
+func TestParseDateID(t *testing.T) {
+	dateID, err := ParseDateID("2026-11-01")
+	assert.Nil(t, err, "Unexpected ParseDateID error")
+	assert.Equal(t, uint64(20261101), dateID, "Unexpected dateID")
+	assert.Equal(t, "2026-11-01", FormatDateID(dateID), "Unexpected formatted dateID")
+	for _, text := range []string{"", "2026-13-01", "01.11.2026", "2026-11-01 10:00"} {
+		_, err := ParseDateID(text)
+		assert.Equalf(t, ErrWrongDate, err, "Unexpected ParseDateID error for %q", text)
+	}
+}
+
+func TestParseMonthDateID(t *testing.T) {
+	dateID, err := ParseMonthDateID("2024-03")
+	assert.Nil(t, err, "Unexpected ParseMonthDateID error")
+	assert.Equal(t, uint64(20240301), dateID, "Unexpected month dateID")
+	for _, text := range []string{"", "2024-13", "03.2024", "2024-03-15"} {
+		_, err := ParseMonthDateID(text)
+		assert.Equalf(t, ErrWrongDate, err, "Unexpected ParseMonthDateID error for %q", text)
+	}
+}
+
+func TestIsDeliveryDay(t *testing.T) {
+	// 2021-10-13 is Wednesday
+	wednesday := time.Date(2021, 10, 13, 7, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		user     User
+		expected bool
+	}{
+		{User{}, true},
+		{User{Weekdays: WorkingDays}, true},
+		{User{Weekdays: 1 << time.Sunday}, false},
+		{User{PausedUntil: 20211013}, true},
+		{User{PausedUntil: 20211014}, false},
+		{User{Weekdays: WorkingDays, PausedUntil: 20211001}, true},
+	}
+	for _, testCase := range testCases {
+		assert.Equalf(t, testCase.expected, testCase.user.IsDeliveryDay(wednesday), "Unexpected result for %+v", testCase.user)
+	}
+}
+
+func TestGetLocalRangeDate(t *testing.T) {
+	india, _ := LoadLocation("UTC+05:30")
+	now := time.Date(2021, 10, 13, 18, 30, 0, 0, time.UTC)
+	ranges := GetLocalMinuteRanges(now, india, time.Hour)
+	assert.Equal(t, 13, GetLocalRangeDate(now, india, time.Hour, ranges[0]).Day(), "Range before midnight belongs to the current day")
+	assert.Equal(t, 14, GetLocalRangeDate(now, india, time.Hour, ranges[1]).Day(), "Range after midnight belongs to the next day")
+	now = time.Date(2021, 10, 13, 18, 15, 0, 0, time.UTC)
+	assert.Equal(t, 13, GetLocalRangeDate(now, india, DefaultSendingSlot, GetLocalMinuteRanges(now, india, DefaultSendingSlot)[0]).Day(), "Unexpected range date")
+}
+
+func TestGetDeliverySettingsInlineKeyboard(t *testing.T) {
+	keyboard := map[string][][]inlineButton{}
+	err := json.Unmarshal([]byte(GetDeliverySettingsInlineKeyboard(1<<time.Monday, false)), &keyboard)
+	assert.Nil(t, err, "Unexpected json.Unmarshal error")
+	buttons := keyboard["inline_keyboard"]
+	assert.Equal(t, 3, len(buttons), "Unexpected amount of rows without pause")
+	assert.Equal(t, "✅ Mo", buttons[0][0].Text, "Selected day should be marked")
+	assert.Equal(t, "Tu", buttons[0][1].Text, "Not selected day shouldn't be marked")
+	callbackData := CallbackData{}
+	assert.Nil(t, json.Unmarshal([]byte(buttons[0][0].CallbackData), &callbackData), "Unexpected json.Unmarshal error")
+	assert.Equal(t, CallbackData{Type: WeekdaysRequest, Weekdays: 1 << time.Monday}, callbackData, "The last selected day can't be unselected")
+	callbackData = CallbackData{}
+	assert.Nil(t, json.Unmarshal([]byte(buttons[1][2].CallbackData), &callbackData), "Unexpected json.Unmarshal error")
+	assert.Equal(t, CallbackData{Type: WeekdaysRequest, Weekdays: 1<<time.Monday | 1<<time.Sunday}, callbackData, "Unexpected Sunday toggle")
+
+	assert.Nil(t, json.Unmarshal([]byte(GetDeliverySettingsInlineKeyboard(0, true)), &keyboard), "Unexpected json.Unmarshal error")
+	buttons = keyboard["inline_keyboard"]
+	assert.Equal(t, 4, len(buttons), "Resume button is expected for paused delivery")
+	assert.Equal(t, "{\"callback_type\":8,\"hint\":0}", buttons[3][0].CallbackData, "Unexpected resume callback data")
+	for _, row := range buttons {
+		for _, button := range row {
+			assert.LessOrEqual(t, len(button.CallbackData), callbackDataMaxLength, "Callback data is too long")
+		}
+	}
+}
+
+func TestDifficulties(t *testing.T) {
+	assert.True(t, Difficulties(0).Contains(hard), "Empty difficulties should mean all")
+	assert.False(t, Difficulties(1<<easy).Contains(hard), "Hard shouldn't be in easy only")
+	assert.True(t, Difficulties(1<<easy).Contains(notSet), "Unknown difficulty shouldn't be filtered out")
+	assert.Equal(t, []uint8{easy, hard}, Difficulties(1<<easy|1<<hard).GetDifficultiesNums(), "Unexpected difficulties nums")
+	assert.Equal(t, []uint8{easy, medium, hard}, Difficulties(0).GetDifficultiesNums(), "Empty difficulties should mean all")
+	assert.Equal(t, Difficulties(1<<easy|1<<medium), Difficulties(0).Toggle(hard), "Toggle of empty difficulties should start from all")
+	testCases := map[Difficulties]string{
+		0:                           "all",
+		AllDifficulties:             "all",
+		1 << hard:                   "Hard",
+		1<<easy | 1<<medium:         "Easy, Medium",
+		AllDifficulties ^ 1<<medium: "Easy, Hard",
+	}
+	for difficulties, expected := range testCases {
+		assert.Equalf(t, expected, difficulties.String(), "Unexpected string for %d", difficulties)
+	}
+	assert.Equal(t, "Go", GetCodeLanguageName("golang"), "Unexpected code language name")
+	assert.Empty(t, GetCodeLanguageName("cobol"), "Unknown code language shouldn't have name")
+}
+
+func TestGetSettingsInlineKeyboard(t *testing.T) {
+	user := User{
+		Subscribed:   true,
+		SendingTimes: []SendingTime{{Minute: 420}, {Minute: 480, Kind: HintSendingTime}, {Minute: 570}},
+		TimeZone:     "Europe/Berlin",
+		Difficulties: 1 << hard,
+		CodeLanguage: "golang",
+	}
+	getButtons := func(section SettingsSection, paused bool) [][]inlineButton {
+		keyboard := map[string][][]inlineButton{}
+		err := json.Unmarshal([]byte(GetSettingsInlineKeyboard(section, user, paused)), &keyboard)
+		assert.Nil(t, err, "Unexpected json.Unmarshal error")
+		for _, row := range keyboard["inline_keyboard"] {
+			for _, button := range row {
+				assert.LessOrEqual(t, len(button.CallbackData), callbackDataMaxLength, "Callback data is too long")
+			}
+		}
+		return keyboard["inline_keyboard"]
+	}
+	buttons := getButtons(MainSettings, false)
+	assert.Equal(t, 3, len(buttons), "Unexpected amount of rows in the main menu")
+	assert.Equal(t, "{\"callback_type\":9,\"hint\":0,\"setting\":2}", buttons[0][1].CallbackData, "Unexpected time zone section callback data")
+
+	buttons = getButtons(SendingTimeSettings, false)
+	assert.Equal(t, 7, len(buttons), "Unexpected amount of rows with hours")
+	assert.Equal(t, "✅ 7:00", buttons[1][3].Text, "Selected hour should be marked")
+	assert.Equal(t, "8:00", buttons[2][0].Text, "Hint time shouldn't be marked")
+	assert.Equal(t, "{\"callback_type\":10,\"hint\":0,\"setting\":1,\"value\":\"23:00\"}", buttons[5][3].CallbackData, "Unexpected hour callback data")
+	assert.Equal(t, "{\"callback_type\":9,\"hint\":0}", buttons[6][0].CallbackData, "Unexpected back callback data")
+
+	buttons = getButtons(TimeZoneSettings, false)
+	assert.Equal(t, "✅ Europe/Berlin", buttons[1][0].Text, "Current time zone should be marked")
+	assert.Equal(t, "{\"callback_type\":10,\"hint\":0,\"setting\":2,\"value\":\"2\"}", buttons[1][0].CallbackData, "Unexpected time zone callback data")
+
+	buttons = getButtons(DifficultySettings, false)
+	assert.Equal(t, []string{"Easy", "Medium", "✅ Hard"}, []string{buttons[0][0].Text, buttons[0][1].Text, buttons[0][2].Text}, "Unexpected difficulty buttons")
+	assert.Equal(t, "{\"callback_type\":10,\"hint\":0,\"setting\":4,\"value\":\"5\"}", buttons[0][0].CallbackData, "Unexpected Easy toggle")
+	assert.Equal(t, "{\"callback_type\":10,\"hint\":0,\"setting\":4,\"value\":\"4\"}", buttons[0][2].CallbackData, "The last selected difficulty can't be unselected")
+	assert.Equal(t, []string{"✅ Skip other days", "Random problem instead"}, []string{buttons[2][0].Text, buttons[2][1].Text}, "Unexpected fallback buttons")
+	assert.Equal(t, "{\"callback_type\":10,\"hint\":0,\"setting\":4,\"value\":\"random\"}", buttons[2][1].CallbackData, "Unexpected fallback callback data")
+
+	buttons = getButtons(CodeLanguageSettings, false)
+	assert.Equal(t, "✅ Go", buttons[2][1].Text, "Current code language should be marked")
+
+	buttons = getButtons(SubscriptionSettings, true)
+	assert.Equal(t, 3, len(buttons), "Resume button is expected for paused delivery")
+	assert.Equal(t, "{\"callback_type\":10,\"hint\":0,\"setting\":6,\"value\":\"resume\"}", buttons[0][0].CallbackData, "Unexpected resume callback data")
+	user.Subscribed = false
+	buttons = getButtons(SubscriptionSettings, false)
+	assert.Equal(t, "{\"callback_type\":9,\"hint\":0,\"setting\":1}", buttons[0][0].CallbackData, "Not subscribed user should be sent to delivery time")
+
+	assert.Equal(t, 1, len(getButtons(LanguageSettings, false)), "Only back button is expected for language")
+}
+
+func TestCallbackTypeString(t *testing.T) {
+	assert.Equal(t, "hint", HintRequest.String(), "Unexpected name of the first type")
+	assert.Equal(t, "calendar", CalendarRequest.String(), "Unexpected name of the last type")
+	assert.Equal(t, "unknown", CallbackType(100).String(), "Unexpected name of unknown type")
 }
@@ -0,0 +1,329 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dartkron/leetcodeBot/v3/internal/logging"
+	"github.com/dartkron/leetcodeBot/v3/pkg/leetcodeclient"
+	"github.com/dartkron/leetcodeBot/v3/pkg/tracing"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Keys of settings in the config file. Environment variable is the upper-cased key, flag is the key with dashes
+const (
+	KeySendingToken            = "sending_token"
+	KeyYDBEndpoint             = "ydb_endpoint"
+	KeyYDBDatabase             = "ydb_database"
+	KeyCacheDir                = "cache_dir"
+	KeyLeetcodeGraphQlURL      = "leetcode_graphql_url"
+	KeySendingSlot             = "sending_slot_minutes"
+	KeyWarmUpDeadline          = "warm_up_deadline_minutes"
+	KeyAlertChatID             = "alert_chat_id"
+	KeyResponseTimeout         = "response_timeout_seconds"
+	KeyProcessingTimeout       = "processing_timeout_seconds"
+	KeyEarlyAnswerEnabled      = "early_answer_enabled"
+	KeyOutboxEnabled           = "outbox_enabled"
+	KeyPersistentDedupeEnabled = "persistent_dedupe_enabled"
+	KeyDedupeCapacity          = "dedupe_capacity"
+	KeyDedupeTTL               = "dedupe_ttl_minutes"
+	KeyLogFormat               = "log_format"
+	KeyLogLevel                = "log_level"
+	KeyMetricsAddress          = "metrics_address"
+	KeyMetricsPushURL          = "metrics_push_url"
+	KeyTracingExporter         = "tracing_exporter"
+	KeyOTLPEndpoint            = "otlp_endpoint"
+)
+
+const (
+	// FileEnv is the environment variable with the path to YAML or TOML config file
+	FileEnv = "CONFIG_FILE"
+	// DefaultCacheDir is the directory of the file cache of tasks
+	DefaultCacheDir = "/tmp/"
+	// DefaultLeetcodeGraphQlURL is the LeetCode API endpoint
+	DefaultLeetcodeGraphQlURL = leetcodeclient.DefaultGraphQlURL
+)
+
+// Config holds settings of the bot, the reminder and tools. Zero durations and limits mean defaults of the application
+type Config struct {
+	SendingToken            string
+	YDBEndpoint             string
+	YDBDatabase             string
+	CacheDir                string
+	LeetcodeGraphQlURL      string
+	SendingSlot             time.Duration
+	WarmUpDeadline          time.Duration
+	AlertChatID             uint64
+	ResponseTimeout         time.Duration
+	ProcessingTimeout       time.Duration
+	EarlyAnswerEnabled      bool
+	OutboxEnabled           bool
+	PersistentDedupeEnabled bool
+	DedupeCapacity          uint64
+	DedupeTTL               time.Duration
+	LogFormat               string
+	LogLevel                string
+	MetricsAddress          string
+	MetricsPushURL          string
+	TracingExporter         string
+	OTLPEndpoint            string
+}
+
+// YDBConnectionString returns YDB connection string of the endpoint and the database
+func (c *Config) YDBConnectionString() string {
+	return fmt.Sprintf("%s/?database=%s", c.YDBEndpoint, c.YDBDatabase)
+}
+
+type option struct {
+	key   string
+	usage string
+	parse func(*Config, string) error
+}
+
+var options = []option{
+	{KeySendingToken, "Telegram bot token", stringOption(func(c *Config) *string { return &c.SendingToken })},
+	{KeyYDBEndpoint, "YDB endpoint, like grpcs://ydb.serverless.yandexcloud.net:2135", stringOption(func(c *Config) *string { return &c.YDBEndpoint })},
+	{KeyYDBDatabase, "YDB database path", stringOption(func(c *Config) *string { return &c.YDBDatabase })},
+	{KeyCacheDir, "directory of the tasks file cache", stringOption(func(c *Config) *string { return &c.CacheDir })},
+	{KeyLeetcodeGraphQlURL, "LeetCode GraphQL API URL", stringOption(func(c *Config) *string { return &c.LeetcodeGraphQlURL })},
+	{KeySendingSlot, "period of the reminder trigger in minutes, it must divide 60", durationOption(time.Minute, func(c *Config) *time.Duration { return &c.SendingSlot })},
+	{KeyWarmUpDeadline, "minutes after the daily flip to store today's task before the alert", durationOption(time.Minute, func(c *Config) *time.Duration { return &c.WarmUpDeadline })},
+	{KeyAlertChatID, "Telegram chat for alerts", uint64Option(func(c *Config) *uint64 { return &c.AlertChatID })},
+	{KeyResponseTimeout, "seconds to answer the request before the placeholder is sent", durationOption(time.Second, func(c *Config) *time.Duration { return &c.ResponseTimeout })},
+	{KeyProcessingTimeout, "seconds to process the request in background", durationOption(time.Second, func(c *Config) *time.Duration { return &c.ProcessingTimeout })},
+	{KeyEarlyAnswerEnabled, "answer slow requests before the end of processing, it needs HTTP server sending flushed responses", boolOption(func(c *Config) *bool { return &c.EarlyAnswerEnabled })},
+	{KeyOutboxEnabled, "send messages through the outbox table", boolOption(func(c *Config) *bool { return &c.OutboxEnabled })},
+	{KeyPersistentDedupeEnabled, "keep processed updates in the updates table", boolOption(func(c *Config) *bool { return &c.PersistentDedupeEnabled })},
+	{KeyDedupeCapacity, "amount of processed updates remembered in memory", uint64Option(func(c *Config) *uint64 { return &c.DedupeCapacity })},
+	{KeyDedupeTTL, "minutes to remember processed updates in memory", durationOption(time.Minute, func(c *Config) *time.Duration { return &c.DedupeTTL })},
+	{KeyLogFormat, "log format, json or text", stringOption(func(c *Config) *string { return &c.LogFormat })},
+	{KeyLogLevel, "minimal log level: debug, info, warn or error", stringOption(func(c *Config) *string { return &c.LogLevel })},
+	{KeyMetricsAddress, "address to serve Prometheus metrics on /metrics in long-running modes, like :9090", stringOption(func(c *Config) *string { return &c.MetricsAddress })},
+	{KeyMetricsPushURL, "Prometheus Pushgateway URL to push metrics of the reminder runs", stringOption(func(c *Config) *string { return &c.MetricsPushURL })},
+	{KeyTracingExporter, "exporter of tracing spans: otlp or stdout, tracing is disabled by default", stringOption(func(c *Config) *string { return &c.TracingExporter })},
+	{KeyOTLPEndpoint, "OTLP/HTTP endpoint of OpenTelemetry Collector, " + tracing.DefaultOTLPEndpoint + " by default", stringOption(func(c *Config) *string { return &c.OTLPEndpoint })},
+}
+
+func stringOption(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func durationOption(unit time.Duration, field func(*Config) *time.Duration) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		amount, err := strconv.Atoi(value)
+		if err != nil || amount <= 0 {
+			return fmt.Errorf("%q should be a positive number", value)
+		}
+		*field(c) = time.Duration(amount) * unit
+		return nil
+	}
+}
+
+func uint64Option(field func(*Config) *uint64) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		number, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q should be a positive number", value)
+		}
+		*field(c) = number
+		return nil
+	}
+}
+
+func boolOption(field func(*Config) *bool) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q should be true or false", value)
+		}
+		*field(c) = enabled
+		return nil
+	}
+}
+
+// Default returns config with default values, which isn't valid without YDB and Telegram settings
+func Default() *Config {
+	return &Config{
+		CacheDir:           DefaultCacheDir,
+		LeetcodeGraphQlURL: DefaultLeetcodeGraphQlURL,
+	}
+}
+
+// Validate checks that required settings are set and values are consistent
+func (c *Config) Validate(required ...string) error {
+	errs := []error{}
+	values := map[string]string{
+		KeySendingToken:       c.SendingToken,
+		KeyYDBEndpoint:        c.YDBEndpoint,
+		KeyYDBDatabase:        c.YDBDatabase,
+		KeyCacheDir:           c.CacheDir,
+		KeyLeetcodeGraphQlURL: c.LeetcodeGraphQlURL,
+	}
+	for _, key := range required {
+		if values[key] == "" {
+			errs = append(errs, fmt.Errorf("%s is required", key))
+		}
+	}
+	if c.SendingSlot > 0 && (c.SendingSlot > time.Hour || time.Hour%c.SendingSlot != 0) {
+		errs = append(errs, fmt.Errorf("%s should divide 60, got %s", KeySendingSlot, c.SendingSlot))
+	}
+	if c.ResponseTimeout > 0 && c.ProcessingTimeout > 0 && c.ResponseTimeout > c.ProcessingTimeout {
+		errs = append(errs, fmt.Errorf("%s shouldn't be greater than %s", KeyResponseTimeout, KeyProcessingTimeout))
+	}
+	_, err := logging.New(io.Discard, c.LogFormat, c.LogLevel)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	exporter, err := tracing.NewExporter(c.TracingExporter, c.OTLPEndpoint)
+	if err != nil {
+		errs = append(errs, err)
+	} else if exporter != nil {
+		_ = exporter.Shutdown(context.Background())
+	}
+	return errors.Join(errs...)
+}
+
+// Loader reads config from the file, environment variables and flags. The later source overrides the former
+type Loader struct {
+	flags    *flag.FlagSet
+	filePath *string
+	values   map[string]*string
+}
+
+// NewLoader constructs Loader, which registers flags in the flag set. Flags are ignored if the flag set is nil
+func NewLoader(flags *flag.FlagSet) *Loader {
+	loader := &Loader{flags: flags, values: map[string]*string{}}
+	if flags == nil {
+		return loader
+	}
+	loader.filePath = flags.String("config", "", "path to YAML or TOML config file, "+FileEnv+" environment variable by default")
+	for _, option := range options {
+		loader.values[option.key] = flags.String(strings.ReplaceAll(option.key, "_", "-"), "", option.usage)
+	}
+	return loader
+}
+
+// Load reads and validates config, flags should be already parsed
+func (l *Loader) Load(required ...string) (*Config, error) {
+	config := Default()
+	filePath := os.Getenv(FileEnv)
+	if l.filePath != nil && *l.filePath != "" {
+		filePath = *l.filePath
+	}
+	if filePath != "" {
+		values, err := readFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		err = config.apply(values, "config file")
+		if err != nil {
+			return nil, err
+		}
+	}
+	envValues := map[string]string{}
+	for _, option := range options {
+		value := os.Getenv(strings.ToUpper(option.key))
+		if value != "" {
+			envValues[option.key] = value
+		}
+	}
+	err := config.apply(envValues, "environment")
+	if err != nil {
+		return nil, err
+	}
+	if l.flags != nil {
+		flagValues := map[string]string{}
+		l.flags.Visit(func(f *flag.Flag) {
+			key := strings.ReplaceAll(f.Name, "-", "_")
+			if _, ok := l.values[key]; ok {
+				flagValues[key] = f.Value.String()
+			}
+		})
+		err = config.apply(flagValues, "flags")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return config, config.Validate(required...)
+}
+
+// Load reads config from the file and environment variables, as there are no flags in serverless functions
+func Load(required ...string) (*Config, error) {
+	return NewLoader(nil).Load(required...)
+}
+
+func (c *Config) apply(values map[string]string, source string) error {
+	errs := []error{}
+	known := map[string]bool{}
+	for _, option := range options {
+		known[option.key] = true
+		value, ok := values[option.key]
+		if !ok {
+			continue
+		}
+		err := option.parse(c, value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("wrong %s in %s: %w", option.key, source, err))
+		}
+	}
+	for key := range values {
+		if !known[key] {
+			errs = append(errs, fmt.Errorf("unknown %s in %s", key, source))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// readFile reads YAML or TOML file by its extension. Only top-level keys are supported in both formats
+func readFile(filePath string) (map[string]string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	switch filepath.Ext(filePath) {
+	case ".yaml", ".yml":
+		return parseYAML(content)
+	case ".toml":
+		return parseTOML(content)
+	}
+	return nil, fmt.Errorf("unknown config file format %q, use .yaml or .toml", filePath)
+}
+
+func parseYAML(content []byte) (map[string]string, error) {
+	parsed := map[string]interface{}{}
+	err := yaml.Unmarshal(content, &parsed)
+	if err != nil {
+		return nil, err
+	}
+	return stringValues(parsed), nil
+}
+
+func parseTOML(content []byte) (map[string]string, error) {
+	parsed := map[string]interface{}{}
+	err := toml.Unmarshal(content, &parsed)
+	if err != nil {
+		return nil, fmt.Errorf("wrong TOML config: %w", err)
+	}
+	return stringValues(parsed), nil
+}
+
+// stringValues formats top-level values of the file to be parsed as environment variables
+func stringValues(parsed map[string]interface{}) map[string]string {
+	values := map[string]string{}
+	for key, value := range parsed {
+		values[key] = fmt.Sprint(value)
+	}
+	return values
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func clearEnv(t *testing.T) {
+	t.Setenv(FileEnv, "")
+	for _, option := range options {
+		t.Setenv(strings.ToUpper(option.key), "")
+	}
+}
+
+func writeConfigFile(t *testing.T, name string, content string) string {
+	filePath := filepath.Join(t.TempDir(), name)
+	assert.Nil(t, os.WriteFile(filePath, []byte(content), 0600), "Unexpected error on writing config file")
+	return filePath
+}
+
+func TestLoadDefaults(t *testing.T) {
+	clearEnv(t)
+	config, err := Load()
+	assert.Nil(t, err, "Unexpected Load error")
+	assert.Equal(t, Default(), config, "Config without sources should have default values")
+	_, err = Load(KeySendingToken, KeyYDBEndpoint)
+	assert.EqualError(t, err, "sending_token is required\nydb_endpoint is required", "Required settings should be validated")
+}
+
+func TestLoadEnv(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("SENDING_TOKEN", "token")
+	t.Setenv("YDB_ENDPOINT", "grpcs://localhost:2135")
+	t.Setenv("YDB_DATABASE", "/local")
+	t.Setenv("SENDING_SLOT_MINUTES", "5")
+	t.Setenv("ALERT_CHAT_ID", "1126")
+	t.Setenv("RESPONSE_TIMEOUT_SECONDS", "2")
+	t.Setenv("OUTBOX_ENABLED", "true")
+	t.Setenv("EARLY_ANSWER_ENABLED", "true")
+	config, err := Load(KeySendingToken, KeyYDBEndpoint, KeyYDBDatabase)
+	assert.Nil(t, err, "Unexpected Load error")
+	expected := Default()
+	expected.SendingToken = "token"
+	expected.YDBEndpoint = "grpcs://localhost:2135"
+	expected.YDBDatabase = "/local"
+	expected.SendingSlot = 5 * time.Minute
+	expected.AlertChatID = 1126
+	expected.ResponseTimeout = 2 * time.Second
+	expected.OutboxEnabled = true
+	expected.EarlyAnswerEnabled = true
+	assert.Equal(t, expected, config, "Unexpected config from environment")
+	assert.Equal(t, "grpcs://localhost:2135/?database=/local", config.YDBConnectionString(), "Unexpected connection string")
+}
+
+func TestLoadWrongValues(t *testing.T) {
+	testCases := map[string]string{
+		"SENDING_SLOT_MINUTES":       "wrong sending_slot_minutes in environment: \"ten\" should be a positive number",
+		"WARM_UP_DEADLINE_MINUTES":   "wrong warm_up_deadline_minutes in environment: \"ten\" should be a positive number",
+		"ALERT_CHAT_ID":              "wrong alert_chat_id in environment: \"ten\" should be a positive number",
+		"PERSISTENT_DEDUPE_ENABLED":  "wrong persistent_dedupe_enabled in environment: \"ten\" should be true or false",
+		"PROCESSING_TIMEOUT_SECONDS": "wrong processing_timeout_seconds in environment: \"ten\" should be a positive number",
+	}
+	for name, expected := range testCases {
+		clearEnv(t)
+		t.Setenv(name, "ten")
+		_, err := Load()
+		assert.EqualError(t, err, expected, "Unexpected error for %s", name)
+	}
+	clearEnv(t)
+	t.Setenv("SENDING_SLOT_MINUTES", "7")
+	_, err := Load()
+	assert.EqualError(t, err, "sending_slot_minutes should divide 60, got 7m0s", "Sending slot should divide an hour")
+	clearEnv(t)
+	t.Setenv("RESPONSE_TIMEOUT_SECONDS", "10")
+	t.Setenv("PROCESSING_TIMEOUT_SECONDS", "5")
+	_, err = Load()
+	assert.EqualError(t, err, "response_timeout_seconds shouldn't be greater than processing_timeout_seconds", "Response timeout should be validated")
+	clearEnv(t)
+	t.Setenv("LOG_FORMAT", "xml")
+	_, err = Load()
+	assert.EqualError(t, err, "unknown log format \"xml\", use json or text", "Log format should be validated")
+	clearEnv(t)
+	t.Setenv("TRACING_EXPORTER", "jaeger")
+	_, err = Load()
+	assert.EqualError(t, err, "unknown tracing exporter \"jaeger\", use stdout or otlp", "Tracing exporter should be validated")
+}
+
+func TestLoadFiles(t *testing.T) {
+	clearEnv(t)
+	t.Setenv(FileEnv, writeConfigFile(t, "config.yaml", "sending_token: yaml\nsending_slot_minutes: 10\noutbox_enabled: true\n"))
+	config, err := Load()
+	assert.Nil(t, err, "Unexpected Load error")
+	assert.Equal(t, "yaml", config.SendingToken, "Unexpected token from YAML")
+	assert.Equal(t, 10*time.Minute, config.SendingSlot, "Unexpected sending slot from YAML")
+	assert.True(t, config.OutboxEnabled, "Unexpected outbox from YAML")
+
+	t.Setenv(FileEnv, writeConfigFile(t, "config.toml", "# Bot settings\nsending_token = \"toml # not a comment\" # token\nalert_chat_id = 1126 # chat\n\ncache_dir = \"\"\"\n/var/cache\"\"\"\n"))
+	config, err = Load()
+	assert.Nil(t, err, "Unexpected Load error")
+	assert.Equal(t, "toml # not a comment", config.SendingToken, "Unexpected token from TOML")
+	assert.Equal(t, uint64(1126), config.AlertChatID, "Unexpected alert chat from TOML")
+	assert.Equal(t, "/var/cache", config.CacheDir, "Unexpected cache dir from TOML")
+	t.Setenv("CACHE_DIR", "/env/cache")
+	config, _ = Load()
+	assert.Equal(t, "/env/cache", config.CacheDir, "Environment should override the file")
+
+	t.Setenv(FileEnv, writeConfigFile(t, "config.yaml", "sending_tokn: typo\n"))
+	_, err = Load()
+	assert.EqualError(t, err, "unknown sending_tokn in config file", "Unknown keys should be reported")
+	t.Setenv(FileEnv, writeConfigFile(t, "config.toml", "sending_token\n"))
+	_, err = Load()
+	assert.EqualError(t, err, "wrong TOML config: toml: expected character =", "Wrong TOML should be reported")
+	t.Setenv(FileEnv, writeConfigFile(t, "config.toml", "[bot]\nsending_token = \"toml\"\n"))
+	_, err = Load()
+	assert.EqualError(t, err, "unknown bot in config file", "Settings should be top-level keys")
+	t.Setenv(FileEnv, writeConfigFile(t, "config.json", "{}"))
+	_, err = Load()
+	assert.NotNil(t, err, "Unknown format should be reported")
+	t.Setenv(FileEnv, filepath.Join(t.TempDir(), "missing.yaml"))
+	_, err = Load()
+	assert.NotNil(t, err, "Missing file should be reported")
+}
+
+func TestLoaderFlags(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("SENDING_TOKEN", "env")
+	t.Setenv("YDB_DATABASE", "/env")
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	loader := NewLoader(flags)
+	filePath := writeConfigFile(t, "config.yaml", "ydb_endpoint: grpcs://file:2135\nydb_database: /file\n")
+	err := flags.Parse([]string{"-config", filePath, "-sending-token", "flag", "-warm-up-deadline-minutes", "45"})
+	assert.Nil(t, err, "Unexpected flags error")
+	config, err := loader.Load(KeySendingToken, KeyYDBEndpoint, KeyYDBDatabase)
+	assert.Nil(t, err, "Unexpected Load error")
+	assert.Equal(t, "flag", config.SendingToken, "Flags should override environment")
+	assert.Equal(t, "grpcs://file:2135", config.YDBEndpoint, "Unexpected endpoint from the file")
+	assert.Equal(t, "/env", config.YDBDatabase, "Environment should override the file")
+	assert.Equal(t, 45*time.Minute, config.WarmUpDeadline, "Unexpected warm-up deadline from flags")
+}
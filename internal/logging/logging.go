@@ -0,0 +1,106 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Formats of the log output
+const (
+	// FormatJSON is parsed by Yandex Cloud Logging, it's the default
+	FormatJSON = "json"
+	// FormatText is easier to read in local runs
+	FormatText = "text"
+)
+
+// CorrelationIDKey is the attribute of all records logged for the same update or broadcast run
+const CorrelationIDKey = "correlationID"
+
+// RunIDKey is the attribute of all records logged for the same broadcast or batch run
+const RunIDKey = "runID"
+
+type contextKey struct{}
+
+// New constructs logger with the format and the level, like "debug", "info", "warn" or "error". Empty values mean JSON and info
+func New(w io.Writer, format string, level string) (*slog.Logger, error) {
+	var slogLevel slog.Level
+	if level != "" {
+		err := slogLevel.UnmarshalText([]byte(level))
+		if err != nil {
+			return nil, fmt.Errorf("unknown log level %q", level)
+		}
+	}
+	handlerOptions := &slog.HandlerOptions{Level: slogLevel}
+	switch strings.ToLower(format) {
+	case "", FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, handlerOptions)), nil
+	case FormatText:
+		return slog.New(slog.NewTextHandler(w, handlerOptions)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q, use %s or %s", format, FormatJSON, FormatText)
+}
+
+// Discard returns logger without output, to silence logs in tests
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// NewCorrelationID generates random ID to find all records of the same update or run
+func NewCorrelationID() string {
+	buffer := make([]byte, 8)
+	_, err := rand.Read(buffer)
+	if err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buffer)
+}
+
+// NewContext returns context carrying the logger
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// WithCorrelation returns context with the logger annotated by new correlation ID and the attributes.
+// Context already having correlated logger is returned as is, so nested calls keep the ID of the outer one
+func WithCorrelation(ctx context.Context, logger *slog.Logger, args ...any) context.Context {
+	if _, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return ctx
+	}
+	return NewContext(ctx, logger.With(append([]any{CorrelationIDKey, NewCorrelationID()}, args...)...))
+}
+
+// WithRunID returns context with the logger annotated by new run ID and the attributes. Unlike correlation ID,
+// every nested run gets its own ID, so runs of the same invocation and their retries can be told apart
+func WithRunID(ctx context.Context, logger *slog.Logger, args ...any) context.Context {
+	ctx = WithCorrelation(ctx, logger)
+	return NewContext(ctx, FromContext(ctx).With(append([]any{RunIDKey, NewCorrelationID()}, args...)...))
+}
+
+// FromContext returns the logger of the context, slog default logger if there is no one
+func FromContext(ctx context.Context) *slog.Logger {
+	return FromContextOr(ctx, slog.Default())
+}
+
+// FromContextOr returns the logger of the context or the fallback one
+func FromContextOr(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return fallback
+}
+
+// Setup sets logger writing to stdout as slog default logger, which is used by all packages
+func Setup(format string, level string) (*slog.Logger, error) {
+	logger, err := New(os.Stdout, format, level)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(logger)
+	return logger, nil
+}
@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	output := &bytes.Buffer{}
+	logger, err := New(output, "", "")
+	assert.Nil(t, err, "Unexpected New error")
+	logger.Debug("Hidden")
+	logger.Info("Shown", "userID", 1126)
+	record := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(output.Bytes(), &record), "Default format should be JSON")
+	assert.Equal(t, "Shown", record["msg"], "Info should be logged by default")
+	assert.Equal(t, float64(1126), record["userID"], "Unexpected attribute")
+
+	output.Reset()
+	logger, err = New(output, FormatText, "warn")
+	assert.Nil(t, err, "Unexpected New error")
+	logger.Info("Hidden")
+	logger.Warn("Shown")
+	assert.Contains(t, output.String(), "level=WARN msg=Shown", "Unexpected text output")
+	assert.NotContains(t, output.String(), "Hidden", "Info shouldn't be logged with warn level")
+
+	_, err = New(output, "xml", "")
+	assert.EqualError(t, err, "unknown log format \"xml\", use json or text", "Unknown format should be reported")
+	_, err = New(output, "", "verbose")
+	assert.EqualError(t, err, "unknown log level \"verbose\"", "Unknown level should be reported")
+}
+
+func TestWithCorrelation(t *testing.T) {
+	output := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(output, nil))
+	assert.Same(t, slog.Default(), FromContext(context.Background()), "Default logger should be returned without context one")
+	assert.Same(t, logger, FromContextOr(context.Background(), logger), "Fallback should be returned without context one")
+
+	ctx := WithCorrelation(context.Background(), logger, "updateID", 100500)
+	assert.Same(t, FromContext(ctx), FromContextOr(ctx, logger), "Context logger should be returned")
+	nestedCtx := WithCorrelation(ctx, logger, "run", "nested")
+	FromContext(nestedCtx).Info("First")
+	FromContext(WithCorrelation(context.Background(), logger)).Info("Second")
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	assert.Len(t, lines, 2, "Unexpected number of records")
+	assert.Contains(t, lines[0], "updateID=100500", "Attributes should be added")
+	assert.NotContains(t, lines[0], "run=nested", "Nested correlation should keep the outer logger")
+	firstID := lines[0][strings.Index(lines[0], CorrelationIDKey):]
+	secondID := lines[1][strings.Index(lines[1], CorrelationIDKey):]
+	assert.Len(t, strings.Fields(firstID)[0], len(CorrelationIDKey)+17, "Correlation ID should have 16 hex digits")
+	assert.NotEqual(t, strings.Fields(firstID)[0], strings.Fields(secondID)[0], "Correlation IDs should be different")
+}
+
+func TestWithRunID(t *testing.T) {
+	output := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(output, nil))
+	ctx := WithCorrelation(context.Background(), logger, "run", "reminder")
+	FromContext(WithRunID(ctx, logger, "job", "dailyTask")).Info("First")
+	FromContext(WithRunID(ctx, logger, "job", "dailyTask")).Info("Second")
+	FromContext(WithRunID(context.Background(), logger, "job", "nudges")).Info("Third")
+	records := []map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		record := map[string]interface{}{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &record), "Log record should be JSON")
+		records = append(records, record)
+	}
+	if assert.Len(t, records, 3, "Unexpected number of records") {
+		assert.Equal(t, records[0][CorrelationIDKey], records[1][CorrelationIDKey], "Nested runs should keep the outer correlation ID")
+		assert.NotEqual(t, records[0][RunIDKey], records[1][RunIDKey], "Every run should get its own ID")
+		assert.Equal(t, "reminder", records[1]["run"], "Outer attributes should be kept")
+		assert.Equal(t, "dailyTask", records[1]["job"], "Attributes should be added")
+		assert.NotEmpty(t, records[2][CorrelationIDKey], "Run without the outer context should get correlation ID")
+		assert.NotEmpty(t, records[2][RunIDKey], "Run without the outer context should get run ID")
+	}
+}
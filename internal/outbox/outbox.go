@@ -0,0 +1,128 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dartkron/leetcodeBot/v3/internal/common"
+	"github.com/dartkron/leetcodeBot/v3/internal/logging"
+	"github.com/dartkron/leetcodeBot/v3/internal/storage"
+)
+
+const (
+	// DefaultLeaseDuration is the time the message is hidden from other dispatchers while it's being sent
+	DefaultLeaseDuration = time.Minute
+	// DefaultBatchSize is the maximum amount of messages sent concurrently
+	DefaultBatchSize = 30
+	// DefaultMaxAttempts is the amount of attempts before the message goes to dead letters
+	DefaultMaxAttempts = 5
+	// DefaultRetryBackoff is the pause before the second attempt, it's doubled for every next one
+	DefaultRetryBackoff = 10 * time.Second
+	maxRetryBackoff     = time.Hour
+)
+
+// Sender sends the message to Telegram API
+type Sender func(context.Context, common.OutboxMessage) error
+
+// Stats is an amount of messages processed by the dispatcher
+type Stats struct {
+	Sent         int `json:"sent"`
+	Retried      int `json:"retried"`
+	DeadLettered int `json:"deadLettered"`
+}
+
+// Dispatcher drains the outbox: leases first messages of chats, sends them and completes or schedules the retry.
+// Messages are sent at least once, the message could be sent twice if the dispatcher dies between sending and completion
+type Dispatcher struct {
+	outbox        storage.Outbox
+	send          Sender
+	LeaseDuration time.Duration
+	BatchSize     int
+	MaxAttempts   uint32
+	RetryBackoff  time.Duration
+	Clock         common.Clock
+}
+
+// Dispatch sends messages till there are no available messages in the outbox or the context is closed
+func (d *Dispatcher) Dispatch(ctx context.Context) (Stats, error) {
+	stats := Stats{}
+	for {
+		if ctx.Err() != nil {
+			return stats, common.ErrClosedContext
+		}
+		messages, err := d.outbox.Lease(ctx, d.Clock.Now(), d.LeaseDuration, d.BatchSize)
+		if err != nil {
+			return stats, err
+		}
+		if len(messages) == 0 {
+			return stats, nil
+		}
+		// Leased messages are from different chats, so they could be sent concurrently without breaking the order
+		var wg sync.WaitGroup
+		var statsMutex sync.Mutex
+		for _, message := range messages {
+			wg.Add(1)
+			go func(message common.OutboxMessage) {
+				defer wg.Done()
+				messageStats := d.dispatch(ctx, message)
+				statsMutex.Lock()
+				stats.Sent += messageStats.Sent
+				stats.Retried += messageStats.Retried
+				stats.DeadLettered += messageStats.DeadLettered
+				statsMutex.Unlock()
+			}(message)
+		}
+		wg.Wait()
+	}
+}
+
+// dispatch sends the message and saves the result of the attempt to the outbox
+func (d *Dispatcher) dispatch(ctx context.Context, message common.OutboxMessage) Stats {
+	err := d.send(ctx, message)
+	if err == nil {
+		err = d.outbox.Complete(ctx, message)
+		if err != nil {
+			logging.FromContext(ctx).Error("Error on completing outbox message", "messageID", message.ID, "chatID", message.ChatID, "error", err)
+		}
+		return Stats{Sent: 1}
+	}
+	message.Attempts++
+	message.LastError = err.Error()
+	stats := Stats{}
+	if message.Attempts >= d.MaxAttempts {
+		message.Status = common.OutboxDead
+		stats.DeadLettered++
+		logging.FromContext(ctx).Error("Outbox message is out of attempts", "messageID", message.ID, "chatID", message.ChatID, "error", err)
+	} else {
+		message.AvailableAt = d.Clock.Now().Add(d.getRetryBackoff(message.Attempts))
+		stats.Retried++
+	}
+	err = d.outbox.Release(ctx, message)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error on releasing outbox message", "messageID", message.ID, "chatID", message.ChatID, "error", err)
+	}
+	return stats
+}
+
+// getRetryBackoff returns pause after the attempts failed attempts
+func (d *Dispatcher) getRetryBackoff(attempts uint32) time.Duration {
+	backoff := d.RetryBackoff
+	for i := uint32(1); i < attempts && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxRetryBackoff)
+}
+
+// NewDispatcher constructs Dispatcher with default lease, batch and retries settings
+func NewDispatcher(outbox storage.Outbox, send Sender) *Dispatcher {
+	return &Dispatcher{
+		outbox:        outbox,
+		send:          send,
+		LeaseDuration: DefaultLeaseDuration,
+		BatchSize:     DefaultBatchSize,
+		MaxAttempts:   DefaultMaxAttempts,
+		RetryBackoff:  DefaultRetryBackoff,
+		Clock:         common.SystemClock{},
+	}
+}
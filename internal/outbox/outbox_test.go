@@ -0,0 +1,106 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dartkron/leetcodeBot/v3/internal/common"
+	"github.com/dartkron/leetcodeBot/v3/internal/storage"
+	"github.com/dartkron/leetcodeBot/v3/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockOutbox is MemoryOutbox which fails Lease when failLease is set
+type MockOutbox struct {
+	*storage.MemoryOutbox
+	failLease bool
+}
+
+func (o *MockOutbox) Lease(ctx context.Context, now time.Time, leaseFor time.Duration, limit int) ([]common.OutboxMessage, error) {
+	if o.failLease {
+		return []common.OutboxMessage{}, tests.ErrBypassTest
+	}
+	return o.MemoryOutbox.Lease(ctx, now, leaseFor, limit)
+}
+
+type mockSender struct {
+	sent     []string
+	failures map[string]int
+	mutex    sync.Mutex
+}
+
+func (s *mockSender) send(ctx context.Context, message common.OutboxMessage) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	body := string(message.Body)
+	if s.failures[body] > 0 {
+		s.failures[body]--
+		return tests.ErrBypassTest
+	}
+	s.sent = append(s.sent, body)
+	return nil
+}
+
+func TestDispatch(t *testing.T) {
+	ctx := context.Background()
+	outbox := &MockOutbox{MemoryOutbox: storage.NewMemoryOutbox(nil)}
+	sender := &mockSender{failures: map[string]int{"first": 2, "dead": 10}}
+	dispatcher := NewDispatcher(outbox, sender.send)
+	dispatcher.RetryBackoff = 0
+	dispatcher.MaxAttempts = 3
+	clock := tests.NewFakeClock(time.Date(2026, time.January, 1, 23, 59, 0, 0, time.UTC))
+	dispatcher.Clock = clock
+	err := outbox.Enqueue(ctx, []common.OutboxMessage{
+		{ChatID: 1, Body: []byte("first")},
+		{ChatID: 1, Body: []byte("second")},
+		{ChatID: 2, Body: []byte("dead")},
+		{ChatID: 2, Body: []byte("after dead")},
+	})
+	assert.Nil(t, err, "Unexpected Enqueue error")
+
+	stats, err := dispatcher.Dispatch(ctx)
+	assert.Nil(t, err, "Unexpected Dispatch error")
+	assert.Equal(t, Stats{Sent: 3, Retried: 4, DeadLettered: 1}, stats, "Unexpected dispatch stats")
+	chatOrder := []string{}
+	for _, body := range sender.sent {
+		if body == "first" || body == "second" {
+			chatOrder = append(chatOrder, body)
+		}
+	}
+	assert.Equal(t, []string{"first", "second"}, chatOrder, "Messages of the chat should be sent in order")
+	assert.Contains(t, sender.sent, "after dead", "Dead message shouldn't block the chat")
+	dead, err := outbox.GetDeadMessages(ctx)
+	assert.Nil(t, err, "Unexpected GetDeadMessages error")
+	assert.Len(t, dead, 1, "Unexpected dead messages")
+	assert.Equal(t, "dead", string(dead[0].Body), "Unexpected dead message")
+	assert.Equal(t, uint32(3), dead[0].Attempts, "Unexpected dead message attempts")
+	assert.Equal(t, tests.ErrBypassTest.Error(), dead[0].LastError, "Unexpected dead message error")
+
+	dispatcher.RetryBackoff = time.Hour
+	sender.failures["later"] = 1
+	assert.Nil(t, outbox.Enqueue(ctx, []common.OutboxMessage{{ChatID: 3, Body: []byte("later")}}), "Unexpected Enqueue error")
+	stats, err = dispatcher.Dispatch(ctx)
+	assert.Nil(t, err, "Unexpected Dispatch error")
+	assert.Equal(t, Stats{Retried: 1}, stats, "Retry shouldn't happen before the backoff")
+	clock.Add(time.Hour)
+	stats, err = dispatcher.Dispatch(ctx)
+	assert.Nil(t, err, "Unexpected Dispatch error")
+	assert.Equal(t, Stats{Sent: 1}, stats, "Message should be retried after the backoff")
+
+	outbox.failLease = true
+	_, err = dispatcher.Dispatch(ctx)
+	assert.Equal(t, tests.ErrBypassTest, err, "Lease error should stop the dispatch")
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = dispatcher.Dispatch(canceledCtx)
+	assert.Equal(t, common.ErrClosedContext, err, "Closed context should stop the dispatch")
+}
+
+func TestGetRetryBackoff(t *testing.T) {
+	dispatcher := NewDispatcher(nil, nil)
+	assert.Equal(t, DefaultRetryBackoff, dispatcher.getRetryBackoff(1), "Unexpected backoff after the first attempt")
+	assert.Equal(t, 4*DefaultRetryBackoff, dispatcher.getRetryBackoff(3), "Backoff should be doubled for every attempt")
+	assert.Equal(t, maxRetryBackoff, dispatcher.getRetryBackoff(100), "Backoff should be limited")
+}
@@ -3,9 +3,11 @@ package storage
 import (
 	"context"
 	"errors"
-	"fmt"
+	"time"
 
 	"github.com/dartkron/leetcodeBot/v3/internal/common"
+	"github.com/dartkron/leetcodeBot/v3/internal/config"
+	"github.com/dartkron/leetcodeBot/v3/internal/logging"
 )
 
 // ErrNoSuchTask returns when storage works, but such task is not found in the storage and the cache
@@ -17,6 +19,12 @@ var ErrNoSuchUser = errors.New("no such user")
 // ErrUserAlreadySubscribed when user already subscribed
 var ErrUserAlreadySubscribed = errors.New("the user is already subscribed for receiving daily tasks at same time, nothing to do")
 
+// ErrSendingTimeOccupied when user already has the sending time of another kind at the same minute
+var ErrSendingTimeOccupied = errors.New("the user already has the sending time of another kind at same time")
+
+// ErrNoSuchSendingTime when user hasn't the sending time to remove
+var ErrNoSuchSendingTime = errors.New("no such sending time")
+
 // ErrUserAlreadyUnsubscribed when user already unsubscribed or were newer subscribed before
 var ErrUserAlreadyUnsubscribed = errors.New("already unsubscribed, nothing to do")
 
@@ -28,26 +36,66 @@ var ErrNoActiveUsersStorage = errors.New("users storage isn't configured")
 // ErrNoActiveTasksStorage by some reasong storage could not work
 var ErrNoActiveTasksStorage = errors.New("tasks storage isn't configured or not available")
 
+// ErrStorageUnavailable returns when YDB failed too many times in a row and queries aren't sent for a while
+var ErrStorageUnavailable = errors.New("storage is unavailable")
+
 type tasksStorekeeper interface {
 	getTask(context.Context, uint64) (common.BotLeetCodeTask, error)
 	saveTask(context.Context, common.BotLeetCodeTask) error
+	getQuestion(context.Context, uint64) (common.BotLeetCodeTask, error)
+	getQuestionBySlug(context.Context, string) (common.BotLeetCodeTask, error)
+	saveQuestion(context.Context, common.BotLeetCodeTask) error
 }
 
 type usersStorekeeper interface {
 	getUser(context.Context, uint64) (common.User, error)
 	saveUser(context.Context, common.User) error
-	subscribeUser(context.Context, uint64, uint8) error
+	addSendingTime(context.Context, uint64, common.SendingTime) error
+	removeSendingTime(context.Context, uint64, uint16) error
+	clearSendingTimes(context.Context, uint64) error
 	unsubscribeUser(context.Context, uint64) error
-	getSubscribedUsers(context.Context, uint8) ([]common.User, error)
+	linkLeetcodeUsername(context.Context, uint64, string) error
+	setTimeZone(context.Context, uint64, string) error
+	setWeekdays(context.Context, uint64, common.Weekdays) error
+	setPausedUntil(context.Context, uint64, uint64) error
+	setDifficulties(context.Context, uint64, common.Difficulties) error
+	setDifficultyFallback(context.Context, uint64, bool) error
+	setCodeLanguage(context.Context, uint64, string) error
+	getSubscribedTimeZones(context.Context) ([]string, error)
+	getSubscribedUsers(context.Context, string, common.MinuteRange, time.Time) ([]common.User, error)
+	subscribeUserToNudge(context.Context, uint64, uint8) error
+	unsubscribeUserFromNudge(context.Context, uint64) error
+	getNudgeUsers(context.Context, string, uint8, uint64) ([]common.User, error)
+	markNudgeSent(context.Context, uint64, uint64) error
+	getDeliveries(context.Context, uint64) ([]common.Delivery, error)
+	saveDelivery(context.Context, common.Delivery) error
 }
 
 // Controller should hide logic of storage layers inside
 type Controller interface {
 	GetTask(context.Context, uint64) (common.BotLeetCodeTask, error)
 	SaveTask(context.Context, common.BotLeetCodeTask) error
-	SubscribeUser(context.Context, common.User, uint8) error
+	GetQuestion(context.Context, uint64) (common.BotLeetCodeTask, error)
+	GetQuestionBySlug(context.Context, string) (common.BotLeetCodeTask, error)
+	SaveQuestion(context.Context, common.BotLeetCodeTask) error
+	SubscribeUser(context.Context, common.User, common.SendingTime) error
+	RemoveSendingTime(context.Context, uint64, uint16) error
 	UnsubscribeUser(context.Context, uint64) error
-	GetSubscribedUsers(context.Context, uint8) ([]common.User, error)
+	GetSubscribedUsers(context.Context, time.Time, time.Duration) ([]common.User, error)
+	GetUser(context.Context, uint64) (common.User, error)
+	LinkLeetcodeUsername(context.Context, common.User, string) error
+	SetUserTimeZone(context.Context, common.User, string) error
+	SetUserWeekdays(context.Context, common.User, common.Weekdays) error
+	SetUserPausedUntil(context.Context, common.User, uint64) error
+	SetUserDifficulties(context.Context, common.User, common.Difficulties) error
+	SetUserDifficultyFallback(context.Context, common.User, bool) error
+	SetUserCodeLanguage(context.Context, common.User, string) error
+	SubscribeUserToNudge(context.Context, common.User, uint8) error
+	UnsubscribeUserFromNudge(context.Context, uint64) error
+	GetNudgeUsers(context.Context, time.Time, time.Duration, uint64) ([]common.User, error)
+	MarkNudgeSent(context.Context, uint64, uint64) error
+	GetDeliveries(context.Context, uint64) ([]common.Delivery, error)
+	SaveDelivery(context.Context, common.Delivery) error
 }
 
 // YDBandFileCacheController is an instance of Controller which store users in database and store tasks into cache AND database
@@ -92,21 +140,23 @@ func (s *YDBandFileCacheController) GetTask(ctx context.Context, dateID uint64)
 	task, err := s.getTaskFromCache(ctx, dateID)
 	if err != nil {
 		if err != ErrNoSuchTask {
-			fmt.Printf("Error on geting task from cache: %q. Fallback to database.\n", err)
+			logging.FromContext(ctx).Warn("Error on getting task from cache, fallback to database", "dateID", dateID, "error", err)
 		}
 		task, err := s.getTaskFromDB(ctx, dateID)
 		if err != nil {
 			return task, err
 		}
+		TaskSourceHits.WithLabelValues(KindDailyTask, SourceYDB).Inc()
 
 		// Since there were no such task in cache, let's add it
 		err = s.saveTaskToCache(ctx, task)
 		if err != nil {
 			// Have cache is a good idea, but no reason to stop show if it's broken
-			fmt.Printf("Error on saving task to cache:: %q\n", err)
+			logging.FromContext(ctx).Warn("Error on saving task to cache", "dateID", task.DateID, "error", err)
 		}
 		return task, nil
 	}
+	TaskSourceHits.WithLabelValues(KindDailyTask, SourceFileCache).Inc()
 	return task, err
 }
 
@@ -114,32 +164,134 @@ func (s *YDBandFileCacheController) GetTask(ctx context.Context, dateID uint64)
 func (s *YDBandFileCacheController) SaveTask(ctx context.Context, task common.BotLeetCodeTask) error {
 	err := s.saveTaskToCache(ctx, task)
 	if err != nil {
-		fmt.Printf("Error on saving task to cache:: %q\n", err)
+		logging.FromContext(ctx).Warn("Error on saving task to cache", "dateID", task.DateID, "error", err)
 	}
 	return s.saveTaskToDB(ctx, task)
 }
 
-// SubscribeUser subscribe and create user in storage if necessary.
-// Returns ErrNoSuchUser ErrUserAlreadySubscribed if user were already subscribed
-func (s *YDBandFileCacheController) SubscribeUser(ctx context.Context, user common.User, sendingHour uint8) error {
+// GetQuestion retrive not daily task by LeetCode questionID from all layers of storage in order and return ErrNoSuchTask if task isn't found
+func (s *YDBandFileCacheController) GetQuestion(ctx context.Context, questionID uint64) (common.BotLeetCodeTask, error) {
+	return s.getQuestionFromAllLayers(ctx, func(storage tasksStorekeeper) (common.BotLeetCodeTask, error) {
+		return storage.getQuestion(ctx, questionID)
+	})
+}
+
+// GetQuestionBySlug retrive not daily task by LeetCode titleSlug from all layers of storage in order and return ErrNoSuchTask if task isn't found
+func (s *YDBandFileCacheController) GetQuestionBySlug(ctx context.Context, titleSlug string) (common.BotLeetCodeTask, error) {
+	return s.getQuestionFromAllLayers(ctx, func(storage tasksStorekeeper) (common.BotLeetCodeTask, error) {
+		return storage.getQuestionBySlug(ctx, titleSlug)
+	})
+}
+
+func (s *YDBandFileCacheController) getQuestionFromAllLayers(ctx context.Context, getQuestion func(tasksStorekeeper) (common.BotLeetCodeTask, error)) (common.BotLeetCodeTask, error) {
+	if s.tasksCache != nil {
+		task, err := getQuestion(s.tasksCache)
+		if err == nil {
+			TaskSourceHits.WithLabelValues(KindQuestion, SourceFileCache).Inc()
+			return task, nil
+		}
+		if err != ErrNoSuchTask {
+			logging.FromContext(ctx).Warn("Error on getting question from cache, fallback to database", "error", err)
+		}
+	}
+	if s.tasksDB == nil {
+		return common.BotLeetCodeTask{}, ErrNoSuchTask
+	}
+	task, err := getQuestion(s.tasksDB)
+	if err != nil {
+		return task, err
+	}
+	TaskSourceHits.WithLabelValues(KindQuestion, SourceYDB).Inc()
+	if s.tasksCache != nil {
+		err = s.tasksCache.saveQuestion(ctx, task)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Error on saving question to cache", "questionID", task.QuestionID, "error", err)
+		}
+	}
+	return task, nil
+}
+
+// SaveQuestion save not daily task to all layers of storage
+func (s *YDBandFileCacheController) SaveQuestion(ctx context.Context, task common.BotLeetCodeTask) error {
+	if s.tasksCache != nil {
+		err := s.tasksCache.saveQuestion(ctx, task)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Error on saving question to cache", "questionID", task.QuestionID, "error", err)
+		}
+	}
+	if s.tasksDB == nil {
+		return nil
+	}
+	return s.tasksDB.saveQuestion(ctx, task)
+}
+
+// SubscribeUser adds sending time to the user, subscribe and create user in storage if necessary.
+// Returns ErrUserAlreadySubscribed if user were already subscribed for the same time and kind,
+// ErrSendingTimeOccupied if the time is taken by another kind, as sending times are unique by the minute
+func (s *YDBandFileCacheController) SubscribeUser(ctx context.Context, user common.User, sendingTime common.SendingTime) error {
 	if s.usersDB == nil {
 		return ErrNoActiveUsersStorage
 	}
 	storedUser, err := s.usersDB.getUser(ctx, user.ID)
+	if err != nil {
+		if err != ErrNoSuchUser {
+			return err
+		}
+		user.Subscribed = true
+		user.SendingTimes = nil
+		err = s.usersDB.saveUser(ctx, user)
+		if err != nil {
+			return err
+		}
+		return s.usersDB.addSendingTime(ctx, user.ID, sendingTime)
+	}
+	if storedUser.Subscribed {
+		for _, storedTime := range storedUser.SendingTimes {
+			if storedTime == sendingTime {
+				return ErrUserAlreadySubscribed
+			}
+			if storedTime.Minute == sendingTime.Minute {
+				return ErrSendingTimeOccupied
+			}
+		}
+	}
+	return s.usersDB.addSendingTime(ctx, user.ID, sendingTime)
+}
+
+// RemoveSendingTime removes sending time of the user at the minute and unsubscribe user if it was the last one.
+// Returns ErrNoSuchSendingTime if user hasn't such sending time
+func (s *YDBandFileCacheController) RemoveSendingTime(ctx context.Context, userID uint64, minute uint16) error {
+	if s.usersDB == nil {
+		return ErrNoActiveUsersStorage
+	}
+	user, err := s.usersDB.getUser(ctx, userID)
 	if err != nil {
 		if err == ErrNoSuchUser {
-			user.Subscribed = true
-			err = s.usersDB.saveUser(ctx, user)
+			err = ErrNoSuchSendingTime
 		}
 		return err
 	}
-	if storedUser.Subscribed && storedUser.SendingHour == sendingHour {
-		return ErrUserAlreadySubscribed
+	found := false
+	for _, sendingTime := range user.SendingTimes {
+		if sendingTime.Minute == minute {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return ErrNoSuchSendingTime
+	}
+	err = s.usersDB.removeSendingTime(ctx, userID, minute)
+	if err != nil {
+		return err
+	}
+	if len(user.SendingTimes) == 1 && user.Subscribed {
+		return s.usersDB.unsubscribeUser(ctx, userID)
 	}
-	return s.usersDB.subscribeUser(ctx, user.ID, sendingHour)
+	return nil
 }
 
-// UnsubscribeUser unsubscribing user with userID.
+// UnsubscribeUser unsubscribing user with userID and forget all sending times.
 // Returns ErrUserAlreadyUnsubscribed if user were already subscribed
 func (s *YDBandFileCacheController) UnsubscribeUser(ctx context.Context, userID uint64) error {
 	if s.usersDB == nil {
@@ -155,23 +307,273 @@ func (s *YDBandFileCacheController) UnsubscribeUser(ctx context.Context, userID
 	if !user.Subscribed {
 		return ErrUserAlreadyUnsubscribed
 	}
-	return s.usersDB.unsubscribeUser(ctx, user.ID)
+	err = s.usersDB.unsubscribeUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	return s.usersDB.clearSendingTimes(ctx, user.ID)
 }
 
 // GetSubscribedUsers necessary when we need to send notification to all subscribed users
-func (s *YDBandFileCacheController) GetSubscribedUsers(ctx context.Context, sendingHour uint8) ([]common.User, error) {
+// Returns users whose local sending times fall into the slot which starts at now, with only matched SendingTimes.
+// Users who have excluded the local weekday or paused delivery are skipped
+func (s *YDBandFileCacheController) GetSubscribedUsers(ctx context.Context, now time.Time, slot time.Duration) ([]common.User, error) {
+	if s.usersDB == nil {
+		return []common.User{}, ErrNoActiveUsersStorage
+	}
+	return s.getUsersForLocalRanges(ctx, now, slot, s.usersDB.getSubscribedUsers)
+}
+
+// getUsersForLocalRanges converts the slot of now into local minutes ranges of every subscribers time zone and collects users for them
+func (s *YDBandFileCacheController) getUsersForLocalRanges(ctx context.Context, now time.Time, slot time.Duration, getUsers func(context.Context, string, common.MinuteRange, time.Time) ([]common.User, error)) ([]common.User, error) {
+	timeZones, err := s.usersDB.getSubscribedTimeZones(ctx)
+	if err != nil {
+		return []common.User{}, err
+	}
+	users := []common.User{}
+	for _, timeZone := range timeZones {
+		location, err := common.LoadLocation(timeZone)
+		if err != nil {
+			// Time zones are checked before saving, so it could be only removed from tzdata one
+			logging.FromContext(ctx).Warn("Skip users with unknown time zone", "timeZone", timeZone, "error", err)
+			continue
+		}
+		for _, minuteRange := range common.GetLocalMinuteRanges(now, location, slot) {
+			rangeUsers, err := getUsers(ctx, timeZone, minuteRange, common.GetLocalRangeDate(now, location, slot, minuteRange))
+			if err != nil {
+				return []common.User{}, err
+			}
+			users = append(users, rangeUsers...)
+		}
+	}
+	return users, nil
+}
+
+// GetUser returns stored user or ErrNoSuchUser if the user has never been saved
+func (s *YDBandFileCacheController) GetUser(ctx context.Context, userID uint64) (common.User, error) {
+	if s.usersDB == nil {
+		return common.User{}, ErrNoActiveUsersStorage
+	}
+	return s.usersDB.getUser(ctx, userID)
+}
+
+// LinkLeetcodeUsername stores LeetCode username for the user and create user in storage if necessary.
+// Empty leetcodeUsername unlinks the profile.
+func (s *YDBandFileCacheController) LinkLeetcodeUsername(ctx context.Context, user common.User, leetcodeUsername string) error {
+	if s.usersDB == nil {
+		return ErrNoActiveUsersStorage
+	}
+	_, err := s.usersDB.getUser(ctx, user.ID)
+	if err != nil {
+		if err == ErrNoSuchUser {
+			user.LeetcodeUsername = leetcodeUsername
+			err = s.usersDB.saveUser(ctx, user)
+		}
+		return err
+	}
+	return s.usersDB.linkLeetcodeUsername(ctx, user.ID, leetcodeUsername)
+}
+
+// SetUserTimeZone stores time zone for the user and create user in storage if necessary.
+// The time zone should be normalized by common.NormalizeTimeZone
+func (s *YDBandFileCacheController) SetUserTimeZone(ctx context.Context, user common.User, timeZone string) error {
+	if s.usersDB == nil {
+		return ErrNoActiveUsersStorage
+	}
+	_, err := s.usersDB.getUser(ctx, user.ID)
+	if err != nil {
+		if err == ErrNoSuchUser {
+			user.TimeZone = timeZone
+			err = s.usersDB.saveUser(ctx, user)
+		}
+		return err
+	}
+	return s.usersDB.setTimeZone(ctx, user.ID, timeZone)
+}
+
+// SetUserWeekdays stores delivery weekdays for the user and create user in storage if necessary
+func (s *YDBandFileCacheController) SetUserWeekdays(ctx context.Context, user common.User, weekdays common.Weekdays) error {
+	if s.usersDB == nil {
+		return ErrNoActiveUsersStorage
+	}
+	_, err := s.usersDB.getUser(ctx, user.ID)
+	if err != nil {
+		if err == ErrNoSuchUser {
+			user.Weekdays = weekdays
+			err = s.usersDB.saveUser(ctx, user)
+		}
+		return err
+	}
+	return s.usersDB.setWeekdays(ctx, user.ID, weekdays)
+}
+
+// SetUserPausedUntil pauses delivery for the user till pausedUntil dateID and create user in storage if necessary.
+// Zero pausedUntil resumes delivery
+func (s *YDBandFileCacheController) SetUserPausedUntil(ctx context.Context, user common.User, pausedUntil uint64) error {
+	if s.usersDB == nil {
+		return ErrNoActiveUsersStorage
+	}
+	_, err := s.usersDB.getUser(ctx, user.ID)
+	if err != nil {
+		if err == ErrNoSuchUser {
+			user.PausedUntil = pausedUntil
+			err = s.usersDB.saveUser(ctx, user)
+		}
+		return err
+	}
+	return s.usersDB.setPausedUntil(ctx, user.ID, pausedUntil)
+}
+
+// SetUserDifficulties stores difficulties of delivered tasks for the user and create user in storage if necessary
+func (s *YDBandFileCacheController) SetUserDifficulties(ctx context.Context, user common.User, difficulties common.Difficulties) error {
+	if s.usersDB == nil {
+		return ErrNoActiveUsersStorage
+	}
+	_, err := s.usersDB.getUser(ctx, user.ID)
+	if err != nil {
+		if err == ErrNoSuchUser {
+			user.Difficulties = difficulties
+			err = s.usersDB.saveUser(ctx, user)
+		}
+		return err
+	}
+	return s.usersDB.setDifficulties(ctx, user.ID, difficulties)
+}
+
+// SetUserDifficultyFallback stores whether the user wants random problem when the daily task difficulty is filtered out
+// and create user in storage if necessary
+func (s *YDBandFileCacheController) SetUserDifficultyFallback(ctx context.Context, user common.User, difficultyFallback bool) error {
+	if s.usersDB == nil {
+		return ErrNoActiveUsersStorage
+	}
+	_, err := s.usersDB.getUser(ctx, user.ID)
+	if err != nil {
+		if err == ErrNoSuchUser {
+			user.DifficultyFallback = difficultyFallback
+			err = s.usersDB.saveUser(ctx, user)
+		}
+		return err
+	}
+	return s.usersDB.setDifficultyFallback(ctx, user.ID, difficultyFallback)
+}
+
+// SetUserCodeLanguage stores preferred programming language for the user and create user in storage if necessary
+func (s *YDBandFileCacheController) SetUserCodeLanguage(ctx context.Context, user common.User, codeLanguage string) error {
+	if s.usersDB == nil {
+		return ErrNoActiveUsersStorage
+	}
+	_, err := s.usersDB.getUser(ctx, user.ID)
+	if err != nil {
+		if err == ErrNoSuchUser {
+			user.CodeLanguage = codeLanguage
+			err = s.usersDB.saveUser(ctx, user)
+		}
+		return err
+	}
+	return s.usersDB.setCodeLanguage(ctx, user.ID, codeLanguage)
+}
+
+// SubscribeUserToNudge enables evening nudge at nudgeHour and create user in storage if necessary.
+// Returns ErrUserAlreadySubscribed if user were already subscribed for the same hour
+func (s *YDBandFileCacheController) SubscribeUserToNudge(ctx context.Context, user common.User, nudgeHour uint8) error {
+	if s.usersDB == nil {
+		return ErrNoActiveUsersStorage
+	}
+	storedUser, err := s.usersDB.getUser(ctx, user.ID)
+	if err != nil {
+		if err == ErrNoSuchUser {
+			user.NudgeSubscribed = true
+			user.NudgeHour = nudgeHour
+			err = s.usersDB.saveUser(ctx, user)
+		}
+		return err
+	}
+	if storedUser.NudgeSubscribed && storedUser.NudgeHour == nudgeHour {
+		return ErrUserAlreadySubscribed
+	}
+	return s.usersDB.subscribeUserToNudge(ctx, user.ID, nudgeHour)
+}
+
+// UnsubscribeUserFromNudge disables evening nudge for user with userID.
+// Returns ErrUserAlreadyUnsubscribed if nudge were not enabled
+func (s *YDBandFileCacheController) UnsubscribeUserFromNudge(ctx context.Context, userID uint64) error {
+	if s.usersDB == nil {
+		return ErrNoActiveUsersStorage
+	}
+	user, err := s.usersDB.getUser(ctx, userID)
+	if err != nil {
+		if err == ErrNoSuchUser {
+			err = ErrUserAlreadyUnsubscribed
+		}
+		return err
+	}
+	if !user.NudgeSubscribed {
+		return ErrUserAlreadyUnsubscribed
+	}
+	return s.usersDB.unsubscribeUserFromNudge(ctx, user.ID)
+}
+
+// GetNudgeUsers returns linked users whose local nudge hour starts in the slot which starts at now
+// and who haven't got nudge for the dateID yet
+func (s *YDBandFileCacheController) GetNudgeUsers(ctx context.Context, now time.Time, slot time.Duration, dateID uint64) ([]common.User, error) {
 	if s.usersDB == nil {
 		return []common.User{}, ErrNoActiveUsersStorage
 	}
-	return s.usersDB.getSubscribedUsers(ctx, sendingHour)
+	return s.getUsersForLocalRanges(ctx, now, slot, func(ctx context.Context, timeZone string, minuteRange common.MinuteRange, _ time.Time) ([]common.User, error) {
+		users := []common.User{}
+		for nudgeHour := uint8(0); nudgeHour < 24; nudgeHour++ {
+			if !minuteRange.Contains(uint16(nudgeHour) * 60) {
+				continue
+			}
+			hourUsers, err := s.usersDB.getNudgeUsers(ctx, timeZone, nudgeHour, dateID)
+			if err != nil {
+				return []common.User{}, err
+			}
+			users = append(users, hourUsers...)
+		}
+		return users, nil
+	})
+}
+
+// MarkNudgeSent remembers that nudge for the dateID is delivered to the user, so it will not be sent twice
+func (s *YDBandFileCacheController) MarkNudgeSent(ctx context.Context, userID uint64, dateID uint64) error {
+	if s.usersDB == nil {
+		return ErrNoActiveUsersStorage
+	}
+	return s.usersDB.markNudgeSent(ctx, userID, dateID)
+}
+
+// GetDeliveries returns log of deliveries of the dateID daily task to all users
+func (s *YDBandFileCacheController) GetDeliveries(ctx context.Context, dateID uint64) ([]common.Delivery, error) {
+	if s.usersDB == nil {
+		return []common.Delivery{}, ErrNoActiveUsersStorage
+	}
+	return s.usersDB.getDeliveries(ctx, dateID)
+}
+
+// SaveDelivery saves the result of the delivery attempt, the previous result of the same user, dateID and kind is replaced
+func (s *YDBandFileCacheController) SaveDelivery(ctx context.Context, delivery common.Delivery) error {
+	if s.usersDB == nil {
+		return ErrNoActiveUsersStorage
+	}
+	return s.usersDB.saveDelivery(ctx, delivery)
 }
 
 // NewYDBandFileCacheController constructs default storage controller
-func NewYDBandFileCacheController() *YDBandFileCacheController {
-	databaseStorage := newYdbStorage()
+func NewYDBandFileCacheController(cfg *config.Config) *YDBandFileCacheController {
+	databaseStorage := newYdbStorage(cfg)
 	return &YDBandFileCacheController{
 		tasksDB:    databaseStorage,
-		tasksCache: newFileCache(),
+		tasksCache: newFileCache(cfg.CacheDir),
 		usersDB:    databaseStorage,
 	}
 }
+
+// NewYDBController constructs storage controller without file cache, tasks are read and saved only in the database
+func NewYDBController(cfg *config.Config) *YDBandFileCacheController {
+	databaseStorage := newYdbStorage(cfg)
+	return &YDBandFileCacheController{
+		tasksDB: databaseStorage,
+		usersDB: databaseStorage,
+	}
+}
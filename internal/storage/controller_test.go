@@ -5,15 +5,19 @@ import (
 	"fmt"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/dartkron/leetcodeBot/v3/internal/common"
+	"github.com/dartkron/leetcodeBot/v3/internal/config"
 	"github.com/dartkron/leetcodeBot/v3/pkg/leetcodeclient"
 	"github.com/dartkron/leetcodeBot/v3/tests"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
 type MockTasksStorekeeper struct {
 	tasks        map[uint64]common.BotLeetCodeTask
+	questions    map[uint64]common.BotLeetCodeTask
 	callsJournal []string
 	IDToFail     uint64
 }
@@ -37,11 +41,47 @@ func (k *MockTasksStorekeeper) saveTask(ctx context.Context, task common.BotLeet
 	return nil
 }
 
+func (k *MockTasksStorekeeper) getQuestion(ctx context.Context, questionID uint64) (common.BotLeetCodeTask, error) {
+	k.callsJournal = append(k.callsJournal, fmt.Sprintf("getQuestion %d", questionID))
+	if questionID == k.IDToFail {
+		return common.BotLeetCodeTask{}, tests.ErrBypassTest
+	}
+	if task, ok := k.questions[questionID]; ok {
+		return task, nil
+	}
+	return common.BotLeetCodeTask{}, ErrNoSuchTask
+}
+
+func (k *MockTasksStorekeeper) getQuestionBySlug(ctx context.Context, titleSlug string) (common.BotLeetCodeTask, error) {
+	k.callsJournal = append(k.callsJournal, fmt.Sprintf("getQuestionBySlug %s", titleSlug))
+	for _, task := range k.questions {
+		if task.TitleSlug == titleSlug {
+			if task.QuestionID == k.IDToFail {
+				return common.BotLeetCodeTask{}, tests.ErrBypassTest
+			}
+			return task, nil
+		}
+	}
+	return common.BotLeetCodeTask{}, ErrNoSuchTask
+}
+
+func (k *MockTasksStorekeeper) saveQuestion(ctx context.Context, task common.BotLeetCodeTask) error {
+	k.callsJournal = append(k.callsJournal, fmt.Sprintf("saveQuestion %d", task.QuestionID))
+	if task.QuestionID == k.IDToFail {
+		return tests.ErrBypassTest
+	}
+	k.questions[task.QuestionID] = task
+	return nil
+}
+
 type MockUsersStorekeeper struct {
 	users                      map[uint64]*common.User
 	callsJournal               []string
 	IDToFail                   uint64
 	getSubscribedUsersMustFail bool
+	getTimeZonesMustFail       bool
+	nudgeSent                  map[uint64]uint64
+	deliveries                 []common.Delivery
 }
 
 func (k *MockUsersStorekeeper) getUser(ctx context.Context, userID uint64) (common.User, error) {
@@ -64,14 +104,52 @@ func (k *MockUsersStorekeeper) saveUser(ctx context.Context, user common.User) e
 	return nil
 }
 
-func (k *MockUsersStorekeeper) subscribeUser(ctx context.Context, userID uint64, sendingHour uint8) error {
-	k.callsJournal = append(k.callsJournal, fmt.Sprintf("subscribeUser %d, sendingHour %d", userID, sendingHour))
+func (k *MockUsersStorekeeper) addSendingTime(ctx context.Context, userID uint64, sendingTime common.SendingTime) error {
+	k.callsJournal = append(k.callsJournal, fmt.Sprintf("addSendingTime %d %s %d", userID, sendingTime, sendingTime.Kind))
 	if userID == k.IDToFail {
 		return tests.ErrBypassTest
 	}
+	user, ok := k.users[userID]
+	if !ok {
+		return ErrNoSuchUser
+	}
+	user.Subscribed = true
+	sendingTimes := []common.SendingTime{}
+	for _, storedTime := range user.SendingTimes {
+		if storedTime.Minute != sendingTime.Minute {
+			sendingTimes = append(sendingTimes, storedTime)
+		}
+	}
+	user.SendingTimes = append(sendingTimes, sendingTime)
+	sort.Slice(user.SendingTimes, func(i, j int) bool {
+		return user.SendingTimes[i].Minute < user.SendingTimes[j].Minute
+	})
+	return nil
+}
+
+func (k *MockUsersStorekeeper) removeSendingTime(ctx context.Context, userID uint64, minute uint16) error {
+	k.callsJournal = append(k.callsJournal, fmt.Sprintf("removeSendingTime %d %d", userID, minute))
+	if userID == k.IDToFail {
+		return tests.ErrBypassTest
+	}
+	user, ok := k.users[userID]
+	if !ok {
+		return ErrNoSuchUser
+	}
+	sendingTimes := []common.SendingTime{}
+	for _, storedTime := range user.SendingTimes {
+		if storedTime.Minute != minute {
+			sendingTimes = append(sendingTimes, storedTime)
+		}
+	}
+	user.SendingTimes = sendingTimes
+	return nil
+}
+
+func (k *MockUsersStorekeeper) clearSendingTimes(ctx context.Context, userID uint64) error {
+	k.callsJournal = append(k.callsJournal, fmt.Sprintf("clearSendingTimes %d", userID))
 	if user, ok := k.users[userID]; ok {
-		user.Subscribed = true
-		user.SendingHour = sendingHour
+		user.SendingTimes = nil
 	} else {
 		return ErrNoSuchUser
 	}
@@ -91,39 +169,262 @@ func (k *MockUsersStorekeeper) unsubscribeUser(ctx context.Context, userID uint6
 	return nil
 }
 
-func (k *MockUsersStorekeeper) getSubscribedUsers(ctx context.Context, sendingHour uint8) ([]common.User, error) {
-	k.callsJournal = append(k.callsJournal, fmt.Sprintf("getSubscribedUsers %d", sendingHour))
+func (k *MockUsersStorekeeper) linkLeetcodeUsername(ctx context.Context, userID uint64, leetcodeUsername string) error {
+	k.callsJournal = append(k.callsJournal, fmt.Sprintf("linkLeetcodeUsername %d %s", userID, leetcodeUsername))
+	if userID == k.IDToFail {
+		return tests.ErrBypassTest
+	}
+	if user, ok := k.users[userID]; ok {
+		user.LeetcodeUsername = leetcodeUsername
+	} else {
+		return ErrNoSuchUser
+	}
+	return nil
+}
+
+func (k *MockUsersStorekeeper) setTimeZone(ctx context.Context, userID uint64, timeZone string) error {
+	k.callsJournal = append(k.callsJournal, fmt.Sprintf("setTimeZone %d %s", userID, timeZone))
+	if userID == k.IDToFail {
+		return tests.ErrBypassTest
+	}
+	if user, ok := k.users[userID]; ok {
+		user.TimeZone = timeZone
+	} else {
+		return ErrNoSuchUser
+	}
+	return nil
+}
+
+func (k *MockUsersStorekeeper) setWeekdays(ctx context.Context, userID uint64, weekdays common.Weekdays) error {
+	k.callsJournal = append(k.callsJournal, fmt.Sprintf("setWeekdays %d %d", userID, weekdays))
+	if userID == k.IDToFail {
+		return tests.ErrBypassTest
+	}
+	if user, ok := k.users[userID]; ok {
+		user.Weekdays = weekdays
+	} else {
+		return ErrNoSuchUser
+	}
+	return nil
+}
+
+func (k *MockUsersStorekeeper) setPausedUntil(ctx context.Context, userID uint64, pausedUntil uint64) error {
+	k.callsJournal = append(k.callsJournal, fmt.Sprintf("setPausedUntil %d %d", userID, pausedUntil))
+	if userID == k.IDToFail {
+		return tests.ErrBypassTest
+	}
+	if user, ok := k.users[userID]; ok {
+		user.PausedUntil = pausedUntil
+	} else {
+		return ErrNoSuchUser
+	}
+	return nil
+}
+
+func (k *MockUsersStorekeeper) setDifficulties(ctx context.Context, userID uint64, difficulties common.Difficulties) error {
+	k.callsJournal = append(k.callsJournal, fmt.Sprintf("setDifficulties %d %d", userID, difficulties))
+	if userID == k.IDToFail {
+		return tests.ErrBypassTest
+	}
+	if user, ok := k.users[userID]; ok {
+		user.Difficulties = difficulties
+	} else {
+		return ErrNoSuchUser
+	}
+	return nil
+}
+
+func (k *MockUsersStorekeeper) setDifficultyFallback(ctx context.Context, userID uint64, difficultyFallback bool) error {
+	k.callsJournal = append(k.callsJournal, fmt.Sprintf("setDifficultyFallback %d %t", userID, difficultyFallback))
+	if userID == k.IDToFail {
+		return tests.ErrBypassTest
+	}
+	if user, ok := k.users[userID]; ok {
+		user.DifficultyFallback = difficultyFallback
+	} else {
+		return ErrNoSuchUser
+	}
+	return nil
+}
+
+func (k *MockUsersStorekeeper) setCodeLanguage(ctx context.Context, userID uint64, codeLanguage string) error {
+	k.callsJournal = append(k.callsJournal, fmt.Sprintf("setCodeLanguage %d %s", userID, codeLanguage))
+	if userID == k.IDToFail {
+		return tests.ErrBypassTest
+	}
+	if user, ok := k.users[userID]; ok {
+		user.CodeLanguage = codeLanguage
+	} else {
+		return ErrNoSuchUser
+	}
+	return nil
+}
+
+func (k *MockUsersStorekeeper) getSubscribedTimeZones(ctx context.Context) ([]string, error) {
+	k.callsJournal = append(k.callsJournal, "getSubscribedTimeZones")
+	if k.getTimeZonesMustFail {
+		return []string{}, tests.ErrBypassTest
+	}
+	timeZones := map[string]bool{}
+	for _, user := range k.users {
+		timeZones[user.TimeZone] = true
+	}
+	resp := []string{}
+	for timeZone := range timeZones {
+		resp = append(resp, timeZone)
+	}
+	sort.Strings(resp)
+	return resp, nil
+}
+
+func (k *MockUsersStorekeeper) getSubscribedUsers(ctx context.Context, timeZone string, minuteRange common.MinuteRange, localDate time.Time) ([]common.User, error) {
+	k.callsJournal = append(k.callsJournal, fmt.Sprintf("getSubscribedUsers %q %d-%d %d", timeZone, minuteRange.From, minuteRange.To, common.GetDateID(localDate)))
+	if k.getSubscribedUsersMustFail {
+		return []common.User{}, tests.ErrBypassTest
+	}
+	resp := []common.User{}
+	for _, user := range k.users {
+		if !user.Subscribed || user.TimeZone != timeZone || !user.IsDeliveryDay(localDate) {
+			continue
+		}
+		matchedUser := *user
+		matchedUser.SendingTimes = nil
+		for _, sendingTime := range user.SendingTimes {
+			if minuteRange.Contains(sendingTime.Minute) {
+				matchedUser.SendingTimes = append(matchedUser.SendingTimes, sendingTime)
+			}
+		}
+		if len(matchedUser.SendingTimes) > 0 {
+			resp = append(resp, matchedUser)
+		}
+	}
+	return resp, nil
+}
+
+func (k *MockUsersStorekeeper) subscribeUserToNudge(ctx context.Context, userID uint64, nudgeHour uint8) error {
+	k.callsJournal = append(k.callsJournal, fmt.Sprintf("subscribeUserToNudge %d, nudgeHour %d", userID, nudgeHour))
+	if userID == k.IDToFail {
+		return tests.ErrBypassTest
+	}
+	if user, ok := k.users[userID]; ok {
+		user.NudgeSubscribed = true
+		user.NudgeHour = nudgeHour
+	} else {
+		return ErrNoSuchUser
+	}
+	return nil
+}
+
+func (k *MockUsersStorekeeper) unsubscribeUserFromNudge(ctx context.Context, userID uint64) error {
+	k.callsJournal = append(k.callsJournal, fmt.Sprintf("unsubscribeUserFromNudge %d", userID))
+	if userID == k.IDToFail {
+		return tests.ErrBypassTest
+	}
+	if user, ok := k.users[userID]; ok {
+		user.NudgeSubscribed = false
+	} else {
+		return ErrNoSuchUser
+	}
+	return nil
+}
+
+func (k *MockUsersStorekeeper) getNudgeUsers(ctx context.Context, timeZone string, nudgeHour uint8, dateID uint64) ([]common.User, error) {
+	k.callsJournal = append(k.callsJournal, fmt.Sprintf("getNudgeUsers %q %d %d", timeZone, nudgeHour, dateID))
 	if k.getSubscribedUsersMustFail {
 		return []common.User{}, tests.ErrBypassTest
 	}
 	resp := []common.User{}
 	for _, user := range k.users {
-		if user.Subscribed {
+		if user.NudgeSubscribed && user.TimeZone == timeZone && user.NudgeHour == nudgeHour && user.LeetcodeUsername != "" && k.nudgeSent[user.ID] != dateID {
 			resp = append(resp, *user)
 		}
 	}
 	return resp, nil
 }
 
+func (k *MockUsersStorekeeper) getDeliveries(ctx context.Context, dateID uint64) ([]common.Delivery, error) {
+	k.callsJournal = append(k.callsJournal, fmt.Sprintf("getDeliveries %d", dateID))
+	if k.getSubscribedUsersMustFail {
+		return []common.Delivery{}, tests.ErrBypassTest
+	}
+	resp := []common.Delivery{}
+	for _, delivery := range k.deliveries {
+		if delivery.DateID == dateID {
+			resp = append(resp, delivery)
+		}
+	}
+	return resp, nil
+}
+
+func (k *MockUsersStorekeeper) saveDelivery(ctx context.Context, delivery common.Delivery) error {
+	k.callsJournal = append(k.callsJournal, fmt.Sprintf("saveDelivery %d %d %s", delivery.UserID, delivery.DateID, delivery.Kind))
+	if delivery.UserID == k.IDToFail {
+		return tests.ErrBypassTest
+	}
+	for i, saved := range k.deliveries {
+		if saved.UserID == delivery.UserID && saved.DateID == delivery.DateID && saved.Kind == delivery.Kind {
+			k.deliveries[i] = delivery
+			return nil
+		}
+	}
+	k.deliveries = append(k.deliveries, delivery)
+	return nil
+}
+
+func (k *MockUsersStorekeeper) markNudgeSent(ctx context.Context, userID uint64, dateID uint64) error {
+	k.callsJournal = append(k.callsJournal, fmt.Sprintf("markNudgeSent %d %d", userID, dateID))
+	if userID == k.IDToFail {
+		return tests.ErrBypassTest
+	}
+	k.nudgeSent[userID] = dateID
+	return nil
+}
+
 func TestNewYDBandFileCacheController(t *testing.T) {
-	storageController := NewYDBandFileCacheController()
+	storageController := NewYDBandFileCacheController(config.Default())
 	assert.NotNil(t, storageController.tasksCache, "NewYDBandFileCacheController should set tasksCache")
 	assert.NotNil(t, storageController.tasksDB, "NewYDBandFileCacheController should set tasksDB")
 	assert.NotNil(t, storageController.usersDB, "NewYDBandFileCacheController should set usersDB")
 }
 
+func TestNewYDBController(t *testing.T) {
+	storageController := NewYDBController(config.Default())
+	assert.Nil(t, storageController.tasksCache, "NewYDBController shouldn't set tasksCache")
+	assert.NotNil(t, storageController.tasksDB, "NewYDBController should set tasksDB")
+	assert.NotNil(t, storageController.usersDB, "NewYDBController should set usersDB")
+}
+
 func TestNotConfiguredStorage(t *testing.T) {
 	storageController := YDBandFileCacheController{}
 	storageController.tasksCache = nil
 	storageController.tasksDB = nil
 	storageController.usersDB = nil
 	assert.Equal(t, storageController.UnsubscribeUser(context.Background(), 3435), ErrNoActiveUsersStorage, "UnsubscribeUser should return ErrNoActiveUsersStorage when users storage isn't set")
-	assert.Equal(t, storageController.SubscribeUser(context.Background(), common.User{}, 7), ErrNoActiveUsersStorage, "SubscribeUser should return ErrNoActiveUsersStorage when users storage isn't set")
-	_, err := storageController.GetSubscribedUsers(context.Background(), 7)
+	assert.Equal(t, storageController.SubscribeUser(context.Background(), common.User{}, common.SendingTime{Minute: 420}), ErrNoActiveUsersStorage, "SubscribeUser should return ErrNoActiveUsersStorage when users storage isn't set")
+	assert.Equal(t, storageController.RemoveSendingTime(context.Background(), 3435, 420), ErrNoActiveUsersStorage, "RemoveSendingTime should return ErrNoActiveUsersStorage when users storage isn't set")
+	_, err := storageController.GetSubscribedUsers(context.Background(), time.Now(), common.DefaultSendingSlot)
 	assert.Equal(t, err, ErrNoActiveUsersStorage, "GetSubscribedUsers should return ErrNoActiveUsersStorage when users storage isn't set")
+	_, err = storageController.GetUser(context.Background(), 3435)
+	assert.Equal(t, err, ErrNoActiveUsersStorage, "GetUser should return ErrNoActiveUsersStorage when users storage isn't set")
+	assert.Equal(t, storageController.LinkLeetcodeUsername(context.Background(), common.User{}, "test"), ErrNoActiveUsersStorage, "LinkLeetcodeUsername should return ErrNoActiveUsersStorage when users storage isn't set")
+	assert.Equal(t, storageController.SubscribeUserToNudge(context.Background(), common.User{}, 19), ErrNoActiveUsersStorage, "SubscribeUserToNudge should return ErrNoActiveUsersStorage when users storage isn't set")
+	assert.Equal(t, storageController.UnsubscribeUserFromNudge(context.Background(), 3435), ErrNoActiveUsersStorage, "UnsubscribeUserFromNudge should return ErrNoActiveUsersStorage when users storage isn't set")
+	_, err = storageController.GetNudgeUsers(context.Background(), time.Now(), common.DefaultSendingSlot, 12312)
+	assert.Equal(t, err, ErrNoActiveUsersStorage, "GetNudgeUsers should return ErrNoActiveUsersStorage when users storage isn't set")
+	assert.Equal(t, storageController.SetUserTimeZone(context.Background(), common.User{}, "Europe/Berlin"), ErrNoActiveUsersStorage, "SetUserTimeZone should return ErrNoActiveUsersStorage when users storage isn't set")
+	assert.Equal(t, err, ErrNoActiveUsersStorage, "GetNudgeUsers should return ErrNoActiveUsersStorage when users storage isn't set")
+	assert.Equal(t, storageController.MarkNudgeSent(context.Background(), 3435, 12312), ErrNoActiveUsersStorage, "MarkNudgeSent should return ErrNoActiveUsersStorage when users storage isn't set")
+	_, err = storageController.GetDeliveries(context.Background(), 12312)
+	assert.Equal(t, err, ErrNoActiveUsersStorage, "GetDeliveries should return ErrNoActiveUsersStorage when users storage isn't set")
+	assert.Equal(t, storageController.SaveDelivery(context.Background(), common.Delivery{}), ErrNoActiveUsersStorage, "SaveDelivery should return ErrNoActiveUsersStorage when users storage isn't set")
 	assert.Nil(t, storageController.SaveTask(context.Background(), common.BotLeetCodeTask{}), "Unexpected error from SaveTask with unconfigured storage")
 	_, err = storageController.GetTask(context.Background(), 12312)
 	assert.Equal(t, err, ErrNoSuchTask, "Unexpected error from GetTask with unconfigured storage")
+	assert.Nil(t, storageController.SaveQuestion(context.Background(), common.BotLeetCodeTask{}), "Unexpected error from SaveQuestion with unconfigured storage")
+	_, err = storageController.GetQuestion(context.Background(), 12312)
+	assert.Equal(t, err, ErrNoSuchTask, "Unexpected error from GetQuestion with unconfigured storage")
+	_, err = storageController.GetQuestionBySlug(context.Background(), "two-sum")
+	assert.Equal(t, err, ErrNoSuchTask, "Unexpected error from GetQuestionBySlug with unconfigured storage")
 }
 
 func getTestController() (*YDBandFileCacheController, *MockTasksStorekeeper, *MockTasksStorekeeper) {
@@ -141,6 +442,18 @@ func getTestController() (*YDBandFileCacheController, *MockTasksStorekeeper, *Mo
 				DateID: 12345,
 			},
 		},
+		questions: map[uint64]common.BotLeetCodeTask{
+			15: {
+				LeetCodeTask: leetcodeclient.LeetCodeTask{
+					QuestionID: 15,
+					TitleSlug:  "3sum",
+					Title:      "3Sum",
+					Content:    "Cached content",
+					Hints:      []string{"Cached hint"},
+					Difficulty: "Medium",
+				},
+			},
+		},
 	}
 	DBStorage := MockTasksStorekeeper{
 		tasks: map[uint64]common.BotLeetCodeTask{
@@ -167,6 +480,28 @@ func getTestController() (*YDBandFileCacheController, *MockTasksStorekeeper, *Mo
 				DateID: 12346,
 			},
 		},
+		questions: map[uint64]common.BotLeetCodeTask{
+			15: {
+				LeetCodeTask: leetcodeclient.LeetCodeTask{
+					QuestionID: 15,
+					TitleSlug:  "3sum",
+					Title:      "3Sum",
+					Content:    "Database content",
+					Hints:      []string{"Database hint"},
+					Difficulty: "Medium",
+				},
+			},
+			16: {
+				LeetCodeTask: leetcodeclient.LeetCodeTask{
+					QuestionID: 16,
+					TitleSlug:  "3sum-closest",
+					Title:      "3Sum Closest",
+					Content:    "Closest content",
+					Hints:      []string{},
+					Difficulty: "Medium",
+				},
+			},
+		},
 	}
 
 	return &YDBandFileCacheController{
@@ -179,8 +514,10 @@ func getTestController() (*YDBandFileCacheController, *MockTasksStorekeeper, *Mo
 
 func TestGetTaskFromCache(t *testing.T) {
 	storageController, cacheStorage, DBStorage := getTestController()
+	hits := testutil.ToFloat64(TaskSourceHits.WithLabelValues(KindDailyTask, SourceFileCache))
 	// Get task from cache
 	task, err := storageController.GetTask(context.Background(), 12345)
+	assert.Equal(t, hits+1, testutil.ToFloat64(TaskSourceHits.WithLabelValues(KindDailyTask, SourceFileCache)), "Cache hit should be counted")
 	assert.Nil(t, err, "Unexpected GetTask error")
 	assert.Equal(t, task, cacheStorage.tasks[12345], "Received task differs with task in storage")
 	assert.Empty(t, DBStorage.callsJournal, "Datase shoudn't be called when task persists in the cache")
@@ -189,8 +526,10 @@ func TestGetTaskFromCache(t *testing.T) {
 
 func TestGetTaskFromDBMissedInCache(t *testing.T) {
 	storageController, cacheStorage, DBStorage := getTestController()
+	hits := testutil.ToFloat64(TaskSourceHits.WithLabelValues(KindDailyTask, SourceYDB))
 	// Get task from DB and check that it will be saved in cache
 	task, err := storageController.GetTask(context.Background(), 12346)
+	assert.Equal(t, hits+1, testutil.ToFloat64(TaskSourceHits.WithLabelValues(KindDailyTask, SourceYDB)), "YDB hit should be counted")
 	assert.Nil(t, err, "Unexpected GetTask error")
 	assert.Equal(t, task, DBStorage.tasks[12346], "Received task differs with task in storage")
 	cacheTask, ok := cacheStorage.tasks[12346]
@@ -308,9 +647,80 @@ func TestSaveTaskToDBAndReplace(t *testing.T) {
 	assert.Equal(t, cacheStorage.callsJournal, []string{"saveTask 12345"}, "Unexpected cache call journal")
 }
 
+func TestGetQuestionFromCache(t *testing.T) {
+	storageController, cacheStorage, DBStorage := getTestController()
+	task, err := storageController.GetQuestion(context.Background(), 15)
+	assert.Nil(t, err, "Unexpected GetQuestion error")
+	assert.Equal(t, cacheStorage.questions[15], task, "Received question differs with question in cache")
+	assert.Empty(t, DBStorage.callsJournal, "Datase shoudn't be called when question persists in the cache")
+	assert.Equal(t, []string{"getQuestion 15"}, cacheStorage.callsJournal, "Unexpected cache calls journal")
+}
+
+func TestGetQuestionFromDBMissedInCache(t *testing.T) {
+	storageController, cacheStorage, DBStorage := getTestController()
+	task, err := storageController.GetQuestion(context.Background(), 16)
+	assert.Nil(t, err, "Unexpected GetQuestion error")
+	assert.Equal(t, DBStorage.questions[16], task, "Received question differs with question in storage")
+	assert.Equal(t, task, cacheStorage.questions[16], "Returned question not saved to cache")
+	assert.Equal(t, []string{"getQuestion 16"}, DBStorage.callsJournal, "Unexpected DB calls journal")
+	assert.Equal(t, []string{"getQuestion 16", "saveQuestion 16"}, cacheStorage.callsJournal, "Unexpected cache calls journal")
+}
+
+func TestGetQuestionFromDBWithBrokenCache(t *testing.T) {
+	storageController, cacheStorage, DBStorage := getTestController()
+	cacheStorage.IDToFail = 15
+	task, err := storageController.GetQuestion(context.Background(), 15)
+	assert.Nil(t, err, "Unexpected GetQuestion error")
+	assert.Equal(t, DBStorage.questions[15], task, "Received question differs with question in storage")
+	assert.Equal(t, []string{"getQuestion 15", "saveQuestion 15"}, cacheStorage.callsJournal, "Unexpected cache calls journal")
+}
+
+func TestGetQuestionNotFound(t *testing.T) {
+	storageController, _, DBStorage := getTestController()
+	_, err := storageController.GetQuestion(context.Background(), 17)
+	assert.Equal(t, ErrNoSuchTask, err, "Unexpected GetQuestion error")
+	DBStorage.IDToFail = 17
+	_, err = storageController.GetQuestion(context.Background(), 17)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected GetQuestion error")
+}
+
+func TestGetQuestionBySlug(t *testing.T) {
+	storageController, cacheStorage, DBStorage := getTestController()
+	task, err := storageController.GetQuestionBySlug(context.Background(), "3sum")
+	assert.Nil(t, err, "Unexpected GetQuestionBySlug error")
+	assert.Equal(t, cacheStorage.questions[15], task, "Received question differs with question in cache")
+	assert.Empty(t, DBStorage.callsJournal, "Datase shoudn't be called when question persists in the cache")
+
+	task, err = storageController.GetQuestionBySlug(context.Background(), "3sum-closest")
+	assert.Nil(t, err, "Unexpected GetQuestionBySlug error")
+	assert.Equal(t, DBStorage.questions[16], task, "Received question differs with question in storage")
+	assert.Equal(t, task, cacheStorage.questions[16], "Returned question not saved to cache")
+	assert.Equal(t, []string{"getQuestionBySlug 3sum-closest"}, DBStorage.callsJournal, "Unexpected DB calls journal")
+	assert.Equal(t, []string{"getQuestionBySlug 3sum", "getQuestionBySlug 3sum-closest", "saveQuestion 16"}, cacheStorage.callsJournal, "Unexpected cache calls journal")
+
+	_, err = storageController.GetQuestionBySlug(context.Background(), "4sum")
+	assert.Equal(t, ErrNoSuchTask, err, "Unexpected GetQuestionBySlug error")
+}
+
+func TestSaveQuestion(t *testing.T) {
+	storageController, cacheStorage, DBStorage := getTestController()
+	taskToSave := DBStorage.questions[16]
+	delete(DBStorage.questions, 16)
+	cacheStorage.IDToFail = 16
+	err := storageController.SaveQuestion(context.Background(), taskToSave)
+	assert.Nil(t, err, "Unexpected SaveQuestion error")
+	assert.Equal(t, taskToSave, DBStorage.questions[16], "Question saved in DB differ with sent one")
+	assert.Equal(t, []string{"saveQuestion 16"}, cacheStorage.callsJournal, "Unexpected cache calls journal")
+
+	DBStorage.IDToFail = 16
+	err = storageController.SaveQuestion(context.Background(), taskToSave)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected SaveQuestion error")
+}
+
 func getTestUsersStorekeeper() *MockUsersStorekeeper {
 	return &MockUsersStorekeeper{
 		getSubscribedUsersMustFail: false,
+		nudgeSent:                  map[uint64]uint64{},
 		users: map[uint64]*common.User{
 			1124: {
 				ID:         1124,
@@ -366,12 +776,21 @@ func TestGetSubscribedUsers(t *testing.T) {
 		for _, user := range usersStore.users {
 			if _, ok := testCase[user.ID]; ok {
 				user.Subscribed = true
-				awaitedList = append(awaitedList, *user)
+				user.SendingTimes = []common.SendingTime{{Minute: 420}, {Minute: 435, Kind: common.HintSendingTime}}
+				awaitedList = append(awaitedList, common.User{
+					ID:           user.ID,
+					ChatID:       user.ChatID,
+					Username:     user.Username,
+					FirstName:    user.FirstName,
+					LastName:     user.LastName,
+					Subscribed:   true,
+					SendingTimes: []common.SendingTime{{Minute: 420}},
+				})
 			} else {
 				user.Subscribed = false
 			}
 		}
-		list, err := storageController.GetSubscribedUsers(context.Background(), 7)
+		list, err := storageController.GetSubscribedUsers(context.Background(), time.Date(2021, 10, 13, 7, 0, 0, 0, time.UTC), common.DefaultSendingSlot)
 		assert.Nil(t, err, "Unexpected GetSubscribedUsers error")
 		sort.Slice(list, func(i, j int) bool {
 			return list[i].ID < list[j].ID
@@ -380,7 +799,7 @@ func TestGetSubscribedUsers(t *testing.T) {
 			return awaitedList[i].ID < awaitedList[j].ID
 		})
 		assert.Equal(t, list, awaitedList, "Unxpected users list from GetSubscribedUsers")
-		assert.Equal(t, usersStore.callsJournal, []string{"getSubscribedUsers 7"}, "Unexpected users store call list")
+		assert.Equal(t, usersStore.callsJournal, []string{"getSubscribedTimeZones", "getSubscribedUsers \"\" 420-435 20211013"}, "Unexpected users store call list")
 	}
 }
 
@@ -390,10 +809,50 @@ func TestGetSubscribedUsersWithError(t *testing.T) {
 		usersDB: usersStore,
 	}
 	usersStore.getSubscribedUsersMustFail = true
-	list, err := storageController.GetSubscribedUsers(context.Background(), 7)
+	list, err := storageController.GetSubscribedUsers(context.Background(), time.Date(2021, 10, 13, 7, 0, 0, 0, time.UTC), common.DefaultSendingSlot)
+	assert.Equal(t, err, tests.ErrBypassTest, "Unexpected GetSubscribedUsers error")
+	assert.Equal(t, list, []common.User{}, "Empty list should be returned from GetSubscribedUsers on error")
+	assert.Equal(t, usersStore.callsJournal, []string{"getSubscribedTimeZones", "getSubscribedUsers \"\" 420-435 20211013"}, "Unexpected users store call list")
+
+	usersStore.callsJournal = []string{}
+	usersStore.getTimeZonesMustFail = true
+	list, err = storageController.GetSubscribedUsers(context.Background(), time.Date(2021, 10, 13, 7, 0, 0, 0, time.UTC), common.DefaultSendingSlot)
 	assert.Equal(t, err, tests.ErrBypassTest, "Unexpected GetSubscribedUsers error")
 	assert.Equal(t, list, []common.User{}, "Empty list should be returned from GetSubscribedUsers on error")
-	assert.Equal(t, usersStore.callsJournal, []string{"getSubscribedUsers 7"}, "Unexpected users store call list")
+	assert.Equal(t, usersStore.callsJournal, []string{"getSubscribedTimeZones"}, "Unexpected users store call list")
+}
+
+func TestGetSubscribedUsersInTimeZones(t *testing.T) {
+	usersStore := getTestUsersStorekeeper()
+	storageController := YDBandFileCacheController{
+		usersDB: usersStore,
+	}
+	// Berlin is UTC+2 in summer and UTC+1 in winter, but users get tasks at 9:00 local time anyway
+	usersStore.users[1126].TimeZone = "Europe/Berlin"
+	usersStore.users[1126].SendingTimes = []common.SendingTime{{Minute: 540}}
+	usersStore.users[1120].TimeZone = "UTC-05:00"
+	usersStore.users[1120].SendingTimes = []common.SendingTime{{Minute: 120}, {Minute: 200, Kind: common.HintSendingTime}}
+	usersStore.users[1124].TimeZone = "Broken/Zone"
+	usersStore.users[1124].Subscribed = true
+	usersStore.users[1124].SendingTimes = []common.SendingTime{{Minute: 540}}
+	testCases := map[time.Time][]uint64{
+		time.Date(2021, 7, 13, 7, 0, 0, 0, time.UTC):   {1126, 1120},
+		time.Date(2021, 12, 13, 7, 0, 0, 0, time.UTC):  {1120},
+		time.Date(2021, 12, 13, 8, 0, 0, 0, time.UTC):  {1126},
+		time.Date(2021, 12, 13, 8, 10, 0, 0, time.UTC): {1126},
+		time.Date(2021, 12, 13, 8, 15, 0, 0, time.UTC): {1120},
+		time.Date(2021, 12, 13, 8, 30, 0, 0, time.UTC): {},
+		time.Date(2021, 12, 13, 9, 0, 0, 0, time.UTC):  {},
+	}
+	for now, expectedIDs := range testCases {
+		list, err := storageController.GetSubscribedUsers(context.Background(), now, common.DefaultSendingSlot)
+		assert.Nil(t, err, "Unexpected GetSubscribedUsers error")
+		listIDs := []uint64{}
+		for _, user := range list {
+			listIDs = append(listIDs, user.ID)
+		}
+		assert.Equalf(t, expectedIDs, listIDs, "Unexpected users for %s", now)
+	}
 }
 
 func TestSubscribeUserNew(t *testing.T) {
@@ -409,11 +868,12 @@ func TestSubscribeUserNew(t *testing.T) {
 		LastName:   "1000lastname",
 		Subscribed: false,
 	}
-	err := storageController.SubscribeUser(context.Background(), newUser, 7)
+	err := storageController.SubscribeUser(context.Background(), newUser, common.SendingTime{Minute: 450})
 	assert.Nil(t, err, "Unexpected SubscribeUser error")
 	newUser.Subscribed = true
+	newUser.SendingTimes = []common.SendingTime{{Minute: 450}}
 	assert.Equal(t, *usersStore.users[1000], newUser, "Stored user differ with the sent one")
-	assert.Equal(t, usersStore.callsJournal, []string{"getUser 1000", "saveUser 1000"}, "Unexpected users store call list")
+	assert.Equal(t, usersStore.callsJournal, []string{"getUser 1000", "saveUser 1000", "addSendingTime 1000 07:30 0"}, "Unexpected users store call list")
 }
 
 func TestSubscribeUserOld(t *testing.T) {
@@ -422,12 +882,12 @@ func TestSubscribeUserOld(t *testing.T) {
 		usersDB: usersStore,
 	}
 	user := *usersStore.users[1124]
-	user.SendingHour += 3
-	err := storageController.SubscribeUser(context.Background(), user, user.SendingHour)
+	err := storageController.SubscribeUser(context.Background(), user, common.SendingTime{Minute: 600})
 	assert.Nil(t, err, "Unexpected SubscribeUser error")
 	user.Subscribed = true
+	user.SendingTimes = []common.SendingTime{{Minute: 600}}
 	assert.Equal(t, user, *usersStore.users[1124], "Stored user differ with the sent one")
-	assert.Equal(t, usersStore.callsJournal, []string{"getUser 1124", fmt.Sprintf("subscribeUser 1124, sendingHour %d", user.SendingHour)}, "Unexpected users store call list")
+	assert.Equal(t, usersStore.callsJournal, []string{"getUser 1124", "addSendingTime 1124 10:00 0"}, "Unexpected users store call list")
 }
 
 func TestSubscribeUserAlreadySubscribed(t *testing.T) {
@@ -436,9 +896,9 @@ func TestSubscribeUserAlreadySubscribed(t *testing.T) {
 		usersDB: usersStore,
 	}
 	usersStore.users[1124].Subscribed = true
-	usersStore.users[1124].SendingHour = 7
+	usersStore.users[1124].SendingTimes = []common.SendingTime{{Minute: 420}}
 	userToSend := *usersStore.users[1124]
-	err := storageController.SubscribeUser(context.Background(), userToSend, 7)
+	err := storageController.SubscribeUser(context.Background(), userToSend, common.SendingTime{Minute: 420})
 	assert.Equal(t, err, ErrUserAlreadySubscribed, "Unexpected SubscribeUser error")
 	assert.Equal(t, *usersStore.users[1124], userToSend, "Stored user differ with the sent one")
 	assert.Equal(t, []string{"getUser 1124"}, usersStore.callsJournal, "Unexpected users store call list")
@@ -449,14 +909,56 @@ func TestSubscribeUserOldForDifferentTime(t *testing.T) {
 	storageController := YDBandFileCacheController{
 		usersDB: usersStore,
 	}
-	usersStore.users[1124].SendingHour = 6
+	usersStore.users[1124].Subscribed = true
+	usersStore.users[1124].SendingTimes = []common.SendingTime{{Minute: 420}}
 	user := *usersStore.users[1124]
-	err := storageController.SubscribeUser(context.Background(), user, 7)
+	// Another time adds one more message per day
+	err := storageController.SubscribeUser(context.Background(), user, common.SendingTime{Minute: 750, Kind: common.HintSendingTime})
 	assert.Nil(t, err, "Unexpected SubscribeUser error")
-	user.Subscribed = true
-	user.SendingHour = 7
+	// Same time with another kind would overwrite the stored one, as sending times are unique by the minute
+	err = storageController.SubscribeUser(context.Background(), user, common.SendingTime{Minute: 420, Kind: common.HintSendingTime})
+	assert.Equal(t, ErrSendingTimeOccupied, err, "Unexpected SubscribeUser error")
+	user.SendingTimes = []common.SendingTime{{Minute: 420}, {Minute: 750, Kind: common.HintSendingTime}}
 	assert.Equal(t, user, *usersStore.users[1124], "Stored user differ with the sent one")
-	assert.Equal(t, usersStore.callsJournal, []string{"getUser 1124", "subscribeUser 1124, sendingHour 7"}, "Unexpected users store call list")
+	assert.Equal(
+		t,
+		[]string{"getUser 1124", "addSendingTime 1124 12:30 1", "getUser 1124"},
+		usersStore.callsJournal,
+		"Unexpected users store call list",
+	)
+}
+
+func TestRemoveSendingTime(t *testing.T) {
+	usersStore := getTestUsersStorekeeper()
+	storageController := YDBandFileCacheController{
+		usersDB: usersStore,
+	}
+	usersStore.users[1126].SendingTimes = []common.SendingTime{{Minute: 420}, {Minute: 750, Kind: common.HintSendingTime}}
+	err := storageController.RemoveSendingTime(context.Background(), 1126, 600)
+	assert.Equal(t, ErrNoSuchSendingTime, err, "Unexpected RemoveSendingTime error")
+	err = storageController.RemoveSendingTime(context.Background(), 1000, 600)
+	assert.Equal(t, ErrNoSuchSendingTime, err, "Unexpected RemoveSendingTime error")
+	err = storageController.RemoveSendingTime(context.Background(), 1126, 750)
+	assert.Nil(t, err, "Unexpected RemoveSendingTime error")
+	assert.True(t, usersStore.users[1126].Subscribed, "User with sending times left should stay subscribed")
+	// The last sending time unsubscribes the user
+	err = storageController.RemoveSendingTime(context.Background(), 1126, 420)
+	assert.Nil(t, err, "Unexpected RemoveSendingTime error")
+	assert.False(t, usersStore.users[1126].Subscribed, "User without sending times should be unsubscribed")
+	assert.Equal(t, []common.SendingTime{}, usersStore.users[1126].SendingTimes, "Unexpected sending times left")
+	assert.Equal(
+		t,
+		[]string{"getUser 1126", "getUser 1000", "getUser 1126", "removeSendingTime 1126 750", "getUser 1126", "removeSendingTime 1126 420", "unsubscribeUser 1126"},
+		usersStore.callsJournal,
+		"Unexpected users store call list",
+	)
+
+	usersStore.callsJournal = []string{}
+	usersStore.users[1120].SendingTimes = []common.SendingTime{{Minute: 420}}
+	usersStore.IDToFail = 1120
+	err = storageController.RemoveSendingTime(context.Background(), 1120, 420)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected RemoveSendingTime error")
+	assert.Equal(t, []string{"getUser 1120"}, usersStore.callsJournal, "Unexpected users store call list")
 }
 
 func TestSubscribeUserWithError(t *testing.T) {
@@ -466,7 +968,7 @@ func TestSubscribeUserWithError(t *testing.T) {
 	}
 	usersStore.IDToFail = 1124
 	userToSend := *usersStore.users[1124]
-	err := storageController.SubscribeUser(context.Background(), userToSend, 7)
+	err := storageController.SubscribeUser(context.Background(), userToSend, common.SendingTime{Minute: 420})
 	assert.Equal(t, err, tests.ErrBypassTest, "Unexpected SubscribeUser error")
 	assert.Equal(t, *usersStore.users[1124], userToSend, "Stored user differ with the sent one")
 	assert.Equal(t, []string{"getUser 1124"}, usersStore.callsJournal, "Unexpected users store call list")
@@ -499,12 +1001,14 @@ func TestUnsubscribeUserAlreadySubscribed(t *testing.T) {
 	storageController := YDBandFileCacheController{
 		usersDB: usersStore,
 	}
+	usersStore.users[1126].SendingTimes = []common.SendingTime{{Minute: 420}}
 	user := *usersStore.users[1126]
 	err := storageController.UnsubscribeUser(context.Background(), 1126)
 	assert.Nil(t, err, "Unexpected SubscribeUser error")
 	user.Subscribed = false
+	user.SendingTimes = nil
 	assert.Equal(t, *usersStore.users[1126], user, "Stored user differ with the sent one")
-	assert.Equal(t, usersStore.callsJournal, []string{"getUser 1126", "unsubscribeUser 1126"}, "Unexpected users store call list")
+	assert.Equal(t, usersStore.callsJournal, []string{"getUser 1126", "unsubscribeUser 1126", "clearSendingTimes 1126"}, "Unexpected users store call list")
 }
 
 func TestUnsubscribeUserWithError(t *testing.T) {
@@ -519,3 +1023,321 @@ func TestUnsubscribeUserWithError(t *testing.T) {
 	assert.Equal(t, *usersStore.users[1124], userToSend, "Stored user differ with the sent one")
 	assert.Equal(t, usersStore.callsJournal, []string{"getUser 1124"}, "Unexpected users store call list")
 }
+
+func TestGetUserFromController(t *testing.T) {
+	usersStore := getTestUsersStorekeeper()
+	storageController := YDBandFileCacheController{
+		usersDB: usersStore,
+	}
+	user, err := storageController.GetUser(context.Background(), 1126)
+	assert.Nil(t, err, "Unexpected GetUser error")
+	assert.Equal(t, *usersStore.users[1126], user, "Unexpected user returned")
+	_, err = storageController.GetUser(context.Background(), 1000)
+	assert.Equal(t, ErrNoSuchUser, err, "Unexpected GetUser error")
+	assert.Equal(t, []string{"getUser 1126", "getUser 1000"}, usersStore.callsJournal, "Unexpected users store call list")
+}
+
+func TestLinkLeetcodeUsernameNewUser(t *testing.T) {
+	usersStore := getTestUsersStorekeeper()
+	storageController := YDBandFileCacheController{
+		usersDB: usersStore,
+	}
+	newUser := common.User{
+		ID:        1000,
+		ChatID:    1000,
+		Username:  "newUser1000",
+		FirstName: "1000firstname",
+	}
+	err := storageController.LinkLeetcodeUsername(context.Background(), newUser, "leetcoder")
+	assert.Nil(t, err, "Unexpected LinkLeetcodeUsername error")
+	newUser.LeetcodeUsername = "leetcoder"
+	assert.Equal(t, newUser, *usersStore.users[1000], "Stored user differ with the sent one")
+	assert.Equal(t, []string{"getUser 1000", "saveUser 1000"}, usersStore.callsJournal, "Unexpected users store call list")
+}
+
+func TestLinkLeetcodeUsernameOldUser(t *testing.T) {
+	usersStore := getTestUsersStorekeeper()
+	storageController := YDBandFileCacheController{
+		usersDB: usersStore,
+	}
+	err := storageController.LinkLeetcodeUsername(context.Background(), common.User{ID: 1126}, "leetcoder")
+	assert.Nil(t, err, "Unexpected LinkLeetcodeUsername error")
+	assert.Equal(t, "leetcoder", usersStore.users[1126].LeetcodeUsername, "LeetCode username isn't stored")
+	assert.True(t, usersStore.users[1126].Subscribed, "Link shouldn't change subscription")
+	assert.Equal(t, []string{"getUser 1126", "linkLeetcodeUsername 1126 leetcoder"}, usersStore.callsJournal, "Unexpected users store call list")
+}
+
+func TestLinkLeetcodeUsernameWithError(t *testing.T) {
+	usersStore := getTestUsersStorekeeper()
+	storageController := YDBandFileCacheController{
+		usersDB: usersStore,
+	}
+	usersStore.IDToFail = 1126
+	err := storageController.LinkLeetcodeUsername(context.Background(), common.User{ID: 1126}, "leetcoder")
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected LinkLeetcodeUsername error")
+	assert.Equal(t, []string{"getUser 1126"}, usersStore.callsJournal, "Unexpected users store call list")
+}
+
+func TestSubscribeUserToNudgeNew(t *testing.T) {
+	usersStore := getTestUsersStorekeeper()
+	storageController := YDBandFileCacheController{
+		usersDB: usersStore,
+	}
+	newUser := common.User{
+		ID:        1000,
+		ChatID:    1000,
+		Username:  "newUser1000",
+		FirstName: "1000firstname",
+	}
+	err := storageController.SubscribeUserToNudge(context.Background(), newUser, 19)
+	assert.Nil(t, err, "Unexpected SubscribeUserToNudge error")
+	newUser.NudgeSubscribed = true
+	newUser.NudgeHour = 19
+	assert.Equal(t, newUser, *usersStore.users[1000], "Stored user differ with the sent one")
+	assert.Equal(t, []string{"getUser 1000", "saveUser 1000"}, usersStore.callsJournal, "Unexpected users store call list")
+}
+
+func TestSubscribeUserToNudgeOld(t *testing.T) {
+	usersStore := getTestUsersStorekeeper()
+	storageController := YDBandFileCacheController{
+		usersDB: usersStore,
+	}
+	usersStore.users[1126].NudgeSubscribed = true
+	usersStore.users[1126].NudgeHour = 18
+	err := storageController.SubscribeUserToNudge(context.Background(), common.User{ID: 1126}, 19)
+	assert.Nil(t, err, "Unexpected SubscribeUserToNudge error")
+	assert.Equal(t, uint8(19), usersStore.users[1126].NudgeHour, "Nudge hour isn't stored")
+	assert.True(t, usersStore.users[1126].Subscribed, "Nudge shouldn't change daily task subscription")
+	assert.Equal(t, []string{"getUser 1126", "subscribeUserToNudge 1126, nudgeHour 19"}, usersStore.callsJournal, "Unexpected users store call list")
+
+	usersStore.callsJournal = []string{}
+	err = storageController.SubscribeUserToNudge(context.Background(), common.User{ID: 1126}, 19)
+	assert.Equal(t, ErrUserAlreadySubscribed, err, "Unexpected SubscribeUserToNudge error")
+	assert.Equal(t, []string{"getUser 1126"}, usersStore.callsJournal, "Unexpected users store call list")
+
+	usersStore.callsJournal = []string{}
+	usersStore.IDToFail = 1126
+	err = storageController.SubscribeUserToNudge(context.Background(), common.User{ID: 1126}, 19)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected SubscribeUserToNudge error")
+	assert.Equal(t, []string{"getUser 1126"}, usersStore.callsJournal, "Unexpected users store call list")
+}
+
+func TestUnsubscribeUserFromNudge(t *testing.T) {
+	usersStore := getTestUsersStorekeeper()
+	storageController := YDBandFileCacheController{
+		usersDB: usersStore,
+	}
+	err := storageController.UnsubscribeUserFromNudge(context.Background(), 1000)
+	assert.Equal(t, ErrUserAlreadyUnsubscribed, err, "Unexpected UnsubscribeUserFromNudge error")
+	err = storageController.UnsubscribeUserFromNudge(context.Background(), 1126)
+	assert.Equal(t, ErrUserAlreadyUnsubscribed, err, "Unexpected UnsubscribeUserFromNudge error")
+	usersStore.users[1126].NudgeSubscribed = true
+	err = storageController.UnsubscribeUserFromNudge(context.Background(), 1126)
+	assert.Nil(t, err, "Unexpected UnsubscribeUserFromNudge error")
+	assert.False(t, usersStore.users[1126].NudgeSubscribed, "Nudge should be disabled")
+	assert.True(t, usersStore.users[1126].Subscribed, "Nudge shouldn't change daily task subscription")
+	usersStore.IDToFail = 1126
+	err = storageController.UnsubscribeUserFromNudge(context.Background(), 1126)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected UnsubscribeUserFromNudge error")
+	assert.Equal(
+		t,
+		[]string{"getUser 1000", "getUser 1126", "getUser 1126", "unsubscribeUserFromNudge 1126", "getUser 1126"},
+		usersStore.callsJournal,
+		"Unexpected users store call list",
+	)
+}
+
+func TestDeliveries(t *testing.T) {
+	usersStore := getTestUsersStorekeeper()
+	storageController := YDBandFileCacheController{
+		usersDB: usersStore,
+	}
+	failed := common.Delivery{UserID: 1126, DateID: 20211013, Kind: "task 07:00", Status: common.DeliveryFailed, Attempts: 1, LastError: "test error"}
+	assert.Nil(t, storageController.SaveDelivery(context.Background(), failed), "Unexpected SaveDelivery error")
+	assert.Nil(t, storageController.SaveDelivery(context.Background(), common.Delivery{UserID: 1120, DateID: 20211012, Kind: "task 07:00", Status: common.DeliverySent}), "Unexpected SaveDelivery error")
+	sent := failed
+	sent.Status = common.DeliverySent
+	sent.Attempts = 2
+	sent.MessageID = 42
+	sent.LastError = ""
+	assert.Nil(t, storageController.SaveDelivery(context.Background(), sent), "Unexpected SaveDelivery error")
+	deliveries, err := storageController.GetDeliveries(context.Background(), 20211013)
+	assert.Nil(t, err, "Unexpected GetDeliveries error")
+	assert.Equal(t, []common.Delivery{sent}, deliveries, "Delivery should be replaced by the next attempt")
+	usersStore.IDToFail = 1126
+	assert.Equal(t, tests.ErrBypassTest, storageController.SaveDelivery(context.Background(), sent), "Unexpected SaveDelivery error")
+	usersStore.getSubscribedUsersMustFail = true
+	_, err = storageController.GetDeliveries(context.Background(), 20211013)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected GetDeliveries error")
+}
+
+func TestGetNudgeUsersAndMarkNudgeSent(t *testing.T) {
+	usersStore := getTestUsersStorekeeper()
+	storageController := YDBandFileCacheController{
+		usersDB: usersStore,
+	}
+	for _, userID := range []uint64{1126, 1120} {
+		usersStore.users[userID].NudgeSubscribed = true
+		usersStore.users[userID].NudgeHour = 19
+		usersStore.users[userID].LeetcodeUsername = "leetcoder"
+	}
+	users, err := storageController.GetNudgeUsers(context.Background(), time.Date(2021, 10, 13, 19, 0, 0, 0, time.UTC), common.DefaultSendingSlot, 20211013)
+	assert.Nil(t, err, "Unexpected GetNudgeUsers error")
+	assert.Equal(t, 2, len(users), "Unexpected nudge users amount")
+	err = storageController.MarkNudgeSent(context.Background(), 1126, 20211013)
+	assert.Nil(t, err, "Unexpected MarkNudgeSent error")
+	users, err = storageController.GetNudgeUsers(context.Background(), time.Date(2021, 10, 13, 19, 0, 0, 0, time.UTC), common.DefaultSendingSlot, 20211013)
+	assert.Nil(t, err, "Unexpected GetNudgeUsers error")
+	assert.Equal(t, []common.User{*usersStore.users[1120]}, users, "User shouldn't get nudge twice a day")
+	assert.Equal(
+		t,
+		[]string{"getSubscribedTimeZones", "getNudgeUsers \"\" 19 20211013", "markNudgeSent 1126 20211013", "getSubscribedTimeZones", "getNudgeUsers \"\" 19 20211013"},
+		usersStore.callsJournal,
+		"Unexpected users store call list",
+	)
+
+	// Nudge hours start only in the first slot of the hour
+	usersStore.callsJournal = []string{}
+	users, err = storageController.GetNudgeUsers(context.Background(), time.Date(2021, 10, 13, 19, 15, 0, 0, time.UTC), common.DefaultSendingSlot, 20211013)
+	assert.Nil(t, err, "Unexpected GetNudgeUsers error")
+	assert.Equal(t, []common.User{}, users, "Nudge shouldn't be sent in the middle of the hour")
+	assert.Equal(t, []string{"getSubscribedTimeZones"}, usersStore.callsJournal, "Unexpected users store call list")
+}
+
+func TestGetSubscribedUsersOnDeliveryDays(t *testing.T) {
+	usersStore := getTestUsersStorekeeper()
+	storageController := YDBandFileCacheController{
+		usersDB: usersStore,
+	}
+	for _, user := range usersStore.users {
+		user.SendingTimes = []common.SendingTime{{Minute: 420}}
+	}
+	usersStore.users[1126].Weekdays = common.WorkingDays
+	usersStore.users[1120].PausedUntil = 20211018
+	// 2021-10-16 is Saturday
+	testCases := map[time.Time][]uint64{
+		time.Date(2021, 10, 15, 7, 0, 0, 0, time.UTC): {1126},
+		time.Date(2021, 10, 16, 7, 0, 0, 0, time.UTC): {},
+		time.Date(2021, 10, 18, 7, 0, 0, 0, time.UTC): {1120, 1126},
+	}
+	for now, expectedIDs := range testCases {
+		list, err := storageController.GetSubscribedUsers(context.Background(), now, common.DefaultSendingSlot)
+		assert.Nil(t, err, "Unexpected GetSubscribedUsers error")
+		listIDs := []uint64{}
+		for _, user := range list {
+			listIDs = append(listIDs, user.ID)
+		}
+		sort.Slice(listIDs, func(i, j int) bool {
+			return listIDs[i] < listIDs[j]
+		})
+		assert.Equalf(t, expectedIDs, listIDs, "Unexpected users for %s", now)
+	}
+}
+
+func TestSetUserWeekdaysAndPausedUntil(t *testing.T) {
+	usersStore := getTestUsersStorekeeper()
+	storageController := YDBandFileCacheController{
+		usersDB: usersStore,
+	}
+	err := storageController.SetUserWeekdays(context.Background(), *usersStore.users[1126], common.WorkingDays)
+	assert.Nil(t, err, "Unexpected SetUserWeekdays error")
+	assert.Equal(t, common.WorkingDays, usersStore.users[1126].Weekdays, "Weekdays isn't stored")
+	err = storageController.SetUserPausedUntil(context.Background(), *usersStore.users[1126], 20261101)
+	assert.Nil(t, err, "Unexpected SetUserPausedUntil error")
+	assert.Equal(t, uint64(20261101), usersStore.users[1126].PausedUntil, "Pause isn't stored")
+	err = storageController.SetUserWeekdays(context.Background(), common.User{ID: 1000}, common.WorkingDays)
+	assert.Nil(t, err, "Unexpected SetUserWeekdays error")
+	err = storageController.SetUserPausedUntil(context.Background(), common.User{ID: 1001}, 20261101)
+	assert.Nil(t, err, "Unexpected SetUserPausedUntil error")
+	assert.Equal(t, common.User{ID: 1000, Weekdays: common.WorkingDays}, *usersStore.users[1000], "Unexpected new user")
+	assert.Equal(t, common.User{ID: 1001, PausedUntil: 20261101}, *usersStore.users[1001], "Unexpected new user")
+	assert.Equal(
+		t,
+		[]string{"getUser 1126", "setWeekdays 1126 62", "getUser 1126", "setPausedUntil 1126 20261101", "getUser 1000", "saveUser 1000", "getUser 1001", "saveUser 1001"},
+		usersStore.callsJournal,
+		"Unexpected users store call list",
+	)
+
+	usersStore.IDToFail = 1126
+	err = storageController.SetUserWeekdays(context.Background(), *usersStore.users[1126], common.EveryDay)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected SetUserWeekdays error")
+	err = storageController.SetUserPausedUntil(context.Background(), *usersStore.users[1126], 0)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected SetUserPausedUntil error")
+
+	storageController.usersDB = nil
+	assert.Equal(t, ErrNoActiveUsersStorage, storageController.SetUserWeekdays(context.Background(), common.User{}, common.EveryDay), "Unexpected SetUserWeekdays error")
+	assert.Equal(t, ErrNoActiveUsersStorage, storageController.SetUserPausedUntil(context.Background(), common.User{}, 0), "Unexpected SetUserPausedUntil error")
+}
+
+func TestSetUserDifficultiesAndCodeLanguage(t *testing.T) {
+	usersStore := getTestUsersStorekeeper()
+	storageController := YDBandFileCacheController{
+		usersDB: usersStore,
+	}
+	err := storageController.SetUserDifficulties(context.Background(), *usersStore.users[1126], 4)
+	assert.Nil(t, err, "Unexpected SetUserDifficulties error")
+	assert.Equal(t, common.Difficulties(4), usersStore.users[1126].Difficulties, "Difficulties isn't stored")
+	err = storageController.SetUserDifficultyFallback(context.Background(), *usersStore.users[1126], true)
+	assert.Nil(t, err, "Unexpected SetUserDifficultyFallback error")
+	assert.True(t, usersStore.users[1126].DifficultyFallback, "Difficulty fallback isn't stored")
+	err = storageController.SetUserCodeLanguage(context.Background(), *usersStore.users[1126], "golang")
+	assert.Nil(t, err, "Unexpected SetUserCodeLanguage error")
+	assert.Equal(t, "golang", usersStore.users[1126].CodeLanguage, "Code language isn't stored")
+	err = storageController.SetUserDifficulties(context.Background(), common.User{ID: 1000}, 1)
+	assert.Nil(t, err, "Unexpected SetUserDifficulties error")
+	err = storageController.SetUserCodeLanguage(context.Background(), common.User{ID: 1001}, "rust")
+	assert.Nil(t, err, "Unexpected SetUserCodeLanguage error")
+	err = storageController.SetUserDifficultyFallback(context.Background(), common.User{ID: 1002}, true)
+	assert.Nil(t, err, "Unexpected SetUserDifficultyFallback error")
+	assert.Equal(t, common.User{ID: 1002, DifficultyFallback: true}, *usersStore.users[1002], "Unexpected new user")
+	assert.Equal(t, common.User{ID: 1000, Difficulties: 1}, *usersStore.users[1000], "Unexpected new user")
+	assert.Equal(t, common.User{ID: 1001, CodeLanguage: "rust"}, *usersStore.users[1001], "Unexpected new user")
+	assert.Equal(
+		t,
+		[]string{"getUser 1126", "setDifficulties 1126 4", "getUser 1126", "setDifficultyFallback 1126 true", "getUser 1126", "setCodeLanguage 1126 golang", "getUser 1000", "saveUser 1000", "getUser 1001", "saveUser 1001", "getUser 1002", "saveUser 1002"},
+		usersStore.callsJournal,
+		"Unexpected users store call list",
+	)
+
+	usersStore.IDToFail = 1126
+	err = storageController.SetUserDifficulties(context.Background(), *usersStore.users[1126], 1)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected SetUserDifficulties error")
+	err = storageController.SetUserCodeLanguage(context.Background(), *usersStore.users[1126], "cpp")
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected SetUserCodeLanguage error")
+	err = storageController.SetUserDifficultyFallback(context.Background(), *usersStore.users[1126], false)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected SetUserDifficultyFallback error")
+
+	storageController.usersDB = nil
+	assert.Equal(t, ErrNoActiveUsersStorage, storageController.SetUserDifficulties(context.Background(), common.User{}, 1), "Unexpected SetUserDifficulties error")
+	assert.Equal(t, ErrNoActiveUsersStorage, storageController.SetUserCodeLanguage(context.Background(), common.User{}, "cpp"), "Unexpected SetUserCodeLanguage error")
+	assert.Equal(t, ErrNoActiveUsersStorage, storageController.SetUserDifficultyFallback(context.Background(), common.User{}, true), "Unexpected SetUserDifficultyFallback error")
+}
+
+func TestSetUserTimeZone(t *testing.T) {
+	usersStore := getTestUsersStorekeeper()
+	storageController := YDBandFileCacheController{
+		usersDB: usersStore,
+	}
+	newUser := common.User{
+		ID:        1000,
+		ChatID:    1000,
+		Username:  "newUser1000",
+		FirstName: "1000firstname",
+	}
+	err := storageController.SetUserTimeZone(context.Background(), newUser, "Europe/Berlin")
+	assert.Nil(t, err, "Unexpected SetUserTimeZone error")
+	newUser.TimeZone = "Europe/Berlin"
+	assert.Equal(t, newUser, *usersStore.users[1000], "Stored user differ with the sent one")
+	err = storageController.SetUserTimeZone(context.Background(), common.User{ID: 1126}, "UTC+05:30")
+	assert.Nil(t, err, "Unexpected SetUserTimeZone error")
+	assert.Equal(t, "UTC+05:30", usersStore.users[1126].TimeZone, "Time zone isn't stored")
+	usersStore.IDToFail = 1126
+	err = storageController.SetUserTimeZone(context.Background(), common.User{ID: 1126}, "UTC")
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected SetUserTimeZone error")
+	assert.Equal(
+		t,
+		[]string{"getUser 1000", "saveUser 1000", "getUser 1126", "setTimeZone 1126 UTC+05:30", "getUser 1126"},
+		usersStore.callsJournal,
+		"Unexpected users store call list",
+	)
+}
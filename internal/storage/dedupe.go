@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dartkron/leetcodeBot/v3/internal/common"
+	"github.com/dartkron/leetcodeBot/v3/internal/config"
+	"github.com/dartkron/leetcodeBot/v3/internal/logging"
+
+	"github.com/yandex-cloud/ydb-go-sdk/v2"
+	"github.com/yandex-cloud/ydb-go-sdk/v2/table"
+)
+
+const (
+	markUpdateQuery = `
+	DECLARE $updateId AS Uint64;
+	DECLARE $processedAt AS Timestamp;
+
+	$update = (SELECT $updateId AS updateId, $processedAt AS processedAt);
+
+	SELECT u.updateId AS updateId
+	FROM $update AS n
+	INNER JOIN updates AS u ON u.updateId = n.updateId;
+
+	UPSERT INTO updates
+	SELECT * FROM $update;
+	`
+	forgetUpdateQuery = `
+	DECLARE $updateId AS Uint64;
+
+	DELETE FROM updates
+	WHERE updateId = $updateId;
+	`
+)
+
+// UpdatesDeduplicator remembers Telegram update IDs, so the update redelivered by Telegram isn't processed twice
+type UpdatesDeduplicator interface {
+	// MarkUpdate remembers the update and returns false if it has been already seen
+	MarkUpdate(context.Context, uint64) (bool, error)
+	// ForgetUpdate removes the update, so it's processed again when Telegram retries it after the failure
+	ForgetUpdate(context.Context, uint64) error
+}
+
+type lruUpdate struct {
+	updateID uint64
+	markedAt time.Time
+}
+
+// LRUDeduplicator keeps up to capacity recent update IDs for ttl in memory.
+// Updates missing in memory are checked in the persistent deduplicator if it's set
+type LRUDeduplicator struct {
+	capacity   int
+	ttl        time.Duration
+	persistent UpdatesDeduplicator
+	updates    map[uint64]*list.Element
+	order      *list.List
+	mutex      sync.Mutex
+	Clock      common.Clock
+}
+
+// MarkUpdate remembers the update and returns false if it has been already seen.
+// Errors of the persistent deduplicator are only printed, because it's better to process the update twice than to lose it
+func (d *LRUDeduplicator) MarkUpdate(ctx context.Context, updateID uint64) (bool, error) {
+	if !d.markInMemory(updateID) {
+		return false, nil
+	}
+	if d.persistent == nil {
+		return true, nil
+	}
+	isNew, err := d.persistent.MarkUpdate(ctx, updateID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error on marking update in persistent storage", "updateID", updateID, "error", err)
+		return true, nil
+	}
+	return isNew, nil
+}
+
+func (d *LRUDeduplicator) markInMemory(updateID uint64) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	now := d.Clock.Now()
+	if element, ok := d.updates[updateID]; ok {
+		if now.Sub(element.Value.(lruUpdate).markedAt) < d.ttl {
+			d.order.MoveToFront(element)
+			return false
+		}
+		d.order.Remove(element)
+		delete(d.updates, updateID)
+	}
+	d.updates[updateID] = d.order.PushFront(lruUpdate{updateID: updateID, markedAt: now})
+	for d.order.Len() > d.capacity {
+		oldest := d.order.Back()
+		d.order.Remove(oldest)
+		delete(d.updates, oldest.Value.(lruUpdate).updateID)
+	}
+	return true
+}
+
+// ForgetUpdate removes the update from memory and the persistent deduplicator
+func (d *LRUDeduplicator) ForgetUpdate(ctx context.Context, updateID uint64) error {
+	d.mutex.Lock()
+	if element, ok := d.updates[updateID]; ok {
+		d.order.Remove(element)
+		delete(d.updates, updateID)
+	}
+	d.mutex.Unlock()
+	if d.persistent == nil {
+		return nil
+	}
+	return d.persistent.ForgetUpdate(ctx, updateID)
+}
+
+// NewLRUDeduplicator constructs LRUDeduplicator, persistent could be nil to keep updates only in memory
+func NewLRUDeduplicator(capacity int, ttl time.Duration, persistent UpdatesDeduplicator) *LRUDeduplicator {
+	return &LRUDeduplicator{
+		capacity:   capacity,
+		ttl:        ttl,
+		persistent: persistent,
+		updates:    map[uint64]*list.Element{},
+		order:      list.New(),
+		Clock:      common.SystemClock{},
+	}
+}
+
+// YDBDeduplicator keeps update IDs in the updates table of YDB, old updates should be removed by the table TTL
+type YDBDeduplicator struct {
+	ydbExecuter queryExecuter
+}
+
+// MarkUpdate remembers the update and returns false if it has been already seen
+func (y *YDBDeduplicator) MarkUpdate(ctx context.Context, updateID uint64) (bool, error) {
+	res, err := y.ydbExecuter.ProcessQuery(ctx, markUpdateQuery, table.NewQueryParameters(
+		table.ValueParam("$updateId", ydb.Uint64Value(updateID)),
+		table.ValueParam("$processedAt", ydb.TimestampValueFromTime(time.Now())),
+	),
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.RowCount() == 0, nil
+}
+
+// ForgetUpdate removes the update from the updates table
+func (y *YDBDeduplicator) ForgetUpdate(ctx context.Context, updateID uint64) error {
+	_, err := y.ydbExecuter.ProcessQuery(ctx, forgetUpdateQuery, table.NewQueryParameters(
+		table.ValueParam("$updateId", ydb.Uint64Value(updateID)),
+	),
+	)
+	return err
+}
+
+// NewYDBDeduplicator constructs YDBDeduplicator in the configured database
+func NewYDBDeduplicator(cfg *config.Config) *YDBDeduplicator {
+	return &YDBDeduplicator{ydbExecuter: newYDBQueryExecuter(cfg)}
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dartkron/leetcodeBot/v3/internal/config"
+	"github.com/dartkron/leetcodeBot/v3/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDeduplicator struct {
+	mock.Mock
+}
+
+func (m *MockDeduplicator) MarkUpdate(ctx context.Context, updateID uint64) (bool, error) {
+	args := m.Called(updateID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockDeduplicator) ForgetUpdate(ctx context.Context, updateID uint64) error {
+	args := m.Called(updateID)
+	return args.Error(0)
+}
+
+func TestLRUDeduplicator(t *testing.T) {
+	ctx := context.Background()
+	deduplicator := NewLRUDeduplicator(2, time.Hour, nil)
+	isNew, err := deduplicator.MarkUpdate(ctx, 1)
+	assert.Nil(t, err, "Unexpected MarkUpdate error")
+	assert.True(t, isNew, "First update should be new")
+	isNew, _ = deduplicator.MarkUpdate(ctx, 1)
+	assert.False(t, isNew, "Repeated update should be duplicate")
+
+	deduplicator.MarkUpdate(ctx, 2)
+	deduplicator.MarkUpdate(ctx, 1)
+	deduplicator.MarkUpdate(ctx, 3)
+	isNew, _ = deduplicator.MarkUpdate(ctx, 2)
+	assert.True(t, isNew, "The least recently used update should be evicted")
+	isNew, _ = deduplicator.MarkUpdate(ctx, 3)
+	assert.False(t, isNew, "Recent update shouldn't be evicted")
+
+	assert.Nil(t, deduplicator.ForgetUpdate(ctx, 3), "Unexpected ForgetUpdate error")
+	isNew, _ = deduplicator.MarkUpdate(ctx, 3)
+	assert.True(t, isNew, "Forgotten update should be new")
+
+	clock := tests.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	deduplicator = NewLRUDeduplicator(2, time.Hour, nil)
+	deduplicator.Clock = clock
+	deduplicator.MarkUpdate(ctx, 1)
+	clock.Add(time.Hour - time.Nanosecond)
+	isNew, _ = deduplicator.MarkUpdate(ctx, 1)
+	assert.False(t, isNew, "Update shouldn't expire before TTL of the clock time")
+	clock.Add(time.Hour)
+	isNew, _ = deduplicator.MarkUpdate(ctx, 1)
+	assert.True(t, isNew, "Expired update should be new")
+}
+
+func TestLRUDeduplicatorPersistent(t *testing.T) {
+	ctx := context.Background()
+	persistent := &MockDeduplicator{}
+	deduplicator := NewLRUDeduplicator(10, time.Hour, persistent)
+	persistent.On("MarkUpdate", uint64(1)).Return(true, nil).Once()
+	persistent.On("MarkUpdate", uint64(2)).Return(false, nil).Once()
+	persistent.On("MarkUpdate", uint64(3)).Return(false, tests.ErrBypassTest).Once()
+	persistent.On("ForgetUpdate", uint64(1)).Return(tests.ErrBypassTest).Once()
+
+	isNew, err := deduplicator.MarkUpdate(ctx, 1)
+	assert.Nil(t, err, "Unexpected MarkUpdate error")
+	assert.True(t, isNew, "Update new for both storages should be new")
+	isNew, _ = deduplicator.MarkUpdate(ctx, 1)
+	assert.False(t, isNew, "Update in memory shouldn't be checked in persistent storage")
+	isNew, _ = deduplicator.MarkUpdate(ctx, 2)
+	assert.False(t, isNew, "Update seen by another instance should be duplicate")
+	isNew, err = deduplicator.MarkUpdate(ctx, 3)
+	assert.Nil(t, err, "Persistent storage errors should be ignored")
+	assert.True(t, isNew, "Update should be processed when persistent storage is broken")
+	assert.Equal(t, tests.ErrBypassTest, deduplicator.ForgetUpdate(ctx, 1), "Unexpected ForgetUpdate error")
+	persistent.AssertExpectations(t)
+}
+
+func TestYDBDeduplicator(t *testing.T) {
+	ctx := context.Background()
+	deduplicator := NewYDBDeduplicator(config.Default())
+	assert.NotNil(t, deduplicator.ydbExecuter, "ydbExecuter must be set in constructor")
+	mockExecuter := new(MockQueryExecuter)
+	mockExecuter.t = t
+	deduplicator.ydbExecuter = mockExecuter
+	mockExecuter.On("ProcessQuery", trimmQuery(markUpdateQuery), mock.Anything).Return(&YDBResultMock{t: t}, nil).Once()
+	mockExecuter.On("ProcessQuery", trimmQuery(markUpdateQuery), mock.Anything).Return(&YDBResultMock{rows: []interface{}{struct{ UpdateID uint64 }{1}}, t: t}, nil).Once()
+	mockExecuter.On("ProcessQuery", trimmQuery(markUpdateQuery), mock.Anything).Return(&YDBResultMock{}, tests.ErrBypassTest).Once()
+	mockExecuter.On("ProcessQuery", trimmQuery(forgetUpdateQuery), mock.Anything).Return(&YDBResultMock{t: t}, nil).Once()
+
+	isNew, err := deduplicator.MarkUpdate(ctx, 1)
+	assert.Nil(t, err, "Unexpected MarkUpdate error")
+	assert.True(t, isNew, "Update without rows should be new")
+	isNew, err = deduplicator.MarkUpdate(ctx, 1)
+	assert.Nil(t, err, "Unexpected MarkUpdate error")
+	assert.False(t, isNew, "Stored update should be duplicate")
+	_, err = deduplicator.MarkUpdate(ctx, 1)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected MarkUpdate error")
+	assert.Nil(t, deduplicator.ForgetUpdate(ctx, 1), "Unexpected ForgetUpdate error")
+	mockExecuter.AssertExpectations(t)
+}
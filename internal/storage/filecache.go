@@ -13,16 +13,31 @@ import (
 
 // fileCache is a tasksStockpile with local filesystem backend
 type fileCache struct {
-	Path string
-	Mask string
+	Path         string
+	Mask         string
+	QuestionMask string
+	SlugMask     string
 }
 
 // getTask from local fs from path based on Path + mask
 func (c *fileCache) getTask(ctx context.Context, dateID uint64) (common.BotLeetCodeTask, error) {
+	return c.readTask(ctx, c.getTaskCachePath(dateID))
+}
+
+// getQuestion from local fs from path based on Path + QuestionMask
+func (c *fileCache) getQuestion(ctx context.Context, questionID uint64) (common.BotLeetCodeTask, error) {
+	return c.readTask(ctx, c.getQuestionCachePath(questionID))
+}
+
+// getQuestionBySlug from local fs from path based on Path + SlugMask
+func (c *fileCache) getQuestionBySlug(ctx context.Context, titleSlug string) (common.BotLeetCodeTask, error) {
+	return c.readTask(ctx, c.getSlugCachePath(titleSlug))
+}
+
+func (c *fileCache) readTask(ctx context.Context, cachePath string) (common.BotLeetCodeTask, error) {
 	respChan := make(chan common.BotLeetCodeTask)
 	errChan := make(chan error)
 	go func() {
-		cachePath := c.getTaskCachePath(dateID)
 		cacheFile, err := os.Open(cachePath)
 		if os.IsNotExist(err) {
 			errChan <- ErrNoSuchTask
@@ -55,9 +70,21 @@ func (c *fileCache) getTask(ctx context.Context, dateID uint64) (common.BotLeetC
 
 // saveTask to local fs cache storage
 func (c *fileCache) saveTask(ctx context.Context, task common.BotLeetCodeTask) error {
+	return c.writeTask(ctx, c.getTaskCachePath(task.DateID), task)
+}
+
+// saveQuestion to local fs cache storage, available both by questionID and titleSlug
+func (c *fileCache) saveQuestion(ctx context.Context, task common.BotLeetCodeTask) error {
+	err := c.writeTask(ctx, c.getQuestionCachePath(task.QuestionID), task)
+	if err != nil {
+		return err
+	}
+	return c.writeTask(ctx, c.getSlugCachePath(task.TitleSlug), task)
+}
+
+func (c *fileCache) writeTask(ctx context.Context, cachePath string, task common.BotLeetCodeTask) error {
 	errChan := make(chan error)
 	go func() {
-		cachePath := c.getTaskCachePath(task.DateID)
 		bytesTask, err := json.Marshal(task)
 		if err != nil {
 			errChan <- err
@@ -79,10 +106,21 @@ func (c *fileCache) getTaskCachePath(dateID uint64) string {
 	return path.Join(c.Path, fmt.Sprintf(c.Mask, dateID))
 }
 
-// NewfileCache construct default fileCacher
-func newFileCache() *fileCache {
+func (c *fileCache) getQuestionCachePath(questionID uint64) string {
+	return path.Join(c.Path, fmt.Sprintf(c.QuestionMask, questionID))
+}
+
+// getSlugCachePath uses only the base of the slug to not go out of Path with a crafted slug
+func (c *fileCache) getSlugCachePath(titleSlug string) string {
+	return path.Join(c.Path, fmt.Sprintf(c.SlugMask, path.Base(path.Clean("/"+titleSlug))))
+}
+
+// NewfileCache construct default fileCacher in the directory
+func newFileCache(dir string) *fileCache {
 	return &fileCache{
-		Path: "/tmp/",
-		Mask: "task_%d.cache",
+		Path:         dir,
+		Mask:         "task_%d.cache",
+		QuestionMask: "question_%d.cache",
+		SlugMask:     "question_%s.cache",
 	}
 }
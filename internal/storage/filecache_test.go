@@ -3,9 +3,11 @@ package storage
 import (
 	"context"
 	"os"
+	"path"
 	"testing"
 
 	"github.com/dartkron/leetcodeBot/v3/internal/common"
+	"github.com/dartkron/leetcodeBot/v3/internal/config"
 	"github.com/dartkron/leetcodeBot/v3/pkg/leetcodeclient"
 	"github.com/stretchr/testify/assert"
 )
@@ -16,7 +18,7 @@ type testCase struct {
 }
 
 func getTestFileStorage() fileCache {
-	fileStorage := newFileCache()
+	fileStorage := newFileCache(config.DefaultCacheDir)
 	fileStorage.Path = "../../tests/data"
 	return *fileStorage
 }
@@ -98,8 +100,48 @@ func TestSaveTaskFileCache(t *testing.T) {
 	assert.Equal(t, common.ErrClosedContext, err, "Unexpected error returned")
 }
 
+func TestQuestionFileCache(t *testing.T) {
+	fileStorage := getTestFileStorage()
+	tempDir := createTempDir(t)
+	defer os.RemoveAll(tempDir)
+	fileStorage.Path = tempDir
+	task := common.BotLeetCodeTask{
+		LeetCodeTask: leetcodeclient.LeetCodeTask{
+			QuestionID: 15,
+			TitleSlug:  "3sum",
+			Title:      "3Sum",
+			Content:    "Find all triplets",
+			Hints:      []string{"First hint"},
+			Difficulty: "Medium",
+		},
+	}
+	_, err := fileStorage.getQuestion(context.Background(), task.QuestionID)
+	assert.Equal(t, ErrNoSuchTask, err, "Unexpected getQuestion error for missing question")
+	err = fileStorage.saveQuestion(context.Background(), task)
+	assert.Nil(t, err, "Unexpected saveQuestion error")
+	_, err = os.Stat(fileStorage.getQuestionCachePath(task.QuestionID))
+	assert.Nil(t, err, "Question cache file not found after saveQuestion")
+	_, err = os.Stat(fileStorage.getTaskCachePath(0))
+	assert.True(t, os.IsNotExist(err), "Question shouldn't be saved as a daily task")
+	loadedTask, err := fileStorage.getQuestion(context.Background(), task.QuestionID)
+	assert.Nil(t, err, "Unexpected getQuestion error")
+	assert.Equal(t, task, loadedTask, "Loaded question differs with saved one")
+	loadedTask, err = fileStorage.getQuestionBySlug(context.Background(), task.TitleSlug)
+	assert.Nil(t, err, "Unexpected getQuestionBySlug error")
+	assert.Equal(t, task, loadedTask, "Loaded by slug question differs with saved one")
+	_, err = fileStorage.getQuestionBySlug(context.Background(), "../3sum")
+	assert.Nil(t, err, "Slug path shouldn't go out of the cache directory")
+	assert.Equal(t, path.Join(tempDir, "question_3sum.cache"), fileStorage.getSlugCachePath("../../3sum"), "Unexpected slug cache path")
+
+	fileStorage.Path = path.Join(tempDir, "nonexistent")
+	err = fileStorage.saveQuestion(context.Background(), task)
+	assert.NotNil(t, err, "saveQuestion to nonexisting path should return an error")
+}
+
 func TestNewFileCache(t *testing.T) {
-	fileCache := newFileCache()
+	fileCache := newFileCache(config.DefaultCacheDir)
 	assert.NotEmpty(t, fileCache.Mask, "Mask should be set in constructor")
+	assert.NotEmpty(t, fileCache.QuestionMask, "QuestionMask should be set in constructor")
+	assert.NotEmpty(t, fileCache.SlugMask, "SlugMask should be set in constructor")
 	assert.NotEmpty(t, fileCache.Path, "Path should be set in constructor")
 }
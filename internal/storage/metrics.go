@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"github.com/dartkron/leetcodeBot/v3/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Sources of tasks for TaskSourceHits
+const (
+	SourceFileCache   = "file_cache"
+	SourceYDB         = "ydb"
+	SourceLeetcodeAPI = "leetcode_api"
+)
+
+// Kinds of tasks for TaskSourceHits
+const (
+	KindDailyTask = "daily_task"
+	KindQuestion  = "question"
+)
+
+// TaskSourceHits counts tasks and questions by the source they are found in. Misses of the cache are hits of YDB and LeetCode API
+var TaskSourceHits = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+	Name: "leetcodebot_task_source_hits_total",
+	Help: "Tasks found in the source",
+}, []string{"kind", "source"})
@@ -0,0 +1,472 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dartkron/leetcodeBot/v3/internal/common"
+	"github.com/dartkron/leetcodeBot/v3/internal/config"
+	"github.com/yandex-cloud/ydb-go-sdk/v2"
+	"github.com/yandex-cloud/ydb-go-sdk/v2/table"
+)
+
+const (
+	// Deliveries are saved in the same transaction with messages, so the rerun doesn't queue the message twice
+	enqueueOutboxQuery = `
+	DECLARE $messages AS List<Struct<
+		chatId: Uint64,
+		id: Uint64,
+		method: String,
+		body: String,
+		status: Uint8,
+		attempts: Uint32,
+		availableAt: Uint64,
+		leaseUntil: Uint64,
+		lastError: String,
+		dateId: Uint64,
+		deliveryKind: String>>;
+	DECLARE $deliveries AS List<Struct<
+		userId: Uint64,
+		dateId: Uint64,
+		kind: String,
+		status: Uint8,
+		attempts: Uint32>>;
+
+	INSERT INTO outbox
+	SELECT chatId, id, method, body, status, attempts, availableAt, leaseUntil, lastError, dateId, deliveryKind
+	FROM AS_TABLE($messages);
+
+	UPSERT INTO deliveries
+	SELECT userId, dateId, kind, status, attempts, CAST(0 AS Uint64) AS messageId, "" AS lastError
+	FROM AS_TABLE($deliveries);
+	`
+	// Only the first pending message of the chat could be leased, so messages of the same chat are sent in order
+	leaseOutboxQuery = `
+	DECLARE $now AS Uint64;
+	DECLARE $leaseUntil AS Uint64;
+	DECLARE $limit AS Uint64;
+
+	$heads = (
+		SELECT chatId, MIN(id) AS id
+		FROM outbox
+		WHERE status = 0
+		GROUP BY chatId
+	);
+	$leased = (
+		SELECT o.chatId AS chatId, o.id AS id, o.method AS method, o.body AS body, o.attempts AS attempts, o.availableAt AS availableAt, o.lastError AS lastError,
+			o.dateId AS dateId, o.deliveryKind AS deliveryKind
+		FROM $heads AS h
+		INNER JOIN outbox AS o ON o.chatId = h.chatId AND o.id = h.id
+		WHERE MAX_OF(o.availableAt, o.leaseUntil) <= $now
+		ORDER BY id
+		LIMIT $limit
+	);
+
+	SELECT chatId, id, method, body, attempts, availableAt, lastError, dateId, deliveryKind
+	FROM $leased;
+
+	UPDATE outbox ON
+	SELECT chatId, id, $leaseUntil AS leaseUntil
+	FROM $leased;
+	`
+	completeOutboxQuery = `
+	DECLARE $chatId AS Uint64;
+	DECLARE $id AS Uint64;
+	DECLARE $userId AS Uint64;
+	DECLARE $dateId AS Uint64;
+	DECLARE $deliveryKind AS String;
+	DECLARE $deliveryStatus AS Uint8;
+
+	DELETE FROM outbox
+	WHERE chatId = $chatId AND id = $id;
+
+	UPDATE deliveries SET status = $deliveryStatus, lastError = ""
+	WHERE dateId = $dateId AND userId = $userId AND kind = $deliveryKind;
+	`
+	releaseOutboxQuery = `
+	DECLARE $chatId AS Uint64;
+	DECLARE $id AS Uint64;
+	DECLARE $status AS Uint8;
+	DECLARE $attempts AS Uint32;
+	DECLARE $availableAt AS Uint64;
+	DECLARE $lastError AS String;
+	DECLARE $userId AS Uint64;
+	DECLARE $dateId AS Uint64;
+	DECLARE $deliveryKind AS String;
+	DECLARE $deliveryStatus AS Uint8;
+	DECLARE $deliveryError AS String;
+
+	UPDATE outbox SET status = $status, attempts = $attempts, availableAt = $availableAt, leaseUntil = 0, lastError = $lastError
+	WHERE chatId = $chatId AND id = $id;
+
+	UPDATE deliveries SET status = $deliveryStatus, lastError = $deliveryError
+	WHERE dateId = $dateId AND userId = $userId AND kind = $deliveryKind;
+	`
+	getDeadOutboxQuery = `
+	DECLARE $status AS Uint8;
+
+	SELECT chatId, id, method, body, attempts, availableAt, lastError, dateId, deliveryKind
+	FROM outbox
+	WHERE status = $status;
+	`
+)
+
+// outboxDeliveryType is type of the deliveries list saved together with outbox messages, it's needed for the empty list
+var outboxDeliveryType = ydb.Struct(
+	ydb.StructField("userId", ydb.TypeUint64),
+	ydb.StructField("dateId", ydb.TypeUint64),
+	ydb.StructField("kind", ydb.TypeString),
+	ydb.StructField("status", ydb.TypeUint8),
+	ydb.StructField("attempts", ydb.TypeUint32),
+)
+
+// Outbox is a durable queue of outgoing Telegram API requests. Producers enqueue rendered messages,
+// and the dispatcher leases them, sends and then completes or releases them with the result of the attempt.
+// Deliveries of messages are saved as queued on Enqueue, as sent on Complete and as failed when the message is dead
+type Outbox interface {
+	Enqueue(context.Context, []common.OutboxMessage) error
+	Lease(context.Context, time.Time, time.Duration, int) ([]common.OutboxMessage, error)
+	Complete(context.Context, common.OutboxMessage) error
+	Release(context.Context, common.OutboxMessage) error
+	GetDeadMessages(context.Context) ([]common.OutboxMessage, error)
+}
+
+var lastOutboxID uint64
+var outboxIDMutex sync.Mutex
+
+// assignOutboxIDs sets increasing IDs based on the time of the clock to messages without ID, so the order of the chat is kept between processes
+func assignOutboxIDs(clock common.Clock, messages []common.OutboxMessage) {
+	outboxIDMutex.Lock()
+	defer outboxIDMutex.Unlock()
+	for i := range messages {
+		if messages[i].ID != 0 {
+			continue
+		}
+		lastOutboxID = max(lastOutboxID+1, uint64(clock.Now().UnixNano()))
+		messages[i].ID = lastOutboxID
+	}
+}
+
+// DeliveryLog saves results of deliveries, Controller is the one
+type DeliveryLog interface {
+	SaveDelivery(context.Context, common.Delivery) error
+}
+
+// getReleasedDeliveryStatus returns status of the delivery of the released message, it's failed only when the message is dead
+func getReleasedDeliveryStatus(message common.OutboxMessage) common.DeliveryStatus {
+	if message.Status == common.OutboxDead {
+		return common.DeliveryFailed
+	}
+	return common.DeliveryQueued
+}
+
+// MemoryOutbox is an instance of Outbox which keeps messages in memory, so they don't survive the restart.
+// It's useful for tests and local runs without the database and replaces SQLite for them. Deliveries are saved
+// to the log after the change of messages, there is no transaction, as nothing survives the restart anyway
+type MemoryOutbox struct {
+	messages    map[uint64][]common.OutboxMessage
+	deliveryLog DeliveryLog
+	mutex       sync.Mutex
+	Clock       common.Clock
+}
+
+// saveDelivery saves the delivery of the message with the status, if the message has one and the log is set
+func (o *MemoryOutbox) saveDelivery(ctx context.Context, message common.OutboxMessage, status common.DeliveryStatus) error {
+	if o.deliveryLog == nil || message.Delivery.Kind == "" {
+		return nil
+	}
+	delivery := message.Delivery
+	delivery.Status = status
+	delivery.LastError = ""
+	if status != common.DeliverySent {
+		delivery.LastError = message.LastError
+	}
+	return o.deliveryLog.SaveDelivery(ctx, delivery)
+}
+
+// Enqueue saves messages to the end of their chats queues
+func (o *MemoryOutbox) Enqueue(ctx context.Context, messages []common.OutboxMessage) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	messages = append([]common.OutboxMessage{}, messages...)
+	assignOutboxIDs(o.Clock, messages)
+	for _, message := range messages {
+		o.messages[message.ChatID] = append(o.messages[message.ChatID], message)
+		err := o.saveDelivery(ctx, message, common.DeliveryQueued)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lease returns up to limit first pending messages of chats which are available at now and leases them for leaseFor
+func (o *MemoryOutbox) Lease(ctx context.Context, now time.Time, leaseFor time.Duration, limit int) ([]common.OutboxMessage, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	candidates := []*common.OutboxMessage{}
+	for _, chatMessages := range o.messages {
+		for i := range chatMessages {
+			if chatMessages[i].Status != common.OutboxPending {
+				continue
+			}
+			if !chatMessages[i].AvailableAt.After(now) && !chatMessages[i].LeaseUntil.After(now) {
+				candidates = append(candidates, &chatMessages[i])
+			}
+			break
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].ID < candidates[j].ID
+	})
+	leased := []common.OutboxMessage{}
+	for _, message := range candidates[:min(limit, len(candidates))] {
+		message.LeaseUntil = now.Add(leaseFor)
+		leased = append(leased, *message)
+	}
+	return leased, nil
+}
+
+func (o *MemoryOutbox) find(message common.OutboxMessage) *common.OutboxMessage {
+	chatMessages := o.messages[message.ChatID]
+	for i := range chatMessages {
+		if chatMessages[i].ID == message.ID {
+			return &chatMessages[i]
+		}
+	}
+	return nil
+}
+
+// Complete removes the sent message from the outbox
+func (o *MemoryOutbox) Complete(ctx context.Context, message common.OutboxMessage) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	chatMessages := o.messages[message.ChatID]
+	for i := range chatMessages {
+		if chatMessages[i].ID == message.ID {
+			o.messages[message.ChatID] = append(chatMessages[:i], chatMessages[i+1:]...)
+			break
+		}
+	}
+	if len(o.messages[message.ChatID]) == 0 {
+		delete(o.messages, message.ChatID)
+	}
+	return o.saveDelivery(ctx, message, common.DeliverySent)
+}
+
+// Release saves status, attempts, next attempt time and the last error of the message and removes the lease
+func (o *MemoryOutbox) Release(ctx context.Context, message common.OutboxMessage) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	stored := o.find(message)
+	if stored == nil {
+		return nil
+	}
+	stored.Status = message.Status
+	stored.Attempts = message.Attempts
+	stored.AvailableAt = message.AvailableAt
+	stored.LeaseUntil = time.Time{}
+	stored.LastError = message.LastError
+	return o.saveDelivery(ctx, *stored, getReleasedDeliveryStatus(*stored))
+}
+
+// GetDeadMessages returns messages which are out of attempts
+func (o *MemoryOutbox) GetDeadMessages(ctx context.Context) ([]common.OutboxMessage, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	dead := []common.OutboxMessage{}
+	for _, chatMessages := range o.messages {
+		for _, message := range chatMessages {
+			if message.Status == common.OutboxDead {
+				dead = append(dead, message)
+			}
+		}
+	}
+	sort.Slice(dead, func(i, j int) bool {
+		return dead[i].ID < dead[j].ID
+	})
+	return dead, nil
+}
+
+// NewMemoryOutbox constructs empty MemoryOutbox saving deliveries to the log, deliveries aren't saved with nil log
+func NewMemoryOutbox(deliveryLog DeliveryLog) *MemoryOutbox {
+	return &MemoryOutbox{messages: map[uint64][]common.OutboxMessage{}, deliveryLog: deliveryLog, Clock: common.SystemClock{}}
+}
+
+// YDBOutbox is an instance of Outbox which keeps messages in the outbox table of YDB.
+// Leasing is done in one serializable transaction, so the same message isn't given to two dispatchers at once
+type YDBOutbox struct {
+	ydbExecuter queryExecuter
+	Clock       common.Clock
+}
+
+func toOutboxTime(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixMilli())
+}
+
+func fromOutboxTime(milliseconds uint64) time.Time {
+	if milliseconds == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(int64(milliseconds))
+}
+
+// Enqueue saves messages to the outbox in one transaction
+func (y *YDBOutbox) Enqueue(ctx context.Context, messages []common.OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	messages = append([]common.OutboxMessage{}, messages...)
+	assignOutboxIDs(y.Clock, messages)
+	values := []ydb.Value{}
+	deliveries := ydb.ZeroValue(ydb.List(outboxDeliveryType))
+	deliveryValues := []ydb.Value{}
+	for _, message := range messages {
+		values = append(values, ydb.StructValue(
+			ydb.StructFieldValue("chatId", ydb.Uint64Value(message.ChatID)),
+			ydb.StructFieldValue("id", ydb.Uint64Value(message.ID)),
+			ydb.StructFieldValue("method", ydb.StringValue([]byte(message.Method))),
+			ydb.StructFieldValue("body", ydb.StringValue(message.Body)),
+			ydb.StructFieldValue("status", ydb.Uint8Value(uint8(message.Status))),
+			ydb.StructFieldValue("attempts", ydb.Uint32Value(message.Attempts)),
+			ydb.StructFieldValue("availableAt", ydb.Uint64Value(toOutboxTime(message.AvailableAt))),
+			ydb.StructFieldValue("leaseUntil", ydb.Uint64Value(toOutboxTime(message.LeaseUntil))),
+			ydb.StructFieldValue("lastError", ydb.StringValue([]byte(message.LastError))),
+			ydb.StructFieldValue("dateId", ydb.Uint64Value(message.Delivery.DateID)),
+			ydb.StructFieldValue("deliveryKind", ydb.StringValue([]byte(message.Delivery.Kind))),
+		))
+		if message.Delivery.Kind != "" {
+			deliveryValues = append(deliveryValues, ydb.StructValue(
+				ydb.StructFieldValue("userId", ydb.Uint64Value(message.ChatID)),
+				ydb.StructFieldValue("dateId", ydb.Uint64Value(message.Delivery.DateID)),
+				ydb.StructFieldValue("kind", ydb.StringValue([]byte(message.Delivery.Kind))),
+				ydb.StructFieldValue("status", ydb.Uint8Value(uint8(common.DeliveryQueued))),
+				ydb.StructFieldValue("attempts", ydb.Uint32Value(message.Delivery.Attempts)),
+			))
+		}
+	}
+	if len(deliveryValues) > 0 {
+		deliveries = ydb.ListValue(deliveryValues...)
+	}
+	_, err := y.ydbExecuter.ProcessQuery(ctx, enqueueOutboxQuery, table.NewQueryParameters(
+		table.ValueParam("$messages", ydb.ListValue(values...)),
+		table.ValueParam("$deliveries", deliveries),
+	),
+	)
+	return err
+}
+
+// Lease returns up to limit first pending messages of chats which are available at now and leases them for leaseFor
+func (y *YDBOutbox) Lease(ctx context.Context, now time.Time, leaseFor time.Duration, limit int) ([]common.OutboxMessage, error) {
+	leaseUntil := now.Add(leaseFor)
+	messages, err := y.queryMessages(ctx, leaseOutboxQuery, table.NewQueryParameters(
+		table.ValueParam("$now", ydb.Uint64Value(toOutboxTime(now))),
+		table.ValueParam("$leaseUntil", ydb.Uint64Value(toOutboxTime(leaseUntil))),
+		table.ValueParam("$limit", ydb.Uint64Value(uint64(limit))),
+	),
+	)
+	for i := range messages {
+		messages[i].LeaseUntil = leaseUntil
+	}
+	return messages, err
+}
+
+// GetDeadMessages returns messages which are out of attempts
+func (y *YDBOutbox) GetDeadMessages(ctx context.Context) ([]common.OutboxMessage, error) {
+	messages, err := y.queryMessages(ctx, getDeadOutboxQuery, table.NewQueryParameters(
+		table.ValueParam("$status", ydb.Uint8Value(uint8(common.OutboxDead))),
+	),
+	)
+	for i := range messages {
+		messages[i].Status = common.OutboxDead
+	}
+	return messages, err
+}
+
+func (y *YDBOutbox) queryMessages(ctx context.Context, query string, queryParams *table.QueryParameters) ([]common.OutboxMessage, error) {
+	res, err := y.ydbExecuter.ProcessQuery(ctx, query, queryParams)
+	if err != nil {
+		return []common.OutboxMessage{}, err
+	}
+
+	var (
+		chatID       *uint64
+		id           *uint64
+		method       *string
+		body         *string
+		attempts     *uint32
+		availableAt  *uint64
+		lastError    *string
+		dateID       *uint64
+		deliveryKind *string
+	)
+	returnValue := []common.OutboxMessage{}
+
+	for res.NextResultSet(ctx, "chatId", "id", "method", "body", "attempts", "availableAt", "lastError", "dateId", "deliveryKind") {
+		for res.NextRow() {
+			err := res.Scan(&chatID, &id, &method, &body, &attempts, &availableAt, &lastError, &dateID, &deliveryKind)
+			if err != nil {
+				return []common.OutboxMessage{}, err
+			}
+			message := common.OutboxMessage{
+				ChatID:      *chatID,
+				ID:          *id,
+				Method:      *method,
+				Body:        []byte(*body),
+				Attempts:    *attempts,
+				AvailableAt: fromOutboxTime(*availableAt),
+				LastError:   *lastError,
+			}
+			// Messages enqueued before deliveries were saved with them don't have the columns
+			if dateID != nil && deliveryKind != nil && *deliveryKind != "" {
+				message.Delivery = common.Delivery{UserID: *chatID, DateID: *dateID, Kind: *deliveryKind}
+			}
+			returnValue = append(returnValue, message)
+		}
+	}
+	return returnValue, res.Err()
+}
+
+// Complete removes the sent message from the outbox and marks its delivery as sent in one transaction
+func (y *YDBOutbox) Complete(ctx context.Context, message common.OutboxMessage) error {
+	_, err := y.ydbExecuter.ProcessQuery(ctx, completeOutboxQuery, table.NewQueryParameters(
+		table.ValueParam("$chatId", ydb.Uint64Value(message.ChatID)),
+		table.ValueParam("$id", ydb.Uint64Value(message.ID)),
+		table.ValueParam("$userId", ydb.Uint64Value(message.Delivery.UserID)),
+		table.ValueParam("$dateId", ydb.Uint64Value(message.Delivery.DateID)),
+		table.ValueParam("$deliveryKind", ydb.StringValue([]byte(message.Delivery.Kind))),
+		table.ValueParam("$deliveryStatus", ydb.Uint8Value(uint8(common.DeliverySent))),
+	),
+	)
+	return err
+}
+
+// Release saves status, attempts, next attempt time and the last error of the message and removes the lease.
+// Delivery of the dead message is marked as failed in the same transaction
+func (y *YDBOutbox) Release(ctx context.Context, message common.OutboxMessage) error {
+	_, err := y.ydbExecuter.ProcessQuery(ctx, releaseOutboxQuery, table.NewQueryParameters(
+		table.ValueParam("$chatId", ydb.Uint64Value(message.ChatID)),
+		table.ValueParam("$id", ydb.Uint64Value(message.ID)),
+		table.ValueParam("$status", ydb.Uint8Value(uint8(message.Status))),
+		table.ValueParam("$attempts", ydb.Uint32Value(message.Attempts)),
+		table.ValueParam("$availableAt", ydb.Uint64Value(toOutboxTime(message.AvailableAt))),
+		table.ValueParam("$lastError", ydb.StringValue([]byte(message.LastError))),
+		table.ValueParam("$userId", ydb.Uint64Value(message.Delivery.UserID)),
+		table.ValueParam("$dateId", ydb.Uint64Value(message.Delivery.DateID)),
+		table.ValueParam("$deliveryKind", ydb.StringValue([]byte(message.Delivery.Kind))),
+		table.ValueParam("$deliveryStatus", ydb.Uint8Value(uint8(getReleasedDeliveryStatus(message)))),
+		table.ValueParam("$deliveryError", ydb.StringValue([]byte(message.LastError))),
+	),
+	)
+	return err
+}
+
+// NewYDBOutbox constructs YDBOutbox in the configured database
+func NewYDBOutbox(cfg *config.Config) *YDBOutbox {
+	return &YDBOutbox{ydbExecuter: newYDBQueryExecuter(cfg), Clock: common.SystemClock{}}
+}
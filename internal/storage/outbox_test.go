@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dartkron/leetcodeBot/v3/internal/common"
+	"github.com/dartkron/leetcodeBot/v3/internal/config"
+	"github.com/dartkron/leetcodeBot/v3/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yandex-cloud/ydb-go-sdk/v2"
+	"github.com/yandex-cloud/ydb-go-sdk/v2/table"
+)
+
+type testOutboxRow struct {
+	ChatID       uint64
+	ID           uint64
+	Method       string
+	Body         string
+	Attempts     uint32
+	AvailableAt  uint64
+	LastError    string
+	DateID       uint64
+	DeliveryKind string
+}
+
+func TestAssignOutboxIDs(t *testing.T) {
+	messages := []common.OutboxMessage{{ChatID: 1}, {ChatID: 1, ID: 5}, {ChatID: 2}}
+	assignOutboxIDs(common.SystemClock{}, messages)
+	assert.NotZero(t, messages[0].ID, "ID should be assigned")
+	assert.Equal(t, uint64(5), messages[1].ID, "Existing ID shouldn't be changed")
+	assert.Greater(t, messages[2].ID, messages[0].ID, "IDs should increase")
+
+	clock := tests.NewFakeClock(time.Now().Add(time.Hour))
+	messages = []common.OutboxMessage{{ChatID: 1}, {ChatID: 1}}
+	assignOutboxIDs(clock, messages)
+	assert.Equal(t, uint64(clock.Now().UnixNano()), messages[0].ID, "ID should be based on the time of the clock")
+	assert.Equal(t, messages[0].ID+1, messages[1].ID, "IDs should increase with the same time of the clock")
+}
+
+func TestMemoryOutbox(t *testing.T) {
+	ctx := context.Background()
+	outbox := NewMemoryOutbox(nil)
+	now := time.Now()
+	err := outbox.Enqueue(ctx, []common.OutboxMessage{
+		{ChatID: 1, Method: "sendMessage", Body: []byte("first")},
+		{ChatID: 1, Method: "sendMessage", Body: []byte("second")},
+		{ChatID: 2, Method: "sendMessage", Body: []byte("other chat")},
+		{ChatID: 3, Method: "sendMessage", Body: []byte("later"), AvailableAt: now.Add(time.Hour)},
+	})
+	assert.Nil(t, err, "Unexpected Enqueue error")
+
+	leased, err := outbox.Lease(ctx, now, time.Minute, 1)
+	assert.Nil(t, err, "Unexpected Lease error")
+	assert.Len(t, leased, 1, "Lease should respect the limit")
+	assert.Equal(t, "first", string(leased[0].Body), "The oldest message should be leased first")
+	assert.Equal(t, now.Add(time.Minute), leased[0].LeaseUntil, "Unexpected lease time")
+	first := leased[0]
+
+	leased, err = outbox.Lease(ctx, now, time.Minute, 10)
+	assert.Nil(t, err, "Unexpected Lease error")
+	assert.Len(t, leased, 1, "Leased, not available and not first messages of the chat shouldn't be leased")
+	assert.Equal(t, "other chat", string(leased[0].Body), "Unexpected leased message")
+	assert.Nil(t, outbox.Complete(ctx, leased[0]), "Unexpected Complete error")
+
+	leased, err = outbox.Lease(ctx, now.Add(2*time.Minute), time.Minute, 10)
+	assert.Nil(t, err, "Unexpected Lease error")
+	assert.Len(t, leased, 1, "Expired lease should be leased again")
+	assert.Equal(t, first.ID, leased[0].ID, "The first message of the chat should be leased again")
+
+	first.Attempts = 1
+	first.LastError = "test error"
+	first.AvailableAt = now.Add(5 * time.Minute)
+	assert.Nil(t, outbox.Release(ctx, first), "Unexpected Release error")
+	leased, err = outbox.Lease(ctx, now.Add(3*time.Minute), time.Minute, 10)
+	assert.Nil(t, err, "Unexpected Lease error")
+	assert.Empty(t, leased, "Messages of the chat shouldn't overtake the message waiting for retry")
+
+	first.Status = common.OutboxDead
+	assert.Nil(t, outbox.Release(ctx, first), "Unexpected Release error")
+	first.LeaseUntil = time.Time{}
+	dead, err := outbox.GetDeadMessages(ctx)
+	assert.Nil(t, err, "Unexpected GetDeadMessages error")
+	assert.Equal(t, []common.OutboxMessage{first}, dead, "Unexpected dead messages")
+	leased, err = outbox.Lease(ctx, now.Add(3*time.Minute), time.Minute, 10)
+	assert.Nil(t, err, "Unexpected Lease error")
+	assert.Len(t, leased, 1, "Dead message shouldn't block the chat")
+	assert.Equal(t, "second", string(leased[0].Body), "Unexpected leased message")
+	assert.Nil(t, outbox.Release(ctx, common.OutboxMessage{ChatID: 5, ID: 5}), "Release of unknown message should be ignored")
+}
+
+type testDeliveryLog struct {
+	deliveries []common.Delivery
+}
+
+func (l *testDeliveryLog) SaveDelivery(ctx context.Context, delivery common.Delivery) error {
+	l.deliveries = append(l.deliveries, delivery)
+	return nil
+}
+
+func TestMemoryOutboxDeliveries(t *testing.T) {
+	ctx := context.Background()
+	deliveryLog := &testDeliveryLog{}
+	outbox := NewMemoryOutbox(deliveryLog)
+	now := time.Now()
+	sentDelivery := common.Delivery{UserID: 1, DateID: 20211013, Kind: "task 07:00", Attempts: 1}
+	deadDelivery := common.Delivery{UserID: 2, DateID: 20211013, Kind: "task 07:00", Attempts: 1}
+	err := outbox.Enqueue(ctx, []common.OutboxMessage{
+		{ChatID: 1, Method: "sendMessage", Body: []byte("sent"), Delivery: sentDelivery},
+		{ChatID: 2, Method: "sendMessage", Body: []byte("dead"), Delivery: deadDelivery},
+		{ChatID: 3, Method: "sendMessage", Body: []byte("without delivery")},
+	})
+	assert.Nil(t, err, "Unexpected Enqueue error")
+	leased, err := outbox.Lease(ctx, now, time.Minute, 10)
+	assert.Nil(t, err, "Unexpected Lease error")
+	assert.Len(t, leased, 3, "Unexpected leased messages")
+
+	assert.Nil(t, outbox.Complete(ctx, leased[0]), "Unexpected Complete error")
+	leased[1].Attempts = 1
+	leased[1].LastError = "test error"
+	assert.Nil(t, outbox.Release(ctx, leased[1]), "Unexpected Release error")
+	leased[1].Status = common.OutboxDead
+	assert.Nil(t, outbox.Release(ctx, leased[1]), "Unexpected Release error")
+	assert.Nil(t, outbox.Complete(ctx, leased[2]), "Unexpected Complete error")
+
+	queued := func(delivery common.Delivery) common.Delivery {
+		delivery.Status = common.DeliveryQueued
+		return delivery
+	}
+	sent := sentDelivery
+	sent.Status = common.DeliverySent
+	retried := queued(deadDelivery)
+	retried.LastError = "test error"
+	failed := retried
+	failed.Status = common.DeliveryFailed
+	assert.Equal(t, []common.Delivery{queued(sentDelivery), queued(deadDelivery), sent, retried, failed}, deliveryLog.deliveries, "Unexpected saved deliveries")
+}
+
+func TestYDBOutbox(t *testing.T) {
+	ctx := context.Background()
+	outbox := NewYDBOutbox(config.Default())
+	assert.NotNil(t, outbox.ydbExecuter, "ydbExecuter must be set in constructor")
+	mockExecuter := new(MockQueryExecuter)
+	mockExecuter.t = t
+	outbox.ydbExecuter = mockExecuter
+	now := time.UnixMilli(1634112000000)
+	mockExecuter.On("ProcessQuery", trimmQuery(enqueueOutboxQuery), mock.Anything).Return(&YDBResultMock{t: t}, nil).Once()
+	mockExecuter.On("ProcessQuery", trimmQuery(leaseOutboxQuery), mock.Anything).Return(
+		&YDBResultMock{
+			rows: []interface{}{
+				testOutboxRow{ChatID: 1, ID: 10, Method: "sendMessage", Body: "first", Attempts: 1, AvailableAt: 1634111000000, LastError: "test error", DateID: 20211013, DeliveryKind: "task 07:00"},
+				testOutboxRow{ChatID: 2, ID: 11, Method: "sendMessage", Body: "second"},
+			},
+			t: t,
+		},
+		nil,
+	).Once()
+	mockExecuter.On("ProcessQuery", trimmQuery(leaseOutboxQuery), mock.Anything).Return(&YDBResultMock{}, tests.ErrBypassTest).Once()
+	mockExecuter.On("ProcessQuery", trimmQuery(completeOutboxQuery), matchQueryParams(
+		table.ValueParam("$chatId", ydb.Uint64Value(1)),
+		table.ValueParam("$id", ydb.Uint64Value(10)),
+		table.ValueParam("$userId", ydb.Uint64Value(1)),
+		table.ValueParam("$dateId", ydb.Uint64Value(20211013)),
+		table.ValueParam("$deliveryKind", ydb.StringValue([]byte("task 07:00"))),
+		table.ValueParam("$deliveryStatus", ydb.Uint8Value(uint8(common.DeliverySent))),
+	)).Return(&YDBResultMock{t: t}, nil).Once()
+	mockExecuter.On("ProcessQuery", trimmQuery(releaseOutboxQuery), matchQueryParams(
+		table.ValueParam("$chatId", ydb.Uint64Value(2)),
+		table.ValueParam("$id", ydb.Uint64Value(11)),
+		table.ValueParam("$status", ydb.Uint8Value(uint8(common.OutboxDead))),
+		table.ValueParam("$attempts", ydb.Uint32Value(0)),
+		table.ValueParam("$availableAt", ydb.Uint64Value(0)),
+		table.ValueParam("$lastError", ydb.StringValue([]byte(""))),
+		table.ValueParam("$userId", ydb.Uint64Value(0)),
+		table.ValueParam("$dateId", ydb.Uint64Value(0)),
+		table.ValueParam("$deliveryKind", ydb.StringValue([]byte(""))),
+		table.ValueParam("$deliveryStatus", ydb.Uint8Value(uint8(common.DeliveryFailed))),
+		table.ValueParam("$deliveryError", ydb.StringValue([]byte(""))),
+	)).Return(&YDBResultMock{t: t}, nil).Once()
+	mockExecuter.On("ProcessQuery", trimmQuery(getDeadOutboxQuery), mock.Anything).Return(
+		&YDBResultMock{
+			rows:      []interface{}{testOutboxRow{}},
+			t:         t,
+			scanError: tests.ErrBypassTest,
+		},
+		nil,
+	).Once()
+
+	assert.Nil(t, outbox.Enqueue(ctx, []common.OutboxMessage{}), "Empty Enqueue shouldn't query the database")
+	assert.Nil(t, outbox.Enqueue(ctx, []common.OutboxMessage{{ChatID: 1, Method: "sendMessage", Body: []byte("first")}}), "Unexpected Enqueue error")
+	leased, err := outbox.Lease(ctx, now, time.Minute, 10)
+	assert.Nil(t, err, "Unexpected Lease error")
+	assert.Equal(t, []common.OutboxMessage{
+		{ChatID: 1, ID: 10, Method: "sendMessage", Body: []byte("first"), Attempts: 1, AvailableAt: time.UnixMilli(1634111000000), LeaseUntil: now.Add(time.Minute), LastError: "test error",
+			Delivery: common.Delivery{UserID: 1, DateID: 20211013, Kind: "task 07:00"}},
+		{ChatID: 2, ID: 11, Method: "sendMessage", Body: []byte("second"), LeaseUntil: now.Add(time.Minute)},
+	}, leased, "Unexpected leased messages")
+	_, err = outbox.Lease(ctx, now, time.Minute, 10)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected Lease error")
+	assert.Nil(t, outbox.Complete(ctx, leased[0]), "Unexpected Complete error")
+	leased[1].Status = common.OutboxDead
+	assert.Nil(t, outbox.Release(ctx, leased[1]), "Unexpected Release error")
+	_, err = outbox.GetDeadMessages(ctx)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected GetDeadMessages error")
+	mockExecuter.AssertExpectations(t)
+}
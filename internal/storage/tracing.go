@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/dartkron/leetcodeBot/v3/internal/common"
+	"github.com/dartkron/leetcodeBot/v3/pkg/tracing"
+)
+
+// TracingController wraps every method of Controller with the span
+type TracingController struct {
+	controller Controller
+}
+
+// NewTracingController constructs TracingController around the controller
+func NewTracingController(controller Controller) *TracingController {
+	return &TracingController{controller: controller}
+}
+
+// traced calls the method in the span named after it. Not found tasks and users are expected, so they don't fail the span
+func traced[T any](ctx context.Context, method string, call func(context.Context) (T, error), args ...interface{}) (T, error) {
+	ctx, span := tracing.Start(ctx, "storage."+method, args...)
+	defer span.End()
+	result, err := call(ctx)
+	if err != ErrNoSuchTask && err != ErrNoSuchUser {
+		span.RecordError(err)
+	}
+	return result, err
+}
+
+func tracedError(ctx context.Context, method string, call func(context.Context) error, args ...interface{}) error {
+	_, err := traced(ctx, method, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, call(ctx)
+	}, args...)
+	return err
+}
+
+// GetTask calls GetTask of the controller in the span
+func (c *TracingController) GetTask(ctx context.Context, dateID uint64) (common.BotLeetCodeTask, error) {
+	return traced(ctx, "GetTask", func(ctx context.Context) (common.BotLeetCodeTask, error) {
+		return c.controller.GetTask(ctx, dateID)
+	}, "dateID", dateID)
+}
+
+// SaveTask calls SaveTask of the controller in the span
+func (c *TracingController) SaveTask(ctx context.Context, task common.BotLeetCodeTask) error {
+	return tracedError(ctx, "SaveTask", func(ctx context.Context) error {
+		return c.controller.SaveTask(ctx, task)
+	}, "dateID", task.DateID)
+}
+
+// GetQuestion calls GetQuestion of the controller in the span
+func (c *TracingController) GetQuestion(ctx context.Context, questionID uint64) (common.BotLeetCodeTask, error) {
+	return traced(ctx, "GetQuestion", func(ctx context.Context) (common.BotLeetCodeTask, error) {
+		return c.controller.GetQuestion(ctx, questionID)
+	}, "questionID", questionID)
+}
+
+// GetQuestionBySlug calls GetQuestionBySlug of the controller in the span
+func (c *TracingController) GetQuestionBySlug(ctx context.Context, titleSlug string) (common.BotLeetCodeTask, error) {
+	return traced(ctx, "GetQuestionBySlug", func(ctx context.Context) (common.BotLeetCodeTask, error) {
+		return c.controller.GetQuestionBySlug(ctx, titleSlug)
+	}, "titleSlug", titleSlug)
+}
+
+// SaveQuestion calls SaveQuestion of the controller in the span
+func (c *TracingController) SaveQuestion(ctx context.Context, task common.BotLeetCodeTask) error {
+	return tracedError(ctx, "SaveQuestion", func(ctx context.Context) error {
+		return c.controller.SaveQuestion(ctx, task)
+	}, "questionID", task.QuestionID)
+}
+
+// SubscribeUser calls SubscribeUser of the controller in the span
+func (c *TracingController) SubscribeUser(ctx context.Context, user common.User, sendingTime common.SendingTime) error {
+	return tracedError(ctx, "SubscribeUser", func(ctx context.Context) error {
+		return c.controller.SubscribeUser(ctx, user, sendingTime)
+	}, "userID", user.ID)
+}
+
+// RemoveSendingTime calls RemoveSendingTime of the controller in the span
+func (c *TracingController) RemoveSendingTime(ctx context.Context, userID uint64, minute uint16) error {
+	return tracedError(ctx, "RemoveSendingTime", func(ctx context.Context) error {
+		return c.controller.RemoveSendingTime(ctx, userID, minute)
+	}, "userID", userID)
+}
+
+// UnsubscribeUser calls UnsubscribeUser of the controller in the span
+func (c *TracingController) UnsubscribeUser(ctx context.Context, userID uint64) error {
+	return tracedError(ctx, "UnsubscribeUser", func(ctx context.Context) error {
+		return c.controller.UnsubscribeUser(ctx, userID)
+	}, "userID", userID)
+}
+
+// GetSubscribedUsers calls GetSubscribedUsers of the controller in the span
+func (c *TracingController) GetSubscribedUsers(ctx context.Context, now time.Time, slot time.Duration) ([]common.User, error) {
+	return traced(ctx, "GetSubscribedUsers", func(ctx context.Context) ([]common.User, error) {
+		return c.controller.GetSubscribedUsers(ctx, now, slot)
+	}, "hour", now.UTC().Hour())
+}
+
+// GetUser calls GetUser of the controller in the span
+func (c *TracingController) GetUser(ctx context.Context, userID uint64) (common.User, error) {
+	return traced(ctx, "GetUser", func(ctx context.Context) (common.User, error) {
+		return c.controller.GetUser(ctx, userID)
+	}, "userID", userID)
+}
+
+// LinkLeetcodeUsername calls LinkLeetcodeUsername of the controller in the span
+func (c *TracingController) LinkLeetcodeUsername(ctx context.Context, user common.User, username string) error {
+	return tracedError(ctx, "LinkLeetcodeUsername", func(ctx context.Context) error {
+		return c.controller.LinkLeetcodeUsername(ctx, user, username)
+	}, "userID", user.ID)
+}
+
+// SetUserTimeZone calls SetUserTimeZone of the controller in the span
+func (c *TracingController) SetUserTimeZone(ctx context.Context, user common.User, timeZone string) error {
+	return tracedError(ctx, "SetUserTimeZone", func(ctx context.Context) error {
+		return c.controller.SetUserTimeZone(ctx, user, timeZone)
+	}, "userID", user.ID)
+}
+
+// SetUserWeekdays calls SetUserWeekdays of the controller in the span
+func (c *TracingController) SetUserWeekdays(ctx context.Context, user common.User, weekdays common.Weekdays) error {
+	return tracedError(ctx, "SetUserWeekdays", func(ctx context.Context) error {
+		return c.controller.SetUserWeekdays(ctx, user, weekdays)
+	}, "userID", user.ID)
+}
+
+// SetUserPausedUntil calls SetUserPausedUntil of the controller in the span
+func (c *TracingController) SetUserPausedUntil(ctx context.Context, user common.User, dateID uint64) error {
+	return tracedError(ctx, "SetUserPausedUntil", func(ctx context.Context) error {
+		return c.controller.SetUserPausedUntil(ctx, user, dateID)
+	}, "userID", user.ID)
+}
+
+// SetUserDifficulties calls SetUserDifficulties of the controller in the span
+func (c *TracingController) SetUserDifficulties(ctx context.Context, user common.User, difficulties common.Difficulties) error {
+	return tracedError(ctx, "SetUserDifficulties", func(ctx context.Context) error {
+		return c.controller.SetUserDifficulties(ctx, user, difficulties)
+	}, "userID", user.ID)
+}
+
+// SetUserDifficultyFallback calls SetUserDifficultyFallback of the controller in the span
+func (c *TracingController) SetUserDifficultyFallback(ctx context.Context, user common.User, fallback bool) error {
+	return tracedError(ctx, "SetUserDifficultyFallback", func(ctx context.Context) error {
+		return c.controller.SetUserDifficultyFallback(ctx, user, fallback)
+	}, "userID", user.ID)
+}
+
+// SetUserCodeLanguage calls SetUserCodeLanguage of the controller in the span
+func (c *TracingController) SetUserCodeLanguage(ctx context.Context, user common.User, language string) error {
+	return tracedError(ctx, "SetUserCodeLanguage", func(ctx context.Context) error {
+		return c.controller.SetUserCodeLanguage(ctx, user, language)
+	}, "userID", user.ID)
+}
+
+// SubscribeUserToNudge calls SubscribeUserToNudge of the controller in the span
+func (c *TracingController) SubscribeUserToNudge(ctx context.Context, user common.User, hour uint8) error {
+	return tracedError(ctx, "SubscribeUserToNudge", func(ctx context.Context) error {
+		return c.controller.SubscribeUserToNudge(ctx, user, hour)
+	}, "userID", user.ID)
+}
+
+// UnsubscribeUserFromNudge calls UnsubscribeUserFromNudge of the controller in the span
+func (c *TracingController) UnsubscribeUserFromNudge(ctx context.Context, userID uint64) error {
+	return tracedError(ctx, "UnsubscribeUserFromNudge", func(ctx context.Context) error {
+		return c.controller.UnsubscribeUserFromNudge(ctx, userID)
+	}, "userID", userID)
+}
+
+// GetNudgeUsers calls GetNudgeUsers of the controller in the span
+func (c *TracingController) GetNudgeUsers(ctx context.Context, now time.Time, slot time.Duration, dateID uint64) ([]common.User, error) {
+	return traced(ctx, "GetNudgeUsers", func(ctx context.Context) ([]common.User, error) {
+		return c.controller.GetNudgeUsers(ctx, now, slot, dateID)
+	}, "hour", now.UTC().Hour(), "dateID", dateID)
+}
+
+// MarkNudgeSent calls MarkNudgeSent of the controller in the span
+func (c *TracingController) MarkNudgeSent(ctx context.Context, userID uint64, dateID uint64) error {
+	return tracedError(ctx, "MarkNudgeSent", func(ctx context.Context) error {
+		return c.controller.MarkNudgeSent(ctx, userID, dateID)
+	}, "userID", userID, "dateID", dateID)
+}
+
+// GetDeliveries calls GetDeliveries of the controller in the span
+func (c *TracingController) GetDeliveries(ctx context.Context, dateID uint64) ([]common.Delivery, error) {
+	return traced(ctx, "GetDeliveries", func(ctx context.Context) ([]common.Delivery, error) {
+		return c.controller.GetDeliveries(ctx, dateID)
+	}, "dateID", dateID)
+}
+
+// SaveDelivery calls SaveDelivery of the controller in the span
+func (c *TracingController) SaveDelivery(ctx context.Context, delivery common.Delivery) error {
+	return tracedError(ctx, "SaveDelivery", func(ctx context.Context) error {
+		return c.controller.SaveDelivery(ctx, delivery)
+	}, "userID", delivery.UserID, "dateID", delivery.DateID)
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dartkron/leetcodeBot/v3/internal/common"
+	"github.com/dartkron/leetcodeBot/v3/pkg/tracing"
+	"github.com/dartkron/leetcodeBot/v3/tests"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingController(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracing.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer tracing.SetTracerProvider(nil)
+	storageController, _, DBStorage := getTestController()
+	DBStorage.IDToFail = 777
+	controller := NewTracingController(storageController)
+
+	ctx, parent := tracing.Start(context.Background(), "parent")
+	task, err := controller.GetTask(ctx, 12345)
+	assert.Nil(t, err, "Unexpected GetTask error")
+	assert.Equal(t, uint64(12345), task.DateID, "Task should be returned from the controller")
+	_, err = controller.GetTask(ctx, 100500)
+	assert.Equal(t, ErrNoSuchTask, err, "Unexpected GetTask error")
+	err = controller.SaveTask(ctx, common.BotLeetCodeTask{DateID: 777})
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected SaveTask error")
+	_, err = controller.GetSubscribedUsers(ctx, time.Date(2026, 1, 1, 5, 0, 0, 0, time.UTC), time.Hour)
+	assert.NotNil(t, err, "Users storage isn't configured in the test controller")
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if assert.Len(t, spans, 5, "Every call should have the span") {
+		names := []string{}
+		for _, span := range spans[:4] {
+			names = append(names, span.Name)
+			assert.Equal(t, parent.TraceID(), span.SpanContext.TraceID().String(), "Spans should be in the trace of the context")
+		}
+		assert.Equal(t, []string{"storage.GetTask", "storage.GetTask", "storage.SaveTask", "storage.GetSubscribedUsers"}, names, "Unexpected span names")
+		assert.Equal(t, []attribute.KeyValue{attribute.Int64("dateID", 12345)}, spans[0].Attributes, "Span should have the arguments")
+		assert.Equal(t, codes.Unset, spans[1].Status.Code, "Not found task shouldn't fail the span")
+		assert.Equal(t, tests.ErrBypassTest.Error(), spans[2].Status.Description, "Errors should fail the span")
+		assert.Equal(t, []attribute.KeyValue{attribute.Int("hour", 5)}, spans[3].Attributes, "Span should have UTC hour")
+		assert.Equal(t, err.Error(), spans[3].Status.Description, "Errors should fail the span")
+	}
+}
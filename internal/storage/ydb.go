@@ -3,21 +3,31 @@ package storage
 import (
 	"context"
 	"encoding/json"
-	"fmt"
-	"os"
+	"errors"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/dartkron/leetcodeBot/v3/internal/common"
+	"github.com/dartkron/leetcodeBot/v3/internal/config"
+	"github.com/dartkron/leetcodeBot/v3/internal/logging"
+	"github.com/dartkron/leetcodeBot/v3/pkg/circuitbreaker"
+	"github.com/dartkron/leetcodeBot/v3/pkg/tracing"
 	"github.com/yandex-cloud/ydb-go-sdk/v2"
 	"github.com/yandex-cloud/ydb-go-sdk/v2/connect"
 	"github.com/yandex-cloud/ydb-go-sdk/v2/table"
 )
 
+const (
+	ydbBreakerThreshold   = 5
+	ydbBreakerOpenTimeout = 10 * time.Second
+)
+
 const (
 	getTaskQuery = `
 	DECLARE $dateId AS Uint64;
 
-	SELECT title, content, questionId, titleSlug, hints, difficulty, topicTags
+	SELECT title, content, questionId, titleSlug, hints, difficulty, topicTags, similarQuestions
 	FROM dailyQuestion
 	WHERE id = $dateId;
 	`
@@ -30,22 +40,80 @@ const (
 	DECLARE $hints AS String;
 	DECLARE $difficulty AS Uint8;
 	DECLARE $topicTags AS String;
+	DECLARE $similarQuestions AS String;
+
+	REPLACE INTO dailyQuestion (id, questionId, titleSlug, title, content, hints, difficulty, topicTags, similarQuestions)
+	VALUES ($dateId, $questionId, $titleSlug, $title, $content, $hints, $difficulty, $topicTags, $similarQuestions);
+	`
+	getQuestionQuery = `
+	DECLARE $questionId AS Uint64;
 
-	REPLACE INTO dailyQuestion (id, questionId, titleSlug, title, content, hints, difficulty, topicTags)
-	VALUES ($dateId, $questionId, $titleSlug, $title, $content, $hints, $difficulty, $topicTags);
+	SELECT title, content, questionId, titleSlug, hints, difficulty, topicTags, similarQuestions
+	FROM question
+	WHERE questionId = $questionId;
+	`
+	getQuestionBySlugQuery = `
+	DECLARE $titleSlug AS String;
+
+	SELECT title, content, questionId, titleSlug, hints, difficulty, topicTags, similarQuestions
+	FROM question VIEW titleSlugIndex
+	WHERE titleSlug = $titleSlug;
+	`
+	replaceQuestionQuery = `
+	DECLARE $questionId AS Uint64;
+	DECLARE $titleSlug AS String;
+	DECLARE $title AS String;
+	DECLARE $content AS String;
+	DECLARE $hints AS String;
+	DECLARE $difficulty AS Uint8;
+	DECLARE $topicTags AS String;
+	DECLARE $similarQuestions AS String;
+
+	REPLACE INTO question (questionId, titleSlug, title, content, hints, difficulty, topicTags, similarQuestions)
+	VALUES ($questionId, $titleSlug, $title, $content, $hints, $difficulty, $topicTags, $similarQuestions);
 	`
 	getUserQuery = `
 	DECLARE $id AS Uint64;
 
-	SELECT chat_id, firstName, lastName, username, subscribed, sendingHour
+	SELECT chat_id, firstName, lastName, username, subscribed, timeZone, leetcodeUsername, nudgeSubscribed, nudgeHour, weekdays, pausedUntil, difficulties, difficultyFallback, codeLanguage
 	FROM users
 	WHERE id = $id;
 	`
+	getSendingTimesQuery = `
+	DECLARE $id AS Uint64;
+
+	SELECT minute, kind
+	FROM sendingTimes
+	WHERE userId = $id
+	ORDER BY minute;
+	`
+	getSubscribedTimeZonesQuery = `
+	SELECT DISTINCT COALESCE(timeZone, "") AS timeZone
+	FROM users
+	WHERE subscribed = true or nudgeSubscribed = true;
+	`
 	getSubscribedUsersQuery = `
-	DECLARE $sendingHour AS Uint8;
-	SELECT id, chat_id, firstName, lastName, username
+	DECLARE $timeZone AS String;
+	DECLARE $fromMinute AS Uint16;
+	DECLARE $toMinute AS Uint16;
+	DECLARE $weekdayBit AS Uint8;
+	DECLARE $dateID AS Uint64;
+	SELECT u.id AS id, u.chat_id AS chat_id, u.firstName AS firstName, u.lastName AS lastName, u.username AS username, t.minute AS minute, t.kind AS kind,
+	COALESCE(u.difficulties, 0) AS difficulties, COALESCE(u.difficultyFallback, false) AS difficultyFallback
+	FROM sendingTimes VIEW minuteIndex AS t
+	INNER JOIN users AS u ON u.id = t.userId
+	WHERE t.minute >= $fromMinute and t.minute < $toMinute and u.subscribed = true and COALESCE(u.timeZone, "") = $timeZone
+	and (COALESCE(u.weekdays, 0) = 0 or BitAnd(u.weekdays, $weekdayBit) != 0) and COALESCE(u.pausedUntil, 0) <= $dateID
+	ORDER BY id, minute;
+	`
+	getNudgeUsersQuery = `
+	DECLARE $timeZone AS String;
+	DECLARE $nudgeHour AS Uint8;
+	DECLARE $dateID AS Uint64;
+	SELECT id, chat_id, firstName, lastName, username, leetcodeUsername
 	FROM users
-	WHERE subscribed = true and sendingHour = $sendingHour;
+	WHERE nudgeSubscribed = true and nudgeHour = $nudgeHour and COALESCE(timeZone, "") = $timeZone and leetcodeUsername != ""
+	and (lastNudgeDateID IS NULL or lastNudgeDateID != $dateID);
 	`
 	saveUserQuery = `
 	DECLARE $id AS Uint64;
@@ -54,16 +122,93 @@ const (
 	DECLARE $lastname AS String;
 	DECLARE $username AS String;
 	DECLARE $subscribed AS Bool;
-	DECLARE $sendingHour AS Uint8;
-
-	REPLACE INTO users (id, chat_id, firstName, lastName, username, subscribed, sendingHour)
-	VALUES ($id, $chat_id, $firstname, $lastname, $username, $subscribed, $sendingHour);
+	DECLARE $timeZone AS String;
+	DECLARE $leetcodeUsername AS String;
+	DECLARE $nudgeSubscribed AS Bool;
+	DECLARE $nudgeHour AS Uint8;
+	DECLARE $weekdays AS Uint8;
+	DECLARE $pausedUntil AS Uint64;
+	DECLARE $difficulties AS Uint8;
+	DECLARE $difficultyFallback AS Bool;
+	DECLARE $codeLanguage AS String;
+
+	REPLACE INTO users (id, chat_id, firstName, lastName, username, subscribed, timeZone, leetcodeUsername, nudgeSubscribed, nudgeHour, weekdays, pausedUntil, difficulties, difficultyFallback, codeLanguage)
+	VALUES ($id, $chat_id, $firstname, $lastname, $username, $subscribed, $timeZone, $leetcodeUsername, $nudgeSubscribed, $nudgeHour, $weekdays, $pausedUntil, $difficulties, $difficultyFallback, $codeLanguage);
 	`
 	subscribeUserQuery = `
 	DECLARE $id AS Uint64;
-	DECLARE $sendingHour AS Uint8;
 
-    UPDATE users set subscribed = true, sendingHour = $sendingHour
+    UPDATE users set subscribed = true
+    WHERE id=$id;
+	`
+	addSendingTimeQuery = `
+	DECLARE $id AS Uint64;
+	DECLARE $minute AS Uint16;
+	DECLARE $kind AS Uint8;
+
+	UPSERT INTO sendingTimes (userId, minute, kind)
+	VALUES ($id, $minute, $kind);
+	`
+	removeSendingTimeQuery = `
+	DECLARE $id AS Uint64;
+	DECLARE $minute AS Uint16;
+
+	DELETE FROM sendingTimes
+	WHERE userId = $id and minute = $minute;
+	`
+	clearSendingTimesQuery = `
+	DECLARE $id AS Uint64;
+
+	DELETE FROM sendingTimes
+	WHERE userId = $id;
+	`
+	setTimeZoneQuery = `
+	DECLARE $id AS Uint64;
+	DECLARE $timeZone AS String;
+
+    UPDATE users set timeZone = $timeZone
+    WHERE id=$id;
+	`
+	setWeekdaysQuery = `
+	DECLARE $id AS Uint64;
+	DECLARE $weekdays AS Uint8;
+
+    UPDATE users set weekdays = $weekdays
+    WHERE id=$id;
+	`
+	setPausedUntilQuery = `
+	DECLARE $id AS Uint64;
+	DECLARE $pausedUntil AS Uint64;
+
+    UPDATE users set pausedUntil = $pausedUntil
+    WHERE id=$id;
+	`
+	setDifficultiesQuery = `
+	DECLARE $id AS Uint64;
+	DECLARE $difficulties AS Uint8;
+
+    UPDATE users set difficulties = $difficulties
+    WHERE id=$id;
+	`
+	setDifficultyFallbackQuery = `
+	DECLARE $id AS Uint64;
+	DECLARE $difficultyFallback AS Bool;
+
+    UPDATE users set difficultyFallback = $difficultyFallback
+    WHERE id=$id;
+	`
+	setCodeLanguageQuery = `
+	DECLARE $id AS Uint64;
+	DECLARE $codeLanguage AS String;
+
+    UPDATE users set codeLanguage = $codeLanguage
+    WHERE id=$id;
+	`
+	linkLeetcodeUsernameQuery = `
+	DECLARE $id AS Uint64;
+	DECLARE $leetcodeUsername AS String;
+
+    UPDATE users set leetcodeUsername = $leetcodeUsername
     WHERE id=$id;
 	`
 	unsubscribeUserQuery = `
@@ -72,6 +217,45 @@ const (
     UPDATE users set subscribed = false
     WHERE id=$id;
 	`
+	subscribeUserToNudgeQuery = `
+	DECLARE $id AS Uint64;
+	DECLARE $nudgeHour AS Uint8;
+
+    UPDATE users set nudgeSubscribed = true, nudgeHour = $nudgeHour
+    WHERE id=$id;
+	`
+	unsubscribeUserFromNudgeQuery = `
+	DECLARE $id AS Uint64;
+
+    UPDATE users set nudgeSubscribed = false
+    WHERE id=$id;
+	`
+	markNudgeSentQuery = `
+	DECLARE $id AS Uint64;
+	DECLARE $dateID AS Uint64;
+
+    UPDATE users set lastNudgeDateID = $dateID
+    WHERE id=$id;
+	`
+	getDeliveriesQuery = `
+	DECLARE $dateID AS Uint64;
+
+	SELECT userId, kind, status, attempts, messageId, lastError
+	FROM deliveries
+	WHERE dateId = $dateID;
+	`
+	saveDeliveryQuery = `
+	DECLARE $userId AS Uint64;
+	DECLARE $dateID AS Uint64;
+	DECLARE $kind AS String;
+	DECLARE $status AS Uint8;
+	DECLARE $attempts AS Uint32;
+	DECLARE $messageId AS Uint64;
+	DECLARE $lastError AS String;
+
+	UPSERT INTO deliveries (userId, dateId, kind, status, attempts, messageId, lastError)
+	VALUES ($userId, $dateID, $kind, $status, $attempts, $messageId, $lastError);
+	`
 )
 
 // YDBResult IMO is what supposed to be a part of ydb package. Interface to allow YDB response mocks
@@ -88,11 +272,13 @@ type queryExecuter interface {
 }
 
 type ydbQueryExecuter struct {
-	ExecQueryFunc      func(context.Context, table.SessionProvider, table.Operation) error
-	GetConnectionFunc  func(context.Context, connect.ConnectParams, ...connect.ConnectOption) (*connect.Connection, error)
-	txc                *table.TransactionControl
-	connection         *connect.Connection
-	connectionInitOnce sync.Once
+	ExecQueryFunc     func(context.Context, table.SessionProvider, table.Operation) error
+	GetConnectionFunc func(context.Context, connect.ConnectParams, ...connect.ConnectOption) (*connect.Connection, error)
+	ConnectionString  string
+	txc               *table.TransactionControl
+	breaker           *circuitbreaker.Breaker
+	connection        *connect.Connection
+	connectionMutex   sync.Mutex
 }
 
 type ydbStorage struct {
@@ -100,69 +286,119 @@ type ydbStorage struct {
 }
 
 type databaseTaskRow struct {
-	title      *string
-	content    *string
-	questionID *uint64
-	titleSlug  *string
-	hints      *string
-	difficulty *uint8
-	topicTags  *string
-}
-
-var initializedExecuter *ydbQueryExecuter
-var executerInitOnce sync.Once
-
-func newYDBQueryExecuter() *ydbQueryExecuter {
-	executerInitOnce.Do(func() {
-		initializedExecuter = &ydbQueryExecuter{
-			txc: table.TxControl(
-				table.BeginTx(table.WithSerializableReadWrite()),
-				table.CommitTx(),
-			),
-			ExecQueryFunc:     table.Retry,
-			GetConnectionFunc: connect.New,
-		}
-	})
-	return initializedExecuter
+	title            *string
+	content          *string
+	questionID       *uint64
+	titleSlug        *string
+	hints            *string
+	difficulty       *uint8
+	topicTags        *string
+	similarQuestions *string
 }
 
-func newYdbStorage() *ydbStorage {
-	ydbExecuter := newYDBQueryExecuter()
+var initializedExecuters = map[string]*ydbQueryExecuter{}
+var initializedExecutersMutex sync.Mutex
+
+// newYDBQueryExecuter returns executer shared by all storages of the same database, so they use one connection
+func newYDBQueryExecuter(cfg *config.Config) *ydbQueryExecuter {
+	initializedExecutersMutex.Lock()
+	defer initializedExecutersMutex.Unlock()
+	connectionString := cfg.YDBConnectionString()
+	if executer, ok := initializedExecuters[connectionString]; ok {
+		return executer
+	}
+	executer := &ydbQueryExecuter{
+		txc: table.TxControl(
+			table.BeginTx(table.WithSerializableReadWrite()),
+			table.CommitTx(),
+		),
+		ExecQueryFunc:     table.Retry,
+		GetConnectionFunc: connect.New,
+		ConnectionString:  connectionString,
+		breaker:           circuitbreaker.New(ydbBreakerThreshold, ydbBreakerOpenTimeout),
+	}
+	executer.breaker.IsFailure = isYDBFailure
+	initializedExecuters[connectionString] = executer
+	return executer
+}
+
+func newYdbStorage(cfg *config.Config) *ydbStorage {
+	ydbExecuter := newYDBQueryExecuter(cfg)
 	return &ydbStorage{
 		ydbExecuter: ydbExecuter,
 	}
 }
 
+// getConnection connects to YDB on the first call. Failed connection isn't cached, so the next call tries to connect again
 func (y *ydbQueryExecuter) getConnection() (*connect.Connection, error) {
-	var err error
-	y.connectionInitOnce.Do(func() {
-		y.connection, err = y.GetConnectionFunc(
-			context.TODO(),
-			connect.MustConnectionString(
-				fmt.Sprintf("%s/?database=%s", os.Getenv("YDB_ENDPOINT"), os.Getenv("YDB_DATABASE")),
-			),
-		)
-	})
+	y.connectionMutex.Lock()
+	defer y.connectionMutex.Unlock()
+	if y.connection != nil {
+		return y.connection, nil
+	}
+	connection, err := y.GetConnectionFunc(context.TODO(), connect.MustConnectionString(y.ConnectionString))
 	if err != nil {
 		return nil, err
 	}
-	if y.connection == nil {
+	if connection == nil {
 		return nil, ErrNoActiveTasksStorage
 	}
+	y.connection = connection
+	return connection, nil
+}
 
-	return y.connection, nil
+// resetConnection closes the broken connection, so the next query reconnects
+func (y *ydbQueryExecuter) resetConnection(connection *connect.Connection) {
+	y.connectionMutex.Lock()
+	defer y.connectionMutex.Unlock()
+	if y.connection != connection {
+		return
+	}
+	y.connection = nil
+	connection.Close()
+}
+
+// isYDBFailure separates YDB outages from errors of particular queries and closed contexts
+func isYDBFailure(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, common.ErrClosedContext) {
+		return false
+	}
+	var opError *ydb.OpError
+	if errors.As(err, &opError) {
+		return opError.Reason == ydb.StatusUnavailable || opError.Reason == ydb.StatusOverloaded
+	}
+	return true
 }
 
 func (y *ydbQueryExecuter) ProcessQuery(ctx context.Context, query string, queryParams *table.QueryParameters) (YDBResult, error) {
-	finishChan := make(chan error)
+	// Parameters are passed separately, so the statement has no user data
+	ctx, span := tracing.Start(ctx, "ydb.ProcessQuery", "db.system", "ydb", "db.statement", strings.Join(strings.Fields(query), " "))
+	defer span.End()
+	var res *table.Result
+	err := y.breaker.Do(func() error {
+		var err error
+		res, err = y.processQuery(ctx, query, queryParams)
+		return err
+	})
+	if errors.Is(err, circuitbreaker.ErrOpen) {
+		span.RecordError(ErrStorageUnavailable)
+		return nil, ErrStorageUnavailable
+	}
+	span.RecordError(err)
+	return res, err
+}
+
+func (y *ydbQueryExecuter) processQuery(ctx context.Context, query string, queryParams *table.QueryParameters) (*table.Result, error) {
+	finishChan := make(chan error, 1)
 	var connection *connect.Connection
-	var err error
 
 	go func() {
+		var err error
 		connection, err = y.getConnection()
 		finishChan <- err
 	}()
 
+	var err error
 	select {
 	case <-ctx.Done():
 		err = common.ErrClosedContext
@@ -181,13 +417,34 @@ func (y *ydbQueryExecuter) ProcessQuery(ctx context.Context, query string, query
 			return err
 		}),
 	)
+	var transportError *ydb.TransportError
+	if errors.As(err, &transportError) {
+		logging.FromContext(ctx).Warn("YDB transport error, reconnecting", "error", err)
+		y.resetConnection(connection)
+	}
 	return res, err
 }
 
 func (y *ydbStorage) getTask(ctx context.Context, dateID uint64) (common.BotLeetCodeTask, error) {
-	res, err := y.ydbExecuter.ProcessQuery(ctx, getTaskQuery, table.NewQueryParameters(
+	return y.queryTask(ctx, getTaskQuery, table.NewQueryParameters(
 		table.ValueParam("$dateId", ydb.Uint64Value(dateID)),
-	))
+	), dateID)
+}
+
+func (y *ydbStorage) getQuestion(ctx context.Context, questionID uint64) (common.BotLeetCodeTask, error) {
+	return y.queryTask(ctx, getQuestionQuery, table.NewQueryParameters(
+		table.ValueParam("$questionId", ydb.Uint64Value(questionID)),
+	), 0)
+}
+
+func (y *ydbStorage) getQuestionBySlug(ctx context.Context, titleSlug string) (common.BotLeetCodeTask, error) {
+	return y.queryTask(ctx, getQuestionBySlugQuery, table.NewQueryParameters(
+		table.ValueParam("$titleSlug", ydb.StringValue([]byte(titleSlug))),
+	), 0)
+}
+
+func (y *ydbStorage) queryTask(ctx context.Context, query string, queryParams *table.QueryParameters, dateID uint64) (common.BotLeetCodeTask, error) {
+	res, err := y.ydbExecuter.ProcessQuery(ctx, query, queryParams)
 	if err != nil {
 		return common.BotLeetCodeTask{}, err
 	}
@@ -197,7 +454,7 @@ func (y *ydbStorage) getTask(ctx context.Context, dateID uint64) (common.BotLeet
 	}
 
 	returnValue := common.BotLeetCodeTask{DateID: dateID}
-	for res.NextResultSet(ctx, "title", "content", "questionId", "titleSlug", "hints", "difficulty", "topicTags") {
+	for res.NextResultSet(ctx, "title", "content", "questionId", "titleSlug", "hints", "difficulty", "topicTags", "similarQuestions") {
 		for res.NextRow() {
 			row := databaseTaskRow{}
 			err = res.Scan(row.scanDestinations()...)
@@ -225,6 +482,7 @@ func (row *databaseTaskRow) scanDestinations() []interface{} {
 		&row.hints,
 		&row.difficulty,
 		&row.topicTags,
+		&row.similarQuestions,
 	}
 }
 
@@ -246,10 +504,24 @@ func (row *databaseTaskRow) toBotLeetCodeTask(dateID uint64) (common.BotLeetCode
 			return common.BotLeetCodeTask{}, err
 		}
 	}
+	if row.similarQuestions != nil && *row.similarQuestions != "" {
+		err = json.Unmarshal([]byte(*row.similarQuestions), &task.SimilarQuestions)
+		if err != nil {
+			return common.BotLeetCodeTask{}, err
+		}
+	}
 	return task, nil
 }
 
 func (y *ydbStorage) saveTask(ctx context.Context, task common.BotLeetCodeTask) error {
+	return y.replaceTask(ctx, replaceTaskQuery, task, table.ValueParam("$dateId", ydb.Uint64Value(task.DateID)))
+}
+
+func (y *ydbStorage) saveQuestion(ctx context.Context, task common.BotLeetCodeTask) error {
+	return y.replaceTask(ctx, replaceQuestionQuery, task)
+}
+
+func (y *ydbStorage) replaceTask(ctx context.Context, query string, task common.BotLeetCodeTask, keyParams ...table.ParameterOption) error {
 	marshalledHints, err := json.Marshal(task.Hints)
 	if err != nil {
 		return err
@@ -258,8 +530,11 @@ func (y *ydbStorage) saveTask(ctx context.Context, task common.BotLeetCodeTask)
 	if err != nil {
 		return err
 	}
-	_, err = y.ydbExecuter.ProcessQuery(ctx, replaceTaskQuery, table.NewQueryParameters(
-		table.ValueParam("$dateId", ydb.Uint64Value(task.DateID)),
+	marshalledSimilarQuestions, err := json.Marshal(task.SimilarQuestions)
+	if err != nil {
+		return err
+	}
+	params := append(keyParams,
 		table.ValueParam("$questionId", ydb.Uint64Value(task.QuestionID)),
 		table.ValueParam("$titleSlug", ydb.StringValue([]byte(task.TitleSlug))),
 		table.ValueParam("$title", ydb.StringValue([]byte(task.Title))),
@@ -267,8 +542,9 @@ func (y *ydbStorage) saveTask(ctx context.Context, task common.BotLeetCodeTask)
 		table.ValueParam("$hints", ydb.StringValue(marshalledHints)),
 		table.ValueParam("$difficulty", ydb.Uint8Value(task.GetDifficultyNum())),
 		table.ValueParam("$topicTags", ydb.StringValue(marshalledTopicTags)),
-	),
+		table.ValueParam("$similarQuestions", ydb.StringValue(marshalledSimilarQuestions)),
 	)
+	_, err = y.ydbExecuter.ProcessQuery(ctx, query, table.NewQueryParameters(params...))
 	return err
 }
 
@@ -287,17 +563,25 @@ func (y *ydbStorage) getUser(ctx context.Context, userID uint64) (common.User, e
 	}
 
 	var (
-		chatID      *uint64
-		username    *string
-		firstName   *string
-		lastName    *string
-		subscribed  *bool
-		sendingHour *uint8
+		chatID             *uint64
+		username           *string
+		firstName          *string
+		lastName           *string
+		subscribed         *bool
+		timeZone           *string
+		leetcodeUsername   *string
+		nudgeSubscribed    *bool
+		nudgeHour          *uint8
+		weekdays           *uint8
+		pausedUntil        *uint64
+		difficulties       *uint8
+		difficultyFallback *bool
+		codeLanguage       *string
 	)
 
 	returnValue := common.User{ID: userID}
 
-	for res.NextResultSet(ctx, "chat_id", "firstName", "lastName", "username", "subscribed", "sendingHour") {
+	for res.NextResultSet(ctx, "chat_id", "firstName", "lastName", "username", "subscribed", "timeZone", "leetcodeUsername", "nudgeSubscribed", "nudgeHour", "weekdays", "pausedUntil", "difficulties", "difficultyFallback", "codeLanguage") {
 		for res.NextRow() {
 			err := res.Scan(
 				&chatID,
@@ -305,7 +589,15 @@ func (y *ydbStorage) getUser(ctx context.Context, userID uint64) (common.User, e
 				&lastName,
 				&username,
 				&subscribed,
-				&sendingHour,
+				&timeZone,
+				&leetcodeUsername,
+				&nudgeSubscribed,
+				&nudgeHour,
+				&weekdays,
+				&pausedUntil,
+				&difficulties,
+				&difficultyFallback,
+				&codeLanguage,
 			)
 			if err != nil {
 				return common.User{}, err
@@ -315,28 +607,121 @@ func (y *ydbStorage) getUser(ctx context.Context, userID uint64) (common.User, e
 			returnValue.FirstName = *firstName
 			returnValue.LastName = *lastName
 			returnValue.Subscribed = *subscribed
-			returnValue.SendingHour = *sendingHour
+			if timeZone != nil {
+				returnValue.TimeZone = *timeZone
+			}
+			if leetcodeUsername != nil {
+				returnValue.LeetcodeUsername = *leetcodeUsername
+			}
+			if nudgeSubscribed != nil && nudgeHour != nil {
+				returnValue.NudgeSubscribed = *nudgeSubscribed
+				returnValue.NudgeHour = *nudgeHour
+			}
+			if weekdays != nil {
+				returnValue.Weekdays = common.Weekdays(*weekdays)
+			}
+			if pausedUntil != nil {
+				returnValue.PausedUntil = *pausedUntil
+			}
+			if difficulties != nil {
+				returnValue.Difficulties = common.Difficulties(*difficulties)
+			}
+			if difficultyFallback != nil {
+				returnValue.DifficultyFallback = *difficultyFallback
+			}
+			if codeLanguage != nil {
+				returnValue.CodeLanguage = *codeLanguage
+			}
+		}
+	}
+	if res.Err() != nil {
+		return common.User{}, res.Err()
+	}
+	returnValue.SendingTimes, err = y.getSendingTimes(ctx, userID)
+	if err != nil {
+		return common.User{}, err
+	}
+	return returnValue, nil
+}
+
+func (y *ydbStorage) getSendingTimes(ctx context.Context, userID uint64) ([]common.SendingTime, error) {
+	res, err := y.ydbExecuter.ProcessQuery(ctx, getSendingTimesQuery,
+		table.NewQueryParameters(
+			table.ValueParam("$id", ydb.Uint64Value(userID)),
+		),
+	)
+	if err != nil {
+		return []common.SendingTime{}, err
+	}
+
+	var (
+		minute *uint16
+		kind   *uint8
+	)
+	returnValue := []common.SendingTime{}
+
+	for res.NextResultSet(ctx, "minute", "kind") {
+		for res.NextRow() {
+			err := res.Scan(&minute, &kind)
+			if err != nil {
+				return []common.SendingTime{}, err
+			}
+			returnValue = append(returnValue, common.SendingTime{Minute: *minute, Kind: common.SendingTimeKind(*kind)})
 		}
 	}
 	return returnValue, res.Err()
 }
 
-func (y *ydbStorage) getSubscribedUsers(ctx context.Context, sendingHour uint8) ([]common.User, error) {
-	res, err := y.ydbExecuter.ProcessQuery(ctx, getSubscribedUsersQuery, table.NewQueryParameters(table.ValueParam("$sendingHour", ydb.Uint8Value(sendingHour))))
+func (y *ydbStorage) getSubscribedTimeZones(ctx context.Context) ([]string, error) {
+	res, err := y.ydbExecuter.ProcessQuery(ctx, getSubscribedTimeZonesQuery, table.NewQueryParameters())
+	if err != nil {
+		return []string{}, err
+	}
+
+	var timeZone *string
+	returnValue := []string{}
+
+	for res.NextResultSet(ctx, "timeZone") {
+		for res.NextRow() {
+			err := res.Scan(&timeZone)
+			if err != nil {
+				return []string{}, err
+			}
+			returnValue = append(returnValue, *timeZone)
+		}
+	}
+	return returnValue, res.Err()
+}
+
+// getSubscribedUsers returns users with sending times in the minuteRange, every user is returned once with all matched SendingTimes.
+// Users who don't want delivery at the localDate are skipped
+func (y *ydbStorage) getSubscribedUsers(ctx context.Context, timeZone string, minuteRange common.MinuteRange, localDate time.Time) ([]common.User, error) {
+	res, err := y.ydbExecuter.ProcessQuery(ctx, getSubscribedUsersQuery, table.NewQueryParameters(
+		table.ValueParam("$timeZone", ydb.StringValue([]byte(timeZone))),
+		table.ValueParam("$fromMinute", ydb.Uint16Value(minuteRange.From)),
+		table.ValueParam("$toMinute", ydb.Uint16Value(minuteRange.To)),
+		table.ValueParam("$weekdayBit", ydb.Uint8Value(uint8(1)<<localDate.Weekday())),
+		table.ValueParam("$dateID", ydb.Uint64Value(common.GetDateID(localDate))),
+	),
+	)
 	if err != nil {
 		return []common.User{}, err
 	}
 
 	var (
-		id        *uint64
-		chatID    *uint64
-		username  *string
-		firstName *string
-		lastName  *string
+		id                 *uint64
+		chatID             *uint64
+		username           *string
+		firstName          *string
+		lastName           *string
+		minute             *uint16
+		kind               *uint8
+		difficulties       *uint8
+		difficultyFallback *bool
 	)
 	returnValue := []common.User{}
 
-	for res.NextResultSet(ctx, "id", "chat_id", "firstName", "lastName", "username") {
+	for res.NextResultSet(ctx, "id", "chat_id", "firstName", "lastName", "username", "minute", "kind", "difficulties", "difficultyFallback") {
 		for res.NextRow() {
 			err := res.Scan(
 				&id,
@@ -344,20 +729,82 @@ func (y *ydbStorage) getSubscribedUsers(ctx context.Context, sendingHour uint8)
 				&firstName,
 				&lastName,
 				&username,
+				&minute,
+				&kind,
+				&difficulties,
+				&difficultyFallback,
 			)
 			if err != nil {
 				return []common.User{}, err
 			}
+			sendingTime := common.SendingTime{Minute: *minute, Kind: common.SendingTimeKind(*kind)}
+			// Rows are ordered by id, so all sending times of the user are adjacent
+			if last := len(returnValue) - 1; last >= 0 && returnValue[last].ID == *id {
+				returnValue[last].SendingTimes = append(returnValue[last].SendingTimes, sendingTime)
+				continue
+			}
 			returnValue = append(returnValue, common.User{
-				ID:          *id,
-				ChatID:      *chatID,
-				Username:    *username,
-				FirstName:   *firstName,
-				LastName:    *lastName,
-				Subscribed:  true,
-				SendingHour: sendingHour,
+				ID:                 *id,
+				ChatID:             *chatID,
+				Username:           *username,
+				FirstName:          *firstName,
+				LastName:           *lastName,
+				Subscribed:         true,
+				SendingTimes:       []common.SendingTime{sendingTime},
+				TimeZone:           timeZone,
+				Difficulties:       common.Difficulties(*difficulties),
+				DifficultyFallback: *difficultyFallback,
 			})
+		}
+	}
+	return returnValue, res.Err()
+}
+
+func (y *ydbStorage) getNudgeUsers(ctx context.Context, timeZone string, nudgeHour uint8, dateID uint64) ([]common.User, error) {
+	res, err := y.ydbExecuter.ProcessQuery(ctx, getNudgeUsersQuery, table.NewQueryParameters(
+		table.ValueParam("$timeZone", ydb.StringValue([]byte(timeZone))),
+		table.ValueParam("$nudgeHour", ydb.Uint8Value(nudgeHour)),
+		table.ValueParam("$dateID", ydb.Uint64Value(dateID)),
+	),
+	)
+	if err != nil {
+		return []common.User{}, err
+	}
+
+	var (
+		id               *uint64
+		chatID           *uint64
+		username         *string
+		firstName        *string
+		lastName         *string
+		leetcodeUsername *string
+	)
+	returnValue := []common.User{}
 
+	for res.NextResultSet(ctx, "id", "chat_id", "firstName", "lastName", "username", "leetcodeUsername") {
+		for res.NextRow() {
+			err := res.Scan(
+				&id,
+				&chatID,
+				&firstName,
+				&lastName,
+				&username,
+				&leetcodeUsername,
+			)
+			if err != nil {
+				return []common.User{}, err
+			}
+			returnValue = append(returnValue, common.User{
+				ID:               *id,
+				ChatID:           *chatID,
+				Username:         *username,
+				FirstName:        *firstName,
+				LastName:         *lastName,
+				TimeZone:         timeZone,
+				LeetcodeUsername: *leetcodeUsername,
+				NudgeSubscribed:  true,
+				NudgeHour:        nudgeHour,
+			})
 		}
 	}
 	return returnValue, res.Err()
@@ -371,16 +818,113 @@ func (y *ydbStorage) saveUser(ctx context.Context, user common.User) error {
 		table.ValueParam("$lastname", ydb.StringValue([]byte(user.LastName))),
 		table.ValueParam("$username", ydb.StringValue([]byte(user.Username))),
 		table.ValueParam("$subscribed", ydb.BoolValue(user.Subscribed)),
-		table.ValueParam("$sendingHour", ydb.Uint8Value(user.SendingHour)),
+		table.ValueParam("$timeZone", ydb.StringValue([]byte(user.TimeZone))),
+		table.ValueParam("$leetcodeUsername", ydb.StringValue([]byte(user.LeetcodeUsername))),
+		table.ValueParam("$nudgeSubscribed", ydb.BoolValue(user.NudgeSubscribed)),
+		table.ValueParam("$nudgeHour", ydb.Uint8Value(user.NudgeHour)),
+		table.ValueParam("$weekdays", ydb.Uint8Value(uint8(user.Weekdays))),
+		table.ValueParam("$pausedUntil", ydb.Uint64Value(user.PausedUntil)),
+		table.ValueParam("$difficulties", ydb.Uint8Value(uint8(user.Difficulties))),
+		table.ValueParam("$difficultyFallback", ydb.BoolValue(user.DifficultyFallback)),
+		table.ValueParam("$codeLanguage", ydb.StringValue([]byte(user.CodeLanguage))),
+	),
+	)
+	return err
+}
+
+// addSendingTime stores the sending time and subscribes the user
+func (y *ydbStorage) addSendingTime(ctx context.Context, userID uint64, sendingTime common.SendingTime) error {
+	_, err := y.ydbExecuter.ProcessQuery(ctx, addSendingTimeQuery, table.NewQueryParameters(
+		table.ValueParam("$id", ydb.Uint64Value(userID)),
+		table.ValueParam("$minute", ydb.Uint16Value(sendingTime.Minute)),
+		table.ValueParam("$kind", ydb.Uint8Value(uint8(sendingTime.Kind))),
+	),
+	)
+	if err != nil {
+		return err
+	}
+	_, err = y.ydbExecuter.ProcessQuery(ctx, subscribeUserQuery, table.NewQueryParameters(
+		table.ValueParam("$id", ydb.Uint64Value(userID)),
+	),
+	)
+	return err
+}
+
+func (y *ydbStorage) removeSendingTime(ctx context.Context, userID uint64, minute uint16) error {
+	_, err := y.ydbExecuter.ProcessQuery(ctx, removeSendingTimeQuery, table.NewQueryParameters(
+		table.ValueParam("$id", ydb.Uint64Value(userID)),
+		table.ValueParam("$minute", ydb.Uint16Value(minute)),
+	),
+	)
+	return err
+}
+
+func (y *ydbStorage) clearSendingTimes(ctx context.Context, userID uint64) error {
+	_, err := y.ydbExecuter.ProcessQuery(ctx, clearSendingTimesQuery, table.NewQueryParameters(
+		table.ValueParam("$id", ydb.Uint64Value(userID)),
+	),
+	)
+	return err
+}
+
+func (y *ydbStorage) setTimeZone(ctx context.Context, userID uint64, timeZone string) error {
+	_, err := y.ydbExecuter.ProcessQuery(ctx, setTimeZoneQuery, table.NewQueryParameters(
+		table.ValueParam("$id", ydb.Uint64Value(userID)),
+		table.ValueParam("$timeZone", ydb.StringValue([]byte(timeZone))),
+	),
+	)
+	return err
+}
+
+func (y *ydbStorage) setWeekdays(ctx context.Context, userID uint64, weekdays common.Weekdays) error {
+	_, err := y.ydbExecuter.ProcessQuery(ctx, setWeekdaysQuery, table.NewQueryParameters(
+		table.ValueParam("$id", ydb.Uint64Value(userID)),
+		table.ValueParam("$weekdays", ydb.Uint8Value(uint8(weekdays))),
 	),
 	)
 	return err
 }
 
-func (y *ydbStorage) subscribeUser(ctx context.Context, userID uint64, sendingHour uint8) error {
-	_, err := y.ydbExecuter.ProcessQuery(ctx, subscribeUserQuery, table.NewQueryParameters(
+func (y *ydbStorage) setPausedUntil(ctx context.Context, userID uint64, pausedUntil uint64) error {
+	_, err := y.ydbExecuter.ProcessQuery(ctx, setPausedUntilQuery, table.NewQueryParameters(
 		table.ValueParam("$id", ydb.Uint64Value(userID)),
-		table.ValueParam("$sendingHour", ydb.Uint8Value(sendingHour)),
+		table.ValueParam("$pausedUntil", ydb.Uint64Value(pausedUntil)),
+	),
+	)
+	return err
+}
+
+func (y *ydbStorage) setDifficulties(ctx context.Context, userID uint64, difficulties common.Difficulties) error {
+	_, err := y.ydbExecuter.ProcessQuery(ctx, setDifficultiesQuery, table.NewQueryParameters(
+		table.ValueParam("$id", ydb.Uint64Value(userID)),
+		table.ValueParam("$difficulties", ydb.Uint8Value(uint8(difficulties))),
+	),
+	)
+	return err
+}
+
+func (y *ydbStorage) setDifficultyFallback(ctx context.Context, userID uint64, difficultyFallback bool) error {
+	_, err := y.ydbExecuter.ProcessQuery(ctx, setDifficultyFallbackQuery, table.NewQueryParameters(
+		table.ValueParam("$id", ydb.Uint64Value(userID)),
+		table.ValueParam("$difficultyFallback", ydb.BoolValue(difficultyFallback)),
+	),
+	)
+	return err
+}
+
+func (y *ydbStorage) setCodeLanguage(ctx context.Context, userID uint64, codeLanguage string) error {
+	_, err := y.ydbExecuter.ProcessQuery(ctx, setCodeLanguageQuery, table.NewQueryParameters(
+		table.ValueParam("$id", ydb.Uint64Value(userID)),
+		table.ValueParam("$codeLanguage", ydb.StringValue([]byte(codeLanguage))),
+	),
+	)
+	return err
+}
+
+func (y *ydbStorage) linkLeetcodeUsername(ctx context.Context, userID uint64, leetcodeUsername string) error {
+	_, err := y.ydbExecuter.ProcessQuery(ctx, linkLeetcodeUsernameQuery, table.NewQueryParameters(
+		table.ValueParam("$id", ydb.Uint64Value(userID)),
+		table.ValueParam("$leetcodeUsername", ydb.StringValue([]byte(leetcodeUsername))),
 	),
 	)
 	return err
@@ -393,3 +937,83 @@ func (y *ydbStorage) unsubscribeUser(ctx context.Context, userID uint64) error {
 	)
 	return err
 }
+
+func (y *ydbStorage) subscribeUserToNudge(ctx context.Context, userID uint64, nudgeHour uint8) error {
+	_, err := y.ydbExecuter.ProcessQuery(ctx, subscribeUserToNudgeQuery, table.NewQueryParameters(
+		table.ValueParam("$id", ydb.Uint64Value(userID)),
+		table.ValueParam("$nudgeHour", ydb.Uint8Value(nudgeHour)),
+	),
+	)
+	return err
+}
+
+func (y *ydbStorage) unsubscribeUserFromNudge(ctx context.Context, userID uint64) error {
+	_, err := y.ydbExecuter.ProcessQuery(ctx, unsubscribeUserFromNudgeQuery, table.NewQueryParameters(
+		table.ValueParam("$id", ydb.Uint64Value(userID)),
+	),
+	)
+	return err
+}
+
+func (y *ydbStorage) markNudgeSent(ctx context.Context, userID uint64, dateID uint64) error {
+	_, err := y.ydbExecuter.ProcessQuery(ctx, markNudgeSentQuery, table.NewQueryParameters(
+		table.ValueParam("$id", ydb.Uint64Value(userID)),
+		table.ValueParam("$dateID", ydb.Uint64Value(dateID)),
+	),
+	)
+	return err
+}
+
+// getDeliveries returns log of all deliveries of the dateID daily task
+func (y *ydbStorage) getDeliveries(ctx context.Context, dateID uint64) ([]common.Delivery, error) {
+	res, err := y.ydbExecuter.ProcessQuery(ctx, getDeliveriesQuery, table.NewQueryParameters(
+		table.ValueParam("$dateID", ydb.Uint64Value(dateID)),
+	),
+	)
+	if err != nil {
+		return []common.Delivery{}, err
+	}
+
+	var (
+		userID    *uint64
+		kind      *string
+		status    *uint8
+		attempts  *uint32
+		messageID *uint64
+		lastError *string
+	)
+	returnValue := []common.Delivery{}
+
+	for res.NextResultSet(ctx, "userId", "kind", "status", "attempts", "messageId", "lastError") {
+		for res.NextRow() {
+			err := res.Scan(&userID, &kind, &status, &attempts, &messageID, &lastError)
+			if err != nil {
+				return []common.Delivery{}, err
+			}
+			returnValue = append(returnValue, common.Delivery{
+				UserID:    *userID,
+				DateID:    dateID,
+				Kind:      *kind,
+				Status:    common.DeliveryStatus(*status),
+				Attempts:  *attempts,
+				MessageID: *messageID,
+				LastError: *lastError,
+			})
+		}
+	}
+	return returnValue, res.Err()
+}
+
+func (y *ydbStorage) saveDelivery(ctx context.Context, delivery common.Delivery) error {
+	_, err := y.ydbExecuter.ProcessQuery(ctx, saveDeliveryQuery, table.NewQueryParameters(
+		table.ValueParam("$userId", ydb.Uint64Value(delivery.UserID)),
+		table.ValueParam("$dateID", ydb.Uint64Value(delivery.DateID)),
+		table.ValueParam("$kind", ydb.StringValue([]byte(delivery.Kind))),
+		table.ValueParam("$status", ydb.Uint8Value(uint8(delivery.Status))),
+		table.ValueParam("$attempts", ydb.Uint32Value(delivery.Attempts)),
+		table.ValueParam("$messageId", ydb.Uint64Value(delivery.MessageID)),
+		table.ValueParam("$lastError", ydb.StringValue([]byte(delivery.LastError))),
+	),
+	)
+	return err
+}
@@ -8,13 +8,17 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/dartkron/leetcodeBot/v3/internal/common"
+	"github.com/dartkron/leetcodeBot/v3/internal/config"
+	"github.com/dartkron/leetcodeBot/v3/pkg/circuitbreaker"
 	"github.com/dartkron/leetcodeBot/v3/pkg/leetcodeclient"
 	"github.com/dartkron/leetcodeBot/v3/tests"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/yandex-cloud/ydb-go-sdk/v2"
+	"github.com/yandex-cloud/ydb-go-sdk/v2/connect"
 	"github.com/yandex-cloud/ydb-go-sdk/v2/table"
 )
 
@@ -129,20 +133,82 @@ func trimmQuery(query string) string {
 	return space.ReplaceAllString(query, " ")
 }
 
+// matchQueryParams matches String() of query parameters with the params in any order, as QueryParameters iterate over a map
+func matchQueryParams(params ...table.ParameterOption) interface{} {
+	expected := make([]string, 0, len(params))
+	for _, param := range params {
+		single := table.NewQueryParameters(param).String()
+		expected = append(expected, single[1:len(single)-1])
+	}
+	return mock.MatchedBy(func(actual string) bool {
+		return matchParamsPermutation("(", expected, actual)
+	})
+}
+
+func matchParamsPermutation(prefix string, rest []string, actual string) bool {
+	if len(rest) == 0 {
+		return prefix+")" == actual
+	}
+	if !strings.HasPrefix(actual, prefix) {
+		return false
+	}
+	for i := range rest {
+		others := append(append([]string{}, rest[:i]...), rest[i+1:]...)
+		if matchParamsPermutation(prefix+rest[i], others, actual) {
+			return true
+		}
+	}
+	return false
+}
+
 func TestNewYdbStorage(t *testing.T) {
-	ydbStore := newYdbStorage()
+	ydbStore := newYdbStorage(config.Default())
 	assert.NotNil(t, ydbStore.ydbExecuter, "ydbExecuter must be set in constructor")
 }
 
 func TestNewYDBExecuter(t *testing.T) {
-	e := newYDBQueryExecuter()
+	e := newYDBQueryExecuter(config.Default())
 	assert.NotNil(t, e.txc, "txc must be set in constructor")
 	assert.NotNil(t, e.ExecQueryFunc, "ExecQueryFunc must be set in constructor")
 	assert.NotNil(t, e.GetConnectionFunc, "GetConnectionFunc must be set in constructor")
+	assert.Equal(t, "/?database=", e.ConnectionString, "ConnectionString must be set in constructor")
+	assert.Same(t, e, newYDBQueryExecuter(config.Default()), "Executer should be shared for the same database")
+	cfg := config.Default()
+	cfg.YDBDatabase = "/other"
+	assert.NotSame(t, e, newYDBQueryExecuter(cfg), "Executer shouldn't be shared for different databases")
+}
+
+func TestProcessQueryReconnect(t *testing.T) {
+	connects := 0
+	e := &ydbQueryExecuter{
+		GetConnectionFunc: func(context.Context, connect.ConnectParams, ...connect.ConnectOption) (*connect.Connection, error) {
+			connects++
+			return nil, tests.ErrBypassTest
+		},
+		ConnectionString: "grpcs://localhost:2135/?database=/local",
+		breaker:          circuitbreaker.New(ydbBreakerThreshold, ydbBreakerOpenTimeout),
+	}
+	for i := 0; i < ydbBreakerThreshold; i++ {
+		_, err := e.ProcessQuery(context.Background(), getTaskQuery, table.NewQueryParameters())
+		assert.Equal(t, tests.ErrBypassTest, err, "Unexpected ProcessQuery error")
+	}
+	assert.Equal(t, ydbBreakerThreshold, connects, "Failed connection shouldn't be cached")
+	_, err := e.ProcessQuery(context.Background(), getTaskQuery, table.NewQueryParameters())
+	assert.Equal(t, ErrStorageUnavailable, err, "Open breaker should return ErrStorageUnavailable")
+	assert.Equal(t, ydbBreakerThreshold, connects, "Open breaker shouldn't connect")
+}
+
+func TestIsYDBFailure(t *testing.T) {
+	assert.True(t, isYDBFailure(tests.ErrBypassTest), "Unknown errors should be failures")
+	assert.True(t, isYDBFailure(&ydb.OpError{Reason: ydb.StatusUnavailable}), "Unavailable should be failure")
+	assert.True(t, isYDBFailure(&ydb.OpError{Reason: ydb.StatusOverloaded}), "Overloaded should be failure")
+	assert.False(t, isYDBFailure(&ydb.OpError{Reason: ydb.StatusSchemeError}), "Query errors shouldn't be failures")
+	assert.False(t, isYDBFailure(common.ErrClosedContext), "Closed context shouldn't be failure")
+	assert.False(t, isYDBFailure(context.Canceled), "Canceled context shouldn't be failure")
 }
 
 func TestGetTaskYDBError(t *testing.T) {
-	storage := newYdbStorage()
+	storage := newYdbStorage(config.Default())
 	mockExecuter := new(MockQueryExecuter)
 	mockExecuter.t = t
 	dateID := uint64(55674)
@@ -165,25 +231,27 @@ func TestGetTaskYDBError(t *testing.T) {
 // It's necessary while in database []string storing as string
 // and Difficulty is storing as uint8
 type databaseBotLeetcodeTask struct {
-	DateID     uint64
-	QuestionID uint64
-	TitleSlug  string
-	Title      string
-	Content    string
-	Hints      string
-	Difficulty uint8
-	TopicTags  string
+	DateID           uint64
+	QuestionID       uint64
+	TitleSlug        string
+	Title            string
+	Content          string
+	Hints            string
+	Difficulty       uint8
+	TopicTags        string
+	SimilarQuestions string
 }
 
 type databaseBotLeetcodeTaskWithNullTopicTags struct {
-	DateID     uint64
-	QuestionID uint64
-	TitleSlug  string
-	Title      string
-	Content    string
-	Hints      string
-	Difficulty uint8
-	TopicTags  *string
+	DateID           uint64
+	QuestionID       uint64
+	TitleSlug        string
+	Title            string
+	Content          string
+	Hints            string
+	Difficulty       uint8
+	TopicTags        *string
+	SimilarQuestions *string
 }
 
 func (dbTask *databaseBotLeetcodeTask) fillFromBotLeetcode(task common.BotLeetCodeTask) error {
@@ -202,12 +270,17 @@ func (dbTask *databaseBotLeetcodeTask) fillFromBotLeetcode(task common.BotLeetCo
 		return err
 	}
 	dbTask.TopicTags = string(marshalledTopicTags)
+	marshalledSimilarQuestions, err := json.Marshal(task.SimilarQuestions)
+	if err != nil {
+		return err
+	}
+	dbTask.SimilarQuestions = string(marshalledSimilarQuestions)
 	dbTask.Difficulty = task.GetDifficultyNum()
 	return nil
 }
 
 func TestGetTaskYDB(t *testing.T) {
-	storage := newYdbStorage()
+	storage := newYdbStorage(config.Default())
 	mockExecuter := new(MockQueryExecuter)
 	mockExecuter.t = t
 	dateID := uint64(55674)
@@ -224,6 +297,9 @@ func TestGetTaskYDB(t *testing.T) {
 				{Name: "Array", Slug: "array"},
 				{Name: "Simulation", Slug: "simulation"},
 			},
+			SimilarQuestions: leetcodeclient.SimilarQuestions{
+				{Title: "Two Sum", TitleSlug: "two-sum", Difficulty: "Easy"},
+			},
 		},
 	}
 	dbTask := databaseBotLeetcodeTask{}
@@ -247,8 +323,97 @@ func TestGetTaskYDB(t *testing.T) {
 	assert.Equal(t, taskToLoad, resp, "Unexpected task returned")
 }
 
+func TestGetQuestionYDB(t *testing.T) {
+	storage := newYdbStorage(config.Default())
+	mockExecuter := new(MockQueryExecuter)
+	mockExecuter.t = t
+	questionID := uint64(15)
+	taskToLoad := common.BotLeetCodeTask{
+		LeetCodeTask: leetcodeclient.LeetCodeTask{
+			QuestionID: questionID,
+			TitleSlug:  "3sum",
+			Title:      "3Sum",
+			Content:    "Find all triplets",
+			Hints:      []string{"First hint"},
+			Difficulty: "Medium",
+			TopicTags: []leetcodeclient.TopicTag{
+				{Name: "Array", Slug: "array"},
+			},
+		},
+	}
+	dbTask := databaseBotLeetcodeTask{}
+	dbTask.fillFromBotLeetcode(taskToLoad)
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(getQuestionQuery),
+		table.NewQueryParameters(
+			table.ValueParam("$questionId", ydb.Uint64Value(questionID)),
+		).String(),
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{dbTask},
+			t:    t,
+		},
+		nil,
+	).Once()
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(getQuestionQuery),
+		table.NewQueryParameters(
+			table.ValueParam("$questionId", ydb.Uint64Value(questionID)),
+		).String(),
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{},
+			t:    t,
+		},
+		nil,
+	).Once()
+	storage.ydbExecuter = mockExecuter
+	resp, err := storage.getQuestion(context.Background(), questionID)
+	assert.Nil(t, err, "Unexpected error")
+	assert.Equal(t, taskToLoad, resp, "Unexpected question returned")
+	_, err = storage.getQuestion(context.Background(), questionID)
+	assert.Equal(t, ErrNoSuchTask, err, "Unexpected error")
+}
+
+func TestGetQuestionBySlugYDB(t *testing.T) {
+	storage := newYdbStorage(config.Default())
+	mockExecuter := new(MockQueryExecuter)
+	mockExecuter.t = t
+	taskToLoad := common.BotLeetCodeTask{
+		LeetCodeTask: leetcodeclient.LeetCodeTask{
+			QuestionID: 16,
+			TitleSlug:  "3sum-closest",
+			Title:      "3Sum Closest",
+			Content:    "Find the closest sum",
+			Hints:      []string{},
+			Difficulty: "Medium",
+		},
+	}
+	dbTask := databaseBotLeetcodeTask{}
+	dbTask.fillFromBotLeetcode(taskToLoad)
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(getQuestionBySlugQuery),
+		table.NewQueryParameters(
+			table.ValueParam("$titleSlug", ydb.StringValue([]byte("3sum-closest"))),
+		).String(),
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{dbTask},
+			t:    t,
+		},
+		nil,
+	)
+	storage.ydbExecuter = mockExecuter
+	resp, err := storage.getQuestionBySlug(context.Background(), "3sum-closest")
+	assert.Nil(t, err, "Unexpected error")
+	assert.Equal(t, taskToLoad, resp, "Unexpected question returned")
+}
+
 func TestGetTaskYDBNoRows(t *testing.T) {
-	storage := newYdbStorage()
+	storage := newYdbStorage(config.Default())
 	mockExecuter := new(MockQueryExecuter)
 	mockExecuter.t = t
 	dateID := uint64(55674)
@@ -272,7 +437,7 @@ func TestGetTaskYDBNoRows(t *testing.T) {
 }
 
 func TestGetTaskYDBBrokenJSON(t *testing.T) {
-	storage := newYdbStorage()
+	storage := newYdbStorage(config.Default())
 	mockExecuter := new(MockQueryExecuter)
 	mockExecuter.t = t
 	dateID := uint64(55674)
@@ -312,7 +477,7 @@ func TestGetTaskYDBBrokenJSON(t *testing.T) {
 }
 
 func TestGetTaskYDBBrokenTopicTagsJSON(t *testing.T) {
-	storage := newYdbStorage()
+	storage := newYdbStorage(config.Default())
 	mockExecuter := new(MockQueryExecuter)
 	mockExecuter.t = t
 	dateID := uint64(55674)
@@ -352,8 +517,49 @@ func TestGetTaskYDBBrokenTopicTagsJSON(t *testing.T) {
 	assert.Equal(t, common.BotLeetCodeTask{}, resp, "Unexpected task returned")
 }
 
+func TestGetTaskYDBBrokenSimilarQuestionsJSON(t *testing.T) {
+	storage := newYdbStorage(config.Default())
+	mockExecuter := new(MockQueryExecuter)
+	mockExecuter.t = t
+	dateID := uint64(55674)
+	taskToLoad := common.BotLeetCodeTask{
+		DateID: dateID,
+		LeetCodeTask: leetcodeclient.LeetCodeTask{
+			QuestionID: 1235,
+			TitleSlug:  "very-very-test-slug",
+			Title:      "Very very test task",
+			Content:    "My test context",
+			Hints:      []string{"First hint", "Second hint", "Third hint"},
+			Difficulty: "Easy",
+			TopicTags:  []leetcodeclient.TopicTag{{Name: "Array", Slug: "array"}},
+		},
+	}
+	dbTask := databaseBotLeetcodeTask{}
+	dbTask.fillFromBotLeetcode(taskToLoad)
+	dbTask.SimilarQuestions = "{\"}"
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(getTaskQuery),
+		table.NewQueryParameters(
+			table.ValueParam("$dateId", ydb.Uint64Value(dateID)),
+		).String(),
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{dbTask},
+			t:    t,
+		},
+		nil,
+	)
+	storage.ydbExecuter = mockExecuter
+	resp, err := storage.getTask(context.Background(), dateID)
+	if assert.NotNil(t, err, "Expect error on broken JSON") {
+		assert.Equal(t, tests.ErrWrongJSON.Error(), err.Error(), "Unexpected error")
+	}
+	assert.Equal(t, common.BotLeetCodeTask{}, resp, "Unexpected task returned")
+}
+
 func TestGetTaskYDBNullTopicTags(t *testing.T) {
-	storage := newYdbStorage()
+	storage := newYdbStorage(config.Default())
 	mockExecuter := new(MockQueryExecuter)
 	mockExecuter.t = t
 	dateID := uint64(55674)
@@ -371,14 +577,15 @@ func TestGetTaskYDBNullTopicTags(t *testing.T) {
 	dbTask := databaseBotLeetcodeTask{}
 	dbTask.fillFromBotLeetcode(taskToLoad)
 	dbTaskWithNullTopicTags := databaseBotLeetcodeTaskWithNullTopicTags{
-		DateID:     dbTask.DateID,
-		QuestionID: dbTask.QuestionID,
-		TitleSlug:  dbTask.TitleSlug,
-		Title:      dbTask.Title,
-		Content:    dbTask.Content,
-		Hints:      dbTask.Hints,
-		Difficulty: dbTask.Difficulty,
-		TopicTags:  nil,
+		DateID:           dbTask.DateID,
+		QuestionID:       dbTask.QuestionID,
+		TitleSlug:        dbTask.TitleSlug,
+		Title:            dbTask.Title,
+		Content:          dbTask.Content,
+		Hints:            dbTask.Hints,
+		Difficulty:       dbTask.Difficulty,
+		TopicTags:        nil,
+		SimilarQuestions: nil,
 	}
 	mockExecuter.On(
 		"ProcessQuery",
@@ -400,7 +607,7 @@ func TestGetTaskYDBNullTopicTags(t *testing.T) {
 }
 
 func TestGetTaskYDBScanError(t *testing.T) {
-	storage := newYdbStorage()
+	storage := newYdbStorage(config.Default())
 	mockExecuter := new(MockQueryExecuter)
 	mockExecuter.t = t
 	dateID := uint64(55674)
@@ -438,7 +645,7 @@ func TestGetTaskYDBScanError(t *testing.T) {
 }
 
 func TestSaveTask(t *testing.T) {
-	storage := newYdbStorage()
+	storage := newYdbStorage(config.Default())
 	mockExecuter := new(MockQueryExecuter)
 	mockExecuter.t = t
 	storage.ydbExecuter = mockExecuter
@@ -459,7 +666,7 @@ func TestSaveTask(t *testing.T) {
 }
 
 func TestSaveTaskErr(t *testing.T) {
-	storage := newYdbStorage()
+	storage := newYdbStorage(config.Default())
 	mockExecuter := new(MockQueryExecuter)
 	mockExecuter.t = t
 	storage.ydbExecuter = mockExecuter
@@ -479,8 +686,41 @@ func TestSaveTaskErr(t *testing.T) {
 	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
 }
 
+func TestSaveQuestionYDB(t *testing.T) {
+	storage := newYdbStorage(config.Default())
+	mockExecuter := new(MockQueryExecuter)
+	mockExecuter.t = t
+	storage.ydbExecuter = mockExecuter
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(replaceQuestionQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{},
+			t:    t,
+		},
+		nil,
+	).Once()
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(replaceQuestionQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{},
+			t:    t,
+		},
+		tests.ErrBypassTest,
+	).Once()
+	err := storage.saveQuestion(context.Background(), common.BotLeetCodeTask{})
+	assert.Nil(t, err, "Unexpected error")
+	err = storage.saveQuestion(context.Background(), common.BotLeetCodeTask{})
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
+}
+
 func TestSaveUser(t *testing.T) {
-	storage := newYdbStorage()
+	storage := newYdbStorage(config.Default())
 	mockExecuter := new(MockQueryExecuter)
 	mockExecuter.t = t
 	storage.ydbExecuter = mockExecuter
@@ -501,7 +741,7 @@ func TestSaveUser(t *testing.T) {
 }
 
 func TestSaveUserErr(t *testing.T) {
-	storage := newYdbStorage()
+	storage := newYdbStorage(config.Default())
 	mockExecuter := new(MockQueryExecuter)
 	mockExecuter.t = t
 	storage.ydbExecuter = mockExecuter
@@ -521,50 +761,111 @@ func TestSaveUserErr(t *testing.T) {
 	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
 }
 
-func TestSubscribeUser(t *testing.T) {
-	storage := newYdbStorage()
+func TestAddSendingTime(t *testing.T) {
+	storage := newYdbStorage(config.Default())
+	mockExecuter := new(MockQueryExecuter)
+	mockExecuter.t = t
+	storage.ydbExecuter = mockExecuter
+	for _, query := range []string{addSendingTimeQuery, subscribeUserQuery} {
+		mockExecuter.On(
+			"ProcessQuery",
+			trimmQuery(query),
+			mock.Anything,
+		).Return(
+			&YDBResultMock{
+				rows: []interface{}{},
+				t:    t,
+			},
+			nil,
+		).Once()
+	}
+	err := storage.addSendingTime(context.Background(), 123, common.SendingTime{Minute: 450, Kind: common.HintSendingTime})
+	assert.Nil(t, err, "Unexpected error")
+	mockExecuter.AssertExpectations(t)
+}
+
+func TestAddSendingTimeError(t *testing.T) {
+	storage := newYdbStorage(config.Default())
 	mockExecuter := new(MockQueryExecuter)
 	mockExecuter.t = t
 	storage.ydbExecuter = mockExecuter
 	mockExecuter.On(
 		"ProcessQuery",
-		trimmQuery(subscribeUserQuery),
+		trimmQuery(addSendingTimeQuery),
 		mock.Anything,
 	).Return(
 		&YDBResultMock{
-			rows:      []interface{}{},
-			t:         t,
-			scanError: tests.ErrBypassTest,
+			rows: []interface{}{},
+			t:    t,
 		},
-		nil,
-	)
-	err := storage.subscribeUser(context.Background(), 123, 7)
-	assert.Nil(t, err, "Unexpected error")
-}
+		tests.ErrBypassTest,
+	).Once()
+	err := storage.addSendingTime(context.Background(), 123, common.SendingTime{Minute: 450})
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
 
-func TestSubscribeUserError(t *testing.T) {
-	storage := newYdbStorage()
-	mockExecuter := new(MockQueryExecuter)
-	mockExecuter.t = t
-	storage.ydbExecuter = mockExecuter
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(addSendingTimeQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{},
+			t:    t,
+		},
+		nil,
+	).Once()
 	mockExecuter.On(
 		"ProcessQuery",
 		trimmQuery(subscribeUserQuery),
 		mock.Anything,
 	).Return(
 		&YDBResultMock{
-			rows:      []interface{}{},
-			t:         t,
-			scanError: tests.ErrBypassTest,
+			rows: []interface{}{},
+			t:    t,
 		},
 		tests.ErrBypassTest,
-	)
-	err := storage.subscribeUser(context.Background(), 123, 7)
+	).Once()
+	err = storage.addSendingTime(context.Background(), 123, common.SendingTime{Minute: 450})
 	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
 }
 
+func TestRemoveSendingTimes(t *testing.T) {
+	storage := newYdbStorage(config.Default())
+	mockExecuter := new(MockQueryExecuter)
+	mockExecuter.t = t
+	storage.ydbExecuter = mockExecuter
+	for _, query := range []string{removeSendingTimeQuery, clearSendingTimesQuery} {
+		mockExecuter.On(
+			"ProcessQuery",
+			trimmQuery(query),
+			mock.Anything,
+		).Return(
+			&YDBResultMock{
+				rows: []interface{}{},
+				t:    t,
+			},
+			nil,
+		).Once()
+		mockExecuter.On(
+			"ProcessQuery",
+			trimmQuery(query),
+			mock.Anything,
+		).Return(
+			&YDBResultMock{
+				rows: []interface{}{},
+				t:    t,
+			},
+			tests.ErrBypassTest,
+		).Once()
+	}
+	assert.Nil(t, storage.removeSendingTime(context.Background(), 123, 450), "Unexpected error")
+	assert.Equal(t, tests.ErrBypassTest, storage.removeSendingTime(context.Background(), 123, 450), "Unexpected error")
+	assert.Nil(t, storage.clearSendingTimes(context.Background(), 123), "Unexpected error")
+	assert.Equal(t, tests.ErrBypassTest, storage.clearSendingTimes(context.Background(), 123), "Unexpected error")
+}
+
 func TestUnsubscribeUser(t *testing.T) {
-	storage := newYdbStorage()
+	storage := newYdbStorage(config.Default())
 	mockExecuter := new(MockQueryExecuter)
 	mockExecuter.t = t
 	storage.ydbExecuter = mockExecuter
@@ -585,7 +886,7 @@ func TestUnsubscribeUser(t *testing.T) {
 }
 
 func TestUnsubscribeUserError(t *testing.T) {
-	storage := newYdbStorage()
+	storage := newYdbStorage(config.Default())
 	mockExecuter := new(MockQueryExecuter)
 	mockExecuter.t = t
 	storage.ydbExecuter = mockExecuter
@@ -605,52 +906,108 @@ func TestUnsubscribeUserError(t *testing.T) {
 	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
 }
 
+func TestLinkLeetcodeUsername(t *testing.T) {
+	storage := newYdbStorage(config.Default())
+	mockExecuter := new(MockQueryExecuter)
+	mockExecuter.t = t
+	storage.ydbExecuter = mockExecuter
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(linkLeetcodeUsernameQuery),
+		matchQueryParams(
+			table.ValueParam("$id", ydb.Uint64Value(123)),
+			table.ValueParam("$leetcodeUsername", ydb.StringValue([]byte("leetcoder"))),
+		),
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{},
+			t:    t,
+		},
+		nil,
+	).Once()
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(linkLeetcodeUsernameQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{},
+			t:    t,
+		},
+		tests.ErrBypassTest,
+	).Once()
+	err := storage.linkLeetcodeUsername(context.Background(), 123, "leetcoder")
+	assert.Nil(t, err, "Unexpected error")
+	err = storage.linkLeetcodeUsername(context.Background(), 123, "")
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
+}
+
+type databaseSubscribedUser struct {
+	ID                 uint64
+	ChatID             uint64
+	Username           string
+	FirstName          string
+	LastName           string
+	Minute             uint16
+	Kind               uint8
+	Difficulties       uint8
+	DifficultyFallback bool
+}
+
 func TestGetSubscribedUsersDB(t *testing.T) {
-	storage := newYdbStorage()
+	storage := newYdbStorage(config.Default())
 	mockExecuter := new(MockQueryExecuter)
 	mockExecuter.t = t
 	storage.ydbExecuter = mockExecuter
 	usersToCheck := []common.User{
 		{
-			ID:          123,
-			ChatID:      123,
-			Username:    "test1",
-			FirstName:   "ftest1",
-			LastName:    "ltest1",
-			Subscribed:  true,
-			SendingHour: 7,
-		},
-		{
-			ID:          122,
-			ChatID:      122,
-			Username:    "test2",
-			FirstName:   "ftest2",
-			LastName:    "ltest2",
-			Subscribed:  true,
-			SendingHour: 7,
+			ID:           122,
+			ChatID:       122,
+			Username:     "test2",
+			FirstName:    "ftest2",
+			LastName:     "ltest2",
+			Subscribed:   true,
+			SendingTimes: []common.SendingTime{{Minute: 420}},
+			TimeZone:     "Europe/Berlin",
 		},
 		{
-			ID:          124,
-			ChatID:      124,
-			Username:    "test3",
-			FirstName:   "ftest3",
-			LastName:    "ltest3",
-			Subscribed:  true,
-			SendingHour: 7,
+			ID:                 123,
+			ChatID:             123,
+			Username:           "test1",
+			FirstName:          "ftest1",
+			LastName:           "ltest1",
+			Subscribed:         true,
+			SendingTimes:       []common.SendingTime{{Minute: 420}, {Minute: 430, Kind: common.HintSendingTime}},
+			TimeZone:           "Europe/Berlin",
+			Difficulties:       6,
+			DifficultyFallback: true,
 		},
 		{
-			ID:          125,
-			ChatID:      125,
-			Username:    "test4",
-			FirstName:   "ftest4",
-			LastName:    "ltest4",
-			Subscribed:  true,
-			SendingHour: 7,
+			ID:           124,
+			ChatID:       124,
+			Username:     "test3",
+			FirstName:    "ftest3",
+			LastName:     "ltest3",
+			Subscribed:   true,
+			SendingTimes: []common.SendingTime{{Minute: 434, Kind: common.HintSendingTime}},
+			TimeZone:     "Europe/Berlin",
 		},
 	}
 	rows := []interface{}{}
 	for _, user := range usersToCheck {
-		rows = append(rows, interface{}(user))
+		for _, sendingTime := range user.SendingTimes {
+			rows = append(rows, interface{}(databaseSubscribedUser{
+				ID:                 user.ID,
+				ChatID:             user.ChatID,
+				Username:           user.Username,
+				FirstName:          user.FirstName,
+				LastName:           user.LastName,
+				Minute:             sendingTime.Minute,
+				Kind:               uint8(sendingTime.Kind),
+				Difficulties:       uint8(user.Difficulties),
+				DifficultyFallback: user.DifficultyFallback,
+			}))
+		}
 	}
 	mockExecuter.On(
 		"ProcessQuery",
@@ -664,13 +1021,13 @@ func TestGetSubscribedUsersDB(t *testing.T) {
 		nil,
 	)
 
-	users, err := storage.getSubscribedUsers(context.Background(), 7)
+	users, err := storage.getSubscribedUsers(context.Background(), "Europe/Berlin", common.MinuteRange{From: 420, To: 435}, time.Now())
 	assert.Nil(t, err, "Unexpected error")
 	assert.Equal(t, usersToCheck, users, "Unexpected users returned")
 }
 
 func TestGetSubscribedUsersDBError(t *testing.T) {
-	storage := newYdbStorage()
+	storage := newYdbStorage(config.Default())
 	mockExecuter := new(MockQueryExecuter)
 	mockExecuter.t = t
 	storage.ydbExecuter = mockExecuter
@@ -686,13 +1043,13 @@ func TestGetSubscribedUsersDBError(t *testing.T) {
 		tests.ErrBypassTest,
 	)
 
-	users, err := storage.getSubscribedUsers(context.Background(), 7)
+	users, err := storage.getSubscribedUsers(context.Background(), "", common.MinuteRange{From: 420, To: 435}, time.Now())
 	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
 	assert.Equal(t, []common.User{}, users, "Unexpected users returned")
 }
 
 func TestGetSubscribedUsersScanError(t *testing.T) {
-	storage := newYdbStorage()
+	storage := newYdbStorage(config.Default())
 	mockExecuter := new(MockQueryExecuter)
 	mockExecuter.t = t
 	storage.ydbExecuter = mockExecuter
@@ -702,33 +1059,56 @@ func TestGetSubscribedUsersScanError(t *testing.T) {
 		mock.Anything,
 	).Return(
 		&YDBResultMock{
-			rows:      []interface{}{common.User{}},
+			rows:      []interface{}{databaseSubscribedUser{}},
 			t:         t,
 			scanError: tests.ErrBypassTest,
 		},
 		nil,
 	)
 
-	users, err := storage.getSubscribedUsers(context.Background(), 7)
+	users, err := storage.getSubscribedUsers(context.Background(), "", common.MinuteRange{From: 420, To: 435}, time.Now())
 	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
 	assert.Equal(t, []common.User{}, users, "Unexpected users returned")
 }
 
 func TestGetUser(t *testing.T) {
-	storage := newYdbStorage()
+	storage := newYdbStorage(config.Default())
 	mockExecuter := new(MockQueryExecuter)
 	mockExecuter.t = t
 	storage.ydbExecuter = mockExecuter
 	userToCheck := common.User{
-		ID:          123,
-		ChatID:      123,
-		Username:    "test1",
-		FirstName:   "ftest1",
-		LastName:    "ltest1",
-		Subscribed:  true,
-		SendingHour: 10,
+		ID:                 123,
+		ChatID:             123,
+		Username:           "test1",
+		FirstName:          "ftest1",
+		LastName:           "ltest1",
+		Subscribed:         true,
+		TimeZone:           "Europe/Berlin",
+		LeetcodeUsername:   "leetcoder",
+		NudgeSubscribed:    true,
+		NudgeHour:          19,
+		Weekdays:           common.WorkingDays,
+		PausedUntil:        20261101,
+		Difficulties:       3,
+		DifficultyFallback: true,
+		CodeLanguage:       "golang",
 	}
-	rows := []interface{}{interface{}(userToCheck)}
+	rows := []interface{}{interface{}(databaseUser{
+		ChatID:             userToCheck.ChatID,
+		Username:           userToCheck.Username,
+		FirstName:          userToCheck.FirstName,
+		LastName:           userToCheck.LastName,
+		Subscribed:         userToCheck.Subscribed,
+		TimeZone:           userToCheck.TimeZone,
+		LeetcodeUsername:   userToCheck.LeetcodeUsername,
+		NudgeSubscribed:    userToCheck.NudgeSubscribed,
+		NudgeHour:          userToCheck.NudgeHour,
+		Weekdays:           uint8(userToCheck.Weekdays),
+		PausedUntil:        userToCheck.PausedUntil,
+		Difficulties:       uint8(userToCheck.Difficulties),
+		DifficultyFallback: userToCheck.DifficultyFallback,
+		CodeLanguage:       userToCheck.CodeLanguage,
+	})}
 	mockExecuter.On(
 		"ProcessQuery",
 		trimmQuery(getUserQuery),
@@ -740,14 +1120,114 @@ func TestGetUser(t *testing.T) {
 		},
 		nil,
 	)
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(getSendingTimesQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{databaseSendingTime{Minute: 600}, databaseSendingTime{Minute: 750, Kind: 1}},
+			t:    t,
+		},
+		nil,
+	).Once()
 
 	user, err := storage.getUser(context.Background(), 123)
 	assert.Nil(t, err, "Unexpected error")
+	userToCheck.SendingTimes = []common.SendingTime{{Minute: 600}, {Minute: 750, Kind: common.HintSendingTime}}
 	assert.Equal(t, userToCheck, user, "Unexpected user returned")
+
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(getSendingTimesQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{},
+			t:    t,
+		},
+		tests.ErrBypassTest,
+	).Once()
+	user, err = storage.getUser(context.Background(), 123)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
+	assert.Equal(t, common.User{}, user, "Unexpected user returned")
+}
+
+type databaseSendingTime struct {
+	Minute uint16
+	Kind   uint8
+}
+
+// It's necessary while in database Weekdays is storing as uint8
+type databaseUser struct {
+	ChatID             uint64
+	Username           string
+	FirstName          string
+	LastName           string
+	Subscribed         bool
+	TimeZone           string
+	LeetcodeUsername   string
+	NudgeSubscribed    bool
+	NudgeHour          uint8
+	Weekdays           uint8
+	PausedUntil        uint64
+	Difficulties       uint8
+	DifficultyFallback bool
+	CodeLanguage       string
+}
+
+type databaseUserWithNullColumns struct {
+	ChatID             uint64
+	Username           string
+	FirstName          string
+	LastName           string
+	Subscribed         bool
+	TimeZone           *string
+	LeetcodeUsername   *string
+	NudgeSubscribed    *bool
+	NudgeHour          *uint8
+	Weekdays           *uint8
+	PausedUntil        *uint64
+	Difficulties       *uint8
+	DifficultyFallback *bool
+	CodeLanguage       *string
+}
+
+func TestGetUserNullColumns(t *testing.T) {
+	storage := newYdbStorage(config.Default())
+	mockExecuter := new(MockQueryExecuter)
+	mockExecuter.t = t
+	storage.ydbExecuter = mockExecuter
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(getUserQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{databaseUserWithNullColumns{ChatID: 123, Username: "test1", FirstName: "ftest1", LastName: "ltest1"}},
+			t:    t,
+		},
+		nil,
+	)
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(getSendingTimesQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{},
+			t:    t,
+		},
+		nil,
+	)
+
+	user, err := storage.getUser(context.Background(), 123)
+	assert.Nil(t, err, "Unexpected error")
+	assert.Equal(t, common.User{ID: 123, ChatID: 123, Username: "test1", FirstName: "ftest1", LastName: "ltest1", SendingTimes: []common.SendingTime{}}, user, "Unexpected user returned")
 }
 
 func TestGetUserNoRows(t *testing.T) {
-	storage := newYdbStorage()
+	storage := newYdbStorage(config.Default())
 	mockExecuter := new(MockQueryExecuter)
 	mockExecuter.t = t
 	storage.ydbExecuter = mockExecuter
@@ -769,7 +1249,7 @@ func TestGetUserNoRows(t *testing.T) {
 }
 
 func TestGetUserErrors(t *testing.T) {
-	storage := newYdbStorage()
+	storage := newYdbStorage(config.Default())
 	mockExecuter := new(MockQueryExecuter)
 	mockExecuter.t = t
 	storage.ydbExecuter = mockExecuter
@@ -791,7 +1271,7 @@ func TestGetUserErrors(t *testing.T) {
 }
 
 func TestGetUserScanError(t *testing.T) {
-	storage := newYdbStorage()
+	storage := newYdbStorage(config.Default())
 	mockExecuter := new(MockQueryExecuter)
 	mockExecuter.t = t
 	storage.ydbExecuter = mockExecuter
@@ -812,3 +1292,356 @@ func TestGetUserScanError(t *testing.T) {
 	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
 	assert.Equal(t, common.User{}, user, "Unexpected user returned")
 }
+
+func TestNudgeSubscriptionYDB(t *testing.T) {
+	storage := newYdbStorage(config.Default())
+	mockExecuter := new(MockQueryExecuter)
+	mockExecuter.t = t
+	storage.ydbExecuter = mockExecuter
+	for query, err := range map[string]error{subscribeUserToNudgeQuery: nil, unsubscribeUserFromNudgeQuery: tests.ErrBypassTest} {
+		mockExecuter.On(
+			"ProcessQuery",
+			trimmQuery(query),
+			mock.Anything,
+		).Return(
+			&YDBResultMock{
+				rows: []interface{}{},
+				t:    t,
+			},
+			err,
+		).Once()
+	}
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(markNudgeSentQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{},
+			t:    t,
+		},
+		nil,
+	).Once()
+	err := storage.subscribeUserToNudge(context.Background(), 123, 19)
+	assert.Nil(t, err, "Unexpected error")
+	err = storage.unsubscribeUserFromNudge(context.Background(), 123)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
+	err = storage.markNudgeSent(context.Background(), 123, 20211013)
+	assert.Nil(t, err, "Unexpected error")
+	mockExecuter.AssertExpectations(t)
+}
+
+type testDeliveryRow struct {
+	UserID    uint64
+	Kind      string
+	Status    uint8
+	Attempts  uint32
+	MessageID uint64
+	LastError string
+}
+
+func TestDeliveriesYDB(t *testing.T) {
+	storage := newYdbStorage(config.Default())
+	mockExecuter := new(MockQueryExecuter)
+	mockExecuter.t = t
+	storage.ydbExecuter = mockExecuter
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(getDeliveriesQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{
+				testDeliveryRow{UserID: 123, Kind: "task 07:00", Status: uint8(common.DeliverySent), Attempts: 1, MessageID: 42},
+				testDeliveryRow{UserID: 124, Kind: "hint 13:00", Status: uint8(common.DeliveryFailed), Attempts: 2, LastError: "test error"},
+			},
+			t: t,
+		},
+		nil,
+	).Once()
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(getDeliveriesQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows:      []interface{}{testDeliveryRow{}},
+			t:         t,
+			scanError: tests.ErrBypassTest,
+		},
+		nil,
+	).Once()
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(getDeliveriesQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{},
+		tests.ErrBypassTest,
+	).Once()
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(saveDeliveryQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{},
+			t:    t,
+		},
+		nil,
+	).Once()
+
+	deliveries, err := storage.getDeliveries(context.Background(), 20211013)
+	assert.Nil(t, err, "Unexpected error")
+	assert.Equal(t, []common.Delivery{
+		{UserID: 123, DateID: 20211013, Kind: "task 07:00", Status: common.DeliverySent, Attempts: 1, MessageID: 42},
+		{UserID: 124, DateID: 20211013, Kind: "hint 13:00", Status: common.DeliveryFailed, Attempts: 2, LastError: "test error"},
+	}, deliveries, "Unexpected deliveries")
+	deliveries, err = storage.getDeliveries(context.Background(), 20211013)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
+	assert.Equal(t, []common.Delivery{}, deliveries, "Unexpected deliveries")
+	_, err = storage.getDeliveries(context.Background(), 20211013)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
+	err = storage.saveDelivery(context.Background(), common.Delivery{UserID: 123, DateID: 20211013, Kind: "task 07:00"})
+	assert.Nil(t, err, "Unexpected error")
+	mockExecuter.AssertExpectations(t)
+}
+
+func TestGetNudgeUsersDB(t *testing.T) {
+	storage := newYdbStorage(config.Default())
+	mockExecuter := new(MockQueryExecuter)
+	mockExecuter.t = t
+	storage.ydbExecuter = mockExecuter
+	usersToCheck := []common.User{
+		{
+			ID:               123,
+			ChatID:           123,
+			Username:         "test1",
+			FirstName:        "ftest1",
+			LastName:         "ltest1",
+			TimeZone:         "Europe/Berlin",
+			LeetcodeUsername: "leetcoder1",
+			NudgeSubscribed:  true,
+			NudgeHour:        19,
+		},
+		{
+			ID:               124,
+			ChatID:           124,
+			Username:         "test2",
+			FirstName:        "ftest2",
+			LastName:         "ltest2",
+			TimeZone:         "Europe/Berlin",
+			LeetcodeUsername: "leetcoder2",
+			NudgeSubscribed:  true,
+			NudgeHour:        19,
+		},
+	}
+	rows := []interface{}{}
+	for _, user := range usersToCheck {
+		rows = append(rows, interface{}(user))
+	}
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(getNudgeUsersQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows: rows,
+			t:    t,
+		},
+		nil,
+	).Once()
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(getNudgeUsersQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows:      []interface{}{common.User{}},
+			t:         t,
+			scanError: tests.ErrBypassTest,
+		},
+		nil,
+	).Once()
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(getNudgeUsersQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{},
+			t:    t,
+		},
+		tests.ErrBypassTest,
+	).Once()
+
+	users, err := storage.getNudgeUsers(context.Background(), "Europe/Berlin", 19, 20211013)
+	assert.Nil(t, err, "Unexpected error")
+	assert.Equal(t, usersToCheck, users, "Unexpected users returned")
+	users, err = storage.getNudgeUsers(context.Background(), "Europe/Berlin", 19, 20211013)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
+	assert.Equal(t, []common.User{}, users, "Unexpected users returned")
+	users, err = storage.getNudgeUsers(context.Background(), "Europe/Berlin", 19, 20211013)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
+	assert.Equal(t, []common.User{}, users, "Unexpected users returned")
+	mockExecuter.AssertExpectations(t)
+}
+
+type databaseTimeZone struct {
+	TimeZone string
+}
+
+func TestGetSubscribedTimeZones(t *testing.T) {
+	storage := newYdbStorage(config.Default())
+	mockExecuter := new(MockQueryExecuter)
+	mockExecuter.t = t
+	storage.ydbExecuter = mockExecuter
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(getSubscribedTimeZonesQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{databaseTimeZone{""}, databaseTimeZone{"Europe/Berlin"}},
+			t:    t,
+		},
+		nil,
+	).Once()
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(getSubscribedTimeZonesQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows:      []interface{}{databaseTimeZone{""}},
+			t:         t,
+			scanError: tests.ErrBypassTest,
+		},
+		nil,
+	).Once()
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(getSubscribedTimeZonesQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{},
+			t:    t,
+		},
+		tests.ErrBypassTest,
+	).Once()
+	timeZones, err := storage.getSubscribedTimeZones(context.Background())
+	assert.Nil(t, err, "Unexpected error")
+	assert.Equal(t, []string{"", "Europe/Berlin"}, timeZones, "Unexpected time zones returned")
+	for i := 0; i < 2; i++ {
+		timeZones, err = storage.getSubscribedTimeZones(context.Background())
+		assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
+		assert.Equal(t, []string{}, timeZones, "Unexpected time zones returned")
+	}
+	mockExecuter.AssertExpectations(t)
+}
+
+func TestSetWeekdaysAndPausedUntil(t *testing.T) {
+	storage := newYdbStorage(config.Default())
+	mockExecuter := new(MockQueryExecuter)
+	mockExecuter.t = t
+	storage.ydbExecuter = mockExecuter
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(setWeekdaysQuery),
+		matchQueryParams(
+			table.ValueParam("$id", ydb.Uint64Value(123)),
+			table.ValueParam("$weekdays", ydb.Uint8Value(62)),
+		),
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{},
+			t:    t,
+		},
+		nil,
+	).Once()
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(setPausedUntilQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{},
+			t:    t,
+		},
+		tests.ErrBypassTest,
+	).Once()
+	err := storage.setWeekdays(context.Background(), 123, common.WorkingDays)
+	assert.Nil(t, err, "Unexpected error")
+	err = storage.setPausedUntil(context.Background(), 123, 20261101)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
+	mockExecuter.AssertExpectations(t)
+}
+
+func TestSetDifficultiesAndCodeLanguage(t *testing.T) {
+	storage := newYdbStorage(config.Default())
+	mockExecuter := new(MockQueryExecuter)
+	mockExecuter.t = t
+	storage.ydbExecuter = mockExecuter
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(setDifficultiesQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{},
+			t:    t,
+		},
+		nil,
+	).Once()
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(setCodeLanguageQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{},
+			t:    t,
+		},
+		tests.ErrBypassTest,
+	).Once()
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(setDifficultyFallbackQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{},
+			t:    t,
+		},
+		nil,
+	).Once()
+	err := storage.setDifficulties(context.Background(), 123, 4)
+	assert.Nil(t, err, "Unexpected error")
+	err = storage.setDifficultyFallback(context.Background(), 123, true)
+	assert.Nil(t, err, "Unexpected error")
+	err = storage.setCodeLanguage(context.Background(), 123, "golang")
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
+	mockExecuter.AssertExpectations(t)
+}
+
+func TestSetTimeZone(t *testing.T) {
+	storage := newYdbStorage(config.Default())
+	mockExecuter := new(MockQueryExecuter)
+	mockExecuter.t = t
+	storage.ydbExecuter = mockExecuter
+	mockExecuter.On(
+		"ProcessQuery",
+		trimmQuery(setTimeZoneQuery),
+		mock.Anything,
+	).Return(
+		&YDBResultMock{
+			rows: []interface{}{},
+			t:    t,
+		},
+		nil,
+	).Once()
+	err := storage.setTimeZone(context.Background(), 123, "Europe/Berlin")
+	assert.Nil(t, err, "Unexpected error")
+	mockExecuter.AssertExpectations(t)
+}
@@ -0,0 +1,113 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen returns when the breaker is open and calls are rejected without reaching the dependency
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State of the breaker
+type State uint8
+
+const (
+	// Closed breaker passes all calls
+	Closed State = iota
+	// Open breaker rejects all calls till OpenTimeout passes
+	Open
+	// HalfOpen breaker passes a single probe call, which decides whether to close or open the breaker again
+	HalfOpen
+)
+
+// Breaker opens after Threshold consecutive failures and rejects calls for OpenTimeout,
+// so the broken dependency isn't hammered and callers fail fast
+type Breaker struct {
+	Threshold   int
+	OpenTimeout time.Duration
+	// IsFailure decides which errors are failures of the dependency, all errors except context cancellation are by default
+	IsFailure func(error) bool
+	now       func() time.Time
+	state     State
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	mutex     sync.Mutex
+}
+
+// State returns the current state of the breaker
+func (b *Breaker) State() State {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.state == Open && b.now().Sub(b.openedAt) >= b.OpenTimeout {
+		return HalfOpen
+	}
+	return b.state
+}
+
+// Allow returns ErrOpen if the call should be rejected. Every allowed call must be followed by Report
+func (b *Breaker) Allow() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	switch b.state {
+	case Open:
+		if b.now().Sub(b.openedAt) < b.OpenTimeout {
+			return ErrOpen
+		}
+		b.state = HalfOpen
+	case HalfOpen:
+	default:
+		return nil
+	}
+	if b.probing {
+		return ErrOpen
+	}
+	b.probing = true
+	return nil
+}
+
+// Report saves the result of the allowed call
+func (b *Breaker) Report(err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.state == HalfOpen {
+		b.probing = false
+	}
+	if err == nil || !b.IsFailure(err) {
+		b.state = Closed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == HalfOpen || b.failures >= b.Threshold {
+		b.state = Open
+		b.openedAt = b.now()
+	}
+}
+
+// Do calls f if the breaker allows and reports its result
+func (b *Breaker) Do(f func() error) error {
+	if err := b.Allow(); err != nil {
+		return err
+	}
+	err := f()
+	b.Report(err)
+	return err
+}
+
+// IsFailure treats all errors except context cancellation as failures
+func IsFailure(err error) bool {
+	return !errors.Is(err, context.Canceled)
+}
+
+// New constructs closed Breaker
+func New(threshold int, openTimeout time.Duration) *Breaker {
+	return &Breaker{
+		Threshold:   threshold,
+		OpenTimeout: openTimeout,
+		IsFailure:   IsFailure,
+		now:         time.Now,
+	}
+}
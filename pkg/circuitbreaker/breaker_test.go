@@ -0,0 +1,42 @@
+package circuitbreaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dartkron/leetcodeBot/v3/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	breaker := New(2, time.Minute)
+	breaker.now = func() time.Time { return now }
+	failure := func() error { return tests.ErrBypassTest }
+	success := func() error { return nil }
+
+	assert.Equal(t, tests.ErrBypassTest, breaker.Do(failure), "Unexpected Do error")
+	assert.Nil(t, breaker.Do(success), "Success should reset failures")
+	assert.Equal(t, tests.ErrBypassTest, breaker.Do(failure), "Unexpected Do error")
+	assert.Equal(t, context.Canceled, breaker.Do(func() error { return context.Canceled }), "Unexpected Do error")
+	assert.Equal(t, Closed, breaker.State(), "Canceled calls shouldn't open the breaker")
+
+	breaker.Do(failure)
+	breaker.Do(failure)
+	assert.Equal(t, Open, breaker.State(), "Consecutive failures should open the breaker")
+	called := false
+	assert.Equal(t, ErrOpen, breaker.Do(func() error { called = true; return nil }), "Open breaker should reject calls")
+	assert.False(t, called, "Open breaker shouldn't call the dependency")
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, HalfOpen, breaker.State(), "Breaker should be half-open after the timeout")
+	assert.Nil(t, breaker.Allow(), "Half-open breaker should allow the probe")
+	assert.Equal(t, ErrOpen, breaker.Allow(), "Half-open breaker should allow only one probe")
+	breaker.Report(tests.ErrBypassTest)
+	assert.Equal(t, Open, breaker.State(), "Failed probe should open the breaker again")
+
+	now = now.Add(time.Minute)
+	assert.Nil(t, breaker.Do(success), "Unexpected probe error")
+	assert.Equal(t, Closed, breaker.State(), "Successful probe should close the breaker")
+}
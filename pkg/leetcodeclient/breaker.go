@@ -0,0 +1,64 @@
+package leetcodeclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dartkron/leetcodeBot/v3/pkg/circuitbreaker"
+)
+
+const (
+	breakerThreshold   = 5
+	breakerOpenTimeout = 30 * time.Second
+)
+
+// ErrLeetcodeUnavailable returns when LeetCode can't be reached or failed too many times in a row and requests aren't sent for a while
+var ErrLeetcodeUnavailable = errors.New("LeetCode is unavailable")
+
+var initializedBreakers = map[string]*circuitbreaker.Breaker{}
+var initializedBreakersMutex sync.Mutex
+
+// getBreaker returns breaker shared by all clients of the same GraphQL API URL, so failures are counted for the whole process
+func getBreaker(graphQlURL string) *circuitbreaker.Breaker {
+	initializedBreakersMutex.Lock()
+	defer initializedBreakersMutex.Unlock()
+	if breaker, ok := initializedBreakers[graphQlURL]; ok {
+		return breaker
+	}
+	breaker := circuitbreaker.New(breakerThreshold, breakerOpenTimeout)
+	initializedBreakers[graphQlURL] = breaker
+	return breaker
+}
+
+// breakingRequester rejects requests while the circuit breaker is open, so the broken LeetCode doesn't slow down every answer.
+// Failures of requests are wrapped with ErrLeetcodeUnavailable as well
+type breakingRequester struct {
+	requester graphQlRequester
+	breaker   *circuitbreaker.Breaker
+}
+
+func (r *breakingRequester) requestGraphQl(ctx context.Context, request graphQlRequest) ([]byte, error) {
+	var response []byte
+	err := r.breaker.Do(func() error {
+		var err error
+		response, err = r.requester.requestGraphQl(ctx, request)
+		return err
+	})
+	if errors.Is(err, circuitbreaker.ErrOpen) {
+		return []byte{}, ErrLeetcodeUnavailable
+	}
+	if err != nil && r.breaker.IsFailure(err) {
+		return response, fmt.Errorf("%w: %w", ErrLeetcodeUnavailable, err)
+	}
+	return response, err
+}
+
+func newBreakingRequester(requester graphQlRequester, breaker *circuitbreaker.Breaker) *breakingRequester {
+	return &breakingRequester{
+		requester: requester,
+		breaker:   breaker,
+	}
+}
@@ -0,0 +1,41 @@
+package leetcodeclient
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dartkron/leetcodeBot/v3/pkg/circuitbreaker"
+	"github.com/dartkron/leetcodeBot/v3/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreakingRequester(t *testing.T) {
+	ctx := context.Background()
+	mockRequester := &MockRequester{}
+	requester := newBreakingRequester(mockRequester, circuitbreaker.New(breakerThreshold, breakerOpenTimeout))
+	mockRequester.On("requestGraphQl", graphQlRequest{Query: "ok"}).Return([]byte("ok"), nil).Once()
+	mockRequester.On("requestGraphQl", graphQlRequest{Query: "canceled"}).Return([]byte{}, context.Canceled).Once()
+	mockRequester.On("requestGraphQl", graphQlRequest{Query: "broken"}).Return([]byte{}, tests.ErrBypassTest).Times(breakerThreshold)
+
+	response, err := requester.requestGraphQl(ctx, graphQlRequest{Query: "ok"})
+	assert.Nil(t, err, "Unexpected requestGraphQl error")
+	assert.Equal(t, []byte("ok"), response, "Unexpected response")
+	_, err = requester.requestGraphQl(ctx, graphQlRequest{Query: "canceled"})
+	assert.Equal(t, context.Canceled, err, "Cancellation shouldn't be reported as unavailable LeetCode")
+	for i := 0; i < breakerThreshold; i++ {
+		_, err = requester.requestGraphQl(ctx, graphQlRequest{Query: "broken"})
+		assert.ErrorIs(t, err, tests.ErrBypassTest, "Errors should be passed before the breaker opens")
+		assert.ErrorIs(t, err, ErrLeetcodeUnavailable, "Failed requests should be reported as unavailable LeetCode")
+	}
+	_, err = requester.requestGraphQl(ctx, graphQlRequest{Query: "broken"})
+	assert.Equal(t, ErrLeetcodeUnavailable, err, "Open breaker should return ErrLeetcodeUnavailable")
+	mockRequester.AssertExpectations(t)
+}
+
+func TestGetBreaker(t *testing.T) {
+	first := getBreaker("https://first.test/graphql")
+	assert.Same(t, first, getBreaker("https://first.test/graphql"), "Clients of the same URL should share the breaker")
+	assert.NotSame(t, first, getBreaker("https://second.test/graphql"), "Clients of different URLs shouldn't share the breaker")
+	client := NewLeetCodeGraphQlClientWithURL("https://first.test/graphql")
+	assert.Same(t, first, client.transport.(*breakingRequester).breaker, "Client should use the breaker of its URL")
+}
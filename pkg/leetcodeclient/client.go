@@ -3,20 +3,34 @@ package leetcodeclient
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand"
 	"strings"
 	"time"
 )
 
+const randomQuestionsPageSize = 20
+
+// RecentSubmissionsLimit is the maximum of recent accepted submissions LeetCode shows publicly
+const RecentSubmissionsLimit = 20
+
+// ErrNoSuchQuestions returns when there are no free questions matching the filter
+var ErrNoSuchQuestions = errors.New("no questions found for the filter")
+
+// ErrNoSuchUser returns when there is no LeetCode user with such username
+var ErrNoSuchUser = errors.New("no such LeetCode user")
+
 // LeetCodeTask is a necessary information about task at LeetCode
 type LeetCodeTask struct {
-	QuestionID uint64     `json:"questionId,string"`
-	TitleSlug  string     `json:"titleSlug"`
-	Title      string     `json:"questionTitle"`
-	Content    string     `json:"content"`
-	Hints      []string   `json:"hints"`
-	Difficulty string     `json:"difficulty"`
-	TopicTags  []TopicTag `json:"topicTags,omitempty"`
+	QuestionID       uint64           `json:"questionId,string"`
+	TitleSlug        string           `json:"titleSlug"`
+	Title            string           `json:"questionTitle"`
+	Content          string           `json:"content"`
+	Hints            []string         `json:"hints"`
+	Difficulty       string           `json:"difficulty"`
+	TopicTags        []TopicTag       `json:"topicTags,omitempty"`
+	SimilarQuestions SimilarQuestions `json:"similarQuestions,omitempty"`
 }
 
 // TopicTag is a LeetCode topic tag attached to a task.
@@ -25,11 +39,121 @@ type TopicTag struct {
 	Slug string `json:"slug"`
 }
 
+// SimilarQuestion is a short description of the question LeetCode suggests as similar to a task.
+type SimilarQuestion struct {
+	Title      string `json:"title"`
+	TitleSlug  string `json:"titleSlug"`
+	Difficulty string `json:"difficulty"`
+}
+
+// SimilarQuestions list of SimilarQuestion with specific json unmarshaller.
+// LeetCode API returns similar questions as a JSON array encoded into a string,
+// but the list could also be stored as a plain JSON array.
+type SimilarQuestions []SimilarQuestion
+
+// UnmarshalJSON parsing similar questions both from a string with encoded JSON and from a plain JSON array
+func (s *SimilarQuestions) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		encoded := ""
+		err := json.Unmarshal(b, &encoded)
+		if err != nil {
+			return err
+		}
+		if encoded == "" {
+			*s = nil
+			return nil
+		}
+		b = []byte(encoded)
+	}
+	questions := []SimilarQuestion{}
+	err := json.Unmarshal(b, &questions)
+	if err != nil {
+		return err
+	}
+	*s = questions
+	return nil
+}
+
+// QuestionsFilter is a set of filters for the LeetCode problems list.
+// Difficulty should be one of EASY, MEDIUM, HARD or empty for any difficulty.
+// Tags are topic tags slugs.
+// SearchKeywords matches questions by number or by words from the title.
+type QuestionsFilter struct {
+	Difficulty     string   `json:"difficulty,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	PaidOnly       *bool    `json:"premiumOnly,omitempty"`
+	SearchKeywords string   `json:"searchKeywords,omitempty"`
+}
+
+// QuestionsListItem is a short description of the question from the LeetCode problems list
+type QuestionsListItem struct {
+	FrontendQuestionID string     `json:"frontendQuestionId"`
+	Title              string     `json:"title"`
+	TitleSlug          string     `json:"titleSlug"`
+	Difficulty         string     `json:"difficulty"`
+	PaidOnly           bool       `json:"paidOnly"`
+	TopicTags          []TopicTag `json:"topicTags,omitempty"`
+}
+
+// QuestionsList is a page of the LeetCode problems list with total amount of questions matched the filter
+type QuestionsList struct {
+	Total     int                 `json:"total"`
+	Questions []QuestionsListItem `json:"questions"`
+}
+
+// SolvedCount is an amount of solved problems of the difficulty. Difficulty "All" means all solved problems.
+type SolvedCount struct {
+	Difficulty string `json:"difficulty"`
+	Count      int    `json:"count"`
+}
+
+// UserProfile is a public part of the LeetCode user profile
+type UserProfile struct {
+	Username     string
+	SolvedCounts []SolvedCount
+	// Streak is an amount of days in a row with accepted submissions
+	Streak int
+}
+
+// GetSolvedCount returns amount of solved problems with the difficulty: All, Easy, Medium or Hard
+func (p *UserProfile) GetSolvedCount(difficulty string) int {
+	for _, solved := range p.SolvedCounts {
+		if strings.EqualFold(solved.Difficulty, difficulty) {
+			return solved.Count
+		}
+	}
+	return 0
+}
+
+// AcceptedSubmission is a public information about accepted solution. Timestamp is a Unix time.
+type AcceptedSubmission struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	TitleSlug string `json:"titleSlug"`
+	Timestamp int64  `json:"timestamp,string"`
+}
+
+// HasAcceptedSubmission checks if there is accepted submission of the question made not earlier than since
+func HasAcceptedSubmission(submissions []AcceptedSubmission, titleSlug string, since time.Time) bool {
+	for _, submission := range submissions {
+		if submission.TitleSlug == titleSlug && submission.Timestamp >= since.Unix() {
+			return true
+		}
+	}
+	return false
+}
+
 // LeetcodeClient represents abstract set of methods required from any possible kind of Leetcode client
 type LeetcodeClient interface {
 	GetDailyQuestionSlug(context.Context, time.Time) (string, error)
+	GetMonthlyChallenges(context.Context, time.Time) ([]DailyChallenge, error)
 	GetQuestionDetailsByTitleSlug(context.Context, string) (LeetCodeTask, error)
 	GetDailyTask(context.Context, time.Time) (LeetCodeTask, error)
+	GetQuestionsList(context.Context, QuestionsFilter, int, int) (QuestionsList, error)
+	GetRandomQuestion(context.Context, QuestionsFilter) (LeetCodeTask, error)
+	SearchQuestions(context.Context, string, int, int) (QuestionsList, error)
+	GetUserProfile(context.Context, string) (UserProfile, error)
+	GetRecentAcceptedSubmissions(context.Context, string, int) ([]AcceptedSubmission, error)
 }
 
 // LeetcodeDate time.Time with specific json unmarshaller to parse json dates
@@ -47,11 +171,21 @@ func (j *LeetcodeDate) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// DailyChallenge is a short description of the daily challenge. Date is midnight in LeetCode time zone
+type DailyChallenge struct {
+	Date       time.Time
+	TitleSlug  string
+	Difficulty string
+}
+
+type challengeQuestionDesc struct {
+	TitleSlug  string `json:"titleSlug"`
+	Difficulty string `json:"difficulty"`
+}
+
 type challengeDesc struct {
-	Date     LeetcodeDate `json:"date"`
-	Question struct {
-		TitleSlug string `json:"titleSlug"`
-	} `json:"question"`
+	Date     LeetcodeDate          `json:"date"`
+	Question challengeQuestionDesc `json:"question"`
 }
 
 type dailyCodingChallengeV2desc struct {
@@ -68,18 +202,48 @@ type questionDesc struct {
 	}
 }
 
+type problemsetQuestionListDesc struct {
+	Data struct {
+		ProblemsetQuestionList QuestionsList `json:"problemsetQuestionList"`
+	} `json:"data"`
+}
+
+type matchedUserDesc struct {
+	Data struct {
+		MatchedUser *struct {
+			Username    string `json:"username"`
+			SubmitStats struct {
+				AcSubmissionNum []SolvedCount `json:"acSubmissionNum"`
+			} `json:"submitStats"`
+			UserCalendar struct {
+				Streak int `json:"streak"`
+			} `json:"userCalendar"`
+		} `json:"matchedUser"`
+	} `json:"data"`
+}
+
+type recentAcSubmissionListDesc struct {
+	Data struct {
+		RecentAcSubmissionList []AcceptedSubmission `json:"recentAcSubmissionList"`
+	} `json:"data"`
+}
+
 // LeetCodeGraphQlClient realization of GraphQL client.
 // Potentially supports different requester types
 type LeetCodeGraphQlClient struct {
 	getDailyQuestionsSlugsReq graphQlRequest
 	getQuestionReq            graphQlRequest
+	getQuestionsListReq       graphQlRequest
+	getUserProfileReq         graphQlRequest
+	getRecentSubmissionsReq   graphQlRequest
 	transport                 graphQlRequester
+	randIntn                  func(int) int
 }
 
 type graphQlRequest struct {
-	OperationName string            `json:"operationName"`
-	Variables     map[string]string `json:"variables"`
-	Query         string            `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+	Query         string                 `json:"query"`
 }
 
 type graphQlRequester interface {
@@ -98,9 +262,26 @@ func (c *LeetCodeGraphQlClient) GetDailyQuestionSlug(ctx context.Context, date t
 	return monthlyChanngelgesSlugs[date.Day()-1].Question.TitleSlug, nil
 }
 
+// GetMonthlyChallenges provides all daily challenges of the date month till today
+func (c *LeetCodeGraphQlClient) GetMonthlyChallenges(ctx context.Context, date time.Time) ([]DailyChallenge, error) {
+	monthlyChallenges, err := c.getMonthlyQuestionsSlugs(ctx, date)
+	if err != nil {
+		return []DailyChallenge{}, err
+	}
+	challenges := make([]DailyChallenge, len(monthlyChallenges))
+	for i, challenge := range monthlyChallenges {
+		challenges[i] = DailyChallenge{
+			Date:       time.Time(challenge.Date),
+			TitleSlug:  challenge.Question.TitleSlug,
+			Difficulty: challenge.Question.Difficulty,
+		}
+	}
+	return challenges, nil
+}
+
 func (c *LeetCodeGraphQlClient) getMonthlyQuestionsSlugs(ctx context.Context, date time.Time) ([]challengeDesc, error) {
 	monthlyQuestionsReq := c.getDailyQuestionsSlugsReq
-	monthlyQuestionsReq.Variables = map[string]string{
+	monthlyQuestionsReq.Variables = map[string]interface{}{
 		"month": fmt.Sprintf("%d", int(date.Month())),
 		"year":  fmt.Sprintf("%d", date.Year()),
 	}
@@ -116,7 +297,7 @@ func (c *LeetCodeGraphQlClient) getMonthlyQuestionsSlugs(ctx context.Context, da
 // GetQuestionDetailsByTitleSlug provides all details of the question: title, text, hints, difficulty by provided titleSlug
 func (c *LeetCodeGraphQlClient) GetQuestionDetailsByTitleSlug(ctx context.Context, titleSlug string) (LeetCodeTask, error) {
 	questionReq := c.getQuestionReq
-	questionReq.Variables = map[string]string{"titleSlug": titleSlug}
+	questionReq.Variables = map[string]interface{}{"titleSlug": titleSlug}
 
 	responseBytes, err := c.transport.requestGraphQl(ctx, questionReq)
 	if err != nil {
@@ -141,24 +322,135 @@ func (c *LeetCodeGraphQlClient) GetDailyTask(ctx context.Context, date time.Time
 	return c.GetQuestionDetailsByTitleSlug(ctx, questionSlug)
 }
 
+// GetQuestionsList provides page of the problems list matched the filter
+func (c *LeetCodeGraphQlClient) GetQuestionsList(ctx context.Context, filter QuestionsFilter, skip int, limit int) (QuestionsList, error) {
+	questionsListReq := c.getQuestionsListReq
+	questionsListReq.Variables = map[string]interface{}{
+		"categorySlug": "",
+		"skip":         skip,
+		"limit":        limit,
+		"filters":      filter,
+	}
+	responseBytes, err := c.transport.requestGraphQl(ctx, questionsListReq)
+	if err != nil {
+		return QuestionsList{}, err
+	}
+	parsed := problemsetQuestionListDesc{}
+	err = json.Unmarshal(responseBytes, &parsed)
+	return parsed.Data.ProblemsetQuestionList, err
+}
+
+// GetRandomQuestion provides all details of the random free question matched the filter
+func (c *LeetCodeGraphQlClient) GetRandomQuestion(ctx context.Context, filter QuestionsFilter) (LeetCodeTask, error) {
+	paidOnly := false
+	filter.PaidOnly = &paidOnly
+	// The first request is only to know how many questions match the filter
+	questionsList, err := c.GetQuestionsList(ctx, filter, 0, 1)
+	if err != nil {
+		return LeetCodeTask{}, err
+	}
+	if questionsList.Total == 0 {
+		return LeetCodeTask{}, ErrNoSuchQuestions
+	}
+	questionsList, err = c.GetQuestionsList(ctx, filter, c.randIntn(questionsList.Total), randomQuestionsPageSize)
+	if err != nil {
+		return LeetCodeTask{}, err
+	}
+	for _, question := range questionsList.Questions {
+		// Filter should exclude paid questions, but better to be sure
+		if !question.PaidOnly {
+			return c.GetQuestionDetailsByTitleSlug(ctx, question.TitleSlug)
+		}
+	}
+	return LeetCodeTask{}, ErrNoSuchQuestions
+}
+
+// SearchQuestions provides page of the free questions matched keywords: number, slug or words from the title
+func (c *LeetCodeGraphQlClient) SearchQuestions(ctx context.Context, keywords string, skip int, limit int) (QuestionsList, error) {
+	paidOnly := false
+	// Search doesn't match slugs as is, but matches words from them
+	keywords = strings.TrimSpace(strings.ReplaceAll(keywords, "-", " "))
+	return c.GetQuestionsList(ctx, QuestionsFilter{SearchKeywords: keywords, PaidOnly: &paidOnly}, skip, limit)
+}
+
+// GetUserProfile provides public profile with solved problems counts. Returns ErrNoSuchUser if there is no such user.
+func (c *LeetCodeGraphQlClient) GetUserProfile(ctx context.Context, username string) (UserProfile, error) {
+	userProfileReq := c.getUserProfileReq
+	userProfileReq.Variables = map[string]interface{}{"username": username}
+	responseBytes, err := c.transport.requestGraphQl(ctx, userProfileReq)
+	if err != nil {
+		return UserProfile{}, err
+	}
+	parsed := matchedUserDesc{}
+	err = json.Unmarshal(responseBytes, &parsed)
+	if err != nil {
+		return UserProfile{}, err
+	}
+	if parsed.Data.MatchedUser == nil {
+		return UserProfile{}, ErrNoSuchUser
+	}
+	return UserProfile{
+		Username:     parsed.Data.MatchedUser.Username,
+		SolvedCounts: parsed.Data.MatchedUser.SubmitStats.AcSubmissionNum,
+		Streak:       parsed.Data.MatchedUser.UserCalendar.Streak,
+	}, nil
+}
+
+// GetRecentAcceptedSubmissions provides last accepted submissions of the user, newest first
+func (c *LeetCodeGraphQlClient) GetRecentAcceptedSubmissions(ctx context.Context, username string, limit int) ([]AcceptedSubmission, error) {
+	recentSubmissionsReq := c.getRecentSubmissionsReq
+	recentSubmissionsReq.Variables = map[string]interface{}{"username": username, "limit": limit}
+	responseBytes, err := c.transport.requestGraphQl(ctx, recentSubmissionsReq)
+	if err != nil {
+		return []AcceptedSubmission{}, err
+	}
+	parsed := recentAcSubmissionListDesc{}
+	err = json.Unmarshal(responseBytes, &parsed)
+	return parsed.Data.RecentAcSubmissionList, err
+}
+
 // NewLeetCodeGraphQlClient construct LeetCode client with default values
 func NewLeetCodeGraphQlClient() *LeetCodeGraphQlClient {
-	return newLeetCodeGraphQlClient(newHTTPGraphQlRequester(nil))
+	return NewLeetCodeGraphQlClientWithURL(DefaultGraphQlURL)
+}
+
+// NewLeetCodeGraphQlClientWithURL construct LeetCode client for the GraphQL API URL, like a proxy or a test server.
+// Clients of the same URL share the circuit breaker
+func NewLeetCodeGraphQlClientWithURL(graphQlURL string) *LeetCodeGraphQlClient {
+	requester := newHTTPGraphQlRequester(nil)
+	requester.GraphQlURL = graphQlURL
+	return newLeetCodeGraphQlClient(newBreakingRequester(requester, getBreaker(graphQlURL)))
 }
 
 func newLeetCodeGraphQlClient(requester graphQlRequester) *LeetCodeGraphQlClient {
 	client := LeetCodeGraphQlClient{
 		getDailyQuestionsSlugsReq: graphQlRequest{
 			OperationName: "dailyCodingQuestionRecords",
-			Query:         `query dailyCodingQuestionRecords($year: Int!, $month: Int!) { dailyCodingChallengeV2(year: $year, month: $month) { challenges {	date question { titleSlug } } } }`,
-			Variables:     make(map[string]string),
+			Query:         `query dailyCodingQuestionRecords($year: Int!, $month: Int!) { dailyCodingChallengeV2(year: $year, month: $month) { challenges {	date question { titleSlug difficulty } } } }`,
+			Variables:     make(map[string]interface{}),
 		},
 		getQuestionReq: graphQlRequest{
 			OperationName: "GetQuestion",
-			Variables:     make(map[string]string),
-			Query:         "query GetQuestion($titleSlug: String!) {question(titleSlug: $titleSlug) { questionId questionTitle difficulty content hints topicTags { name slug } }}",
+			Variables:     make(map[string]interface{}),
+			Query:         "query GetQuestion($titleSlug: String!) {question(titleSlug: $titleSlug) { questionId questionTitle difficulty content hints topicTags { name slug } similarQuestions }}",
+		},
+		getQuestionsListReq: graphQlRequest{
+			OperationName: "problemsetQuestionList",
+			Variables:     make(map[string]interface{}),
+			Query:         "query problemsetQuestionList($categorySlug: String, $limit: Int, $skip: Int, $filters: QuestionListFilterInput) { problemsetQuestionList: questionList(categorySlug: $categorySlug limit: $limit skip: $skip filters: $filters) { total: totalNum questions: data { frontendQuestionId: questionFrontendId title titleSlug difficulty paidOnly: isPaidOnly topicTags { name slug } } } }",
+		},
+		getUserProfileReq: graphQlRequest{
+			OperationName: "userProfile",
+			Variables:     make(map[string]interface{}),
+			Query:         "query userProfile($username: String!) { matchedUser(username: $username) { username submitStats: submitStatsGlobal { acSubmissionNum { difficulty count submissions } } userCalendar { streak } } }",
+		},
+		getRecentSubmissionsReq: graphQlRequest{
+			OperationName: "recentAcSubmissions",
+			Variables:     make(map[string]interface{}),
+			Query:         "query recentAcSubmissions($username: String!, $limit: Int!) { recentAcSubmissionList(username: $username, limit: $limit) { id title titleSlug timestamp } }",
 		},
 		transport: requester,
+		randIntn:  rand.Intn,
 	}
 	return &client
 }
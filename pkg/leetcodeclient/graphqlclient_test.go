@@ -2,7 +2,10 @@ package leetcodeclient
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"os"
+	"path"
 	"testing"
 	"time"
 
@@ -55,7 +58,7 @@ func TestGetMonthlyQuestionsSlugs(t *testing.T) {
 	client.transport = mockRequester
 	loc, _ := time.LoadLocation("America/Los_Angeles")
 	chaptersReq := client.getDailyQuestionsSlugsReq
-	chaptersReq.Variables = map[string]string{
+	chaptersReq.Variables = map[string]interface{}{
 		"year":  "1986",
 		"month": "4",
 	}
@@ -66,7 +69,7 @@ func TestGetMonthlyQuestionsSlugs(t *testing.T) {
 		[]byte(""),
 		tests.ErrBypassTest,
 	).Times(1)
-	chaptersReq.Variables = map[string]string{
+	chaptersReq.Variables = map[string]interface{}{
 		"year":  "1995",
 		"month": "8",
 	}
@@ -77,7 +80,7 @@ func TestGetMonthlyQuestionsSlugs(t *testing.T) {
 		[]byte("{\"data\":{\"dailyCodingChallengeV2\":{\"challenges\":[{\"date\":\"1995-08-01\",\"question\":{\"titleSlug\":\"test-title\"}},{\"date\":\"1995-08-02\",\"question\":{\"titleSlug\":\"test-title2\"}},{\"date\":\"1995-08-03\",\"question\":{\"titleSlug\":\"test-title3\"}}]}}}"),
 		nil,
 	).Times(1)
-	chaptersReq.Variables = map[string]string{
+	chaptersReq.Variables = map[string]interface{}{
 		"year":  "1986",
 		"month": "5",
 	}
@@ -90,13 +93,7 @@ func TestGetMonthlyQuestionsSlugs(t *testing.T) {
 	).Times(1)
 	testCases := []sliceDateChallengesError{
 		{time.Date(1986, time.April, 26, 01, 23, 47, 0, loc), []challengeDesc{}, tests.ErrBypassTest},
-		{time.Date(1995, time.August, 26, 01, 23, 47, 0, loc), []challengeDesc{{Date: LeetcodeDate(time.Date(1995, time.August, 1, 0, 0, 0, 0, loc)), Question: struct {
-			TitleSlug string "json:\"titleSlug\""
-		}{TitleSlug: "test-title"}}, {Date: LeetcodeDate(time.Date(1995, time.August, 2, 0, 0, 0, 0, loc)), Question: struct {
-			TitleSlug string "json:\"titleSlug\""
-		}{TitleSlug: "test-title2"}}, {Date: LeetcodeDate(time.Date(1995, time.August, 3, 0, 0, 0, 0, loc)), Question: struct {
-			TitleSlug string "json:\"titleSlug\""
-		}{TitleSlug: "test-title3"}}}, nil},
+		{time.Date(1995, time.August, 26, 01, 23, 47, 0, loc), []challengeDesc{{Date: LeetcodeDate(time.Date(1995, time.August, 1, 0, 0, 0, 0, loc)), Question: challengeQuestionDesc{TitleSlug: "test-title"}}, {Date: LeetcodeDate(time.Date(1995, time.August, 2, 0, 0, 0, 0, loc)), Question: challengeQuestionDesc{TitleSlug: "test-title2"}}, {Date: LeetcodeDate(time.Date(1995, time.August, 3, 0, 0, 0, 0, loc)), Question: challengeQuestionDesc{TitleSlug: "test-title3"}}}, nil},
 		{time.Date(1986, time.May, 26, 01, 23, 47, 0, loc), []challengeDesc(nil), tests.ErrWrongJSON},
 	}
 	for _, testCase := range testCases {
@@ -117,7 +114,7 @@ func TestGetDailyQuestionSlug(t *testing.T) {
 	client.transport = mockRequester
 	loc, _ := time.LoadLocation("America/Los_Angeles")
 	chaptersReq := client.getDailyQuestionsSlugsReq
-	chaptersReq.Variables = map[string]string{
+	chaptersReq.Variables = map[string]interface{}{
 		"year":  "1986",
 		"month": "4",
 	}
@@ -128,7 +125,7 @@ func TestGetDailyQuestionSlug(t *testing.T) {
 		[]byte(""),
 		tests.ErrBypassTest,
 	).Times(1)
-	chaptersReq.Variables = map[string]string{
+	chaptersReq.Variables = map[string]interface{}{
 		"year":  "1995",
 		"month": "8",
 	}
@@ -158,6 +155,48 @@ func TestGetDailyQuestionSlug(t *testing.T) {
 	mockRequester.AssertExpectations(t)
 }
 
+func TestGetMonthlyChallenges(t *testing.T) {
+	client := NewLeetCodeGraphQlClient()
+	mockRequester := &MockRequester{}
+	client.transport = mockRequester
+	loc, _ := time.LoadLocation("America/Los_Angeles")
+	chaptersReq := client.getDailyQuestionsSlugsReq
+	chaptersReq.Variables = map[string]interface{}{
+		"year":  "1986",
+		"month": "4",
+	}
+	mockRequester.On(
+		"requestGraphQl",
+		chaptersReq,
+	).Return(
+		[]byte(""),
+		tests.ErrBypassTest,
+	).Times(1)
+	chaptersReq.Variables = map[string]interface{}{
+		"year":  "1995",
+		"month": "8",
+	}
+	mockRequester.On(
+		"requestGraphQl",
+		chaptersReq,
+	).Return(
+		[]byte("{\"data\":{\"dailyCodingChallengeV2\":{\"challenges\":[{\"date\":\"1995-08-01\",\"question\":{\"titleSlug\":\"test-title\",\"difficulty\":\"Easy\"}},{\"date\":\"1995-08-02\",\"question\":{\"titleSlug\":\"test-title2\",\"difficulty\":\"Hard\"}}]}}}"),
+		nil,
+	).Times(1)
+
+	challenges, err := client.GetMonthlyChallenges(context.Background(), time.Date(1986, time.April, 26, 01, 23, 47, 0, loc))
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
+	assert.Equal(t, []DailyChallenge{}, challenges, "Unexpected response")
+
+	challenges, err = client.GetMonthlyChallenges(context.Background(), time.Date(1995, time.August, 26, 01, 23, 47, 0, loc))
+	assert.Nil(t, err, "Unexpected error")
+	assert.Equal(t, []DailyChallenge{
+		{Date: time.Date(1995, time.August, 1, 0, 0, 0, 0, loc), TitleSlug: "test-title", Difficulty: "Easy"},
+		{Date: time.Date(1995, time.August, 2, 0, 0, 0, 0, loc), TitleSlug: "test-title2", Difficulty: "Hard"},
+	}, challenges, "Unexpected response")
+	mockRequester.AssertExpectations(t)
+}
+
 type sliceStringLeetcodeTaskError struct {
 	str  string
 	task LeetCodeTask
@@ -170,12 +209,12 @@ func TestGetQuestionDetailsByTitleSlug(t *testing.T) {
 	client.transport = mockRequester
 	makeReq := func(slug string) graphQlRequest {
 		r := client.getQuestionReq
-		r.Variables = map[string]string{"titleSlug": slug}
+		r.Variables = map[string]interface{}{"titleSlug": slug}
 		return r
 	}
 	mockRequester.On("requestGraphQl", makeReq("test-title0")).Return([]byte{}, tests.ErrBypassTest).Times(1)
 	mockRequester.On("requestGraphQl", makeReq("test-title")).Return(
-		[]byte("{\"data\":{\"question\":{\"questionId\":\"1254\",\"questionTitle\":\"Test title\",\"difficulty\":\"Easy\",\"content\":\"<p>My very test content <code>with code</code></p>\\n\\n\",\"hints\":[\"First hint\",\"Second hint\"],\"topicTags\":[{\"name\":\"Array\",\"slug\":\"array\"},{\"name\":\"Simulation\",\"slug\":\"simulation\"}]}}}"),
+		[]byte("{\"data\":{\"question\":{\"questionId\":\"1254\",\"questionTitle\":\"Test title\",\"difficulty\":\"Easy\",\"content\":\"<p>My very test content <code>with code</code></p>\\n\\n\",\"hints\":[\"First hint\",\"Second hint\"],\"topicTags\":[{\"name\":\"Array\",\"slug\":\"array\"},{\"name\":\"Simulation\",\"slug\":\"simulation\"}],\"similarQuestions\":\"[{\\\"title\\\": \\\"Two Sum\\\", \\\"titleSlug\\\": \\\"two-sum\\\", \\\"difficulty\\\": \\\"Easy\\\", \\\"translatedTitle\\\": null}]\"}}}"),
 		nil,
 	).Times(1)
 	mockRequester.On("requestGraphQl", makeReq("test-title1")).Return([]byte("{\""), nil).Times(1)
@@ -193,6 +232,9 @@ func TestGetQuestionDetailsByTitleSlug(t *testing.T) {
 				{Name: "Array", Slug: "array"},
 				{Name: "Simulation", Slug: "simulation"},
 			},
+			SimilarQuestions: SimilarQuestions{
+				{Title: "Two Sum", TitleSlug: "two-sum", Difficulty: "Easy"},
+			},
 		}, nil},
 		{"test-title1", LeetCodeTask{}, tests.ErrWrongJSON},
 	}
@@ -208,6 +250,28 @@ func TestGetQuestionDetailsByTitleSlug(t *testing.T) {
 	mockRequester.AssertExpectations(t)
 }
 
+func TestSimilarQuestionsUnmarshal(t *testing.T) {
+	expected := SimilarQuestions{
+		{Title: "Two Sum", TitleSlug: "two-sum", Difficulty: "Easy"},
+		{Title: "3Sum", TitleSlug: "3sum", Difficulty: "Medium"},
+	}
+	testCases := map[string]SimilarQuestions{
+		"\"[{\\\"title\\\": \\\"Two Sum\\\", \\\"titleSlug\\\": \\\"two-sum\\\", \\\"difficulty\\\": \\\"Easy\\\", \\\"translatedTitle\\\": null}, {\\\"title\\\": \\\"3Sum\\\", \\\"titleSlug\\\": \\\"3sum\\\", \\\"difficulty\\\": \\\"Medium\\\", \\\"translatedTitle\\\": null}]\"": expected,
+		"[{\"title\":\"Two Sum\",\"titleSlug\":\"two-sum\",\"difficulty\":\"Easy\"},{\"title\":\"3Sum\",\"titleSlug\":\"3sum\",\"difficulty\":\"Medium\"}]":                                                                                                                              expected,
+		"\"[]\"": {},
+		"\"\"":   nil,
+	}
+	for source, result := range testCases {
+		questions := SimilarQuestions{}
+		err := json.Unmarshal([]byte(source), &questions)
+		assert.Nil(t, err, "Unexpected error")
+		assert.Equal(t, result, questions, "Unexpected similar questions after parse")
+	}
+	questions := SimilarQuestions{}
+	err := json.Unmarshal([]byte("\"[{\\\"title\\\"\""), &questions)
+	assert.Equal(t, tests.ErrWrongJSON.Error(), err.Error(), "Unexpected parse error")
+}
+
 type sliceDateLeetcodeTaskError struct {
 	date time.Time
 	task LeetCodeTask
@@ -220,7 +284,7 @@ func TestGetDailyTask(t *testing.T) {
 	client.transport = mockRequester
 	loc, _ := time.LoadLocation("America/Los_Angeles")
 	chaptersReq := client.getDailyQuestionsSlugsReq
-	chaptersReq.Variables = map[string]string{
+	chaptersReq.Variables = map[string]interface{}{
 		"year":  "1986",
 		"month": "4",
 	}
@@ -231,7 +295,7 @@ func TestGetDailyTask(t *testing.T) {
 		[]byte(""),
 		tests.ErrBypassTest,
 	).Times(1)
-	chaptersReq.Variables = map[string]string{
+	chaptersReq.Variables = map[string]interface{}{
 		"year":  "1995",
 		"month": "8",
 	}
@@ -244,7 +308,7 @@ func TestGetDailyTask(t *testing.T) {
 	).Times(2)
 	makeQuestionReq := func(slug string) graphQlRequest {
 		r := client.getQuestionReq
-		r.Variables = map[string]string{"titleSlug": slug}
+		r.Variables = map[string]interface{}{"titleSlug": slug}
 		return r
 	}
 	mockRequester.On("requestGraphQl", makeQuestionReq("test-title2")).Return(
@@ -280,13 +344,222 @@ func TestGetDailyTask(t *testing.T) {
 	mockRequester.AssertExpectations(t)
 }
 
+func TestGetQuestionsList(t *testing.T) {
+	client := NewLeetCodeGraphQlClient()
+	mockRequester := &MockRequester{}
+	client.transport = mockRequester
+	makeReq := func(filter QuestionsFilter, skip int, limit int) graphQlRequest {
+		r := client.getQuestionsListReq
+		r.Variables = map[string]interface{}{"categorySlug": "", "skip": skip, "limit": limit, "filters": filter}
+		return r
+	}
+	mockRequester.On("requestGraphQl", makeReq(QuestionsFilter{}, 0, 1)).Return([]byte{}, tests.ErrBypassTest).Times(1)
+	mockRequester.On("requestGraphQl", makeReq(QuestionsFilter{Difficulty: "EASY", Tags: []string{"array"}}, 10, 2)).Return(
+		[]byte("{\"data\":{\"problemsetQuestionList\":{\"total\":567,\"questions\":[{\"frontendQuestionId\":\"1\",\"title\":\"Two Sum\",\"titleSlug\":\"two-sum\",\"difficulty\":\"Easy\",\"paidOnly\":false,\"topicTags\":[{\"name\":\"Array\",\"slug\":\"array\"}]},{\"frontendQuestionId\":\"156\",\"title\":\"Binary Tree Upside Down\",\"titleSlug\":\"binary-tree-upside-down\",\"difficulty\":\"Medium\",\"paidOnly\":true,\"topicTags\":[]}]}}}"),
+		nil,
+	).Times(1)
+	mockRequester.On("requestGraphQl", makeReq(QuestionsFilter{}, 5, 5)).Return([]byte("{\""), nil).Times(1)
+
+	list, err := client.GetQuestionsList(context.Background(), QuestionsFilter{}, 0, 1)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
+	assert.Equal(t, QuestionsList{}, list, "Unexpected response")
+
+	list, err = client.GetQuestionsList(context.Background(), QuestionsFilter{Difficulty: "EASY", Tags: []string{"array"}}, 10, 2)
+	assert.Nil(t, err, "Unexpected error")
+	assert.Equal(t, QuestionsList{
+		Total: 567,
+		Questions: []QuestionsListItem{
+			{FrontendQuestionID: "1", Title: "Two Sum", TitleSlug: "two-sum", Difficulty: "Easy", TopicTags: []TopicTag{{Name: "Array", Slug: "array"}}},
+			{FrontendQuestionID: "156", Title: "Binary Tree Upside Down", TitleSlug: "binary-tree-upside-down", Difficulty: "Medium", PaidOnly: true, TopicTags: []TopicTag{}},
+		},
+	}, list, "Unexpected response")
+
+	_, err = client.GetQuestionsList(context.Background(), QuestionsFilter{}, 5, 5)
+	assert.Equal(t, tests.ErrWrongJSON.Error(), err.Error(), "Unexpected error")
+	mockRequester.AssertExpectations(t)
+}
+
+func TestSearchQuestions(t *testing.T) {
+	client := NewLeetCodeGraphQlClient()
+	mockRequester := &MockRequester{}
+	client.transport = mockRequester
+	paidOnly := false
+	makeReq := func(keywords string, skip int, limit int) graphQlRequest {
+		r := client.getQuestionsListReq
+		r.Variables = map[string]interface{}{"categorySlug": "", "skip": skip, "limit": limit, "filters": QuestionsFilter{SearchKeywords: keywords, PaidOnly: &paidOnly}}
+		return r
+	}
+	mockRequester.On("requestGraphQl", makeReq("two sum", 5, 5)).Return(
+		[]byte("{\"data\":{\"problemsetQuestionList\":{\"total\":6,\"questions\":[{\"frontendQuestionId\":\"167\",\"title\":\"Two Sum II - Input Array Is Sorted\",\"titleSlug\":\"two-sum-ii-input-array-is-sorted\",\"difficulty\":\"Medium\",\"paidOnly\":false}]}}}"),
+		nil,
+	).Times(1)
+	mockRequester.On("requestGraphQl", makeReq("42", 0, 5)).Return([]byte{}, tests.ErrBypassTest).Times(1)
+
+	list, err := client.SearchQuestions(context.Background(), "two-sum", 5, 5)
+	assert.Nil(t, err, "Unexpected error")
+	assert.Equal(t, QuestionsList{
+		Total: 6,
+		Questions: []QuestionsListItem{
+			{FrontendQuestionID: "167", Title: "Two Sum II - Input Array Is Sorted", TitleSlug: "two-sum-ii-input-array-is-sorted", Difficulty: "Medium"},
+		},
+	}, list, "Unexpected response")
+
+	_, err = client.SearchQuestions(context.Background(), " 42 ", 0, 5)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
+	mockRequester.AssertExpectations(t)
+}
+
+func TestGetRandomQuestion(t *testing.T) {
+	client := NewLeetCodeGraphQlClient()
+	mockRequester := &MockRequester{}
+	client.transport = mockRequester
+	client.randIntn = func(n int) int {
+		return n - 1
+	}
+	paidOnly := false
+	makeListReq := func(difficulty string, skip int, limit int) graphQlRequest {
+		r := client.getQuestionsListReq
+		r.Variables = map[string]interface{}{"categorySlug": "", "skip": skip, "limit": limit, "filters": QuestionsFilter{Difficulty: difficulty, PaidOnly: &paidOnly}}
+		return r
+	}
+	makeQuestionReq := func(slug string) graphQlRequest {
+		r := client.getQuestionReq
+		r.Variables = map[string]interface{}{"titleSlug": slug}
+		return r
+	}
+	mockRequester.On("requestGraphQl", makeListReq("EASY", 0, 1)).Return(
+		[]byte("{\"data\":{\"problemsetQuestionList\":{\"total\":3,\"questions\":[{\"frontendQuestionId\":\"1\",\"title\":\"Two Sum\",\"titleSlug\":\"two-sum\",\"difficulty\":\"Easy\",\"paidOnly\":false}]}}}"),
+		nil,
+	).Times(1)
+	mockRequester.On("requestGraphQl", makeListReq("EASY", 2, randomQuestionsPageSize)).Return(
+		[]byte("{\"data\":{\"problemsetQuestionList\":{\"total\":3,\"questions\":[{\"frontendQuestionId\":\"156\",\"title\":\"Binary Tree Upside Down\",\"titleSlug\":\"binary-tree-upside-down\",\"difficulty\":\"Easy\",\"paidOnly\":true},{\"frontendQuestionId\":\"9\",\"title\":\"Palindrome Number\",\"titleSlug\":\"palindrome-number\",\"difficulty\":\"Easy\",\"paidOnly\":false}]}}}"),
+		nil,
+	).Times(1)
+	mockRequester.On("requestGraphQl", makeQuestionReq("palindrome-number")).Return(
+		[]byte("{\"data\":{\"question\":{\"questionId\":\"9\",\"questionTitle\":\"Palindrome Number\",\"difficulty\":\"Easy\",\"content\":\"Test content\",\"hints\":[\"First hint\"]}}}"),
+		nil,
+	).Times(1)
+	mockRequester.On("requestGraphQl", makeListReq("HARD", 0, 1)).Return(
+		[]byte("{\"data\":{\"problemsetQuestionList\":{\"total\":0,\"questions\":[]}}}"),
+		nil,
+	).Times(1)
+	mockRequester.On("requestGraphQl", makeListReq("MEDIUM", 0, 1)).Return(
+		[]byte("{\"data\":{\"problemsetQuestionList\":{\"total\":1,\"questions\":[{\"frontendQuestionId\":\"156\",\"title\":\"Binary Tree Upside Down\",\"titleSlug\":\"binary-tree-upside-down\",\"difficulty\":\"Medium\",\"paidOnly\":true}]}}}"),
+		nil,
+	).Times(1)
+	mockRequester.On("requestGraphQl", makeListReq("MEDIUM", 0, randomQuestionsPageSize)).Return(
+		[]byte("{\"data\":{\"problemsetQuestionList\":{\"total\":1,\"questions\":[{\"frontendQuestionId\":\"156\",\"title\":\"Binary Tree Upside Down\",\"titleSlug\":\"binary-tree-upside-down\",\"difficulty\":\"Medium\",\"paidOnly\":true}]}}}"),
+		nil,
+	).Times(1)
+	mockRequester.On("requestGraphQl", makeListReq("", 0, 1)).Return([]byte{}, tests.ErrBypassTest).Times(1)
+
+	task, err := client.GetRandomQuestion(context.Background(), QuestionsFilter{Difficulty: "EASY"})
+	assert.Nil(t, err, "Unexpected error")
+	assert.Equal(t, LeetCodeTask{QuestionID: 9, TitleSlug: "palindrome-number", Title: "Palindrome Number", Difficulty: "Easy", Content: "Test content", Hints: []string{"First hint"}}, task, "Unexpected response")
+
+	task, err = client.GetRandomQuestion(context.Background(), QuestionsFilter{Difficulty: "HARD"})
+	assert.Equal(t, ErrNoSuchQuestions, err, "Unexpected error")
+	assert.Equal(t, LeetCodeTask{}, task, "Unexpected response")
+
+	task, err = client.GetRandomQuestion(context.Background(), QuestionsFilter{Difficulty: "MEDIUM"})
+	assert.Equal(t, ErrNoSuchQuestions, err, "Unexpected error")
+	assert.Equal(t, LeetCodeTask{}, task, "Unexpected response")
+
+	task, err = client.GetRandomQuestion(context.Background(), QuestionsFilter{})
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
+	assert.Equal(t, LeetCodeTask{}, task, "Unexpected response")
+	mockRequester.AssertExpectations(t)
+}
+
+func readGraphQlFixture(t *testing.T, name string) []byte {
+	fixture, err := os.ReadFile(path.Join("../../tests/data", name))
+	assert.Nil(t, err, "Unexpected error on reading fixture")
+	return fixture
+}
+
+func TestGetUserProfile(t *testing.T) {
+	client := NewLeetCodeGraphQlClient()
+	mockRequester := &MockRequester{}
+	client.transport = mockRequester
+	makeReq := func(username string) graphQlRequest {
+		r := client.getUserProfileReq
+		r.Variables = map[string]interface{}{"username": username}
+		return r
+	}
+	mockRequester.On("requestGraphQl", makeReq("leetcoder")).Return(readGraphQlFixture(t, "graphql_matchedUser.json"), nil).Times(1)
+	mockRequester.On("requestGraphQl", makeReq("nobody")).Return(readGraphQlFixture(t, "graphql_matchedUser_not_found.json"), nil).Times(1)
+	mockRequester.On("requestGraphQl", makeReq("broken")).Return([]byte("{\""), nil).Times(1)
+	mockRequester.On("requestGraphQl", makeReq("error")).Return([]byte{}, tests.ErrBypassTest).Times(1)
+
+	profile, err := client.GetUserProfile(context.Background(), "leetcoder")
+	assert.Nil(t, err, "Unexpected error")
+	assert.Equal(t, "leetcoder", profile.Username, "Unexpected username")
+	assert.Equal(t, 12, profile.Streak, "Unexpected streak")
+	for difficulty, count := range map[string]int{"All": 412, "Easy": 153, "medium": 214, "HARD": 45, "Unknown": 0} {
+		assert.Equalf(t, count, profile.GetSolvedCount(difficulty), "Unexpected solved count for %s", difficulty)
+	}
+
+	_, err = client.GetUserProfile(context.Background(), "nobody")
+	assert.Equal(t, ErrNoSuchUser, err, "Unexpected error")
+	_, err = client.GetUserProfile(context.Background(), "broken")
+	assert.Equal(t, tests.ErrWrongJSON.Error(), err.Error(), "Unexpected error")
+	_, err = client.GetUserProfile(context.Background(), "error")
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
+	mockRequester.AssertExpectations(t)
+}
+
+func TestGetRecentAcceptedSubmissions(t *testing.T) {
+	client := NewLeetCodeGraphQlClient()
+	mockRequester := &MockRequester{}
+	client.transport = mockRequester
+	makeReq := func(username string, limit int) graphQlRequest {
+		r := client.getRecentSubmissionsReq
+		r.Variables = map[string]interface{}{"username": username, "limit": limit}
+		return r
+	}
+	mockRequester.On("requestGraphQl", makeReq("leetcoder", RecentSubmissionsLimit)).Return(readGraphQlFixture(t, "graphql_recentAcSubmissionList.json"), nil).Times(1)
+	mockRequester.On("requestGraphQl", makeReq("error", 1)).Return([]byte{}, tests.ErrBypassTest).Times(1)
+
+	submissions, err := client.GetRecentAcceptedSubmissions(context.Background(), "leetcoder", RecentSubmissionsLimit)
+	assert.Nil(t, err, "Unexpected error")
+	assert.Equal(t, []AcceptedSubmission{
+		{ID: "1079553611", Title: "Two Sum", TitleSlug: "two-sum", Timestamp: 1696118400},
+		{ID: "1079001234", Title: "Trapping Rain Water", TitleSlug: "trapping-rain-water", Timestamp: 1696032000},
+	}, submissions, "Unexpected submissions")
+
+	_, err = client.GetRecentAcceptedSubmissions(context.Background(), "error", 1)
+	assert.Equal(t, tests.ErrBypassTest, err, "Unexpected error")
+	mockRequester.AssertExpectations(t)
+}
+
+func TestHasAcceptedSubmission(t *testing.T) {
+	submissions := []AcceptedSubmission{
+		{TitleSlug: "two-sum", Timestamp: 1696118400},
+		{TitleSlug: "trapping-rain-water", Timestamp: 1696032000},
+	}
+	assert.True(t, HasAcceptedSubmission(submissions, "two-sum", time.Unix(1696118400, 0)), "Submission at the since moment should count")
+	assert.False(t, HasAcceptedSubmission(submissions, "trapping-rain-water", time.Unix(1696118400, 0)), "Old submission shouldn't count")
+	assert.False(t, HasAcceptedSubmission(submissions, "3sum", time.Unix(0, 0)), "Unexpected submission found")
+	assert.False(t, HasAcceptedSubmission(nil, "two-sum", time.Unix(0, 0)), "Unexpected submission found in empty list")
+}
+
 func TestNewLeetCodeGraphQlClient(t *testing.T) {
 	client := NewLeetCodeGraphQlClient()
 	assert.NotEmpty(t, client.getDailyQuestionsSlugsReq.OperationName, "getChaptersReq.OperationName must be configured in constructor")
 	assert.NotEmpty(t, client.getQuestionReq.OperationName, "getQuestionReq.OperationName must be configured in constructor")
+	assert.NotEmpty(t, client.getQuestionsListReq.OperationName, "getQuestionsListReq.OperationName must be configured in constructor")
+	assert.NotEmpty(t, client.getUserProfileReq.OperationName, "getUserProfileReq.OperationName must be configured in constructor")
+	assert.NotEmpty(t, client.getRecentSubmissionsReq.OperationName, "getRecentSubmissionsReq.OperationName must be configured in constructor")
+	assert.NotNil(t, client.randIntn, "randIntn must be configured in constructor")
 	assert.NotNil(t, client.transport, "transport must be configured in constructor")
 }
 
+func TestNewLeetCodeGraphQlClientWithURL(t *testing.T) {
+	client := NewLeetCodeGraphQlClientWithURL("http://localhost/graphql")
+	requester := client.transport.(*breakingRequester).requester.(*httpGraphQlRequester)
+	assert.Equal(t, "http://localhost/graphql", requester.GraphQlURL, "GraphQlURL must be set in constructor")
+}
+
 func TestNewLeetCodeGraphQlClientLocal(t *testing.T) {
 	client := newLeetCodeGraphQlClient(nil)
 	assert.Nil(t, client.transport, "transport must be set in private constructor")
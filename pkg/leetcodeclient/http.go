@@ -4,16 +4,37 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
+	"time"
+
+	"github.com/dartkron/leetcodeBot/v3/pkg/tracing"
 )
 
+// DefaultGraphQlURL is the LeetCode GraphQL API URL
+const DefaultGraphQlURL = "https://leetcode.com/graphql"
+
 type httpGraphQlRequester struct {
 	GraphQlURL string
 	HTTPClient *http.Client
 }
 
 func (requester *httpGraphQlRequester) requestGraphQl(ctx context.Context, request graphQlRequest) ([]byte, error) {
+	ctx, span := tracing.Start(ctx, "leetcode.requestGraphQl", "operation", request.OperationName)
+	defer span.End()
+	start := time.Now()
+	body, err := requester.doRequest(ctx, request)
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+		span.RecordError(err)
+	}
+	requestDuration.WithLabelValues(request.OperationName, outcome).Observe(time.Since(start).Seconds())
+	return body, err
+}
+
+func (requester *httpGraphQlRequester) doRequest(ctx context.Context, request graphQlRequest) ([]byte, error) {
 	bodyBuffer := new(bytes.Buffer)
 	err := json.NewEncoder(bodyBuffer).Encode(request)
 	if err != nil {
@@ -25,12 +46,17 @@ func (requester *httpGraphQlRequester) requestGraphQl(ctx context.Context, reque
 	req.Header.Add("user-agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36 Edg/122.0.0.0")
 	req.Header.Add("x-csrftoken", "AhmTr4FHZa0sXagk4bEdWYRr50fK5BKa1a0F2ybPwBpoEWuNhKNKmMWlfaCjgCuq") // FIXME: (dartkron): This is a temporary solution and stays valid only for a year, needs to be replaced with getCsrfToken() function
 	req.Header.Add("accept", "*/*")
+	tracing.InjectHeaders(ctx, req.Header)
 
 	response, err := requester.HTTPClient.Do(req)
 
 	if err != nil {
 		return []byte{}, err
 	}
+	defer response.Body.Close()
+	if response.StatusCode >= http.StatusInternalServerError {
+		return []byte{}, fmt.Errorf("LeetCode responded with status %d", response.StatusCode)
+	}
 	return io.ReadAll(response.Body)
 }
 
@@ -39,7 +65,7 @@ func newHTTPGraphQlRequester(httpClient *http.Client) *httpGraphQlRequester {
 		httpClient = &http.Client{}
 	}
 	return &httpGraphQlRequester{
-		GraphQlURL: "https://leetcode.com/graphql",
+		GraphQlURL: DefaultGraphQlURL,
 		HTTPClient: httpClient,
 	}
 }
@@ -2,15 +2,22 @@ package leetcodeclient
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
 	"testing"
 
+	"github.com/dartkron/leetcodeBot/v3/pkg/tracing"
 	"github.com/dartkron/leetcodeBot/v3/tests"
 	"github.com/dartkron/leetcodeBot/v3/tests/mocks"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 type testRequest struct {
@@ -22,6 +29,8 @@ type testRequest struct {
 func TestRequestGraphQl(t *testing.T) {
 	httpTransportMock := &mocks.MockHTTPTransport{}
 	requester := newHTTPGraphQlRequester(&http.Client{Transport: httpTransportMock})
+	succeeded := tests.HistogramCount(requestDuration.WithLabelValues("Test operation", "ok"))
+	failed := tests.HistogramCount(requestDuration.WithLabelValues("", "error"))
 
 	expected_headers := http.Header{
 		"Content-Type": []string{"application/json"},
@@ -76,11 +85,21 @@ func TestRequestGraphQl(t *testing.T) {
 		nil,
 	).Times(1)
 
+	httpTransportMock.On(
+		"RoundTrip",
+		"https://leetcode.com/graphql",
+		expected_headers,
+		"{\"operationName\":\"\",\"variables\":null,\"query\":\"Query for server error\"}\n",
+	).Return(
+		&http.Response{StatusCode: 502, Body: io.NopCloser(strings.NewReader("Bad Gateway"))},
+		nil,
+	).Times(1)
+
 	testCases := []testRequest{
 		{
 			req: graphQlRequest{
 				OperationName: "Test operation",
-				Variables:     map[string]string{"testVariableName": "testVariableVal"},
+				Variables:     map[string]interface{}{"testVariableName": "testVariableVal"},
 				Query:         "Some test query",
 			},
 			resp: "0",
@@ -92,10 +111,17 @@ func TestRequestGraphQl(t *testing.T) {
 			resp: "",
 			err:  &url.Error{Op: "Post", URL: "https://leetcode.com/graphql", Err: tests.ErrBypassTest},
 		},
+		{
+			req: graphQlRequest{
+				Query: "Query for server error",
+			},
+			resp: "",
+			err:  fmt.Errorf("LeetCode responded with status 502"),
+		},
 		{
 			req: graphQlRequest{
 				OperationName: "Test operation no variables",
-				Variables:     map[string]string{},
+				Variables:     map[string]interface{}{},
 				Query:         "Some test query1",
 			},
 			resp: "1",
@@ -103,7 +129,7 @@ func TestRequestGraphQl(t *testing.T) {
 		{
 			req: graphQlRequest{
 				OperationName: "Test operation two variables",
-				Variables:     map[string]string{"testVariableName": "testVariableVal", "testVariableName1": "testVariableVal1"},
+				Variables:     map[string]interface{}{"testVariableName": "testVariableVal", "testVariableName1": "testVariableVal1"},
 				Query:         "Some test query2",
 			},
 			resp: "2",
@@ -111,7 +137,7 @@ func TestRequestGraphQl(t *testing.T) {
 		{
 			req: graphQlRequest{
 				OperationName: "Test operation three variables",
-				Variables:     map[string]string{"testVariableName": "testVariableVal", "testVariableName1": "testVariableVal1", "testVariableName2": "testVariableVal2"},
+				Variables:     map[string]interface{}{"testVariableName": "testVariableVal", "testVariableName1": "testVariableVal1", "testVariableName2": "testVariableVal2"},
 				Query:         "Some test query3",
 			},
 			resp: "3",
@@ -124,6 +150,36 @@ func TestRequestGraphQl(t *testing.T) {
 		assert.Equal(t, string(resp), testCase.resp)
 	}
 	httpTransportMock.AssertExpectations(t)
+	assert.Equal(t, succeeded+1, tests.HistogramCount(requestDuration.WithLabelValues("Test operation", "ok")), "Request duration should be observed by operation")
+	assert.Equal(t, failed+2, tests.HistogramCount(requestDuration.WithLabelValues("", "error")), "Failed requests should be observed")
+}
+
+func TestRequestGraphQlTraceparent(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracing.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer tracing.SetTracerProvider(nil)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	httpTransportMock := &mocks.MockHTTPTransport{}
+	requester := newHTTPGraphQlRequester(&http.Client{Transport: httpTransportMock})
+	traceparents := []string{}
+	httpTransportMock.On(
+		"RoundTrip",
+		"https://leetcode.com/graphql",
+		mock.MatchedBy(func(header http.Header) bool {
+			traceparents = append(traceparents, header.Get("traceparent"))
+			return true
+		}),
+		mock.Anything,
+	).Return(&http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("0"))}, nil).Once()
+	_, err := requester.requestGraphQl(context.Background(), graphQlRequest{OperationName: "Traced operation"})
+	assert.Nil(t, err, "Unexpected requestGraphQl error")
+	httpTransportMock.AssertExpectations(t)
+
+	spans := exporter.GetSpans()
+	if assert.Len(t, spans, 1, "Request should have the span") && assert.NotEmpty(t, traceparents, "Request should be sent") {
+		span := spans[0].SpanContext
+		assert.Equal(t, "00-"+span.TraceID().String()+"-"+span.SpanID().String()+"-01", traceparents[len(traceparents)-1], "Request should have traceparent of the span")
+	}
 }
 
 func TestNewHTTPGraphQlRequester(t *testing.T) {
@@ -0,0 +1,13 @@
+package leetcodeclient
+
+import (
+	"github.com/dartkron/leetcodeBot/v3/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// requestDuration is the duration of GraphQL requests by operation and outcome, "ok" or "error"
+var requestDuration = metrics.Factory.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "leetcode_request_duration_seconds",
+	Help:    "Duration of LeetCode GraphQL requests",
+	Buckets: prometheus.DefBuckets,
+}, []string{"operation", "outcome"})
@@ -20,6 +20,12 @@ func (m *MockLeetcodeClient) GetDailyQuestionSlug(ctx context.Context, date time
 	return args.String(0), args.Error(1)
 }
 
+// GetMonthlyChallenges use dateID strings to match responses
+func (m *MockLeetcodeClient) GetMonthlyChallenges(ctx context.Context, date time.Time) ([]leetcodeclient.DailyChallenge, error) {
+	args := m.Called(common.GetDateID(date))
+	return args.Get(0).([]leetcodeclient.DailyChallenge), args.Error(1)
+}
+
 // GetQuestionDetailsByTitleSlug mock function meets the interface
 func (m *MockLeetcodeClient) GetQuestionDetailsByTitleSlug(ctx context.Context, questionSlug string) (leetcodeclient.LeetCodeTask, error) {
 	args := m.Called(questionSlug)
@@ -31,3 +37,33 @@ func (m *MockLeetcodeClient) GetDailyTask(ctx context.Context, date time.Time) (
 	args := m.Called(common.GetDateID(date))
 	return args.Get(0).(leetcodeclient.LeetCodeTask), args.Error(1)
 }
+
+// GetQuestionsList mock function meets the interface
+func (m *MockLeetcodeClient) GetQuestionsList(ctx context.Context, filter leetcodeclient.QuestionsFilter, skip int, limit int) (leetcodeclient.QuestionsList, error) {
+	args := m.Called(filter, skip, limit)
+	return args.Get(0).(leetcodeclient.QuestionsList), args.Error(1)
+}
+
+// GetRandomQuestion mock function meets the interface
+func (m *MockLeetcodeClient) GetRandomQuestion(ctx context.Context, filter leetcodeclient.QuestionsFilter) (leetcodeclient.LeetCodeTask, error) {
+	args := m.Called(filter)
+	return args.Get(0).(leetcodeclient.LeetCodeTask), args.Error(1)
+}
+
+// SearchQuestions mock function meets the interface
+func (m *MockLeetcodeClient) SearchQuestions(ctx context.Context, keywords string, skip int, limit int) (leetcodeclient.QuestionsList, error) {
+	args := m.Called(keywords, skip, limit)
+	return args.Get(0).(leetcodeclient.QuestionsList), args.Error(1)
+}
+
+// GetUserProfile mock function meets the interface
+func (m *MockLeetcodeClient) GetUserProfile(ctx context.Context, username string) (leetcodeclient.UserProfile, error) {
+	args := m.Called(username)
+	return args.Get(0).(leetcodeclient.UserProfile), args.Error(1)
+}
+
+// GetRecentAcceptedSubmissions mock function meets the interface
+func (m *MockLeetcodeClient) GetRecentAcceptedSubmissions(ctx context.Context, username string, limit int) ([]leetcodeclient.AcceptedSubmission, error) {
+	args := m.Called(username, limit)
+	return args.Get(0).([]leetcodeclient.AcceptedSubmission), args.Error(1)
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// Default is the registry of the application metrics. It's separate from the global one of Prometheus,
+// so only the application metrics are pushed from serverless functions
+var Default = prometheus.NewRegistry()
+
+// Factory registers new metrics in Default registry
+var Factory = promauto.With(Default)
+
+// Handler serves metrics of Default registry to Prometheus scrapes on /metrics
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Default, promhttp.HandlerOpts{})
+}
+
+// Push replaces metrics of the job in Prometheus Pushgateway, as serverless functions can't be scraped.
+// http.DefaultClient is used if the client is nil
+func Push(ctx context.Context, httpClient *http.Client, pushgatewayURL string, job string) error {
+	pusher := push.New(pushgatewayURL, job).Gatherer(Default)
+	if httpClient != nil {
+		pusher = pusher.Client(httpClient)
+	}
+	return pusher.PushContext(ctx)
+}
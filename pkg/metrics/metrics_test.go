@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+var testUpdatesTotal = Factory.NewCounterVec(prometheus.CounterOpts{Name: "test_updates_total", Help: "Updates by command"}, []string{"command"})
+
+func TestHandler(t *testing.T) {
+	testUpdatesTotal.WithLabelValues("/task").Inc()
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code, "Unexpected status")
+	assert.Contains(t, recorder.Body.String(), "# TYPE test_updates_total counter\n", "Metrics should be served")
+	assert.Contains(t, recorder.Body.String(), "test_updates_total{command=\"/task\"}", "Metrics should be served")
+}
+
+func TestPush(t *testing.T) {
+	testUpdatesTotal.WithLabelValues("/task").Inc()
+	requests := []string{}
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		resp.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	err := Push(context.Background(), server.Client(), server.URL, "reminder")
+	assert.Nil(t, err, "Unexpected Push error")
+	assert.Equal(t, []string{"PUT /metrics/job/reminder"}, requests, "Unexpected push request")
+	status = http.StatusBadRequest
+	err = Push(context.Background(), nil, server.URL, "reminder")
+	assert.NotNil(t, err, "Pushgateway errors should be returned")
+}
@@ -0,0 +1,86 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Names of exporters for NewExporter
+const (
+	ExporterNone   = ""
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// DefaultOTLPEndpoint is the OTLP/HTTP endpoint of the local OpenTelemetry Collector
+const DefaultOTLPEndpoint = "http://localhost:4318"
+
+// ServiceName is the service.name resource attribute of exported spans
+const ServiceName = "leetcodebot"
+
+// NewExporter returns exporter by the name, nil for ExporterNone. Endpoint is used only by OTLP exporter
+func NewExporter(name string, endpoint string) (sdktrace.SpanExporter, error) {
+	switch strings.ToLower(name) {
+	case ExporterNone:
+		return nil, nil
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		if endpoint == "" {
+			endpoint = DefaultOTLPEndpoint
+		}
+		return otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(endpoint))
+	}
+	return nil, fmt.Errorf("unknown tracing exporter %q, use %s or %s", name, ExporterStdout, ExporterOTLP)
+}
+
+// NewTracerProvider constructs the provider exporting spans of the service in batches
+func NewTracerProvider(exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", ServiceName))),
+	)
+}
+
+var (
+	setupConfig string
+	setupMutex  sync.Mutex
+)
+
+// Setup sets the provider with the exporter by the name and W3C trace context propagation, tracing is disabled for ExporterNone.
+// Serverless functions call it on every invocation, so the provider of the same config is kept with its batch
+func Setup(exporterName string, endpoint string) error {
+	setupMutex.Lock()
+	defer setupMutex.Unlock()
+	config := strings.ToLower(exporterName) + " " + endpoint
+	if getTracerProvider() != nil && config == setupConfig {
+		return nil
+	}
+	exporter, err := NewExporter(exporterName, endpoint)
+	if err != nil {
+		return err
+	}
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	previous := getTracerProvider()
+	setupConfig = config
+	if exporter == nil {
+		SetTracerProvider(nil)
+	} else {
+		SetTracerProvider(NewTracerProvider(exporter))
+	}
+	if previous != nil {
+		return previous.Shutdown(context.Background())
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+)
+
+func TestNewExporter(t *testing.T) {
+	exporter, err := NewExporter(ExporterNone, "")
+	assert.Nil(t, err, "Unexpected NewExporter error")
+	assert.Nil(t, exporter, "Tracing should be disabled by default")
+	exporter, _ = NewExporter("STDOUT", "")
+	assert.IsType(t, &stdouttrace.Exporter{}, exporter, "Unexpected stdout exporter")
+	exporter, _ = NewExporter(ExporterOTLP, "")
+	assert.IsType(t, &otlptrace.Exporter{}, exporter, "Unexpected OTLP exporter")
+	_, err = NewExporter("jaeger", "")
+	assert.EqualError(t, err, "unknown tracing exporter \"jaeger\", use stdout or otlp", "Unknown exporter should be reported")
+}
+
+func TestSetup(t *testing.T) {
+	defer SetTracerProvider(nil)
+	assert.Nil(t, Setup(ExporterStdout, ""), "Unexpected Setup error")
+	provider := getTracerProvider()
+	assert.NotNil(t, provider, "Provider should be set")
+	assert.Nil(t, Setup(ExporterStdout, ""), "Unexpected Setup error")
+	assert.Same(t, provider, getTracerProvider(), "Provider of the same config should be kept")
+	assert.Nil(t, Setup(ExporterNone, ""), "Unexpected Setup error")
+	assert.Nil(t, getTracerProvider(), "Tracing should be disabled")
+	assert.NotNil(t, Setup("jaeger", ""), "Unknown exporter should be reported")
+}
+
+func TestOTLPExport(t *testing.T) {
+	defer SetTracerProvider(nil)
+	requests := []string{}
+	requestsMutex := sync.Mutex{}
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		requestsMutex.Lock()
+		defer requestsMutex.Unlock()
+		requests = append(requests, req.Method+" "+req.URL.Path+" "+req.Header.Get("Content-Type"))
+	}))
+	defer server.Close()
+
+	assert.Nil(t, Setup(ExporterOTLP, server.URL), "Unexpected Setup error")
+	_, span := Start(context.Background(), "storage.GetTask", "dateID", uint64(20260101))
+	span.End()
+	assert.Empty(t, requests, "Spans should be exported in batches")
+	assert.Nil(t, Shutdown(context.Background()), "Unexpected Shutdown error")
+	assert.Equal(t, []string{"POST /v1/traces application/x-protobuf"}, requests, "Spans should be exported on shutdown")
+	assert.Nil(t, getTracerProvider(), "Tracing should be disabled after shutdown")
+}
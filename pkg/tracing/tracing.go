@@ -0,0 +1,156 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// scopeName is the instrumentation scope of the application spans
+const scopeName = "github.com/dartkron/leetcodeBot/v3/pkg/tracing"
+
+// Span measures one operation. Methods of nil Span do nothing, so code is the same with disabled tracing
+type Span struct {
+	span trace.Span
+}
+
+// toAttribute converts the value to OpenTelemetry attribute, unknown types are formatted as strings
+func toAttribute(key string, value interface{}) attribute.KeyValue {
+	switch typed := value.(type) {
+	case bool:
+		return attribute.Bool(key, typed)
+	case string:
+		return attribute.String(key, typed)
+	case int:
+		return attribute.Int(key, typed)
+	case int8:
+		return attribute.Int(key, int(typed))
+	case int16:
+		return attribute.Int(key, int(typed))
+	case int32:
+		return attribute.Int(key, int(typed))
+	case int64:
+		return attribute.Int64(key, typed)
+	case uint8:
+		return attribute.Int(key, int(typed))
+	case uint16:
+		return attribute.Int(key, int(typed))
+	case uint32:
+		return attribute.Int64(key, int64(typed))
+	case uint:
+		if uint64(typed) <= math.MaxInt64 {
+			return attribute.Int64(key, int64(typed))
+		}
+	case uint64:
+		if typed <= math.MaxInt64 {
+			return attribute.Int64(key, int64(typed))
+		}
+	case float32:
+		return attribute.Float64(key, float64(typed))
+	case float64:
+		return attribute.Float64(key, typed)
+	}
+	return attribute.String(key, fmt.Sprint(value))
+}
+
+func toAttributes(args []interface{}) []attribute.KeyValue {
+	attributes := make([]attribute.KeyValue, 0, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		attributes = append(attributes, toAttribute(fmt.Sprint(args[i]), args[i+1]))
+	}
+	return attributes
+}
+
+// SetAttributes adds key-value pairs to the span, like slog attributes
+func (s *Span) SetAttributes(args ...interface{}) {
+	if s == nil {
+		return
+	}
+	s.span.SetAttributes(toAttributes(args)...)
+}
+
+// RecordError marks the span as failed, nil errors are ignored
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End finishes the span, the second call does nothing
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.span.End()
+}
+
+// TraceID returns the hex ID of the trace, it's empty for nil Span
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.span.SpanContext().TraceID().String()
+}
+
+var (
+	globalProvider *sdktrace.TracerProvider
+	globalMutex    sync.RWMutex
+)
+
+// SetTracerProvider sets the provider used by Start and Flush, nil disables tracing
+func SetTracerProvider(provider *sdktrace.TracerProvider) {
+	globalMutex.Lock()
+	defer globalMutex.Unlock()
+	globalProvider = provider
+}
+
+func getTracerProvider() *sdktrace.TracerProvider {
+	globalMutex.RLock()
+	defer globalMutex.RUnlock()
+	return globalProvider
+}
+
+// Start starts the span as a child of the context span and returns the context with it. Without the provider it returns nil Span
+func Start(ctx context.Context, name string, args ...interface{}) (context.Context, *Span) {
+	provider := getTracerProvider()
+	if provider == nil {
+		return ctx, nil
+	}
+	ctx, span := provider.Tracer(scopeName).Start(ctx, name, trace.WithAttributes(toAttributes(args)...))
+	return ctx, &Span{span: span}
+}
+
+// Flush exports all ended spans. Serverless functions should call it before returning, as they could be frozen
+func Flush(ctx context.Context) error {
+	provider := getTracerProvider()
+	if provider == nil {
+		return nil
+	}
+	return provider.ForceFlush(ctx)
+}
+
+// Shutdown exports all ended spans and stops the provider, tracing is disabled after it
+func Shutdown(ctx context.Context) error {
+	provider := getTracerProvider()
+	if provider == nil {
+		return nil
+	}
+	SetTracerProvider(nil)
+	return provider.Shutdown(ctx)
+}
+
+// InjectHeaders adds W3C traceparent of the context span to headers of the outgoing request
+func InjectHeaders(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
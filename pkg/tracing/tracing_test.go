@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/dartkron/leetcodeBot/v3/tests"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func setTestProvider() *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	return exporter
+}
+
+func TestSpans(t *testing.T) {
+	exporter := setTestProvider()
+	defer SetTracerProvider(nil)
+	ctx, parent := Start(context.Background(), "parent", "updateID", 100500)
+	_, child := Start(ctx, "child")
+	child.SetAttributes("dateID", uint64(20260101), "slug", "two-sum", "ratio", 0.5, "cached", true, "odd")
+	child.RecordError(nil)
+	child.RecordError(tests.ErrBypassTest)
+	child.End()
+	child.End()
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if assert.Len(t, spans, 2, "Every span should be exported once") {
+		childData, parentData := spans[0], spans[1]
+		assert.Equal(t, "child", childData.Name, "Unexpected span name")
+		assert.Equal(t, parent.TraceID(), childData.SpanContext.TraceID().String(), "Child should have the trace of the parent")
+		assert.Equal(t, parentData.SpanContext.SpanID(), childData.Parent.SpanID(), "Child should have the parent")
+		assert.False(t, parentData.Parent.IsValid(), "Root span shouldn't have the parent")
+		assert.Equal(t, []attribute.KeyValue{attribute.Int("updateID", 100500)}, parentData.Attributes, "Unexpected attributes")
+		assert.Equal(t, []attribute.KeyValue{
+			attribute.Int64("dateID", 20260101),
+			attribute.String("slug", "two-sum"),
+			attribute.Float64("ratio", 0.5),
+			attribute.Bool("cached", true),
+		}, childData.Attributes, "Unexpected attributes")
+		assert.Equal(t, sdktrace.Status{Code: codes.Error, Description: tests.ErrBypassTest.Error()}, childData.Status, "Error should be recorded")
+		assert.Equal(t, codes.Unset, parentData.Status.Code, "Parent shouldn't fail")
+	}
+}
+
+func TestDisabledTracing(t *testing.T) {
+	ctx, span := Start(context.Background(), "disabled")
+	assert.Nil(t, span, "Span shouldn't be started without the provider")
+	assert.Equal(t, context.Background(), ctx, "Context shouldn't be changed without the provider")
+	span.SetAttributes("key", "value")
+	span.RecordError(tests.ErrBypassTest)
+	span.End()
+	assert.Empty(t, span.TraceID(), "Nil span shouldn't have trace ID")
+	assert.Nil(t, Flush(context.Background()), "Flush without the provider should do nothing")
+	assert.Nil(t, Shutdown(context.Background()), "Shutdown without the provider should do nothing")
+}
+
+func TestInjectHeaders(t *testing.T) {
+	setTestProvider()
+	defer SetTracerProvider(nil)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	header := http.Header{}
+	InjectHeaders(context.Background(), header)
+	assert.Empty(t, header, "Headers shouldn't be added without the span")
+
+	ctx, span := Start(context.Background(), "request")
+	defer span.End()
+	InjectHeaders(ctx, header)
+	assert.Regexp(t, "^00-"+span.TraceID()+"-[0-9a-f]{16}-01$", header.Get("traceparent"), "W3C traceparent should be added")
+}
+
+func TestToAttribute(t *testing.T) {
+	assert.Equal(t, attribute.Int("hour", 5), toAttribute("hour", uint8(5)), "Small integers should be ints")
+	assert.Equal(t, attribute.String("id", "18446744073709551615"), toAttribute("id", uint64(18446744073709551615)), "Too big integers should be strings")
+	assert.Equal(t, attribute.String("weekdays", "[1 2]"), toAttribute("weekdays", []int{1, 2}), "Unknown types should be strings")
+}
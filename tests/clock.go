@@ -0,0 +1,38 @@
+package tests
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeClock is a clock for tests, which stays at the set time till it's moved
+type FakeClock struct {
+	now   time.Time
+	mutex sync.Mutex
+}
+
+// Now returns the current fake time
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// Set moves the clock to the time
+func (c *FakeClock) Set(now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = now
+}
+
+// Add moves the clock forward by the duration
+func (c *FakeClock) Add(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// NewFakeClock constructs FakeClock set to the time
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
@@ -0,0 +1,15 @@
+package tests
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// HistogramCount returns the number of observations of the histogram
+func HistogramCount(observer prometheus.Observer) uint64 {
+	metric := &dto.Metric{}
+	if histogram, ok := observer.(prometheus.Metric); !ok || histogram.Write(metric) != nil {
+		return 0
+	}
+	return metric.GetHistogram().GetSampleCount()
+}